@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+// aptCacheMount is where a build finds its dependency cache inside the
+// pbocker container. buildPath is mounted on /tmp/build.
+const aptCacheMount = "/tmp/build/aptcache"
+
+// aptCaches holds the dependency cache of every architecture this builder
+// builds for.
+var aptCaches map[string]*aptCache
+
+// aptCacheCounters adds up what the builds took from their cache and what
+// they had to download.
+var aptCacheCounters struct {
+	hitBytes  atomic.Uint64
+	missBytes atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// aptCache is a pool of the .deb files build dependencies are installed
+// from. Builds never write to the pool: each one gets a directory of its
+// own, seeded with hard links to the pooled packages, and the packages it
+// downloaded are linked back into the pool once it succeeded. Files only
+// enter or leave the pool whole, so concurrent builds never see a partial
+// package.
+type aptCache struct {
+	dir   string
+	limit int64 // Bytes, 0 for no limit
+
+	mu       sync.Mutex
+	size     int64
+	packages int
+}
+
+// newAptCaches opens the dependency cache of every architecture under the
+// builder workdir.
+func newAptCaches() (map[string]*aptCache, error) {
+	limit := int64(irgshConfig.Builder.AptCacheSize) * 1024 * 1024
+	caches := make(map[string]*aptCache)
+	for _, arch := range builderArchitectures() {
+		c, err := newAptCache(filepath.Join(irgshConfig.Builder.Workdir, "aptcache", arch), limit)
+		if err != nil {
+			return nil, err
+		}
+		caches[arch] = c
+	}
+	return caches, nil
+}
+
+func newAptCache(dir string, limit int64) (*aptCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &aptCache{dir: dir, limit: limit}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.evict(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// debs lists the packages in dir.
+func debs(dir string) ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ".deb") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// seed fills the cache directory of a build with the pooled packages.
+func (c *aptCache) seed(dst string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	pooled, err := debs(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range pooled {
+		err := os.Link(filepath.Join(c.dir, info.Name()), filepath.Join(dst, info.Name()))
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// store adds the packages a build downloaded to the pool, then evicts the
+// packages pooled first to stay under the size limit.
+func (c *aptCache) store(src string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	downloaded, err := debs(src)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, info := range downloaded {
+		pooled := filepath.Join(c.dir, info.Name())
+		err := os.Link(filepath.Join(src, info.Name()), pooled)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// apt keeps the Last-Modified time of the archive as mtime, stamp
+		// the package with the time it entered the pool instead
+		if err := os.Chtimes(pooled, now, now); err != nil {
+			return err
+		}
+	}
+	return c.evict()
+}
+
+// evict removes the packages that entered the pool first, going by the
+// mtime store stamped them with, until it fits the size limit, and updates
+// the pool size. It is called with mu held.
+func (c *aptCache) evict() error {
+	pooled, err := debs(c.dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(pooled, func(i, j int) bool {
+		return pooled[i].ModTime().Before(pooled[j].ModTime())
+	})
+
+	var size int64
+	for _, info := range pooled {
+		size += info.Size()
+	}
+	kept := len(pooled)
+	for _, info := range pooled {
+		if c.limit <= 0 || size <= c.limit {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, info.Name())); err != nil {
+			return err
+		}
+		size -= info.Size()
+		kept--
+		aptCacheCounters.evictions.Add(1)
+	}
+	c.size, c.packages = size, kept
+	return nil
+}
+
+// aptCacheOpts returns the pbuilder options pointing a build to its
+// dependency cache and to the caching proxy, if any.
+func aptCacheOpts(cached bool) string {
+	var opts []string
+	if cached {
+		opts = append(opts, "--aptcache", aptCacheMount)
+	}
+	if irgshConfig.Builder.AptProxy != "" {
+		opts = append(opts, "--http-proxy", irgshConfig.Builder.AptProxy)
+	}
+	return strings.Join(opts, " ")
+}
+
+// aptFetchPattern matches the lines apt prints before it installs packages,
+// "Need to get 1,234 kB of archives." when none of them is cached and
+// "Need to get 1,234 kB/5,678 kB of archives." otherwise.
+var aptFetchPattern = regexp.MustCompile(`Need to get ([0-9.,]+ [kMG]?B)(?:/([0-9.,]+ [kMG]?B))? of archives`)
+
+// recordAptFetches adds the dependency bytes a build took from its cache
+// and downloaded, as reported in its log, to the cache statistics.
+func recordAptFetches(logPath string) {
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		log.Printf("Failed to read %s for apt cache statistics: %v\n", logPath, err)
+		return
+	}
+	hit, miss := parseAptFetches(string(content))
+	aptCacheCounters.hitBytes.Add(hit)
+	aptCacheCounters.missBytes.Add(miss)
+}
+
+// parseAptFetches returns the bytes apt found in its cache and downloaded,
+// according to a build log.
+func parseAptFetches(buildLog string) (hit, miss uint64) {
+	for _, m := range aptFetchPattern.FindAllStringSubmatch(buildLog, -1) {
+		fetched := parseAptSize(m[1])
+		total := fetched
+		if m[2] != "" {
+			total = parseAptSize(m[2])
+		}
+		miss += fetched
+		if total > fetched {
+			hit += total - fetched
+		}
+	}
+	return hit, miss
+}
+
+// parseAptSize parses the sizes apt prints, such as "1,234 kB" or "1.5 MB".
+func parseAptSize(s string) uint64 {
+	number, unit, _ := strings.Cut(s, " ")
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	switch unit {
+	case "kB":
+		value *= 1e3
+	case "MB":
+		value *= 1e6
+	case "GB":
+		value *= 1e9
+	}
+	return uint64(value)
+}
+
+// aptCacheStats sums up the dependency caches for the heartbeat.
+func aptCacheStats() *monitoring.AptCacheStats {
+	stats := &monitoring.AptCacheStats{
+		HitBytes:  aptCacheCounters.hitBytes.Load(),
+		MissBytes: aptCacheCounters.missBytes.Load(),
+		Evictions: aptCacheCounters.evictions.Load(),
+	}
+	for _, c := range aptCaches {
+		c.mu.Lock()
+		stats.Packages += c.packages
+		stats.Size += uint64(c.size)
+		stats.Limit += uint64(c.limit)
+		c.mu.Unlock()
+	}
+	return stats
+}
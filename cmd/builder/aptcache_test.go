@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDeb(t *testing.T, dir, name string, size int, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestAptCache_SeedAndStore(t *testing.T) {
+	pool := filepath.Join(t.TempDir(), "amd64")
+	cache, err := newAptCache(pool, 250)
+	require.NoError(t, err)
+
+	now := time.Now()
+	writeDeb(t, pool, "libfoo_1.0_amd64.deb", 100, now.Add(-2*time.Hour))
+	require.NoError(t, os.WriteFile(filepath.Join(pool, "Packages"), nil, 0644))
+
+	buildDir := filepath.Join(t.TempDir(), "aptcache")
+	require.NoError(t, cache.seed(buildDir))
+	_, err = os.Stat(filepath.Join(buildDir, "libfoo_1.0_amd64.deb"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(buildDir, "Packages"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The build downloaded two more packages, the one pooled first gets
+	// evicted, however old the archive says the new ones are
+	writeDeb(t, buildDir, "libbar_2.0_amd64.deb", 100, now.Add(-3*time.Hour))
+	writeDeb(t, buildDir, "libbaz_3.0_all.deb", 100, now)
+	evictions := aptCacheCounters.evictions.Load()
+	require.NoError(t, cache.store(buildDir))
+
+	pooled, err := debs(pool)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range pooled {
+		names = append(names, info.Name())
+	}
+	assert.ElementsMatch(t, []string{"libbar_2.0_amd64.deb", "libbaz_3.0_all.deb"}, names)
+	assert.Equal(t, int64(200), cache.size)
+	assert.Equal(t, 2, cache.packages)
+	assert.Equal(t, evictions+1, aptCacheCounters.evictions.Load())
+}
+
+func TestParseAptFetches(t *testing.T) {
+	buildLog := `Reading package lists...
+Need to get 1,234 kB of archives.
+Get:1 http://deb.debian.org/debian sid/main amd64 libfoo amd64 1.0 [1,234 kB]
+Need to get 1.5 MB/4.5 MB of archives.
+Need to get 0 B/512 B of archives.
+`
+	hit, miss := parseAptFetches(buildLog)
+	assert.Equal(t, uint64(3_000_512), hit)
+	assert.Equal(t, uint64(2_734_000), miss)
+}
+
+func TestAptCacheOpts(t *testing.T) {
+	assert.Equal(t, "", aptCacheOpts(false))
+	assert.Equal(t, "--aptcache /tmp/build/aptcache", aptCacheOpts(true))
+
+	irgshConfig.Builder.AptProxy = "http://192.168.1.10:3142"
+	defer func() { irgshConfig.Builder.AptProxy = "" }()
+	assert.Equal(t, "--aptcache /tmp/build/aptcache --http-proxy http://192.168.1.10:3142", aptCacheOpts(true))
+}
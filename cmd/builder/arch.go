@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/queue"
+)
+
+// The second build of a reproducibility check is handed back to the queue
+// every reproRetryDelay while the builder that ran the first build, or any
+// builder before the first build started, picks it up, for up to
+// reproDeferral after the submission. Past that, any builder runs it.
+const (
+	reproRetryDelay = 2 * time.Minute
+	reproDeferral   = time.Hour
+)
+
+// dockerPlatforms maps Debian architecture names to the container platforms
+// docker and podman take.
+var dockerPlatforms = map[string]string{
+	"amd64":   "linux/amd64",
+	"arm64":   "linux/arm64",
+	"armhf":   "linux/arm/v7",
+	"armel":   "linux/arm/v5",
+	"i386":    "linux/386",
+	"ppc64el": "linux/ppc64le",
+	"s390x":   "linux/s390x",
+	"riscv64": "linux/riscv64",
+}
+
+// builderArchitectures returns the architectures this builder instance is
+// configured to build for.
+func builderArchitectures() []string {
+	archs := strings.Fields(irgshConfig.Builder.Architectures)
+	if len(archs) == 0 {
+		return []string{"amd64"}
+	}
+	return archs
+}
+
+func builderLabels() []string {
+	return strings.Fields(irgshConfig.Builder.Labels)
+}
+
+// builderQueues returns the build queues this builder consumes: one per
+// architecture, plus one per architecture and label.
+func builderQueues() []string {
+	codename := irgshConfig.Builder.UpstreamDistCodename
+	var queues []string
+	for _, arch := range builderArchitectures() {
+		queues = append(queues, queue.Build(codename, arch, ""))
+		for _, label := range builderLabels() {
+			queues = append(queues, queue.Build(codename, arch, label))
+		}
+	}
+	return queues
+}
+
+func supportsArchitecture(arch string) bool {
+	for _, a := range builderArchitectures() {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// buildArchitecture returns the target architecture of a build payload.
+// Payloads queued before per-architecture builds carry none and are built
+// for the builder's first configured architecture.
+func buildArchitecture(build payload.Build) string {
+	if build.Architecture != "" {
+		return build.Architecture
+	}
+	return builderArchitectures()[0]
+}
+
+// buildID returns the identifier of a build task. Each architecture of a
+// pipeline is built by its own task, stored under "<taskUUID>.<arch>". The
+// second build of a reproducibility check is stored next to the first one,
+// under "<taskUUID>.<arch>.repro".
+func buildID(build payload.Build) string {
+	id := build.TaskUUID
+	if build.Architecture != "" {
+		id += "." + build.Architecture
+	}
+	if build.ReproCheck {
+		id += ".repro"
+	}
+	return id
+}
+
+// deferReproBuild reports whether the second build of a reproducibility
+// check should be left to another builder: the first build, started by the
+// builder instance firstInstance, has not started yet or ran on this
+// builder, and the pipeline is recent enough for another builder to still
+// show up.
+func deferReproBuild(build payload.Build, firstInstance string, now time.Time) bool {
+	if !build.ReproCheck || now.Sub(build.Timestamp) > reproDeferral {
+		return false
+	}
+	return firstInstance == "" || firstInstance == monitoring.GenerateInstanceID(monitoring.InstanceTypeBuilder)
+}
+
+// firstBuildInstance returns the builder instance that started the first
+// build of the reproducibility check build, empty while none did. When
+// chief cannot tell, the second build is not held back: ok is false.
+func firstBuildInstance(build payload.Build) (instance string, ok bool) {
+	first := build
+	first.ReproCheck = false
+	instance, err := newChiefClient().TaskInstance(context.Background(), build.TaskUUID, buildID(first))
+	if err != nil {
+		log.Printf("Could not look up the first build of %s: %v\n", buildID(build), err)
+		return "", false
+	}
+	return instance, true
+}
+
+// pbuilderBuildOpts returns the extra pbuilder options for a build payload.
+// Only one architecture of a pipeline builds the arch:all packages.
+func pbuilderBuildOpts(build payload.Build) string {
+	if build.Architecture == "" || build.BuildArchIndep {
+		return ""
+	}
+	return "--binary-arch"
+}
+
+// binNMUEnv returns the environment passing a binary-only rebuild to the
+// build script. The reason goes through the environment rather than
+// PBUILDER_BUILD_OPTS, as it may contain spaces.
+func binNMUEnv(build payload.Build) map[string]string {
+	if build.BinNMU <= 0 {
+		return nil
+	}
+	return map[string]string{
+		"BINNMU_VERSION":    strconv.Itoa(build.BinNMU),
+		"BINNMU_MESSAGE":    build.BinNMUReason,
+		"BINNMU_MAINTAINER": build.Maintainer,
+	}
+}
+
+func dockerPlatform(arch string) string {
+	if platform, ok := dockerPlatforms[arch]; ok {
+		return platform
+	}
+	return "linux/" + arch
+}
+
+// buildContainerName names the pbocker container of a build, so it can be
+// killed when its pipeline gets cancelled. Docker does not allow the plus
+// signs build ids may contain.
+func buildContainerName(id string) string {
+	return "irgsh-build-" + strings.ReplaceAll(id, "+", "_")
+}
+
+func baseTgzPath(arch string) string {
+	return "/var/cache/pbuilder/base-" + arch + ".tgz"
+}
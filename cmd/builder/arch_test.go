@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+)
+
+func TestBuildArchitecture(t *testing.T) {
+	irgshConfig.Builder.Architectures = "arm64 amd64"
+
+	assert.Equal(t, "amd64", buildArchitecture(payload.Build{Architecture: "amd64"}))
+	assert.Equal(t, "arm64", buildArchitecture(payload.Build{}))
+	assert.True(t, supportsArchitecture("amd64"))
+	assert.False(t, supportsArchitecture("i386"))
+}
+
+func TestBuilderQueues(t *testing.T) {
+	irgshConfig.Builder.UpstreamDistCodename = "sid"
+	irgshConfig.Builder.Architectures = "amd64 arm64"
+	irgshConfig.Builder.Labels = "big-memory"
+	defer func() { irgshConfig.Builder.Labels = "" }()
+
+	assert.Equal(t, []string{
+		"irgsh.build.sid.amd64",
+		"irgsh.build.sid.amd64.big-memory",
+		"irgsh.build.sid.arm64",
+		"irgsh.build.sid.arm64.big-memory",
+	}, builderQueues())
+}
+
+func TestBuildID(t *testing.T) {
+	assert.Equal(t, "uuid.arm64", buildID(payload.Build{Header: payload.Header{TaskUUID: "uuid"}, Architecture: "arm64"}))
+	assert.Equal(t, "uuid", buildID(payload.Build{Header: payload.Header{TaskUUID: "uuid"}}))
+	assert.Equal(t, "uuid.arm64.repro", buildID(payload.Build{Header: payload.Header{TaskUUID: "uuid"}, Architecture: "arm64", ReproCheck: true}))
+}
+
+func TestDeferReproBuild(t *testing.T) {
+	here := monitoring.GenerateInstanceID(monitoring.InstanceTypeBuilder)
+	now := time.Now()
+	build := payload.Build{Header: payload.Header{TaskUUID: "uuid"}, Timestamp: now, Architecture: "amd64", ReproCheck: true}
+
+	// Another builder ran the first build
+	assert.False(t, deferReproBuild(build, "elsewhere-builder", now))
+
+	// This builder ran the first build, or no builder started it yet
+	assert.True(t, deferReproBuild(build, here, now))
+	assert.True(t, deferReproBuild(build, "", now))
+	assert.False(t, deferReproBuild(build, here, now.Add(reproDeferral+time.Minute)))
+
+	build.ReproCheck = false
+	assert.False(t, deferReproBuild(build, here, now))
+}
+
+func TestPbuilderBuildOpts(t *testing.T) {
+	assert.Equal(t, "", pbuilderBuildOpts(payload.Build{}))
+	assert.Equal(t, "", pbuilderBuildOpts(payload.Build{Architecture: "amd64", BuildArchIndep: true}))
+	assert.Equal(t, "--binary-arch", pbuilderBuildOpts(payload.Build{Architecture: "arm64"}))
+}
+
+func TestBinNMUEnv(t *testing.T) {
+	assert.Nil(t, binNMUEnv(payload.Build{}))
+	assert.Equal(t, map[string]string{
+		"BINNMU_VERSION":    "2",
+		"BINNMU_MESSAGE":    "Rebuild against libfoo2",
+		"BINNMU_MAINTAINER": "Jane Doe <jane@example.com>",
+	}, binNMUEnv(payload.Build{
+		Maintainer:   "Jane Doe <jane@example.com>",
+		BinNMU:       2,
+		BinNMUReason: "Rebuild against libfoo2",
+	}))
+}
+
+func TestBuildContainerName(t *testing.T) {
+	assert.Equal(t, "irgsh-build-task_uuid_FP_hello.amd64", buildContainerName("task_uuid_FP_hello.amd64"))
+	assert.Equal(t, "irgsh-build-task_uuid_FP_gtk_3.0.arm64", buildContainerName("task_uuid_FP_gtk+3.0.arm64"))
+}
+
+func TestDockerPlatform(t *testing.T) {
+	assert.Equal(t, "linux/amd64", dockerPlatform("amd64"))
+	assert.Equal(t, "linux/arm/v7", dockerPlatform("armhf"))
+	assert.Equal(t, "linux/loong64", dockerPlatform("loong64"))
+}
@@ -1,30 +1,66 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/RichardKnop/machinery/v1/tasks"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
 	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
 	"github.com/blankon/irgsh-go/pkg/systemutil"
 )
 
+func newChiefClient() *chiefclient.Client {
+	return chiefclient.New(irgshConfig.Chief.Address, irgshConfig.Worker.Token)
+}
+
 func uploadLog(logPath string, id string) {
-	// Upload the log to chief
-	cmdStr := "curl -v -F 'uploadFile=@" + logPath + "' '"
-	cmdStr += irgshConfig.Chief.Address + "/api/v1/log-upload?id=" + id + "&type=build'"
-	_, err := systemutil.CmdExec(
-		cmdStr,
-		"Uploading log file to chief",
-		"",
-	)
+	uploadTaskLog(logPath, id, "build")
+}
+
+// uploadTaskLog uploads the log of the kind task of id to chief.
+func uploadTaskLog(logPath, id, kind string) {
+	err := newChiefClient().UploadLog(context.Background(), id, kind, logPath)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
 }
 
+// cancelPollInterval is how often a running build looks up whether its
+// pipeline got cancelled.
+const cancelPollInterval = 5 * time.Second
+
+// isCancelled reports whether the pipeline of taskUUID has been cancelled.
+// When the flags cannot be looked up, the build goes on.
+func isCancelled(taskUUID string) bool {
+	if cancelFlags == nil {
+		return false
+	}
+	cancelled, err := cancelFlags.IsCancelled(context.Background(), taskUUID)
+	if err != nil {
+		log.Println(err.Error())
+		return false
+	}
+	return cancelled
+}
+
+// killBuildContainer stops the pbocker container running the build id.
+func killBuildContainer(id string) {
+	err := containers.Kill(buildContainerName(id))
+	if err != nil {
+		log.Printf("Failed to kill the build container of %s: %v\n", id, err)
+	}
+}
+
 func sendBuildNotification(taskUUID, status string, jobInfo notification.JobNotificationInfo) {
 	notification.SendJobNotification(
 		irgshConfig.Notification.WebhookURL,
@@ -36,108 +72,172 @@ func sendBuildNotification(taskUUID, status string, jobInfo notification.JobNoti
 }
 
 // Main task wrapper
-func Build(payload string) (next string, err error) {
-	in := []byte(payload)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
+func Build(data string) (next string, err error) {
+	var build payload.Build
+	if err = payload.Decode(data, &build); err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+	next = data
+
+	if build.ReproCheck {
+		first, ok := firstBuildInstance(build)
+		if ok && deferReproBuild(build, first, time.Now()) {
+			// Machinery queues the task again, for any builder to take
+			log.Printf("Leaving the second build of %s to another builder\n", buildID(build))
+			return next, tasks.NewErrRetryTaskLater("first build not started yet or built here", reproRetryDelay)
+		}
+	}
 
-	taskUUID := raw["taskUUID"].(string)
-	fmt.Println("Processing pipeline :" + taskUUID)
+	taskUUID := build.TaskUUID
+	id := buildID(build)
+	arch := buildArchitecture(build)
+	fmt.Println("Processing pipeline :" + taskUUID + " (" + arch + ")")
 
 	// Extract job info for notifications
 	jobInfo := notification.JobNotificationInfo{
-		PackageName:    raw["packageName"].(string),
-		PackageVersion: raw["packageVersion"].(string),
-		Maintainer:     raw["maintainer"].(string),
-		IsExperimental: raw["isExperimental"].(bool),
-	}
-	if sourceURL, ok := raw["sourceUrl"].(string); ok {
-		jobInfo.SourceURL = sourceURL
-	}
-	if sourceBranch, ok := raw["sourceBranch"].(string); ok {
-		jobInfo.SourceBranch = sourceBranch
-	}
-	if packageURL, ok := raw["packageUrl"].(string); ok {
-		jobInfo.PackageURL = packageURL
-	}
-	if packageBranch, ok := raw["packageBranch"].(string); ok {
-		jobInfo.PackageBranch = packageBranch
+		PackageName:    build.PackageName,
+		PackageVersion: build.PackageVersion,
+		Maintainer:     build.Maintainer,
+		IsExperimental: build.IsExperimental,
+		SourceURL:      build.SourceURL,
+		SourceBranch:   build.SourceBranch,
+		PackageURL:     build.PackageURL,
+		PackageBranch:  build.PackageBranch,
 	}
 
-	logPath := irgshConfig.Builder.Workdir + "/artifacts/" + taskUUID + "/build.log"
+	logPath := irgshConfig.Builder.Workdir + "/artifacts/" + id + "/build.log"
 	go systemutil.StreamLog(logPath)
 
-	// Ensure notification is always sent on completion
+	// Ensure notification is always sent on completion. The second build
+	// of a reproducibility check publishes nothing, nobody waits for it.
 	defer func() {
-		if err != nil {
+		if build.ReproCheck {
+			return
+		}
+		if errors.Is(err, cancel.ErrCancelled) {
+			sendBuildNotification(taskUUID, "CANCELLED", jobInfo)
+		} else if errors.Is(err, cancel.ErrTimedOut) {
+			sendBuildNotification(taskUUID, "TIMEOUT", jobInfo)
+		} else if err != nil {
 			sendBuildNotification(taskUUID, "FAILED", jobInfo)
 		} else {
 			sendBuildNotification(taskUUID, "SUCCESS", jobInfo)
 		}
 	}()
 
-	next, err = BuildPreparation(payload)
+	task := chiefclient.TaskEvent{TaskID: id, Stage: "build", Architecture: arch}
+	err = newChiefClient().Claim(context.Background(), id, "build")
+	if err != nil {
+		// Chief refuses the log of an unclaimed task, keep it here.
+		systemutil.WriteLog(logPath, "[ BUILD FAILED ] Task claim failed: "+err.Error())
+		reportClaimFailure(taskUUID, task, err)
+		return
+	}
+	reportEnd := reportStart(taskUUID, task)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	if isCancelled(taskUUID) {
+		err = cancel.ErrCancelled
+		systemutil.WriteLog(logPath, "[ BUILD CANCELLED ]")
+		uploadLog(logPath, id)
+		return
+	}
+
+	if !supportsArchitecture(arch) {
+		err = fmt.Errorf("this builder does not build for %s, it is configured for: %s", arch, irgshConfig.Builder.Architectures)
+		systemutil.WriteLog(logPath, "[ BUILD FAILED ] "+err.Error())
+		uploadLog(logPath, id)
+		return
+	}
+
+	err = BuildPreparation(build)
 	if err != nil {
 		systemutil.WriteLog(logPath, "[ BUILD FAILED ] Build preparation failed: "+err.Error())
-		uploadLog(logPath, taskUUID)
+		uploadLog(logPath, id)
 		return
 	}
 
-	next, err = BuildPackage(payload)
+	// Stop the build container once it runs out of time, or as soon as the
+	// pipeline gets cancelled
+	timeout := irgshConfig.Builder.TimeoutFor(build.PackageName)
+	buildCtx, stopBuild := context.WithTimeout(context.Background(), timeout)
+	if cancelFlags != nil {
+		go cancel.Watch(buildCtx, cancelFlags, taskUUID, cancelPollInterval, stopBuild)
+	}
+	err = BuildPackage(buildCtx, build)
+	timedOut := errors.Is(buildCtx.Err(), context.DeadlineExceeded)
+	stopBuild()
+	if isCancelled(taskUUID) {
+		// The build may have just finished, its artifact is not wanted either way
+		err = cancel.ErrCancelled
+		systemutil.WriteLog(logPath, "[ BUILD CANCELLED ]")
+		uploadLog(logPath, id)
+		return
+	}
+	if timedOut {
+		err = fmt.Errorf("%w after %s", cancel.ErrTimedOut, timeout)
+		systemutil.WriteLog(logPath, "[ BUILD TIMEOUT ] The build did not finish within "+timeout.String()+", its container was killed")
+		uploadLog(logPath, id)
+		return
+	}
 	if err != nil {
 		systemutil.WriteLog(logPath, "[ BUILD FAILED ] Package build failed: "+err.Error())
-		uploadLog(logPath, taskUUID)
+		uploadLog(logPath, id)
 		return
 	}
 
-	next, err = StorePackage(payload)
+	// The second build of a reproducibility check has the same sources,
+	// lintian checked them already
+	if !build.ReproCheck {
+		err = LintPackage(build)
+		if err != nil {
+			systemutil.WriteLog(logPath, "[ BUILD FAILED ] "+err.Error())
+			uploadLog(logPath, id)
+			return
+		}
+	}
+
+	err = StorePackage(build)
 
 	if err != nil {
 		systemutil.WriteLog(logPath, "[ BUILD FAILED ] Package artifact upload failed: "+err.Error())
-		uploadLog(logPath, taskUUID)
+		uploadLog(logPath, id)
 		return
 	}
 
 	systemutil.WriteLog(logPath, "[ BUILD DONE ]")
-	uploadLog(logPath, taskUUID)
+	uploadLog(logPath, id)
 
 	fmt.Println("Done.")
 
 	return
 }
 
-func BuildPreparation(payload string) (next string, err error) {
-	in := []byte(payload)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
-
-	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+func BuildPreparation(build payload.Build) (err error) {
+	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + buildID(build)
 	logPath := buildPath + "/build.log"
 
-	targetDir := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+	targetDir := buildPath
 	err = os.MkdirAll(targetDir, 0755)
 	if err != nil {
 		log.Printf("error: %v\n", err)
 		return
 	}
 
-	target := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string) + "/debuild.tar.gz"
+	target := buildPath + "/debuild.tar.gz"
 	// Downloading the submission tarball from chief
-	cmdStr := "curl -v -o " + target + " "
-	cmdStr += irgshConfig.Chief.Address + "/submissions/" + raw["taskUUID"].(string) + ".tar.gz"
-	log.Println(cmdStr)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Fetching the submission tarball from chief",
-		logPath,
-	)
+	systemutil.WriteLog(logPath, "Fetching the submission tarball from chief")
+	err = newChiefClient().DownloadSubmission(context.Background(), build.TaskUUID, target)
 	if err != nil {
 		log.Printf("error: %v\n", err)
 		return
 	}
 
 	// Extract the signed dsc
-	cmdStr = "cd " + irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+	cmdStr := "cd " + buildPath
 	cmdStr += " && tar -xvf debuild.tar.gz "
 	cmdStr += " && rm -rf debuild.tar.gz "
 	_, err = systemutil.CmdExec(
@@ -150,16 +250,14 @@ func BuildPreparation(payload string) (next string, err error) {
 		return
 	}
 
-	next = payload
 	return
 }
 
-func BuildPackage(payload string) (next string, err error) {
-	in := []byte(payload)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
-
-	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+// BuildPackage builds the package in a pbocker container, which is killed
+// once ctx is done.
+func BuildPackage(ctx context.Context, build payload.Build) (err error) {
+	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + buildID(build)
+	arch := buildArchitecture(build)
 	err = os.MkdirAll(buildPath, 0755)
 	if err != nil {
 		log.Printf("error: %v\n", err)
@@ -168,13 +266,13 @@ func BuildPackage(payload string) (next string, err error) {
 
 	logPath := buildPath + "/build.log"
 
-	packageNameVersion := raw["packageName"].(string) + "-" + raw["packageVersion"].(string)
-	if len(raw["packageExtendedVersion"].(string)) > 0 {
-		packageNameVersion += "-" + raw["packageExtendedVersion"].(string)
+	packageNameVersion := build.PackageName + "-" + build.PackageVersion
+	if len(build.PackageExtendedVersion) > 0 {
+		packageNameVersion += "-" + build.PackageExtendedVersion
 	}
 
 	// Copy the maintainer's generated files from signed dir
-	cmdStr := "cd " + irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+	cmdStr := "cd " + buildPath
 	cmdStr += " && cp signed/* ."
 	log.Println(cmdStr)
 	_, err = systemutil.CmdExec(
@@ -187,29 +285,93 @@ func BuildPackage(payload string) (next string, err error) {
 		return
 	}
 
-	// Cleanup pbuilder cache result
-	_, _ = systemutil.CmdExec(
-		"rm -rf /var/cache/pbuilder/result/*",
-		"",
-		"",
-	)
+	// The image is tagged with the version of the templates it was built
+	// from, see pbocker.go
+	image, err := pbockerImage(arch)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	imageID, err := containers.ImageID(ctx, image)
+	if err != nil {
+		err = fmt.Errorf("pbocker image %s not found, run irgsh-builder init-builder: %w", image, err)
+		log.Println(err.Error())
+		return
+	}
+	systemutil.WriteLog(logPath, "Building in "+image+" ("+imageID+")")
+
+	// Building the package. Every build gets a container of its own, with
+	// its own pbuilder result directory, and only shares buildPath with
+	// the host, so builds can run side by side. Killing the runtime client
+	// would leave the container running, so the container itself is
+	// killed. A container left behind by a builder that crashed would
+	// hold the name of the build, it is removed first.
+	_ = containers.Remove(buildContainerName(buildID(build)))
+	stopKill := context.AfterFunc(ctx, func() {
+		killBuildContainer(buildID(build))
+	})
+	defer stopKill()
+
+	// Build dependencies are installed from the cache the builds of arch
+	// share, see aptcache.go
+	cache := aptCaches[arch]
+	cacheDir := buildPath + "/aptcache"
+	if cache != nil {
+		if err := cache.seed(cacheDir); err != nil {
+			log.Printf("Failed to seed the apt cache of %s: %v\n", buildID(build), err)
+			cache = nil
+		}
+	}
+	defer os.RemoveAll(cacheDir)
 
-	// Building the package
-	cmdStr = "docker run -v " + irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
-	cmdStr += ":/tmp/build --privileged=true --user 0:0 -i pbocker bash -c /build.sh" // See builder/init.go to modify this script
-	fmt.Println(cmdStr)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Building the package",
-		logPath,
-	)
+	// Build dependencies uploaded to our repository do not have to wait
+	// for upstream, see repodeps.go
+	withRepo, err := writeRepoSources(buildPath, build)
 	if err != nil {
 		log.Println(err.Error())
 		return
 	}
+	if withRepo {
+		systemutil.WriteLog(logPath, "Installing build dependencies from "+strings.Join(repoSources(build), ", "))
+	}
+
+	env := map[string]string{
+		"PBUILDER_BUILD_OPTS": strings.Join(strings.Fields(pbuilderBuildOpts(build)+" "+aptCacheOpts(cache != nil)+" "+repoSourcesOpts(withRepo)), " "),
+	}
+	for k, v := range binNMUEnv(build) {
+		env[k] = v
+	}
+
+	// See templates/build.sh.tmpl to modify the build script
+	err = containers.Run(ctx, runSpec{
+		Name:       buildContainerName(buildID(build)),
+		Image:      image,
+		Platform:   dockerPlatform(arch),
+		Env:        env,
+		Volumes:    []volume{{Host: buildPath, Container: "/tmp/build"}},
+		Privileged: true,
+		Command:    []string{"bash", "-c", "/build.sh"},
+	}, logPath)
+	if cache != nil {
+		recordAptFetches(logPath)
+	}
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	// A killed build may leave a partial package behind, only a
+	// successful one feeds the cache. pbuilder only copies the packages
+	// it downloaded to the build's cache directory, the seeded links to
+	// the pool are left untouched.
+	if cache != nil {
+		if err := cache.store(cacheDir); err != nil {
+			log.Printf("Failed to store the apt cache of %s: %v\n", buildID(build), err)
+		}
+	}
 
 	// Check if .deb files were created
-	debPattern := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string) + "/*.deb"
+	debPattern := buildPath + "/*.deb"
 	debFiles, _ := filepath.Glob(debPattern)
 	if len(debFiles) == 0 {
 		err = fmt.Errorf("no .deb files were created after build")
@@ -219,8 +381,8 @@ func BuildPackage(payload string) (next string, err error) {
 	log.Printf("Found %d .deb file(s): %v\n", len(debFiles), debFiles)
 
 	// Use the generated files from maintainer
-	if len(raw["sourceUrl"].(string)) > 0 {
-		cmdStr := "cd " + irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string)
+	if len(build.SourceURL) > 0 {
+		cmdStr := "cd " + buildPath
 		cmdStr += " && cp signed/* . "
 		log.Println(cmdStr)
 		_, err = systemutil.CmdExec(
@@ -234,26 +396,18 @@ func BuildPackage(payload string) (next string, err error) {
 		}
 	}
 
-	next = payload
 	return
 }
 
-func StorePackage(payload string) (next string, err error) {
-	in := []byte(payload)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
-
-	logPath := irgshConfig.Builder.Workdir + "/artifacts/" + raw["taskUUID"].(string) + "/build.log"
+func StorePackage(build payload.Build) (err error) {
+	id := buildID(build)
+	logPath := irgshConfig.Builder.Workdir + "/artifacts/" + id + "/build.log"
 
 	cmdStr := "cd " + irgshConfig.Builder.Workdir + "/artifacts/ && "
-	cmdStr += "tar -zcvf " + raw["taskUUID"].(string) + ".tar.gz " + raw["taskUUID"].(string)
-	cmdStr += " && curl -v -F 'uploadFile=@" + irgshConfig.Builder.Workdir
-	cmdStr += "/artifacts/" + raw["taskUUID"].(string) + ".tar.gz' "
-	cmdStr += irgshConfig.Chief.Address + "/api/v1/artifact-upload?id="
-	cmdStr += raw["taskUUID"].(string)
+	cmdStr += "tar -zcvf " + id + ".tar.gz " + id
 	_, err = systemutil.CmdExec(
 		cmdStr,
-		"",
+		"Packing the build artifact",
 		logPath,
 	)
 	if err != nil {
@@ -261,6 +415,12 @@ func StorePackage(payload string) (next string, err error) {
 		return
 	}
 
-	next = payload
+	systemutil.WriteLog(logPath, "Uploading the build artifact to chief")
+	err = newChiefClient().UploadArtifact(context.Background(), id, irgshConfig.Builder.Workdir+"/artifacts/"+id+".tar.gz")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+
 	return
 }
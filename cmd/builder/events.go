@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+// reportEvent reports event on a claimed task of the pipeline taskUUID to
+// chief, as this builder instance. Chief derives the state of the pipeline
+// from the events, failing to report one is logged.
+func reportEvent(taskUUID string, event chiefclient.TaskEvent) {
+	event.Instance = monitoring.GenerateInstanceID(monitoring.InstanceTypeBuilder)
+	if err := newChiefClient().ReportEvent(context.Background(), taskUUID, event); err != nil {
+		log.Printf("Failed to report the %s event of %s: %v\n", event.Event, event.TaskID, err)
+	}
+}
+
+// reportStart reports that the task of task started and returns the
+// function reporting how it ended, given the error it returned.
+func reportStart(taskUUID string, task chiefclient.TaskEvent) func(err error) {
+	startedAt := time.Now()
+	task.Event = chiefclient.EventStarted
+	reportEvent(taskUUID, task)
+	return func(err error) {
+		reportEvent(taskUUID, endEvent(task, err, time.Since(startedAt)))
+	}
+}
+
+// reportClaimFailure reports that the task of task failed as chief refused
+// its claim with err. Chief only records the failure when no other worker
+// holds the claim.
+func reportClaimFailure(taskUUID string, task chiefclient.TaskEvent, err error) {
+	task.Event = chiefclient.EventFailed
+	task.Detail = "task claim failed: " + err.Error()
+	reportEvent(taskUUID, task)
+}
+
+// endEvent returns the event ending the task started, which returned err
+// after running for elapsed.
+func endEvent(started chiefclient.TaskEvent, err error, elapsed time.Duration) chiefclient.TaskEvent {
+	event := started
+	event.DurationSeconds = elapsed.Seconds()
+	switch {
+	case errors.Is(err, cancel.ErrCancelled):
+		event.Event = chiefclient.EventCancelled
+	case errors.Is(err, cancel.ErrTimedOut):
+		event.Event = chiefclient.EventTimeout
+		event.Detail = err.Error()
+	case err != nil:
+		event.Event = chiefclient.EventFailed
+		event.Detail = err.Error()
+	default:
+		event.Event = chiefclient.EventDone
+	}
+	return event
+}
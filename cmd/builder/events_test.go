@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
+)
+
+func TestEndEvent(t *testing.T) {
+	started := chiefclient.TaskEvent{TaskID: "task.amd64", Stage: "build", Architecture: "amd64", Event: chiefclient.EventStarted}
+
+	tests := []struct {
+		err    error
+		event  string
+		detail string
+	}{
+		{nil, chiefclient.EventDone, ""},
+		{cancel.ErrCancelled, chiefclient.EventCancelled, ""},
+		{fmt.Errorf("%w after 1h0m0s", cancel.ErrTimedOut), chiefclient.EventTimeout, cancel.ErrTimedOut.Error() + " after 1h0m0s"},
+		{errors.New("exit status 1"), chiefclient.EventFailed, "exit status 1"},
+	}
+	for _, tt := range tests {
+		event := endEvent(started, tt.err, 90*time.Second)
+		assert.Equal(t, tt.event, event.Event)
+		assert.Equal(t, tt.detail, event.Detail)
+		assert.Equal(t, "task.amd64", event.TaskID)
+		assert.Equal(t, "amd64", event.Architecture)
+		assert.Equal(t, 90.0, event.DurationSeconds)
+	}
+}
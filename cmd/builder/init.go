@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -48,17 +49,19 @@ func InitBase() (err error) {
 		logPath,
 	)
 
+	archs := builderArchitectures()
+
 	// Create /root/.pbuilderrc with builder config
 	fmt.Println("Creating /root/.pbuilderrc...")
 	pbuilderrcContent := fmt.Sprintf(`# Generated by irgsh-builder init
 MIRRORSITE="%s"
 DISTRIBUTION="%s"
-BASETGZ="/var/cache/pbuilder/base.tgz"
+BASETGZ="%s"
 BUILDRESULT="/var/cache/pbuilder/result/"
 APTCACHE="/var/cache/pbuilder/aptcache/"
 BUILDPLACE="/var/cache/pbuilder/build/"
 USENETWORK=yes
-`, irgshConfig.Builder.UpstreamDistUrl, irgshConfig.Builder.UpstreamDistCodename)
+`, irgshConfig.Builder.UpstreamDistUrl, irgshConfig.Builder.UpstreamDistCodename, baseTgzPath(archs[0]))
 
 	err = ioutil.WriteFile("/root/.pbuilderrc", []byte(pbuilderrcContent), 0644)
 	if err != nil {
@@ -67,9 +70,9 @@ USENETWORK=yes
 	}
 	fmt.Println("Created /root/.pbuilderrc with MIRRORSITE=" + irgshConfig.Builder.UpstreamDistUrl + " and DISTRIBUTION=" + irgshConfig.Builder.UpstreamDistCodename)
 
-	cmdStr = "pbuilder create --debootstrapopts --variant=buildd"
-	if strings.Contains(irgshConfig.Builder.UpstreamDistUrl, "debian") && (strings.Contains(distribution, "Ubuntu") ||
-		strings.Contains(distribution, "Pop")) {
+	useDebianKeyring := strings.Contains(irgshConfig.Builder.UpstreamDistUrl, "debian") && (strings.Contains(distribution, "Ubuntu") ||
+		strings.Contains(distribution, "Pop"))
+	if useDebianKeyring {
 		_, err = systemutil.CmdExec(
 			"apt-get update && apt-get -y install debian-archive-keyring",
 			"Creating pbuilder base.tgz",
@@ -79,27 +82,35 @@ USENETWORK=yes
 			fmt.Printf("error: %v\n", err)
 			return
 		}
-		cmdStr = "pbuilder create --distribution " + irgshConfig.Builder.UpstreamDistCodename + " --mirror " + irgshConfig.Builder.UpstreamDistUrl + " --debootstrapopts \"--keyring=/usr/share/keyrings/debian-archive-keyring.gpg\""
-	}
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Creating pbuilder base.tgz",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
 	}
 
-	cmdStr = "pbuilder update"
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Updating base.tgz",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
+	// One base.tgz per architecture. Foreign architectures are bootstrapped
+	// through qemu-user-static, which has to be installed on the host.
+	for _, arch := range archs {
+		cmdStr = "pbuilder create --architecture " + arch + " --basetgz " + baseTgzPath(arch) + " --debootstrapopts --variant=buildd"
+		if useDebianKeyring {
+			cmdStr = "pbuilder create --architecture " + arch + " --basetgz " + baseTgzPath(arch) + " --distribution " + irgshConfig.Builder.UpstreamDistCodename + " --mirror " + irgshConfig.Builder.UpstreamDistUrl + " --debootstrapopts \"--keyring=/usr/share/keyrings/debian-archive-keyring.gpg\""
+		}
+		_, err = systemutil.CmdExec(
+			cmdStr,
+			"Creating pbuilder base.tgz for "+arch,
+			logPath,
+		)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
+
+		cmdStr = "pbuilder update --basetgz " + baseTgzPath(arch)
+		_, err = systemutil.CmdExec(
+			cmdStr,
+			"Updating base.tgz for "+arch,
+			logPath,
+		)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
 	}
 
 	cmdStr = "chmod a+rw /var/cache/pbuilder/base*"
@@ -138,16 +149,18 @@ func UpdateBase() (err error) {
 	logPath := "/tmp/irgsh-builder-update-base-" + uuid.New().String() + ".log"
 	go systemutil.StreamLog(logPath)
 
-	fmt.Println("Updating base.tgz...")
-	cmdStr := "sudo pbuilder update"
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Updating base.tgz",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
+	for _, arch := range builderArchitectures() {
+		fmt.Println("Updating base.tgz for " + arch + "...")
+		cmdStr := "sudo pbuilder update --basetgz " + baseTgzPath(arch)
+		_, err = systemutil.CmdExec(
+			cmdStr,
+			"Updating base.tgz for "+arch,
+			logPath,
+		)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
 	}
 
 	fmt.Println("Done.")
@@ -160,53 +173,43 @@ func InitBuilder() (err error) {
 	logPath += "/irgsh-builder-init-" + uuid.New().String() + ".log"
 	go systemutil.StreamLog(logPath)
 
-	fmt.Println("Preparing containerized pbuilder...")
+	for _, arch := range builderArchitectures() {
+		err = initPbocker(arch, logPath)
+		if err != nil {
+			return
+		}
+	}
 
-	cmdStr := `mkdir -p ` + irgshConfig.Builder.Workdir + `/pbocker && \
-    cp /var/cache/pbuilder/base.tgz ` + irgshConfig.Builder.Workdir + `/pbocker/base.tgz`
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Copying base.tgz",
-		logPath,
-	)
+	fmt.Println("Done.")
+
+	return
+}
+
+// initPbocker builds the pbocker image for a single architecture from the
+// matching base.tgz and the embedded templates, then checks the image.
+func initPbocker(arch, logPath string) (err error) {
+	fmt.Println("Preparing containerized pbuilder for " + arch + "...")
+
+	files, err := renderPbocker(newPbockerData(arch))
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		return
 	}
+	version := files.version()
 
-	// build.sh script is written here.
-	// We're only taking the *.deb and *.buildinfo (if any) files from pbuilder result
-	cmdStr = `echo 'FROM debian:latest' > ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN apt-get update && apt-get -y install pbuilder' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN echo "MIRRORSITE=` + irgshConfig.Builder.UpstreamDistUrl + `" > /root/.pbuilderrc' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN echo "BUILDUSERID=0" >> /root/.pbuilderrc' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN echo "BUILDUSERNAME=root" >> /root/.pbuilderrc' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN echo "USENETWORK=yes"' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'COPY base.tgz /var/cache/pbuilder/' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile && \
-    echo 'RUN mkdir -p /var/cache/pbuilder/hooks'
-    echo 'RUN echo '\''#!/bin/bash\n\
-    cp /etc/resolv.conf /etc/resolv.conf.bak 2>/dev/null || true\n\
-    cat > /etc/resolv.conf << RESOLV\n\
-    nameserver 1.1.1.1\n\
-    nameserver 8.8.8.8\n\
-    RESOLV'\'' > /var/cache/pbuilder/hooks/G01resolvconf && \
-        chmod +x /var/cache/pbuilder/hooks/G01resolvconf'
-    echo 'RUN echo "pbuilder --build /tmp/build/*.dsc \n cp -vR /var/cache/pbuilder/result/*.deb /tmp/build/ \n cp -vR /var/cache/pbuilder/result/*.buildinfo /tmp/build/ || true" > /build.sh && chmod a+x /build.sh' >> ` + irgshConfig.Builder.Workdir + `/pbocker/Dockerfile`
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Preparing Dockerfile",
-		logPath,
-	)
+	pbockerDir := irgshConfig.Builder.Workdir + "/pbocker/" + arch
+	err = os.RemoveAll(pbockerDir + "/hooks")
+	if err == nil {
+		err = files.write(pbockerDir)
+	}
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		return
 	}
 
-	cmdStr = `cd ` + irgshConfig.Builder.Workdir +
-		`/pbocker && docker build --no-cache -t pbocker .`
 	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Building pbocker docker image",
+		"cp "+baseTgzPath(arch)+" "+pbockerDir+"/base.tgz",
+		"Copying base.tgz",
 		logPath,
 	)
 	if err != nil {
@@ -214,7 +217,41 @@ func InitBuilder() (err error) {
 		return
 	}
 
-	fmt.Println("Done.")
+	image := pbockerRepository(arch) + ":" + version
+	err = containers.BuildImage(context.Background(), imageSpec{
+		ContextDir: pbockerDir,
+		Platform:   dockerPlatform(arch),
+		Tags:       []string{image, pbockerRepository(arch) + ":latest"},
+		Labels: map[string]string{
+			pbockerVersionLabel: version,
+			builderVersionLabel: app.Version,
+		},
+	}, logPath)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	err = validatePbocker(image, arch, logPath)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+	fmt.Println("Built " + image)
 
 	return
 }
+
+// validatePbocker checks that an image holds everything a build needs.
+func validatePbocker(image, arch, logPath string) error {
+	err := containers.Run(context.Background(), runSpec{
+		Image:    image,
+		Platform: dockerPlatform(arch),
+		Command: []string{"sh", "-c",
+			"command -v pbuilder && test -x /build.sh && test -s /var/cache/pbuilder/base.tgz && test -s /root/.pbuilderrc"},
+	}, logPath)
+	if err != nil {
+		return fmt.Errorf("pbocker image %s is incomplete: %w", image, err)
+	}
+	return nil
+}
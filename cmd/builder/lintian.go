@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/lintian"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// lintianTimeout bounds the time lintian has to check the packages of a
+// build.
+const lintianTimeout = 30 * time.Minute
+
+// LintPackage runs lintian on the packages of a successful build and
+// uploads its output as the lintian log of the build, which chief records
+// the tags of. It fails when lintian reported one of the tags the payload
+// lists as failing the build. lintian failing to check the packages only
+// fails the build when the payload lists such tags, they could not be
+// looked for otherwise.
+func LintPackage(build payload.Build) (err error) {
+	id := buildID(build)
+	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + id
+	logPath := buildPath + "/build.log"
+	lintianLogPath := buildPath + "/lintian.log"
+	failTags := build.LintianFailTags
+
+	if err := newChiefClient().Claim(context.Background(), id, "lintian"); err != nil {
+		if len(failTags) > 0 {
+			return fmt.Errorf("lintian could not run, its task claim failed: %w", err)
+		}
+		systemutil.WriteLog(logPath, "Skipping lintian, its task claim failed: "+err.Error())
+		return nil
+	}
+
+	tags, lintErr := runLintian(build, lintianLogPath)
+	uploadTaskLog(lintianLogPath, id, "lintian")
+	if lintErr != nil {
+		if len(failTags) > 0 {
+			return fmt.Errorf("lintian could not check the packages, which the lintian tags failing the builds of %s require: %w", build.Component, lintErr)
+		}
+		systemutil.WriteLog(logPath, "Lintian could not check the packages: "+lintErr.Error())
+		return nil
+	}
+	systemutil.WriteLog(logPath, "Lintian reported "+strconv.Itoa(len(tags))+" tag(s), see the lintian log")
+
+	failures := lintianFailures(tags, failTags)
+	if len(failures) > 0 {
+		return fmt.Errorf("lintian reported %s, which fail the builds of %s", strings.Join(failures, ", "), build.Component)
+	}
+	return nil
+}
+
+// runLintian checks the packages in a pbocker container, writing the output
+// of lintian to logPath, and returns the tags it reported.
+func runLintian(build payload.Build, logPath string) ([]lintian.Tag, error) {
+	buildPath := irgshConfig.Builder.Workdir + "/artifacts/" + buildID(build)
+	arch := buildArchitecture(build)
+
+	image, err := pbockerImage(arch)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := context.WithTimeout(context.Background(), lintianTimeout)
+	defer stop()
+	name := buildID(build) + ".lintian"
+	_ = containers.Remove(buildContainerName(name))
+	stopKill := context.AfterFunc(ctx, func() {
+		killBuildContainer(name)
+	})
+	defer stopKill()
+
+	// See templates/lintian.sh.tmpl to modify the lintian script
+	err = containers.Run(ctx, runSpec{
+		Name:     buildContainerName(name),
+		Image:    image,
+		Platform: dockerPlatform(arch),
+		Volumes:  []volume{{Host: buildPath, Container: "/tmp/build"}},
+		Command:  []string{"bash", "-c", "/lintian.sh"},
+	}, logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return lintian.Parse(f)
+}
+
+// lintianFailures returns the names of the tags among failTags that lintian
+// reported, in the order it reported them. Overridden tags do not count.
+func lintianFailures(tags []lintian.Tag, failTags []string) []string {
+	fail := make(map[string]bool, len(failTags))
+	for _, name := range failTags {
+		fail[name] = true
+	}
+	var failures []string
+	for _, tag := range tags {
+		if !fail[tag.Name] || tag.Severity == lintian.Overridden || tag.Severity == lintian.Masked {
+			continue
+		}
+		failures = append(failures, tag.Name)
+		fail[tag.Name] = false
+	}
+	return failures
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/blankon/irgsh-go/internal/lintian"
+)
+
+func TestLintianFailures(t *testing.T) {
+	tags := []lintian.Tag{
+		{Severity: lintian.Warning, Package: "hello source", Name: "out-of-date-standards-version"},
+		{Severity: lintian.Error, Package: "hello", Name: "no-copyright-file"},
+		{Severity: lintian.Error, Package: "hello-doc", Name: "no-copyright-file"},
+		{Severity: lintian.Overridden, Package: "hello", Name: "binary-without-manpage"},
+	}
+
+	assert.Equal(t, []string{"no-copyright-file"}, lintianFailures(tags, []string{"no-copyright-file", "binary-without-manpage"}))
+	assert.Empty(t, lintianFailures(tags, nil))
+}
@@ -13,6 +13,7 @@ import (
 	machineryConfig "github.com/RichardKnop/machinery/v1/config"
 	"github.com/urfave/cli"
 
+	"github.com/blankon/irgsh-go/internal/cancel"
 	"github.com/blankon/irgsh-go/internal/config"
 	"github.com/blankon/irgsh-go/internal/monitoring"
 )
@@ -20,12 +21,17 @@ import (
 var (
 	app        *cli.App
 	configPath string
-	server     *machinery.Server
 	version    string
 
 	irgshConfig = config.IrgshConfig{}
 
+	// cancelFlags tells whether the pipeline of a build has been cancelled.
+	cancelFlags *cancel.Flags
+
 	activeTasks atomic.Int32
+	// buildSlots caps the builds running at the same time across the
+	// per-queue workers to builder.concurrency.
+	buildSlots *buildSlotGate
 )
 
 func main() {
@@ -40,6 +46,10 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	containers, err = newContainerRuntime(irgshConfig.Builder.Runtime)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	app = cli.NewApp()
 	app.Name = "irgsh-go"
@@ -80,6 +90,21 @@ func main() {
 
 	app.Action = func(c *cli.Context) error {
 
+		if irgshConfig.Builder.Runtime == "podman-rootless" {
+			if err := checkRootlessWorkdir(irgshConfig.Builder.Workdir); err != nil {
+				fmt.Println("Could not run rootless builds : " + err.Error())
+				return err
+			}
+		}
+
+		aptCaches, err = newAptCaches()
+		if err != nil {
+			fmt.Println("Could not open the apt cache : " + err.Error())
+			return err
+		}
+
+		checkPbockerImages()
+
 		go serve()
 
 		// Start monitoring heartbeat if enabled
@@ -87,22 +112,46 @@ func main() {
 			go startMonitoringHeartbeat()
 		}
 
-		server, err = machinery.NewServer(
-			&machineryConfig.Config{
-				Broker:        irgshConfig.Redis,
-				ResultBackend: irgshConfig.Redis,
-				DefaultQueue:  "irgsh",
-			},
-		)
+		buildSlots = newBuildSlotGate(irgshConfig.Builder.Concurrency)
+
+		cancelFlags, err = cancel.NewFlags(irgshConfig.Redis)
 		if err != nil {
-			fmt.Println("Could not create server : " + err.Error())
+			fmt.Println("Could not create cancellation flags : " + err.Error())
+			return err
 		}
 
-		// Wrap Build task with monitoring
-		server.RegisterTask("build", BuildWithMonitoring)
+		// Chief routes each build to the queue matching its architecture
+		// and labels, so consume every queue this builder can serve. The
+		// workers share the build slots, and only fetch a task once they
+		// hold one.
+		errorsChan := make(chan error)
+		for _, q := range builderQueues() {
+			server, err := machinery.NewServer(
+				&machineryConfig.Config{
+					Broker:        irgshConfig.Redis,
+					ResultBackend: irgshConfig.Redis,
+					DefaultQueue:  q,
+				},
+			)
+			if err != nil {
+				fmt.Println("Could not create server : " + err.Error())
+				return err
+			}
+
+			// Wrap Build and Test tasks with monitoring
+			server.RegisterTask("build", BuildWithMonitoring)
+			server.RegisterTask("test", TestWithMonitoring)
+
+			log.Println("Consuming build queue " + q)
+			worker := server.NewWorker("builder", irgshConfig.Builder.Concurrency)
+			worker.SetPreConsumeHandler(func(*machinery.Worker) bool {
+				buildSlots.Fetch()
+				return true
+			})
+			worker.LaunchAsync(errorsChan)
+		}
 
-		worker := server.NewWorker("builder", 1)
-		err = worker.Launch()
+		err = <-errorsChan
 		if err != nil {
 			fmt.Println("Could not launch worker : " + err.Error())
 		}
@@ -115,12 +164,27 @@ func main() {
 
 // BuildWithMonitoring wraps the Build function with active task tracking
 func BuildWithMonitoring(payload string) (string, error) {
+	buildSlots.Start()
+	defer buildSlots.Done()
+
 	activeTasks.Add(1)
 	defer activeTasks.Add(-1)
 
 	return Build(payload)
 }
 
+// TestWithMonitoring wraps the Test function with active task tracking, the
+// tests take one of the build slots
+func TestWithMonitoring(payload string) (string, error) {
+	buildSlots.Start()
+	defer buildSlots.Done()
+
+	activeTasks.Add(1)
+	defer activeTasks.Add(-1)
+
+	return Test(payload)
+}
+
 func startMonitoringHeartbeat() {
 	ttl := time.Duration(irgshConfig.Monitoring.InstanceTimeout) * time.Second
 	interval := time.Duration(irgshConfig.Monitoring.HeartbeatInterval) * time.Second
@@ -128,7 +192,14 @@ func startMonitoringHeartbeat() {
 		context.Background(),
 		irgshConfig.Redis, ttl,
 		monitoring.InstanceTypeBuilder, irgshConfig.Builder.Workdir,
-		interval, func() int { return int(activeTasks.Load()) },
+		interval, monitoring.Capabilities{
+			Architectures: builderArchitectures(),
+			DistCodename:  irgshConfig.Builder.UpstreamDistCodename,
+			Labels:        builderLabels(),
+		},
+		irgshConfig.Builder.Concurrency,
+		func() int { return int(activeTasks.Load()) },
+		aptCacheStats,
 	)
 }
 
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// pbockerTemplates holds the build context of the pbocker images. Every
+// file is rendered as a template and written without its .tmpl suffix.
+//
+//go:embed templates
+var pbockerTemplates embed.FS
+
+// Labels set on the pbocker images.
+const (
+	pbockerVersionLabel = "org.blankon.irgsh.pbocker.version"
+	builderVersionLabel = "org.blankon.irgsh.builder.version"
+)
+
+// pbockerData is what the pbocker templates are rendered with.
+type pbockerData struct {
+	Arch       string
+	MirrorSite string
+	Rootless   bool // Built for a rootless podman, which cannot create device nodes
+}
+
+// pbockerContext is a rendered build context, keyed by file path.
+type pbockerContext map[string][]byte
+
+func newPbockerData(arch string) pbockerData {
+	return pbockerData{
+		Arch:       arch,
+		MirrorSite: irgshConfig.Builder.UpstreamDistUrl,
+		Rootless:   irgshConfig.Builder.Runtime == "podman-rootless",
+	}
+}
+
+// renderPbocker renders the build context of the pbocker image for data.
+func renderPbocker(data pbockerData) (pbockerContext, error) {
+	if data.Arch == "" || data.MirrorSite == "" {
+		return nil, fmt.Errorf("pbocker image needs an architecture and a mirror")
+	}
+	files := pbockerContext{}
+	err := fs.WalkDir(pbockerTemplates, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		tmpl, err := template.New(d.Name()).Option("missingkey=error").ParseFS(pbockerTemplates, path)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "templates/"), ".tmpl")
+		files[name] = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := files["Dockerfile"]; !ok {
+		return nil, fmt.Errorf("pbocker templates have no Dockerfile")
+	}
+	return files, nil
+}
+
+// version identifies the content of a build context. It tags the image
+// built from it, so a build log tells which image produced the packages.
+func (c pbockerContext) version() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(c[name]))
+		h.Write(c[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// write writes the build context into dir.
+func (c pbockerContext) write(dir string) error {
+	for name, content := range c {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pbockerImage returns the image builds for arch run in, tagged with the
+// version of the build context this builder renders.
+func pbockerImage(arch string) (string, error) {
+	files, err := renderPbocker(newPbockerData(arch))
+	if err != nil {
+		return "", err
+	}
+	return pbockerRepository(arch) + ":" + files.version(), nil
+}
+
+func pbockerRepository(arch string) string {
+	return "pbocker-" + arch
+}
+
+// checkPbockerImages warns about the architectures whose image has not
+// been built from the current templates yet.
+func checkPbockerImages() {
+	for _, arch := range builderArchitectures() {
+		image, err := pbockerImage(arch)
+		if err != nil {
+			log.Printf("Warning: %v\n", err)
+			continue
+		}
+		if _, err := containers.ImageID(context.Background(), image); err != nil {
+			log.Println("Warning: pbocker image " + image + " is missing, run irgsh-builder init-builder")
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPbocker(t *testing.T) {
+	files, err := renderPbocker(pbockerData{Arch: "arm64", MirrorSite: "http://deb.debian.org/debian"})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"Dockerfile", "pbuilderrc", "build.sh", "test.sh", "run-tests.sh", "lintian.sh", "hooks/G01resolvconf", "hooks/D10irgshrepo"}, keys(files))
+	assert.Contains(t, string(files["pbuilderrc"]), `MIRRORSITE="http://deb.debian.org/debian"`)
+	assert.Contains(t, string(files["Dockerfile"]), "COPY build.sh /build.sh")
+	assert.Contains(t, string(files["build.sh"]), `pbuilder --build $PBUILDER_BUILD_OPTS "${binnmu[@]}" /tmp/build/*.dsc`)
+	assert.Contains(t, string(files["Dockerfile"]), "COPY run-tests.sh /run-tests.sh")
+	assert.Contains(t, string(files["test.sh"]), `pbuilder execute --bindmounts /tmp/test -- /run-tests.sh "$TEST_COMMAND"`)
+	assert.Contains(t, string(files["Dockerfile"]), "COPY lintian.sh /lintian.sh")
+
+	dir := t.TempDir()
+	require.NoError(t, files.write(dir))
+	hook, err := os.ReadFile(filepath.Join(dir, "hooks", "G01resolvconf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hook), "nameserver 1.1.1.1")
+	hook, err = os.ReadFile(filepath.Join(dir, "hooks", "D10irgshrepo"))
+	require.NoError(t, err)
+	assert.Contains(t, string(hook), "/etc/apt/sources.list.d/irgsh-repo.list")
+}
+
+func TestRenderPbocker_Rootless(t *testing.T) {
+	files, err := renderPbocker(pbockerData{Arch: "amd64", MirrorSite: "http://deb.debian.org/debian"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(files["pbuilderrc"]), "BINDMOUNTS")
+	assert.NotContains(t, string(files["Dockerfile"]), "tar --delete")
+
+	rootless, err := renderPbocker(pbockerData{Arch: "amd64", MirrorSite: "http://deb.debian.org/debian", Rootless: true})
+	require.NoError(t, err)
+	assert.Contains(t, string(rootless["pbuilderrc"]), `BINDMOUNTS="/dev"`)
+	assert.Contains(t, string(rootless["Dockerfile"]), "xargs -r tar --delete -f base.tar")
+	assert.NotEqual(t, files.version(), rootless.version())
+}
+
+func TestRenderPbocker_MissingMirror(t *testing.T) {
+	_, err := renderPbocker(pbockerData{Arch: "amd64"})
+	assert.Error(t, err)
+}
+
+func TestPbockerContextVersion(t *testing.T) {
+	render := func(mirror string) string {
+		files, err := renderPbocker(pbockerData{Arch: "amd64", MirrorSite: mirror})
+		require.NoError(t, err)
+		return files.version()
+	}
+
+	v := render("http://deb.debian.org/debian")
+	assert.Len(t, v, 12)
+	assert.Equal(t, v, render("http://deb.debian.org/debian"))
+	assert.NotEqual(t, v, render("http://kartolo.sby.datautama.net.id/debian"))
+}
+
+func keys(files pbockerContext) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// testID returns the identifier of the working directory and container of
+// a test task. The task is claimed, and its log uploaded, under the
+// identifier of the build it tests.
+func testID(build payload.Build) string {
+	return buildID(build) + ".test"
+}
+
+// Test runs the package tests against the packages built for the
+// architecture of the payload. Each architecture of a pipeline is tested by
+// its own task, the repo task of the pipeline only runs once they all
+// passed.
+func Test(data string) (next string, err error) {
+	var build payload.Build
+	if err = payload.Decode(data, &build); err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+	next = data
+
+	taskUUID := build.TaskUUID
+	arch := buildArchitecture(build)
+	fmt.Println("Testing pipeline :" + taskUUID + " (" + arch + ")")
+
+	testPath := irgshConfig.Builder.Workdir + "/artifacts/" + testID(build)
+	logPath := testPath + "/test.log"
+	if err = os.MkdirAll(testPath, 0755); err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+	go systemutil.StreamLog(logPath)
+
+	taskID := buildID(build)
+	task := chiefclient.TaskEvent{TaskID: taskID, Stage: "test", Architecture: arch}
+	err = newChiefClient().Claim(context.Background(), taskID, "test")
+	if err != nil {
+		// Chief refuses the log of an unclaimed task, keep it here.
+		systemutil.WriteLog(logPath, "[ TEST FAILED ] Task claim failed: "+err.Error())
+		reportClaimFailure(taskUUID, task, err)
+		return
+	}
+	reportEnd := reportStart(taskUUID, task)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	if isCancelled(taskUUID) {
+		err = cancel.ErrCancelled
+		systemutil.WriteLog(logPath, "[ TEST CANCELLED ]")
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+
+	if !supportsArchitecture(arch) {
+		err = fmt.Errorf("this builder does not build for %s, it is configured for: %s", arch, irgshConfig.Builder.Architectures)
+		systemutil.WriteLog(logPath, "[ TEST FAILED ] "+err.Error())
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+
+	err = TestPreparation(build)
+	if err != nil {
+		systemutil.WriteLog(logPath, "[ TEST FAILED ] Test preparation failed: "+err.Error())
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+
+	// The tests get as long as the build did
+	timeout := irgshConfig.Builder.TimeoutFor(build.PackageName)
+	testCtx, stopTest := context.WithTimeout(context.Background(), timeout)
+	if cancelFlags != nil {
+		go cancel.Watch(testCtx, cancelFlags, taskUUID, cancelPollInterval, stopTest)
+	}
+	err = TestPackage(testCtx, build)
+	timedOut := errors.Is(testCtx.Err(), context.DeadlineExceeded)
+	stopTest()
+	if isCancelled(taskUUID) {
+		err = cancel.ErrCancelled
+		systemutil.WriteLog(logPath, "[ TEST CANCELLED ]")
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+	if timedOut {
+		err = fmt.Errorf("%w after %s", cancel.ErrTimedOut, timeout)
+		systemutil.WriteLog(logPath, "[ TEST TIMEOUT ] The tests did not finish within "+timeout.String()+", their container was killed")
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+	if err != nil {
+		systemutil.WriteLog(logPath, "[ TEST FAILED ] Package tests failed: "+err.Error())
+		uploadTaskLog(logPath, taskID, "test")
+		return
+	}
+
+	systemutil.WriteLog(logPath, "[ TEST DONE ]")
+	uploadTaskLog(logPath, taskID, "test")
+
+	fmt.Println("Done.")
+
+	return
+}
+
+// TestPreparation fetches the build artifact to test from chief. It holds
+// the source package along with the packages built from it. The arch:all
+// packages are only built for the first architecture of the pipeline, they
+// are fetched from its build artifact.
+func TestPreparation(build payload.Build) (err error) {
+	testPath := irgshConfig.Builder.Workdir + "/artifacts/" + testID(build)
+	logPath := testPath + "/test.log"
+	artifact := buildID(build)
+
+	err = fetchTestArtifact(artifact, testPath, logPath)
+	if err != nil {
+		return
+	}
+
+	if len(build.Architectures) == 0 || build.Architectures[0] == buildArchitecture(build) {
+		return
+	}
+	indep := build
+	indep.Architecture = build.Architectures[0]
+	indepArtifact := buildID(indep)
+	err = fetchTestArtifact(indepArtifact, testPath, logPath)
+	if err != nil {
+		return
+	}
+
+	cmdStr := "cd " + testPath
+	cmdStr += " && find " + indepArtifact + " -maxdepth 1 -name '*_all.deb' -exec cp {} " + artifact + "/ \\;"
+	cmdStr += " && rm -rf " + indepArtifact
+	_, err = systemutil.CmdExec(
+		cmdStr,
+		"Adding the arch:all packages built for "+indep.Architecture,
+		logPath,
+	)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+
+	return
+}
+
+// fetchTestArtifact downloads the build artifact from chief and extracts
+// it in testPath.
+func fetchTestArtifact(artifact, testPath, logPath string) (err error) {
+	systemutil.WriteLog(logPath, "Fetching the build artifact "+artifact+" from chief")
+	err = newChiefClient().DownloadArtifact(context.Background(), artifact, testPath+"/"+artifact+".tar.gz")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+
+	cmdStr := "cd " + testPath
+	cmdStr += " && rm -rf " + artifact
+	cmdStr += " && tar -xzf " + artifact + ".tar.gz"
+	cmdStr += " && rm -f " + artifact + ".tar.gz"
+	_, err = systemutil.CmdExec(
+		cmdStr,
+		"Extracting the build artifact",
+		logPath,
+	)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+		return
+	}
+
+	return
+}
+
+// TestPackage runs the package tests in a pbocker container, which is
+// killed once ctx is done.
+func TestPackage(ctx context.Context, build payload.Build) (err error) {
+	testPath := irgshConfig.Builder.Workdir + "/artifacts/" + testID(build)
+	logPath := testPath + "/test.log"
+	arch := buildArchitecture(build)
+
+	image, err := pbockerImage(arch)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	imageID, err := containers.ImageID(ctx, image)
+	if err != nil {
+		err = fmt.Errorf("pbocker image %s not found, run irgsh-builder init-builder: %w", image, err)
+		log.Println(err.Error())
+		return
+	}
+	systemutil.WriteLog(logPath, "Testing in "+image+" ("+imageID+")")
+
+	// See BuildPackage, the tests are isolated the same way
+	_ = containers.Remove(buildContainerName(testID(build)))
+	stopKill := context.AfterFunc(ctx, func() {
+		killBuildContainer(testID(build))
+	})
+	defer stopKill()
+
+	// See templates/test.sh.tmpl to modify the test script
+	err = containers.Run(ctx, runSpec{
+		Name:       buildContainerName(testID(build)),
+		Image:      image,
+		Platform:   dockerPlatform(arch),
+		Env:        map[string]string{"TEST_COMMAND": irgshConfig.Builder.TestCommand},
+		Volumes:    []volume{{Host: testPath + "/" + buildID(build), Container: "/tmp/test"}},
+		Privileged: true,
+		Command:    []string{"bash", "-c", "/test.sh"},
+	}, logPath)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	return
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blankon/irgsh-go/internal/payload"
+)
+
+// The apt sources and signing key of our repository are written next to
+// the source package, where the D10irgshrepo hook installs them in the
+// chroot, see templates/hooks/D10irgshrepo.tmpl.
+const (
+	repoSourcesFile = "irgsh-repo.list"
+	repoKeyFile     = "irgsh-repo.asc"
+	repoKeyring     = "/etc/apt/keyrings/irgsh-repo.asc"
+)
+
+// repoSources returns the apt sources of our repository a build installs
+// its dependencies from besides the upstream mirror: the suite, and its
+// experimental twin for experimental submissions. It returns nil when the
+// build only uses the upstream mirror.
+func repoSources(build payload.Build) []string {
+	if build.UpstreamOnly || irgshConfig.Builder.RepoURL == "" {
+		return nil
+	}
+	suite, components := build.Suite, ""
+	for _, dist := range irgshConfig.Repo.Suites() {
+		if suite == "" || dist.Codename == suite {
+			suite, components = dist.Codename, dist.Components
+			break
+		}
+	}
+	if suite == "" {
+		return nil
+	}
+	if components == "" {
+		components = "main"
+	}
+
+	base := strings.TrimSuffix(irgshConfig.Builder.RepoURL, "/")
+	source := func(codename string) string {
+		return fmt.Sprintf("deb [signed-by=%s] %s/%s %s %s", repoKeyring, base, codename, codename, components)
+	}
+	sources := []string{source(suite)}
+	if build.IsExperimental {
+		sources = append(sources, source(suite+"-experimental"))
+	}
+	return sources
+}
+
+// writeRepoSources writes the apt sources of our repository and its signing
+// key in the build directory. It reports whether the build uses them.
+func writeRepoSources(buildPath string, build payload.Build) (bool, error) {
+	sources := repoSources(build)
+	if len(sources) == 0 {
+		return false, nil
+	}
+	key, err := os.ReadFile(irgshConfig.Builder.RepoKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the repository key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(buildPath, repoKeyFile), key, 0644); err != nil {
+		return false, err
+	}
+	list := strings.Join(sources, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(buildPath, repoSourcesFile), []byte(list), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// repoSourcesOpts returns the pbuilder options making the build directory,
+// and the sources written there, visible from the chroot.
+func repoSourcesOpts(used bool) string {
+	if !used {
+		return ""
+	}
+	return "--bindmounts /tmp/build"
+}
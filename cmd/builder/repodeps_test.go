@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withRepoConfig(t *testing.T, url, key string) {
+	t.Helper()
+	saved := irgshConfig
+	t.Cleanup(func() { irgshConfig = saved })
+	irgshConfig.Builder.RepoURL = url
+	irgshConfig.Builder.RepoKey = key
+	irgshConfig.Repo = config.RepoConfig{
+		Distributions: []config.DistributionConfig{
+			{Codename: "verbeek", Components: "main restricted"},
+			{Codename: "tambora"},
+		},
+	}
+}
+
+func TestRepoSources(t *testing.T) {
+	withRepoConfig(t, "http://repo.blankon.id/", "/etc/irgsh/repo.asc")
+
+	assert.Equal(t, []string{
+		"deb [signed-by=/etc/apt/keyrings/irgsh-repo.asc] http://repo.blankon.id/verbeek verbeek main restricted",
+	}, repoSources(payload.Build{}))
+	assert.Equal(t, []string{
+		"deb [signed-by=/etc/apt/keyrings/irgsh-repo.asc] http://repo.blankon.id/tambora tambora main",
+		"deb [signed-by=/etc/apt/keyrings/irgsh-repo.asc] http://repo.blankon.id/tambora-experimental tambora-experimental main",
+	}, repoSources(payload.Build{Suite: "tambora", IsExperimental: true}))
+	assert.Nil(t, repoSources(payload.Build{UpstreamOnly: true}))
+	// Suites missing from the builder config only get the main component
+	assert.Equal(t, []string{
+		"deb [signed-by=/etc/apt/keyrings/irgsh-repo.asc] http://repo.blankon.id/nanggar nanggar main",
+	}, repoSources(payload.Build{Suite: "nanggar"}))
+
+	irgshConfig.Builder.RepoURL = ""
+	assert.Nil(t, repoSources(payload.Build{}))
+}
+
+func TestWriteRepoSources(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "repo.asc")
+	require.NoError(t, os.WriteFile(key, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\n"), 0644))
+	withRepoConfig(t, "http://repo.blankon.id", key)
+
+	buildPath := t.TempDir()
+	used, err := writeRepoSources(buildPath, payload.Build{Suite: "verbeek"})
+	require.NoError(t, err)
+	assert.True(t, used)
+	assert.Equal(t, "--bindmounts /tmp/build", repoSourcesOpts(used))
+
+	list, err := os.ReadFile(filepath.Join(buildPath, repoSourcesFile))
+	require.NoError(t, err)
+	assert.Equal(t, "deb [signed-by=/etc/apt/keyrings/irgsh-repo.asc] http://repo.blankon.id/verbeek verbeek main restricted\n", string(list))
+	_, err = os.Stat(filepath.Join(buildPath, repoKeyFile))
+	assert.NoError(t, err)
+
+	used, err = writeRepoSources(t.TempDir(), payload.Build{UpstreamOnly: true})
+	require.NoError(t, err)
+	assert.False(t, used)
+	assert.Equal(t, "", repoSourcesOpts(used))
+
+	irgshConfig.Builder.RepoKey = filepath.Join(t.TempDir(), "missing.asc")
+	_, err = writeRepoSources(t.TempDir(), payload.Build{})
+	assert.Error(t, err)
+}
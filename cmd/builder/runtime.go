@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// containerRuntime builds the pbocker images and runs the builds in them.
+type containerRuntime interface {
+	// BuildImage builds an image from a build context directory.
+	BuildImage(ctx context.Context, spec imageSpec, logPath string) error
+	// ImageID returns the ID of an image, or an error when it does not
+	// exist.
+	ImageID(ctx context.Context, image string) (string, error)
+	// Run runs a container until its command exits or ctx is done.
+	Run(ctx context.Context, spec runSpec, logPath string) error
+	// Kill stops a running container.
+	Kill(name string) error
+	// Remove removes a container left behind, running or not.
+	Remove(name string) error
+}
+
+// imageSpec describes an image to build.
+type imageSpec struct {
+	ContextDir string
+	Platform   string
+	Tags       []string
+	Labels     map[string]string
+}
+
+// runSpec describes a container to run.
+type runSpec struct {
+	Name       string
+	Image      string
+	Platform   string
+	Env        map[string]string
+	Volumes    []volume
+	Privileged bool
+	Command    []string
+}
+
+// volume mounts a host directory into a container.
+type volume struct {
+	Host      string
+	Container string
+}
+
+// containers is the runtime the builds run on, see builder.runtime.
+var containers containerRuntime = cliRuntime{command: "docker"}
+
+// newContainerRuntime returns the runtime called name in the builder
+// config.
+func newContainerRuntime(name string) (containerRuntime, error) {
+	switch name {
+	case "", "docker":
+		return cliRuntime{command: "docker"}, nil
+	case "podman":
+		return cliRuntime{command: "podman"}, nil
+	case "podman-rootless":
+		return cliRuntime{command: "podman", rootless: true}, nil
+	}
+	return nil, fmt.Errorf("unknown container runtime %q", name)
+}
+
+// cliRuntime drives the docker command line, or the podman one, which
+// takes the same arguments. A rootless podman runs the containers in a user
+// namespace of the builder user, whose root is the builder user and whose
+// other users are its subordinate ids, see /etc/subuid and /etc/subgid.
+type cliRuntime struct {
+	command  string
+	rootless bool
+}
+
+func (r cliRuntime) buildArgs(spec imageSpec) []string {
+	args := []string{r.command, "build", "--no-cache"}
+	if spec.Platform != "" {
+		args = append(args, "--platform", spec.Platform)
+	}
+	for _, tag := range spec.Tags {
+		args = append(args, "-t", tag)
+	}
+	for _, key := range sortedKeys(spec.Labels) {
+		args = append(args, "--label", key+"="+spec.Labels[key])
+	}
+	return append(args, spec.ContextDir)
+}
+
+func (r cliRuntime) runArgs(spec runSpec) []string {
+	args := []string{r.command, "run", "--rm", "-i"}
+	if spec.Name != "" {
+		args = append(args, "--name", spec.Name)
+	}
+	if spec.Platform != "" {
+		args = append(args, "--platform", spec.Platform)
+	}
+	for _, key := range sortedKeys(spec.Env) {
+		args = append(args, "-e", key+"="+spec.Env[key])
+	}
+	for _, v := range spec.Volumes {
+		args = append(args, "-v", v.Host+":"+v.Container)
+	}
+	if spec.Privileged {
+		args = append(args, "--privileged=true", "--user", "0:0")
+	}
+	if r.rootless {
+		// The files root writes in the volumes belong to the builder user,
+		// which cannot relabel them
+		args = append(args, "--userns", "keep-id:uid=0,gid=0", "--security-opt", "label=disable")
+	}
+	args = append(args, spec.Image)
+	return append(args, spec.Command...)
+}
+
+// chownArgs hands the files the users of a rootless container left in a
+// volume back to the builder user, the root of its user namespace.
+func (r cliRuntime) chownArgs(v volume) []string {
+	return []string{r.command, "unshare", "chown", "-R", "0:0", v.Host}
+}
+
+func (r cliRuntime) BuildImage(ctx context.Context, spec imageSpec, logPath string) error {
+	_, err := systemutil.CmdExecContext(ctx, shellJoin(r.buildArgs(spec)), "Building image "+strings.Join(spec.Tags, ", "), logPath)
+	return err
+}
+
+func (r cliRuntime) ImageID(ctx context.Context, image string) (string, error) {
+	out, err := systemutil.CmdExecContext(ctx, shellJoin([]string{r.command, "image", "inspect", "--format", "{{.Id}}", image}), "", "")
+	return strings.TrimSpace(out), err
+}
+
+func (r cliRuntime) Run(ctx context.Context, spec runSpec, logPath string) error {
+	_, err := systemutil.CmdExecContext(ctx, shellJoin(r.runArgs(spec)), "Running "+spec.Image+" with "+r.command, logPath)
+	if !r.rootless {
+		return err
+	}
+	// Even after a failed or killed build, for the builder to clean the
+	// volumes up and cache the packages apt downloaded
+	for _, v := range spec.Volumes {
+		if _, chownErr := systemutil.CmdExec(shellJoin(r.chownArgs(v)), "", ""); chownErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to take %s back from the container: %w", v.Host, chownErr))
+		}
+	}
+	return err
+}
+
+func (r cliRuntime) Kill(name string) error {
+	_, err := systemutil.CmdExec(shellJoin([]string{r.command, "kill", name}), "", "")
+	return err
+}
+
+func (r cliRuntime) Remove(name string) error {
+	_, err := systemutil.CmdExec(shellJoin([]string{r.command, "rm", "--force", name}), "", "")
+	return err
+}
+
+// checkRootlessWorkdir checks that the containers of a rootless podman can
+// write in the builder workdir and its apt cache. Their root is the user
+// running the builder, which has to own them.
+func checkRootlessWorkdir(workdir string) error {
+	if os.Geteuid() == 0 {
+		return fmt.Errorf("builder.runtime podman-rootless runs the builds as the user running irgsh-builder, run it as the builder user rather than root")
+	}
+	for _, dir := range []string{workdir, filepath.Join(workdir, "aptcache")} {
+		info, err := os.Stat(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+			return fmt.Errorf("%s is owned by uid %d, builder.runtime podman-rootless needs it owned by the builder user (uid %d)", dir, stat.Uid, os.Geteuid())
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellJoin quotes args into a command line for CmdExec, which runs its
+// commands through bash.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,+@") == "" {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
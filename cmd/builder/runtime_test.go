@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLIRuntime_BuildArgs(t *testing.T) {
+	args := cliRuntime{command: "docker"}.buildArgs(imageSpec{
+		ContextDir: "/var/lib/irgsh/builder/pbocker/amd64",
+		Platform:   "linux/amd64",
+		Tags:       []string{"pbocker-amd64:0123456789ab", "pbocker-amd64:latest"},
+		Labels:     map[string]string{pbockerVersionLabel: "0123456789ab", builderVersionLabel: "0.1.0"},
+	})
+	assert.Equal(t, []string{
+		"docker", "build", "--no-cache", "--platform", "linux/amd64",
+		"-t", "pbocker-amd64:0123456789ab", "-t", "pbocker-amd64:latest",
+		"--label", "org.blankon.irgsh.builder.version=0.1.0",
+		"--label", "org.blankon.irgsh.pbocker.version=0123456789ab",
+		"/var/lib/irgsh/builder/pbocker/amd64",
+	}, args)
+}
+
+func TestCLIRuntime_RunArgs(t *testing.T) {
+	args := cliRuntime{command: "docker"}.runArgs(runSpec{
+		Name:       "irgsh-build-task.amd64",
+		Image:      "pbocker-amd64:0123456789ab",
+		Platform:   "linux/amd64",
+		Env:        map[string]string{"PBUILDER_BUILD_OPTS": "--binary-arch"},
+		Volumes:    []volume{{Host: "/var/lib/irgsh/builder/artifacts/task.amd64", Container: "/tmp/build"}},
+		Privileged: true,
+		Command:    []string{"bash", "-c", "/build.sh"},
+	})
+	assert.Equal(t, []string{
+		"docker", "run", "--rm", "-i", "--name", "irgsh-build-task.amd64",
+		"--platform", "linux/amd64",
+		"-e", "PBUILDER_BUILD_OPTS=--binary-arch",
+		"-v", "/var/lib/irgsh/builder/artifacts/task.amd64:/tmp/build",
+		"--privileged=true", "--user", "0:0",
+		"pbocker-amd64:0123456789ab", "bash", "-c", "/build.sh",
+	}, args)
+}
+
+func TestCLIRuntime_RootlessPodman(t *testing.T) {
+	r, err := newContainerRuntime("podman-rootless")
+	require.NoError(t, err)
+
+	spec := runSpec{
+		Image:      "pbocker-amd64:0123456789ab",
+		Volumes:    []volume{{Host: "/var/lib/irgsh/builder/artifacts/task.amd64", Container: "/tmp/build"}},
+		Privileged: true,
+		Command:    []string{"bash", "-c", "/build.sh"},
+	}
+	assert.Equal(t, []string{
+		"podman", "run", "--rm", "-i",
+		"-v", "/var/lib/irgsh/builder/artifacts/task.amd64:/tmp/build",
+		"--privileged=true", "--user", "0:0",
+		"--userns", "keep-id:uid=0,gid=0", "--security-opt", "label=disable",
+		"pbocker-amd64:0123456789ab", "bash", "-c", "/build.sh",
+	}, r.(cliRuntime).runArgs(spec))
+	assert.Equal(t, []string{
+		"podman", "unshare", "chown", "-R", "0:0", "/var/lib/irgsh/builder/artifacts/task.amd64",
+	}, r.(cliRuntime).chownArgs(spec.Volumes[0]))
+}
+
+func TestCheckRootlessWorkdir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		assert.ErrorContains(t, checkRootlessWorkdir(t.TempDir()), "rather than root")
+		return
+	}
+	dir := t.TempDir()
+	assert.NoError(t, checkRootlessWorkdir(dir))
+	// The workdir and the apt cache are created on the first run
+	assert.NoError(t, checkRootlessWorkdir(filepath.Join(dir, "missing")))
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(t, "docker kill irgsh-build-task.amd64", shellJoin([]string{"docker", "kill", "irgsh-build-task.amd64"}))
+	assert.Equal(t, "-e 'OPTS=--binary-arch --aptcache /tmp/build/aptcache' ''", shellJoin([]string{"-e", "OPTS=--binary-arch --aptcache /tmp/build/aptcache", ""}))
+	assert.Equal(t, `'it'\''s'`, shellJoin([]string{"it's"}))
+}
+
+func TestNewContainerRuntime(t *testing.T) {
+	r, err := newContainerRuntime("")
+	require.NoError(t, err)
+	assert.Equal(t, cliRuntime{command: "docker"}, r)
+
+	r, err = newContainerRuntime("podman")
+	require.NoError(t, err)
+	assert.Equal(t, cliRuntime{command: "podman"}, r)
+
+	r, err = newContainerRuntime("podman-rootless")
+	require.NoError(t, err)
+	assert.Equal(t, cliRuntime{command: "podman", rootless: true}, r)
+
+	_, err = newContainerRuntime("lxc")
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// fetchTimeout bounds how long a fetch holds its build slot. Machinery
+// polls a queue for a second at most, and has no hook telling that the
+// poll came back empty, so a fetch that did not start a task by then gave
+// its slot back.
+const fetchTimeout = 5 * time.Second
+
+// slotPollInterval is how often a fetch or a task waiting for a free build
+// slot checks again.
+var slotPollInterval = 200 * time.Millisecond
+
+// buildSlotGate caps the builds running at the same time to
+// builder.concurrency, across the workers consuming each build queue. The
+// workers only fetch a task once they hold a free slot, so the tasks this
+// builder cannot run yet stay queued for the other builders.
+type buildSlotGate struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	fetches []time.Time // When the pending fetches started, oldest first
+	now     func() time.Time
+}
+
+func newBuildSlotGate(slots int) *buildSlotGate {
+	return &buildSlotGate{slots: slots, now: time.Now}
+}
+
+// Fetch blocks until a build slot is free, and holds it for the fetch
+// about to be made.
+func (g *buildSlotGate) Fetch() {
+	for !g.tryTake(func() { g.fetches = append(g.fetches, g.now()) }) {
+		time.Sleep(slotPollInterval)
+	}
+}
+
+// Start takes the slot held by the oldest pending fetch for the task it
+// got, or waits for a free one when that fetch has timed out.
+func (g *buildSlotGate) Start() {
+	g.mu.Lock()
+	g.expireFetches()
+	if len(g.fetches) > 0 {
+		g.fetches = g.fetches[1:]
+		g.running++
+		g.mu.Unlock()
+		return
+	}
+	g.mu.Unlock()
+
+	for !g.tryTake(func() { g.running++ }) {
+		time.Sleep(slotPollInterval)
+	}
+}
+
+// Done gives the slot of a finished task back.
+func (g *buildSlotGate) Done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+}
+
+// tryTake runs take when a build slot is free.
+func (g *buildSlotGate) tryTake(take func()) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.expireFetches()
+	if g.running+len(g.fetches) >= g.slots {
+		return false
+	}
+	take()
+	return true
+}
+
+func (g *buildSlotGate) expireFetches() {
+	now := g.now()
+	for len(g.fetches) > 0 && now.Sub(g.fetches[0]) > fetchTimeout {
+		g.fetches = g.fetches[1:]
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// returnsWithin tells whether f returns before timeout.
+func returnsWithin(f func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestBuildSlotGate_FetchesOnlyWithFreeSlots(t *testing.T) {
+	slotPollInterval = time.Millisecond
+	gate := newBuildSlotGate(2)
+
+	// Two workers fetch at once, a third one waits for a slot
+	gate.Fetch()
+	gate.Fetch()
+	assert.False(t, returnsWithin(gate.Fetch, 50*time.Millisecond))
+
+	// Both fetches got a task, which run in their slots
+	gate.Start()
+	gate.Start()
+	assert.Equal(t, 2, gate.running)
+	assert.Empty(t, gate.fetches)
+
+	// The waiting fetch goes once a task is done
+	gate.Done()
+	assert.Eventually(t, func() bool {
+		gate.mu.Lock()
+		defer gate.mu.Unlock()
+		return len(gate.fetches) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestBuildSlotGate_EmptyFetchesTimeOut(t *testing.T) {
+	slotPollInterval = time.Millisecond
+	gate := newBuildSlotGate(1)
+	now := time.Now()
+	gate.now = func() time.Time { return now }
+
+	// The queue had nothing for the fetch, which gives its slot back
+	gate.Fetch()
+	assert.False(t, returnsWithin(gate.Fetch, 50*time.Millisecond))
+	now = now.Add(fetchTimeout + time.Second)
+	assert.True(t, returnsWithin(gate.Fetch, time.Second))
+
+	// A task fetched after its fetch timed out still gets a slot
+	now = now.Add(fetchTimeout + time.Second)
+	assert.True(t, returnsWithin(gate.Start, time.Second))
+	assert.False(t, returnsWithin(gate.Start, 50*time.Millisecond))
+	assert.Equal(t, 1, gate.running)
+}
@@ -6,8 +6,10 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
 	"github.com/blankon/irgsh-go/pkg/httputil"
 )
 
@@ -18,11 +20,25 @@ type ChiefService interface {
 	GetMaintainers() []domain.Maintainer
 	ListMaintainersRaw() (string, error)
 	SubmitPackage(domain.Submission) (domain.SubmitPayloadResponse, error)
+	SubmitBatch(domain.BatchSubmission) (domain.BatchResponse, error)
 	RetryPipeline(string) (domain.SubmitPayloadResponse, error)
+	CancelPipeline(signedRequest []byte) (domain.SubmitPayloadResponse, error)
+	JobEvents(string) (domain.JobEventsResponse, error)
+	RecordTaskEvent(string, domain.TaskEvent) error
+	BuildDurations(string) (domain.BuildDurationsResponse, error)
+	PromotePackage([]byte) (domain.SubmitPayloadResponse, error)
+	RemovePackage([]byte) (domain.SubmitPayloadResponse, error)
+	RebuildPackage([]byte) (domain.SubmitPayloadResponse, error)
+	ListSnapshots() ([]domain.Snapshot, error)
+	CreateSnapshot([]byte) (domain.SubmitPayloadResponse, error)
+	RestoreSnapshot([]byte) (domain.SubmitPayloadResponse, error)
 	BuildStatus(string) (domain.BuildStatusResponse, error)
 	ISOStatus(string) (string, string, error)
 	BuildISO(domain.ISOSubmission) (domain.SubmitPayloadResponse, error)
-	UploadArtifact(string, io.Reader) error
+	ClaimTask(token, id, kind, remoteAddr string) error
+	AuthorizeUpload(token, id, kind, remoteAddr string) error
+	AuthorizeEvent(token string, event domain.TaskEvent, remoteAddr string) error
+	UploadArtifact(string, io.Reader, string) error
 	UploadLog(string, string, io.Reader) error
 	UploadSubmission([]byte, io.Reader) (string, error)
 }
@@ -95,6 +111,126 @@ func PackageSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, payload)
 }
 
+func BatchSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var batch domain.BatchSubmission
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.SubmitBatch(batch)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// maxSignedRequestSize bounds the body of clearsigned API requests.
+const maxSignedRequestSize = 64 << 10
+
+func PromoteHandler(w http.ResponseWriter, r *http.Request) {
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.PromotePackage(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func RebuildHandler(w http.ResponseWriter, r *http.Request) {
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.RebuildPackage(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func RemoveHandler(w http.ResponseWriter, r *http.Request) {
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.RemovePackage(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func SnapshotListHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := chiefService.ListSnapshots()
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+func SnapshotCreateHandler(w http.ResponseWriter, r *http.Request) {
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.CreateSnapshot(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func SnapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.RestoreSnapshot(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
 func BuildStatusHandler(w http.ResponseWriter, r *http.Request) {
 	keys, ok := r.URL.Query()["uuid"]
 	if !ok {
@@ -157,6 +293,102 @@ func RetryHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, payload)
 }
 
+func CancelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	signedRequest, err := io.ReadAll(io.LimitReader(r.Body, maxSignedRequestSize))
+	if err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payload, err := chiefService.CancelPipeline(signedRequest)
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// JobEventsHandler lists the events of the tasks of a pipeline.
+func JobEventsHandler(w http.ResponseWriter, r *http.Request) {
+	events, err := chiefService.JobEvents(r.PathValue("uuid"))
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+// BuildDurationsHandler reports the p50 and p95 build durations per package
+// and per builder, of the package given by the package parameter or of
+// every package.
+func BuildDurationsHandler(w http.ResponseWriter, r *http.Request) {
+	durations, err := chiefService.BuildDurations(r.URL.Query().Get("package"))
+	if err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, durations)
+}
+
+// TaskEventHandler records the event a worker reports on a task it claimed,
+// or the failure of a task whose claim chief refused.
+func TaskEventHandler(w http.ResponseWriter, r *http.Request) {
+	var event domain.TaskEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		log.Println(err.Error())
+		writeJSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if event.TaskID == "" || event.Stage == "" {
+		writeJSONError(w, http.StatusBadRequest, "taskId and stage are required")
+		return
+	}
+
+	if err := chiefService.AuthorizeEvent(workerToken(r), event, r.RemoteAddr); err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+	if err := chiefService.RecordTaskEvent(r.PathValue("uuid"), event); err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// workerToken returns the bearer token a worker request carries.
+func workerToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+// ClaimHandler records that the calling worker took a task, so that only it
+// can upload the task's artifacts and logs.
+func ClaimHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	kind := r.URL.Query().Get("kind")
+	if id == "" || kind == "" {
+		writeJSONError(w, http.StatusBadRequest, "id and kind parameters are required")
+		return
+	}
+
+	if err := chiefService.ClaimTask(workerToken(r), id, kind, r.RemoteAddr); err != nil {
+		writeUsecaseError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func artifactUploadHandler() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		keys, ok := r.URL.Query()["id"]
@@ -169,6 +401,11 @@ func artifactUploadHandler() http.HandlerFunc {
 
 		id := keys[0]
 
+		if err := chiefService.AuthorizeUpload(workerToken(r), id, "build", r.RemoteAddr); err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+
 		file, _, err := r.FormFile("uploadFile")
 		if err != nil {
 			log.Println(err.Error())
@@ -177,7 +414,8 @@ func artifactUploadHandler() http.HandlerFunc {
 		}
 		defer file.Close()
 
-		if err := chiefService.UploadArtifact(id, file); err != nil {
+		checksum := r.Header.Get(chiefclient.ChecksumHeader)
+		if err := chiefService.UploadArtifact(id, file, checksum); err != nil {
 			writeUsecaseError(w, err)
 			return
 		}
@@ -208,6 +446,11 @@ func logUploadHandler() http.HandlerFunc {
 
 		logType := keys[0]
 
+		if err := chiefService.AuthorizeUpload(workerToken(r), id, logType, r.RemoteAddr); err != nil {
+			writeUsecaseError(w, err)
+			return
+		}
+
 		file, _, err := r.FormFile("uploadFile")
 		if err != nil {
 			log.Println(err.Error())
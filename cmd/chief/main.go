@@ -16,10 +16,12 @@ import (
 	artifactEndpoint "github.com/blankon/irgsh-go/internal/artifact/endpoint"
 	artifactRepo "github.com/blankon/irgsh-go/internal/artifact/repo"
 	artifactService "github.com/blankon/irgsh-go/internal/artifact/service"
+	"github.com/blankon/irgsh-go/internal/cancel"
 	chiefrepository "github.com/blankon/irgsh-go/internal/chief/repository"
 	chiefusecase "github.com/blankon/irgsh-go/internal/chief/usecase"
 	"github.com/blankon/irgsh-go/internal/config"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/queue"
 	"github.com/blankon/irgsh-go/internal/storage"
 )
 
@@ -86,20 +88,28 @@ func main() {
 			&machineryConfig.Config{
 				Broker:        irgshConfig.Redis,
 				ResultBackend: irgshConfig.Redis,
-				DefaultQueue:  "irgsh",
+				DefaultQueue:  queue.Default,
 			},
 		)
 		if err != nil {
 			log.Fatalf("Could not create server: %v", err)
 		}
 
-		taskQueue := chiefrepository.NewMachineryTaskQueue(server)
+		cancelFlags, err := cancel.NewFlags(irgshConfig.Redis)
+		if err != nil {
+			log.Fatalf("Could not create cancellation flags: %v", err)
+		}
+
+		taskQueue := chiefrepository.NewMachineryTaskQueue(server, cancelFlags)
 		svc, err := chiefusecase.NewChiefUsecase(
 			irgshConfig,
 			taskQueue,
 			monitoringRegistry,
 			chiefStorage,
 			chiefGPG,
+			chiefrepository.NewRepoClient(irgshConfig.Repo.Address),
+			storage.NewWorkerStore(storageDB, 0),
+			storage.NewBatchStore(storageDB, 0),
 			version,
 		)
 		if err != nil {
@@ -112,6 +122,9 @@ func main() {
 		if irgshConfig.Monitoring.Enabled && monitoringRegistry != nil {
 			go startInstanceCleanup(irgshConfig, monitoringRegistry)
 		}
+		go startBatchScheduler(svc)
+		go startReproChecks(svc)
+		go startJobReconciliation(svc)
 
 		// Graceful shutdown
 		shutdownDone := make(chan struct{})
@@ -143,8 +156,20 @@ func setupRoutes(cfg config.IrgshConfig, artifactEP *artifactEndpoint.ArtifactHT
 
 	mux.HandleFunc("/api/v1/artifacts", artifactEP.GetArtifactListHandler)
 	mux.HandleFunc("/api/v1/submit", PackageSubmitHandler)
+	mux.HandleFunc("/api/v1/batch", BatchSubmitHandler)
 	mux.HandleFunc("/api/v1/status", BuildStatusHandler)
 	mux.HandleFunc("/api/v1/retry", RetryHandler)
+	mux.HandleFunc("/api/v1/cancel", CancelHandler)
+	mux.HandleFunc("GET /api/v1/jobs/{uuid}/events", JobEventsHandler)
+	mux.HandleFunc("POST /api/v1/jobs/{uuid}/events", TaskEventHandler)
+	mux.HandleFunc("GET /api/v1/stats/build-durations", BuildDurationsHandler)
+	mux.HandleFunc("/api/v1/promote", PromoteHandler)
+	mux.HandleFunc("/api/v1/remove", RemoveHandler)
+	mux.HandleFunc("/api/v1/rebuild", RebuildHandler)
+	mux.HandleFunc("/api/v1/snapshots", SnapshotListHandler)
+	mux.HandleFunc("/api/v1/snapshot-create", SnapshotCreateHandler)
+	mux.HandleFunc("/api/v1/snapshot-restore", SnapshotRestoreHandler)
+	mux.HandleFunc("/api/v1/claim", ClaimHandler)
 	mux.HandleFunc("/api/v1/artifact-upload", artifactUploadHandler())
 	mux.HandleFunc("/api/v1/log-upload", logUploadHandler())
 	mux.HandleFunc("/api/v1/submission-upload", submissionUploadHandler())
@@ -187,6 +212,45 @@ func startInstanceCleanup(cfg config.IrgshConfig, registry *monitoring.Registry)
 	}
 }
 
+// batchScheduleInterval is how often the packages of running batches are
+// checked for dependencies that finished building.
+const batchScheduleInterval = 15 * time.Second
+
+func startBatchScheduler(svc *chiefusecase.ChiefUsecase) {
+	ticker := time.NewTicker(batchScheduleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		svc.ScheduleBatches()
+	}
+}
+
+// reproCheckInterval is how often the pipelines checked for
+// reproducibility are checked for builds to compare.
+const reproCheckInterval = 30 * time.Second
+
+func startReproChecks(svc *chiefusecase.ChiefUsecase) {
+	ticker := time.NewTicker(reproCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		svc.CheckReproducibility()
+	}
+}
+
+// jobReconcileInterval is how often the unfinished jobs are checked for
+// tasks that ended without their worker reporting it.
+const jobReconcileInterval = 5 * time.Minute
+
+func startJobReconciliation(svc *chiefusecase.ChiefUsecase) {
+	ticker := time.NewTicker(jobReconcileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		svc.ReconcileJobs()
+	}
+}
+
 func handleShutdown(httpServer *http.Server, storageDB *storage.DB, registry *monitoring.Registry) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
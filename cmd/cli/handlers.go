@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/blankon/irgsh-go/internal/cli/domain"
 	"github.com/urfave/cli"
@@ -12,12 +13,20 @@ import (
 type CLIService interface {
 	SaveConfig(cfg domain.Config) error
 	SubmitPackage(ctx context.Context, params domain.SubmitParams) (domain.SubmitResponse, error)
+	SubmitBatch(ctx context.Context, batchPath string, ignoreChecks bool) (domain.BatchResponse, error)
 	PackageStatus(ctx context.Context, pipelineID string) (domain.PackageStatus, error)
 	PackageLog(ctx context.Context, pipelineID string) (buildLog, repoLog string, err error)
 	SubmitISO(ctx context.Context, repoURL, branch string) (domain.SubmitResponse, error)
 	ISOStatus(ctx context.Context, pipelineID string) (domain.ISOStatus, error)
 	ISOLog(ctx context.Context, pipelineID string) (string, error)
 	RetryPipeline(ctx context.Context, pipelineID string) (domain.RetryResponse, error)
+	CancelPipeline(ctx context.Context, pipelineID string) (domain.CancelResponse, error)
+	PromotePackage(ctx context.Context, packageName, packageVersion, suite string) (domain.SubmitResponse, error)
+	RemovePackage(ctx context.Context, packageName, suite, component string) (domain.SubmitResponse, error)
+	RebuildPackages(ctx context.Context, packageNames []string, suite, reason string) ([]domain.SubmitResponse, error)
+	ListSnapshots(ctx context.Context) ([]domain.Snapshot, error)
+	CreateSnapshot(ctx context.Context, repository string) (domain.SubmitResponse, error)
+	RestoreSnapshot(ctx context.Context, snapshotID string) (domain.SubmitResponse, error)
 	UpdateCLI(ctx context.Context) error
 }
 
@@ -47,7 +56,7 @@ func buildApp(ctx context.Context, svc CLIService, version string) *cli.App {
 		},
 		{
 			Name:  "package",
-			Usage: "Submit a package build job, or use subcommands (status, log)",
+			Usage: "Submit a package build job, or use subcommands (batch, status, log, cancel, promote, remove)",
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "source",
@@ -81,9 +90,41 @@ func buildApp(ctx context.Context, svc CLIService, version string) *cli.App {
 					Name:  "force-version",
 					Usage: "Force overwrite existing package version in repository",
 				},
+				cli.StringFlag{
+					Name:  "suite",
+					Usage: "Target suite, must match the debian/changelog distribution (default: chief's default suite)",
+				},
+				cli.StringFlag{
+					Name:  "builder-label",
+					Usage: "Only build on builders advertising this label",
+				},
+				cli.BoolFlag{
+					Name:  "upstream-only",
+					Usage: "Install build dependencies from the upstream mirror only, not from our repository",
+				},
+				cli.BoolFlag{
+					Name:  "check-reproducibility",
+					Usage: "Build the package a second time and compare the binaries of both builds",
+				},
+				cli.BoolFlag{
+					Name:  "run-tests",
+					Usage: "Run the package tests (debian/tests) on the built packages before publishing them",
+				},
 			},
 			Action: packageSubmitAction(ctx, svc),
 			Subcommands: []cli.Command{
+				{
+					Name:      "batch",
+					Usage:     "Submit several packages, built in the order of their build dependencies",
+					ArgsUsage: "<file.yaml>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "ignore-checks",
+							Usage: "Ignore all validation check and restriction",
+						},
+					},
+					Action: packageBatchAction(ctx, svc),
+				},
 				{
 					Name:   "status",
 					Usage:  "Check status of a package build pipeline",
@@ -94,6 +135,56 @@ func buildApp(ctx context.Context, svc CLIService, version string) *cli.App {
 					Usage:  "Read the logs of a package build pipeline",
 					Action: packageLogAction(ctx, svc),
 				},
+				{
+					Name:      "cancel",
+					Usage:     "Cancel a queued or running package build pipeline",
+					ArgsUsage: "[pipeline-id]",
+					Action:    packageCancelAction(ctx, svc),
+				},
+				{
+					Name:      "promote",
+					Usage:     "Promote a package version from experimental to dev",
+					ArgsUsage: "<name> <version>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "suite",
+							Usage: "Suite to promote within (default: chief's default suite)",
+						},
+					},
+					Action: packagePromoteAction(ctx, svc),
+				},
+				{
+					Name:      "remove",
+					Usage:     "Remove a source package and its binaries from the repository",
+					ArgsUsage: "<source>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "suite",
+							Usage: "Suite to remove the package from (default: chief's default suite)",
+						},
+						cli.StringFlag{
+							Name:  "component",
+							Usage: "Repository component holding the package (required)",
+						},
+					},
+					Action: packageRemoveAction(ctx, svc),
+				},
+				{
+					Name:      "rebuild",
+					Usage:     "Rebuild the binaries of published source packages (binNMU)",
+					ArgsUsage: "<source>...",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "suite",
+							Usage: "Suite to rebuild the packages in (default: chief's default suite)",
+						},
+						cli.StringFlag{
+							Name:  "reason",
+							Usage: "Changelog entry of the rebuild, e.g. \"Rebuild against libfoo2\" (required)",
+						},
+					},
+					Action: packageRebuildAction(ctx, svc),
+				},
 			},
 		},
 		{
@@ -132,6 +223,29 @@ func buildApp(ctx context.Context, svc CLIService, version string) *cli.App {
 				},
 			},
 		},
+		{
+			Name:  "snapshot",
+			Usage: "Repository snapshot commands (list, create, restore)",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "List the repository snapshots, newest first",
+					Action: snapshotListAction(ctx, svc),
+				},
+				{
+					Name:      "create",
+					Usage:     "Take a snapshot of a repository",
+					ArgsUsage: "[repository]",
+					Action:    snapshotCreateAction(ctx, svc),
+				},
+				{
+					Name:      "restore",
+					Usage:     "Roll a repository back to a snapshot",
+					ArgsUsage: "<snapshot-id>",
+					Action:    snapshotRestoreAction(ctx, svc),
+				},
+			},
+		},
 		{
 			Name:   "update",
 			Usage:  "Update the irgsh-cli tool",
@@ -159,20 +273,32 @@ func configAction(svc CLIService) cli.ActionFunc {
 func packageSubmitAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		params := domain.SubmitParams{
-			PackageURL:     c.String("package"),
-			SourceURL:      c.String("source"),
-			Component:      c.String("component"),
-			PackageBranch:  c.String("package-branch"),
-			SourceBranch:   c.String("source-branch"),
-			IsExperimental: c.Bool("experimental"),
-			IgnoreChecks:   c.Bool("ignore-checks"),
-			ForceVersion:   c.Bool("force-version"),
+			PackageURL:           c.String("package"),
+			SourceURL:            c.String("source"),
+			Component:            c.String("component"),
+			PackageBranch:        c.String("package-branch"),
+			SourceBranch:         c.String("source-branch"),
+			IsExperimental:       c.Bool("experimental"),
+			IgnoreChecks:         c.Bool("ignore-checks"),
+			ForceVersion:         c.Bool("force-version"),
+			BuilderLabel:         c.String("builder-label"),
+			Suite:                c.String("suite"),
+			UpstreamOnly:         c.Bool("upstream-only"),
+			CheckReproducibility: c.Bool("check-reproducibility"),
+			RunTests:             c.Bool("run-tests"),
 		}
 		_, err := svc.SubmitPackage(ctx, params)
 		return err
 	}
 }
 
+func packageBatchAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.SubmitBatch(ctx, c.Args().First(), c.Bool("ignore-checks"))
+		return err
+	}
+}
+
 func packageStatusAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		pipelineID := c.Args().First()
@@ -182,6 +308,23 @@ func packageStatusAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 		}
 		fmt.Printf("Job Status:   %s\n", status.JobStatus)
 		fmt.Printf("Build Status: %s\n", status.BuildStatus)
+		for _, arch := range status.Architectures {
+			fmt.Printf("  %-10s  %s\n", arch.Architecture+":", arch.BuildStatus)
+		}
+		if status.Estimate != nil {
+			fmt.Printf("Estimated:    %s left to build (p50 %s, p95 %s over %d past build(s))\n", status.Estimate.Remaining, status.Estimate.P50, status.Estimate.P95, status.Estimate.Builds)
+		}
+		if status.Lintian != nil {
+			fmt.Printf("Lintian:      %d error(s), %d warning(s), %d info\n", status.Lintian.Errors, status.Lintian.Warnings, status.Lintian.Info)
+		}
+		if status.TestStatus != "" {
+			fmt.Printf("Test Status:  %s\n", status.TestStatus)
+			for _, arch := range status.Architectures {
+				if arch.TestStatus != "" {
+					fmt.Printf("  %-10s  %s\n", arch.Architecture+":", arch.TestStatus)
+				}
+			}
+		}
 		fmt.Printf("Repo Status:  %s\n", status.RepoStatus)
 		return nil
 	}
@@ -200,6 +343,58 @@ func packageLogAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	}
 }
 
+func packagePromoteAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.PromotePackage(ctx, c.Args().Get(0), c.Args().Get(1), c.String("suite"))
+		return err
+	}
+}
+
+func packageRemoveAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.RemovePackage(ctx, c.Args().First(), c.String("suite"), c.String("component"))
+		return err
+	}
+}
+
+func packageRebuildAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.RebuildPackages(ctx, c.Args(), c.String("suite"), c.String("reason"))
+		return err
+	}
+}
+
+func snapshotListAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		snapshots, err := svc.ListSnapshots(ctx)
+		if err != nil {
+			return err
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots")
+			return nil
+		}
+		for _, s := range snapshots {
+			fmt.Printf("%s\t%s\t%s\n", s.ID, s.Repository, s.CreatedAt.Local().Format(time.RFC3339))
+		}
+		return nil
+	}
+}
+
+func snapshotCreateAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.CreateSnapshot(ctx, c.Args().First())
+		return err
+	}
+}
+
+func snapshotRestoreAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		_, err := svc.RestoreSnapshot(ctx, c.Args().First())
+		return err
+	}
+}
+
 func livebuildSubmitAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		_, err := svc.SubmitISO(ctx, c.String("lb-url"), c.String("lb-branch"))
@@ -232,6 +427,14 @@ func livebuildLogAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	}
 }
 
+func packageCancelAction(ctx context.Context, svc CLIService) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		pipelineID := c.Args().First()
+		_, err := svc.CancelPipeline(ctx, pipelineID)
+		return err
+	}
+}
+
 func retryAction(ctx context.Context, svc CLIService) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		pipelineID := c.Args().First()
@@ -1,33 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 
+	"github.com/blankon/irgsh-go/internal/chiefclient"
 	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
 	"github.com/blankon/irgsh-go/pkg/systemutil"
 )
 
-// ISOSubmission represents the payload for ISO build
-type ISOSubmission struct {
-	TaskUUID  string `json:"taskUUID"`
-	RepoURL   string `json:"repoUrl"`
-	Branch    string `json:"branch"`
-	Timestamp string `json:"timestamp"`
+func newChiefClient() *chiefclient.Client {
+	return chiefclient.New(irgshConfig.Chief.Address, irgshConfig.Worker.Token)
 }
 
 func uploadLog(logPath string, id string) {
 	// Upload the log to chief
-	cmdStr := "curl -v -F 'uploadFile=@" + logPath + "' '"
-	cmdStr += irgshConfig.Chief.Address + "/api/v1/log-upload?id=" + id + "&type=iso'"
-	_, err := systemutil.CmdExec(
-		cmdStr,
-		"Uploading log file to chief",
-		"",
-	)
+	err := newChiefClient().UploadLog(context.Background(), id, "iso", logPath)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
@@ -44,12 +36,11 @@ func sendISONotification(taskUUID, status string, jobInfo notification.JobNotifi
 }
 
 // BuildISO is the main ISO build task
-func BuildISO(payload string) (next string, err error) {
-	in := []byte(payload)
-	var submission ISOSubmission
-	err = json.Unmarshal(in, &submission)
+func BuildISO(data string) (next string, err error) {
+	var submission payload.ISO
+	err = payload.Decode(data, &submission)
 	if err != nil {
-		log.Printf("Failed to unmarshal payload: %v\n", err)
+		log.Printf("Failed to decode payload: %v\n", err)
 		return "", err
 	}
 
@@ -83,6 +74,13 @@ func BuildISO(payload string) (next string, err error) {
 		}
 	}()
 
+	err = newChiefClient().Claim(context.Background(), taskUUID, "iso")
+	if err != nil {
+		// Chief refuses the log of an unclaimed task, keep it here.
+		systemutil.WriteLog(logPath, "[ ISO BUILD FAILED ] Task claim failed: "+err.Error())
+		return "", err
+	}
+
 	// Run the iso-build.sh script from /usr/share/irgsh/
 	scriptPath := "/usr/share/irgsh/iso-build.sh"
 
@@ -136,6 +134,6 @@ func BuildISO(payload string) (next string, err error) {
 	uploadLog(logPath, taskUUID)
 
 	fmt.Println("ISO build done.")
-	next = payload
+	next = data
 	return
 }
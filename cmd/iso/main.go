@@ -15,6 +15,7 @@ import (
 
 	"github.com/blankon/irgsh-go/internal/config"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/queue"
 )
 
 var (
@@ -93,7 +94,7 @@ func main() {
 			&machineryConfig.Config{
 				Broker:        irgshConfig.Redis,
 				ResultBackend: irgshConfig.Redis,
-				DefaultQueue:  "irgsh",
+				DefaultQueue:  queue.Default,
 			},
 		)
 		if err != nil {
@@ -130,7 +131,9 @@ func startMonitoringHeartbeat() {
 		context.Background(),
 		irgshConfig.Redis, ttl,
 		monitoring.InstanceTypeISO, irgshConfig.ISO.Workdir,
-		interval, func() int { return int(activeTasks.Load()) },
+		interval, monitoring.Capabilities{}, 1,
+		func() int { return int(activeTasks.Load()) },
+		nil,
 	)
 }
 
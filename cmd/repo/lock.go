@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockRepositories takes the lock serializing the changes to the reprepro
+// repositories of this instance, waiting for it to be released by whoever
+// holds it. The repo worker holds it while running a task and the snapshot
+// commands while they run, so that they never change the repositories at
+// once, and SourcesHandler while it reads them. It returns the function
+// releasing the lock.
+func lockRepositories() (func(), error) {
+	lockFile, err := os.OpenFile(irgshConfig.Repo.Workdir+"/repo.lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the repository lock: %w", err)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("failed to take the repository lock: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
+
+// withRepositoryLock runs task while holding the repository lock.
+func withRepositoryLock(task func() error) error {
+	unlock, err := lockRepositories()
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return err
+	}
+	defer unlock()
+	return task()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/config"
+)
+
+func TestLockRepositories(t *testing.T) {
+	irgshConfig.Repo = config.RepoConfig{Workdir: t.TempDir()}
+	t.Cleanup(func() { irgshConfig.Repo = config.RepoConfig{} })
+
+	unlock, err := lockRepositories()
+	require.NoError(t, err)
+
+	taken := make(chan struct{})
+	go func() {
+		_ = withRepositoryLock(func() error {
+			close(taken)
+			return nil
+		})
+	}()
+
+	select {
+	case <-taken:
+		t.Fatal("the lock was taken twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-taken:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock was not taken once released")
+	}
+	assert.FileExists(t, irgshConfig.Repo.Workdir+"/repo.lock")
+}
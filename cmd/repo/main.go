@@ -6,15 +6,19 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	machinery "github.com/RichardKnop/machinery/v1"
 	machineryConfig "github.com/RichardKnop/machinery/v1/config"
+	"github.com/manifoldco/promptui"
 	"github.com/urfave/cli"
 
+	"github.com/blankon/irgsh-go/internal/cancel"
 	"github.com/blankon/irgsh-go/internal/config"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/queue"
 )
 
 var (
@@ -25,6 +29,9 @@ var (
 
 	irgshConfig = config.IrgshConfig{}
 	activeTasks atomic.Int32
+
+	// cancelFlags tells whether the pipeline of a task has been cancelled.
+	cancelFlags *cancel.Flags
 )
 
 func main() {
@@ -90,6 +97,69 @@ func main() {
 				return err
 			},
 		},
+		{
+			Name:  "snapshot",
+			Usage: "manage repository snapshots",
+			Subcommands: []cli.Command{
+				{
+					Name:      "create",
+					Usage:     "take a snapshot of a repository",
+					ArgsUsage: "[repository]",
+					Action: func(c *cli.Context) (err error) {
+						repository := c.Args().First()
+						if repository == "" {
+							repository = irgshConfig.Repo.Suites()[0].Codename
+						}
+						var s snapshot
+						err = withRepositoryLock(func() (err error) {
+							s, err = createSnapshot(repository, "", irgshConfig.Repo.Workdir+"/snapshot.log")
+							return err
+						})
+						if err != nil {
+							return err
+						}
+						fmt.Println("Snapshot " + s.ID + " created")
+						return nil
+					},
+				},
+				{
+					Name:  "list",
+					Usage: "list the snapshots, newest first",
+					Action: func(c *cli.Context) (err error) {
+						return printSnapshots()
+					},
+				},
+				{
+					Name:      "restore",
+					Usage:     "roll a repository back to a snapshot",
+					ArgsUsage: "<snapshot-id>",
+					Action: func(c *cli.Context) (err error) {
+						id := c.Args().First()
+						if id == "" {
+							return cli.NewExitError("Error: snapshot id is required", 1)
+						}
+						prompt := promptui.Prompt{
+							Label:     "Are you sure you want to restore snapshot " + id + "? Changes made since then will be lost.",
+							IsConfirm: true,
+						}
+						result, err := prompt.Run()
+						if err != nil || strings.ToLower(result) != "y" {
+							return nil
+						}
+						var s snapshot
+						err = withRepositoryLock(func() (err error) {
+							s, err = restoreSnapshot(id, irgshConfig.Repo.Workdir+"/snapshot.log")
+							return err
+						})
+						if err != nil {
+							return err
+						}
+						fmt.Println(s.Repository + " restored to snapshot " + s.ID)
+						return nil
+					},
+				},
+			},
+		},
 	}
 
 	app.Action = func(c *cli.Context) error {
@@ -101,19 +171,30 @@ func main() {
 			go startMonitoringHeartbeat()
 		}
 
+		var err error
+		cancelFlags, err = cancel.NewFlags(irgshConfig.Redis)
+		if err != nil {
+			fmt.Println("Could not create cancellation flags : " + err.Error())
+			return err
+		}
+
 		server, err := machinery.NewServer(
 			&machineryConfig.Config{
 				Broker:        irgshConfig.Redis,
 				ResultBackend: irgshConfig.Redis,
-				DefaultQueue:  "irgsh",
+				DefaultQueue:  queue.Default,
 			},
 		)
 		if err != nil {
 			fmt.Println("Could not create server : " + err.Error())
 		}
 
-		// Wrap Repo task with monitoring
+		// Wrap the tasks with monitoring, each one holding the repository lock
 		server.RegisterTask("repo", RepoWithMonitoring)
+		server.RegisterTask("promote", PromoteWithMonitoring)
+		server.RegisterTask("remove", RemoveWithMonitoring)
+		server.RegisterTask("snapshot", SnapshotWithMonitoring)
+		server.RegisterTask("restore", RestoreWithMonitoring)
 		// One worker for synchronous
 		worker := server.NewWorker("repo", 1)
 		err = worker.Launch()
@@ -131,7 +212,49 @@ func RepoWithMonitoring(payload string) error {
 	activeTasks.Add(1)
 	defer activeTasks.Add(-1)
 
-	return Repo(payload)
+	return withRepositoryLock(func() error {
+		return Repo(payload)
+	})
+}
+
+// PromoteWithMonitoring wraps the Promote function with active task tracking
+func PromoteWithMonitoring(payload string) error {
+	activeTasks.Add(1)
+	defer activeTasks.Add(-1)
+
+	return withRepositoryLock(func() error {
+		return Promote(payload)
+	})
+}
+
+// RemoveWithMonitoring wraps the Remove function with active task tracking
+func RemoveWithMonitoring(payload string) error {
+	activeTasks.Add(1)
+	defer activeTasks.Add(-1)
+
+	return withRepositoryLock(func() error {
+		return Remove(payload)
+	})
+}
+
+// SnapshotWithMonitoring wraps the Snapshot function with active task tracking
+func SnapshotWithMonitoring(payload string) error {
+	activeTasks.Add(1)
+	defer activeTasks.Add(-1)
+
+	return withRepositoryLock(func() error {
+		return Snapshot(payload)
+	})
+}
+
+// RestoreWithMonitoring wraps the Restore function with active task tracking
+func RestoreWithMonitoring(payload string) error {
+	activeTasks.Add(1)
+	defer activeTasks.Add(-1)
+
+	return withRepositoryLock(func() error {
+		return Restore(payload)
+	})
 }
 
 func startMonitoringHeartbeat() {
@@ -141,7 +264,9 @@ func startMonitoringHeartbeat() {
 		context.Background(),
 		irgshConfig.Redis, ttl,
 		monitoring.InstanceTypeRepo, irgshConfig.Repo.Workdir,
-		interval, func() int { return int(activeTasks.Load()) },
+		interval, monitoring.Capabilities{}, 1,
+		func() int { return int(activeTasks.Load()) },
+		nil,
 	)
 }
 
@@ -151,20 +276,17 @@ func IndexHandler(w http.ResponseWriter, r *http.Request) {
 
 func serve() {
 	http.HandleFunc("/", IndexHandler)
-	http.Handle("/dev/",
-		http.StripPrefix("/dev/",
-			http.FileServer(
-				http.Dir(irgshConfig.Repo.Workdir+"/"+irgshConfig.Repo.DistCodename+"/www"),
-			),
-		),
-	)
-	http.Handle("/experimental/",
-		http.StripPrefix("/experimental/",
-			http.FileServer(
-				http.Dir(irgshConfig.Repo.Workdir+"/"+irgshConfig.Repo.DistCodename+"-experimental/www"),
-			),
-		),
-	)
+	http.HandleFunc("/snapshots/", SnapshotsHandler)
+	http.HandleFunc("/sources/", SourcesHandler)
+	for i, dist := range irgshConfig.Repo.Suites() {
+		serveSuite("/"+dist.Codename+"/", dist.Codename)
+		serveSuite("/"+dist.Codename+"-experimental/", dist.Codename+"-experimental")
+		// The default suite stays reachable under its historical prefixes.
+		if i == 0 {
+			serveSuite("/dev/", dist.Codename)
+			serveSuite("/experimental/", dist.Codename+"-experimental")
+		}
+	}
 	port := os.Getenv("PORT")
 	if len(port) < 1 {
 		port = "8082"
@@ -172,3 +294,14 @@ func serve() {
 	log.Println("irgsh-go repo is now live on port " + port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
+
+// serveSuite serves the published tree of a reprepro repository under prefix.
+func serveSuite(prefix, repository string) {
+	http.Handle(prefix,
+		http.StripPrefix(prefix,
+			http.FileServer(
+				http.Dir(irgshConfig.Repo.Workdir+"/"+repository+"/www"),
+			),
+		),
+	)
+}
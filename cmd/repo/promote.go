@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/repo"
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// Promote copies the source and binary packages of a package version from
+// the experimental distribution of a suite into the suite itself. Both
+// distributions are separate reprepro repositories, so the files are taken
+// from the experimental pool and included again.
+func Promote(data string) (err error) {
+	fmt.Println("##### Promoting the package from experimental")
+	var promotion payload.Promote
+	if err = payload.Decode(data, &promotion); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	taskUUID := promotion.TaskUUID
+	packageName := promotion.PackageName
+	packageVersion := promotion.PackageVersion
+
+	jobInfo := notification.JobNotificationInfo{
+		PackageName:    packageName,
+		PackageVersion: packageVersion,
+		Maintainer:     promotion.Maintainer,
+	}
+
+	logDir := irgshConfig.Repo.Workdir + "/artifacts/" + taskUUID
+	logPath := logDir + "/repo.log"
+	os.MkdirAll(logDir, 0755)
+	go systemutil.StreamLog(logPath)
+
+	if err = claimTask(taskUUID, logPath); err != nil {
+		return
+	}
+	// Deferred first, so the end of the task is reported once its log is uploaded
+	reportEnd := reportStart(taskUUID)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	defer func() {
+		status := "SUCCESS"
+		if errors.Is(err, cancel.ErrCancelled) {
+			status = "CANCELLED"
+			systemutil.WriteLog(logPath, "[ PROMOTE CANCELLED ]")
+		} else if err != nil {
+			status = "FAILED"
+			systemutil.WriteLog(logPath, "[ PROMOTE FAILED ] "+err.Error())
+		} else {
+			systemutil.WriteLog(logPath, "[ PROMOTE DONE ]")
+		}
+		uploadLog(logPath, taskUUID)
+		notification.SendJobNotification(
+			irgshConfig.Notification.WebhookURL,
+			"Promote",
+			taskUUID,
+			status,
+			jobInfo,
+		)
+	}()
+
+	if isCancelled(taskUUID) {
+		return cancel.ErrCancelled
+	}
+
+	dist, ok := irgshConfig.Repo.Suite(promotion.Suite)
+	if !ok {
+		return fmt.Errorf("unknown suite %s", promotion.Suite)
+	}
+	err = promotePackage(newReprepro(logPath), dist.Codename, packageName, packageVersion)
+	if err != nil {
+		return err
+	}
+	snapshotAfter(dist.Codename, taskUUID, logPath)
+
+	return nil
+}
+
+// promotePackage includes the files of a package version found in the pool
+// of the experimental distribution of codename into codename itself.
+func promotePackage(rr *repo.Reprepro, codename, packageName, packageVersion string) error {
+	experimental := codename + "-experimental"
+
+	sources, err := rr.List(experimental, "dsc",
+		fmt.Sprintf("Package (== %s), Version (== %s)", packageName, packageVersion))
+	if err != nil {
+		return fmt.Errorf("failed to list source packages: %w", err)
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("%s %s is not in %s", packageName, packageVersion, experimental)
+	}
+	binaries, err := rr.List(experimental, "deb",
+		fmt.Sprintf("$Source (== %s), $SourceVersion (== %s)", packageName, packageVersion))
+	if err != nil {
+		return fmt.Errorf("failed to list binary packages: %w", err)
+	}
+
+	for _, f := range binaries {
+		err = rr.IncludeDeb(codename, f.Component, []string{f.Path}, repo.IncludeOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to inject deb file: %w", err)
+		}
+	}
+	for _, f := range sources {
+		err = rr.IncludeDsc(codename, f.Component, f.Path, repo.IncludeOptions{IgnoreWrongDistribution: true})
+		if err != nil {
+			return fmt.Errorf("failed to inject dsc file: %w", err)
+		}
+	}
+
+	err = rr.Export(codename)
+	if err != nil {
+		return fmt.Errorf("failed to export repository: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/repo"
+)
+
+func TestPromotePackage(t *testing.T) {
+	fake := &fakeReprepro{lists: map[string]string{
+		"dsc": "main 2.10-3 /srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3.dsc\n",
+		"deb": "main 2.10-3 /srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3_amd64.deb\n",
+	}}
+	rr := repo.NewReprepro(fake, "/srv/repo", "", "")
+
+	require.NoError(t, promotePackage(rr, "verbeek", "hello", "2.10-3"))
+	assert.Equal(t, []string{
+		"-T dsc --list-format ${$component} ${Version} ${$fullfilename}\n listfilter verbeek-experimental Package (== hello), Version (== 2.10-3)",
+		"-T deb --list-format ${$component} ${Version} ${$fullfilename}\n listfilter verbeek-experimental $Source (== hello), $SourceVersion (== 2.10-3)",
+		"-v -v -v --nothingiserror --component main includedeb verbeek /srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3_amd64.deb",
+		"-v -v -v --nothingiserror --ignore=wrongdistribution --component main includedsc verbeek /srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3.dsc",
+		"-v -v -v export verbeek",
+	}, fake.commands())
+}
+
+func TestPromotePackage_NotInExperimental(t *testing.T) {
+	fake := &fakeReprepro{}
+	rr := repo.NewReprepro(fake, "/srv/repo", "", "")
+
+	err := promotePackage(rr, "verbeek", "hello", "2.10-3")
+	assert.ErrorContains(t, err, "hello 2.10-3 is not in verbeek-experimental")
+	assert.Len(t, fake.calls, 1)
+}
+
+func TestRemovePackage(t *testing.T) {
+	fake := &fakeReprepro{lists: map[string]string{
+		"": "main 2.10-3 /srv/repo/verbeek/pool/main/h/hello/hello_2.10-3.dsc\n",
+	}}
+	rr := repo.NewReprepro(fake, "/srv/repo", "", "")
+
+	require.NoError(t, removePackage(rr, "verbeek", "main", "hello", ""))
+	assert.Equal(t, []string{
+		"--list-format ${$component} ${Version} ${$fullfilename}\n listfilter verbeek $Source (== hello)",
+		"-v -v -v -C main removefilter verbeek $Source (== hello)",
+		"-v -v -v export verbeek",
+	}, fake.commands())
+
+	err := removePackage(rr, "verbeek", "restricted", "hello", "")
+	assert.ErrorContains(t, err, "hello is not in verbeek/restricted")
+}
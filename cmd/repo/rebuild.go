@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blankon/irgsh-go/internal/repo"
+)
+
+// publishedSource describes a source package published in a repository, so
+// chief can queue a binary-only rebuild of it.
+// The JSON tags must stay in sync with internal/chief/domain/rebuild.go.
+type publishedSource struct {
+	PackageName string `json:"packageName"`
+	Version     string `json:"version"`
+	Component   string `json:"component"`
+	Dsc         string `json:"dsc"`    // Path relative to the published tree of the repository
+	BinNMU      int    `json:"binNMU"` // Highest rebuild number of its binaries
+}
+
+// sourceNamePattern matches Debian source package names, which end up in
+// reprepro formulas.
+var sourceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]+$`)
+
+// errSourceNotFound is returned when a repository does not hold a source
+// package.
+var errSourceNotFound = errors.New("source package not found")
+
+// findSource looks up the source package name in repository, along with the
+// highest binary-only rebuild number of its binaries. When the repository
+// lists several versions of it, the highest is the one apt installs from.
+func findSource(rr *repo.Reprepro, repository, name string) (publishedSource, error) {
+	sources, err := rr.List(repository, "dsc", fmt.Sprintf("Package (== %s)", name))
+	if err != nil {
+		return publishedSource{}, fmt.Errorf("failed to list source packages: %w", err)
+	}
+	if len(sources) == 0 {
+		return publishedSource{}, errSourceNotFound
+	}
+	dsc := sources[0]
+	for _, f := range sources[1:] {
+		if repo.CompareVersions(f.Version, dsc.Version) > 0 {
+			dsc = f
+		}
+	}
+	_, rel, found := strings.Cut(filepath.ToSlash(dsc.Path), "/pool/")
+	if !found {
+		return publishedSource{}, fmt.Errorf("%s is not in a pool", dsc.Path)
+	}
+	source := publishedSource{
+		PackageName: name,
+		Version:     dsc.Version,
+		Component:   dsc.Component,
+		Dsc:         "pool/" + rel,
+	}
+
+	binaries, err := rr.List(repository, "deb", fmt.Sprintf("$Source (== %s)", name))
+	if err != nil {
+		return publishedSource{}, fmt.Errorf("failed to list binary packages: %w", err)
+	}
+	for _, f := range binaries {
+		suffix, ok := strings.CutPrefix(f.Version, source.Version+"+b")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > source.BinNMU {
+			source.BinNMU = n
+		}
+	}
+	return source, nil
+}
+
+// SourcesHandler describes the source package <name> published in a
+// repository as JSON on /sources/<repository>/<name>.
+func SourcesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "sources are read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	repository, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/sources/"), "/")
+	if !isRepository(repository) || !sourceNamePattern.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+	// Not while a task or a snapshot command rewrites the index
+	var source publishedSource
+	err := withRepositoryLock(func() (err error) {
+		source, err = findSource(newReprepro(""), repository, name)
+		return err
+	})
+	if errors.Is(err, errSourceNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(source)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/repo"
+)
+
+func TestFindSource(t *testing.T) {
+	fake := &fakeReprepro{lists: map[string]string{
+		"dsc": "main 2.10-3 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10-3.dsc\n",
+		"deb": "main 2.10-3+b2 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10-3+b2_amd64.deb\n" +
+			"main 2.10-3 /srv/repo/verbeek/www/pool/main/h/hello/hello-doc_2.10-3_all.deb\n" +
+			"main 2.10-3+b1 /srv/repo/verbeek/www/pool/main/h/hello/libhello1_2.10-3+b1_arm64.deb\n",
+	}}
+	rr := repo.NewReprepro(fake, "/srv/repo", "", "")
+
+	source, err := findSource(rr, "verbeek", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, publishedSource{
+		PackageName: "hello",
+		Version:     "2.10-3",
+		Component:   "main",
+		Dsc:         "pool/main/h/hello/hello_2.10-3.dsc",
+		BinNMU:      2,
+	}, source)
+	assert.Equal(t, []string{
+		"-T dsc --list-format ${$component} ${Version} ${$fullfilename}\n listfilter verbeek Package (== hello)",
+		"-T deb --list-format ${$component} ${Version} ${$fullfilename}\n listfilter verbeek $Source (== hello)",
+	}, fake.commands())
+
+	// Of the versions the repository keeps, the highest is rebuilt
+	fake = &fakeReprepro{lists: map[string]string{
+		"dsc": "main 2.9-1 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.9-1.dsc\n" +
+			"main 2.10-3 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10-3.dsc\n" +
+			"main 2.10~rc1-1 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10~rc1-1.dsc\n",
+	}}
+	source, err = findSource(repo.NewReprepro(fake, "/srv/repo", "", ""), "verbeek", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "2.10-3", source.Version)
+	assert.Equal(t, "pool/main/h/hello/hello_2.10-3.dsc", source.Dsc)
+
+	// The pool file names leave the epoch out, the versions keep it
+	fake = &fakeReprepro{lists: map[string]string{
+		"dsc": "main 1:1.0-1 /srv/repo/verbeek/www/pool/main/h/hello/hello_1.0-1.dsc\n" +
+			"main 2.10-3 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10-3.dsc\n",
+		"deb": "main 1:1.0-1+b4 /srv/repo/verbeek/www/pool/main/h/hello/hello_1.0-1+b4_amd64.deb\n",
+	}}
+	source, err = findSource(repo.NewReprepro(fake, "/srv/repo", "", ""), "verbeek", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "1:1.0-1", source.Version)
+	assert.Equal(t, "pool/main/h/hello/hello_1.0-1.dsc", source.Dsc)
+	assert.Equal(t, 4, source.BinNMU)
+
+	_, err = findSource(repo.NewReprepro(&fakeReprepro{}, "/srv/repo", "", ""), "verbeek", "hello")
+	assert.ErrorIs(t, err, errSourceNotFound)
+}
+
+func TestSourcesHandler(t *testing.T) {
+	irgshConfig.Repo = config.RepoConfig{Workdir: t.TempDir(), DistCodename: "verbeek"}
+	defer func() { irgshConfig.Repo = config.RepoConfig{} }()
+	defer func(r repo.Runner) { repreproRunner = r }(repreproRunner)
+	repreproRunner = &fakeReprepro{lists: map[string]string{
+		"dsc": "main 2.10-3 /srv/repo/verbeek/www/pool/main/h/hello/hello_2.10-3.dsc\n",
+	}}
+
+	rec := httptest.NewRecorder()
+	SourcesHandler(rec, httptest.NewRequest(http.MethodGet, "/sources/verbeek/hello", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var source publishedSource
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &source))
+	assert.Equal(t, "2.10-3", source.Version)
+	assert.Zero(t, source.BinNMU)
+	assert.FileExists(t, irgshConfig.Repo.Workdir+"/repo.lock")
+
+	for _, path := range []string{"/sources/unknown/hello", "/sources/verbeek/", "/sources/verbeek/hello)", "/sources/verbeek/a/b"} {
+		rec = httptest.NewRecorder()
+		SourcesHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+
+	repreproRunner = &fakeReprepro{}
+	rec = httptest.NewRecorder()
+	SourcesHandler(rec, httptest.NewRequest(http.MethodGet, "/sources/verbeek/hello", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	SourcesHandler(rec, httptest.NewRequest(http.MethodPost, "/sources/verbeek/hello", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/repo"
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// Remove removes a source package and the binary packages built from it
+// from a component of a suite.
+func Remove(data string) (err error) {
+	fmt.Println("##### Removing the package from the repository")
+	var removal payload.Remove
+	if err = payload.Decode(data, &removal); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	taskUUID := removal.TaskUUID
+	packageName := removal.PackageName
+	component := removal.Component
+
+	jobInfo := notification.JobNotificationInfo{
+		PackageName: packageName,
+		Maintainer:  removal.Maintainer,
+	}
+
+	logDir := irgshConfig.Repo.Workdir + "/artifacts/" + taskUUID
+	logPath := logDir + "/repo.log"
+	os.MkdirAll(logDir, 0755)
+	go systemutil.StreamLog(logPath)
+
+	if err = claimTask(taskUUID, logPath); err != nil {
+		return
+	}
+	// Deferred first, so the end of the task is reported once its log is uploaded
+	reportEnd := reportStart(taskUUID)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	defer func() {
+		status := "SUCCESS"
+		if errors.Is(err, cancel.ErrCancelled) {
+			status = "CANCELLED"
+			systemutil.WriteLog(logPath, "[ REMOVE CANCELLED ]")
+		} else if err != nil {
+			status = "FAILED"
+			systemutil.WriteLog(logPath, "[ REMOVE FAILED ] "+err.Error())
+		} else {
+			systemutil.WriteLog(logPath, "[ REMOVE DONE ]")
+		}
+		uploadLog(logPath, taskUUID)
+		notification.SendJobNotification(
+			irgshConfig.Notification.WebhookURL,
+			"Remove",
+			taskUUID,
+			status,
+			jobInfo,
+		)
+	}()
+
+	if isCancelled(taskUUID) {
+		return cancel.ErrCancelled
+	}
+
+	dist, ok := irgshConfig.Repo.Suite(removal.Suite)
+	if !ok {
+		return fmt.Errorf("unknown suite %s", removal.Suite)
+	}
+	systemutil.WriteLog(logPath, fmt.Sprintf("Removal of %s from %s/%s requested by %s",
+		packageName, dist.Codename, component, jobInfo.Maintainer))
+
+	err = removePackage(newReprepro(logPath), dist.Codename, component, packageName, logPath)
+	if err != nil {
+		return err
+	}
+	snapshotAfter(dist.Codename, taskUUID, logPath)
+
+	return nil
+}
+
+// removePackage removes a source package and the binaries built from it
+// from a component of codename.
+func removePackage(rr *repo.Reprepro, codename, component, packageName, logPath string) error {
+	formula := fmt.Sprintf("$Source (== %s)", packageName)
+	files, err := rr.List(codename, "", formula)
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+	found := false
+	for _, f := range files {
+		if f.Component == component {
+			systemutil.WriteLog(logPath, "Removing "+f.Path)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s is not in %s/%s", packageName, codename, component)
+	}
+
+	err = rr.RemoveFilter(codename, component, formula)
+	if err != nil {
+		return fmt.Errorf("failed to remove the package: %w", err)
+	}
+
+	err = rr.Export(codename)
+	if err != nil {
+		return fmt.Errorf("failed to export repository: %w", err)
+	}
+	return nil
+}
@@ -1,31 +1,112 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/monitoring"
 	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/repo"
 	"github.com/blankon/irgsh-go/pkg/systemutil"
 	"github.com/manifoldco/promptui"
 )
 
+// repreproRunner runs reprepro. Tests replace it with a fake.
+var repreproRunner repo.Runner = repo.ExecRunner{}
+
+// newReprepro returns a reprepro client for the repositories of this
+// instance, logging to logPath.
+func newReprepro(logPath string) *repo.Reprepro {
+	gnupgHome := irgshConfig.Repo.GnupgDir
+	if irgshConfig.IsDev {
+		gnupgHome = ""
+	}
+	return repo.NewReprepro(repreproRunner, irgshConfig.Repo.Workdir, gnupgHome, logPath)
+}
+
+func newChiefClient() *chiefclient.Client {
+	return chiefclient.New(irgshConfig.Chief.Address, irgshConfig.Worker.Token)
+}
+
 func uploadLog(logPath string, id string) {
 	// Upload the log to chief
-	cmdStr := "curl -v -F 'uploadFile=@" + logPath + "' '" + irgshConfig.Chief.Address + "/api/v1/log-upload?id=" + id + "&type=repo'"
-	fmt.Println(cmdStr)
-	_, err := systemutil.CmdExec(
-		cmdStr,
-		"Uploading log file to chief",
-		"",
-	)
+	err := newChiefClient().UploadLog(context.Background(), id, "repo", logPath)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
 }
 
+// claimTask claims the repo task of taskUUID on chief. Chief refuses the log
+// of an unclaimed task, so a failed claim is only logged locally, and
+// reported as the failure of the task.
+func claimTask(taskUUID, logPath string) error {
+	err := newChiefClient().Claim(context.Background(), taskUUID, "repo")
+	if err != nil {
+		systemutil.WriteLog(logPath, "[ REPO FAILED ] Task claim failed: "+err.Error())
+		reportEvent(taskUUID, chiefclient.TaskEvent{
+			Event:  chiefclient.EventFailed,
+			Detail: "task claim failed: " + err.Error(),
+		})
+	}
+	return err
+}
+
+// reportEvent reports event on the repo task of taskUUID to chief, as this
+// repo instance. Chief derives the state of the pipeline from the events,
+// failing to report one is logged.
+func reportEvent(taskUUID string, event chiefclient.TaskEvent) {
+	event.TaskID = taskUUID
+	event.Stage = "repo"
+	event.Instance = monitoring.GenerateInstanceID(monitoring.InstanceTypeRepo)
+	if err := newChiefClient().ReportEvent(context.Background(), taskUUID, event); err != nil {
+		log.Printf("Failed to report the %s event of %s: %v\n", event.Event, taskUUID, err)
+	}
+}
+
+// reportStart reports that the repo task of taskUUID started and returns
+// the function reporting how it ended, given the error it returned.
+func reportStart(taskUUID string) func(err error) {
+	startedAt := time.Now()
+	reportEvent(taskUUID, chiefclient.TaskEvent{Event: chiefclient.EventStarted})
+	return func(err error) {
+		event := chiefclient.TaskEvent{
+			Event:           chiefclient.EventDone,
+			DurationSeconds: time.Since(startedAt).Seconds(),
+		}
+		if errors.Is(err, cancel.ErrCancelled) {
+			event.Event = chiefclient.EventCancelled
+		} else if err != nil {
+			event.Event = chiefclient.EventFailed
+			event.Detail = err.Error()
+		}
+		reportEvent(taskUUID, event)
+	}
+}
+
+// isCancelled reports whether the pipeline of taskUUID has been cancelled.
+// When the flags cannot be looked up, the task goes on.
+func isCancelled(taskUUID string) bool {
+	if cancelFlags == nil {
+		return false
+	}
+	cancelled, err := cancelFlags.IsCancelled(context.Background(), taskUUID)
+	if err != nil {
+		log.Println(err.Error())
+		return false
+	}
+	return cancelled
+}
+
 func sendRepoNotification(taskUUID, status string, jobInfo notification.JobNotificationInfo) {
 	notification.SendJobNotification(
 		irgshConfig.Notification.WebhookURL,
@@ -36,38 +117,138 @@ func sendRepoNotification(taskUUID, status string, jobInfo notification.JobNotif
 	)
 }
 
+// artifactIDs returns the build artifacts of a pipeline, one per
+// architecture. Payloads queued before per-architecture builds reference a
+// single artifact named after the task UUID.
+func artifactIDs(build payload.Build) []string {
+	var ids []string
+	for _, arch := range build.Architectures {
+		ids = append(ids, build.TaskUUID+"."+arch)
+	}
+	if len(ids) == 0 {
+		ids = []string{build.TaskUUID}
+	}
+	return ids
+}
+
+// injectArtifacts includes the source and binary packages of the build
+// artifacts of a pipeline into repository. A binary-only rebuild only
+// includes the binary packages, its source is already in the repository.
+func injectArtifacts(rr *repo.Reprepro, build payload.Build, repository string, artifacts []string) error {
+	isExperimental := build.IsExperimental
+	component := build.Component
+	artifactsDir := irgshConfig.Repo.Workdir + "/artifacts/"
+
+	if build.BinNMU > 0 {
+		return includeDebs(rr, repository, component, artifacts)
+	}
+
+	// The source package is identical across architectures, take it from
+	// the first artifact.
+	dscs, _ := filepath.Glob(artifactsDir + artifacts[0] + "/*.dsc")
+	if len(dscs) == 0 {
+		return fmt.Errorf("no dsc file in artifact %s", artifacts[0])
+	}
+
+	if isExperimental {
+		// Ignore version conflict
+		source, err := dscSource(dscs[0])
+		if err == nil {
+			fmt.Println("This is experimental package, remove any existing package.")
+			err = rr.Remove(repository, source)
+		}
+		if err != nil {
+			// Ignore err
+			fmt.Printf("error: %v\n", err)
+		}
+	}
+
+	// Handle force version - remove specific version before injecting
+	if build.ForceVersion && !isExperimental {
+		// Construct the full version string
+		packageName := build.PackageName
+		fullVersion := build.PackageVersion
+		if build.PackageExtendedVersion != "" {
+			fullVersion = build.PackageVersion + "-" + build.PackageExtendedVersion
+		}
+
+		// Remove the specific source version and the binary packages
+		// from the repository. They might not exist yet.
+		if err := rr.RemoveSrc(repository, packageName, fullVersion); err != nil {
+			fmt.Printf("error (ignored): %v\n", err)
+		}
+		if err := rr.Remove(repository, packageName); err != nil {
+			fmt.Printf("error (ignored): %v\n", err)
+		}
+	}
+
+	if err := includeDebs(rr, repository, component, artifacts); err != nil {
+		return err
+	}
+
+	// Injecting source package via .dsc (avoids checksum mismatch between
+	// CLI-built .deb and builder-built .deb that .changes would reference)
+	err := rr.IncludeDsc(repository, component, dscs[0], repo.IncludeOptions{
+		IgnoreWrongDistribution: isExperimental,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to inject dsc file: %w", err)
+	}
+	return nil
+}
+
+// includeDebs includes the binary packages of every architecture.
+func includeDebs(rr *repo.Reprepro, repository, component string, artifacts []string) error {
+	artifactsDir := irgshConfig.Repo.Workdir + "/artifacts/"
+	for _, id := range artifacts {
+		debs, _ := filepath.Glob(artifactsDir + id + "/*.deb")
+		if err := rr.IncludeDeb(repository, component, debs, repo.IncludeOptions{}); err != nil {
+			return fmt.Errorf("failed to inject deb files of %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// dscSource returns the source package name of a .dsc file.
+func dscSource(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if source, ok := strings.CutPrefix(line, "Source:"); ok {
+			return strings.TrimSpace(source), nil
+		}
+	}
+	return "", fmt.Errorf("no source name in %s", path)
+}
+
 // Main task wrapper
-func Repo(payload string) (err error) {
+func Repo(data string) (err error) {
 	fmt.Println("##### Submitting the package into the repository")
-	in := []byte(payload)
-	var raw map[string]interface{}
-	json.Unmarshal(in, &raw)
+	var build payload.Build
+	if err = payload.Decode(data, &build); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
 
-	taskUUID := raw["taskUUID"].(string)
+	taskUUID := build.TaskUUID
 
 	experimentalSuffix := "-experimental"
-	if !raw["isExperimental"].(bool) {
+	if !build.IsExperimental {
 		experimentalSuffix = ""
 	}
 
 	// Extract job info for notifications
 	jobInfo := notification.JobNotificationInfo{
-		PackageName:    raw["packageName"].(string),
-		PackageVersion: raw["packageVersion"].(string),
-		Maintainer:     raw["maintainer"].(string),
-		IsExperimental: raw["isExperimental"].(bool),
-	}
-	if sourceURL, ok := raw["sourceUrl"].(string); ok {
-		jobInfo.SourceURL = sourceURL
-	}
-	if sourceBranch, ok := raw["sourceBranch"].(string); ok {
-		jobInfo.SourceBranch = sourceBranch
-	}
-	if packageURL, ok := raw["packageUrl"].(string); ok {
-		jobInfo.PackageURL = packageURL
-	}
-	if packageBranch, ok := raw["packageBranch"].(string); ok {
-		jobInfo.PackageBranch = packageBranch
+		PackageName:    build.PackageName,
+		PackageVersion: build.PackageVersion,
+		Maintainer:     build.Maintainer,
+		IsExperimental: build.IsExperimental,
+		SourceURL:      build.SourceURL,
+		SourceBranch:   build.SourceBranch,
+		PackageURL:     build.PackageURL,
+		PackageBranch:  build.PackageBranch,
 	}
 
 	logPath := irgshConfig.Repo.Workdir + "/artifacts/"
@@ -76,187 +257,66 @@ func Repo(payload string) (err error) {
 
 	// Ensure notification is always sent on completion
 	defer func() {
-		if err != nil {
+		if errors.Is(err, cancel.ErrCancelled) {
+			sendRepoNotification(taskUUID, "CANCELLED", jobInfo)
+		} else if err != nil {
 			sendRepoNotification(taskUUID, "FAILED", jobInfo)
 		} else {
 			sendRepoNotification(taskUUID, "SUCCESS", jobInfo)
 		}
 	}()
 
-	cmdStr := fmt.Sprintf(`mkdir -p %s/artifacts && \
-	cd %s/artifacts/ && \
-	wget %s/artifacts/%s.tar.gz && \
-	tar -xvf %s.tar.gz`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Chief.Address,
-		taskUUID,
-		taskUUID,
-	)
-	_, err = systemutil.CmdExec(cmdStr, "Downloading the artifact", logPath)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		systemutil.WriteLog(logPath, "[ REPO FAILED ] Failed to download artifact: "+err.Error())
-		uploadLog(logPath, taskUUID)
+	if err = claimTask(taskUUID, logPath); err != nil {
 		return
 	}
+	reportEnd := reportStart(taskUUID)
+	defer func() {
+		reportEnd(err)
+	}()
 
-	gnupgDir := "GNUPGHOME=" + irgshConfig.Repo.GnupgDir
-	if irgshConfig.IsDev {
-		gnupgDir = ""
-	}
-	if raw["isExperimental"].(bool) {
-		// Ignore version conflict
-		cmdStr = fmt.Sprintf(`mkdir -p %s/%s && cd %s/%s/ && \
-		%s reprepro -v -v -v --nothingiserror remove %s \
-		$(cat %s/artifacts/%s/*.dsc | grep 'Source:' | cut -d ' ' -f 2)`,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			gnupgDir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			irgshConfig.Repo.Workdir,
-			taskUUID,
-		)
-		_, errExp := systemutil.CmdExec(
-			cmdStr,
-			"This is experimental package, remove any existing package.",
-			logPath,
-		)
-		if errExp != nil {
-			// Ignore err
-			fmt.Printf("error: %v\n", errExp)
-		}
-	}
-
-	// Handle force version - remove specific version before injecting
-	forceVersion, ok := raw["forceVersion"].(bool)
-	if ok && forceVersion && !raw["isExperimental"].(bool) {
-		// Construct the full version string
-		packageName := raw["packageName"].(string)
-		packageVersion := raw["packageVersion"].(string)
-		packageExtendedVersion, _ := raw["packageExtendedVersion"].(string)
-		fullVersion := packageVersion
-		if packageExtendedVersion != "" {
-			fullVersion = packageVersion + "-" + packageExtendedVersion
-		}
-
-		// Remove the specific source version from the repository
-		cmdStr = fmt.Sprintf(`mkdir -p %s/%s && cd %s/%s/ && \
-		%s reprepro -v -v -v --nothingiserror removesrc %s %s %s`,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			gnupgDir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			packageName,
-			fullVersion,
-		)
-		_, errForce := systemutil.CmdExec(
-			cmdStr,
-			fmt.Sprintf("Force version: removing existing source package %s version %s", packageName, fullVersion),
-			logPath,
-		)
-		if errForce != nil {
-			// Ignore err - package might not exist yet
-			fmt.Printf("error (ignored): %v\n", errForce)
-		}
-
-		// Remove the binary packages as well
-		cmdStr = fmt.Sprintf(`mkdir -p %s/%s && cd %s/%s/ && \
-		%s reprepro -v -v -v --nothingiserror remove %s %s`,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			irgshConfig.Repo.Workdir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			gnupgDir,
-			irgshConfig.Repo.DistCodename+experimentalSuffix,
-			packageName,
-		)
-		_, errForce = systemutil.CmdExec(
-			cmdStr,
-			fmt.Sprintf("Force version: removing existing binary packages for %s", packageName),
-			logPath,
-		)
-		if errForce != nil {
-			// Ignore err - package might not exist yet
-			fmt.Printf("error (ignored): %v\n", errForce)
-		}
+	// The builds may have finished before their pipeline got cancelled
+	if isCancelled(taskUUID) {
+		err = cancel.ErrCancelled
+		systemutil.WriteLog(logPath, "[ REPO CANCELLED ]")
+		uploadLog(logPath, taskUUID)
+		return
 	}
 
-	// Injecting the package
-	cmdStr = fmt.Sprintf(`mkdir -p %s/%s && cd %s/%s/ && \
-	%s reprepro -v -v -v --nothingiserror --component %s includedeb %s %s/artifacts/%s/*.deb`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		gnupgDir,
-		raw["component"],
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		irgshConfig.Repo.Workdir,
-		taskUUID,
-	)
-
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Injecting the deb files from artifact to the repository",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		systemutil.WriteLog(logPath, "[ REPO FAILED ] Failed to inject deb files: "+err.Error())
+	dist, ok := irgshConfig.Repo.Suite(build.Suite)
+	if !ok {
+		err = fmt.Errorf("unknown suite %s", build.Suite)
+		systemutil.WriteLog(logPath, "[ REPO FAILED ] "+err.Error())
 		uploadLog(logPath, taskUUID)
 		return
 	}
 
-	// Injecting source package via .dsc (avoids checksum mismatch between
-	// CLI-built .deb and builder-built .deb that .changes would reference)
-	ignoreDistribution := ""
-	if raw["isExperimental"].(bool) {
-		ignoreDistribution = "--ignore=wrongdistribution"
+	artifacts := artifactIDs(build)
+	artifactsDir := irgshConfig.Repo.Workdir + "/artifacts"
+	os.MkdirAll(artifactsDir, 0755)
+	client := newChiefClient()
+	for _, id := range artifacts {
+		systemutil.WriteLog(logPath, "Downloading the artifact "+id)
+		err = client.DownloadArtifact(context.Background(), id, artifactsDir+"/"+id+".tar.gz")
+		if err == nil {
+			_, err = systemutil.CmdExec("cd "+artifactsDir+" && tar -xvf "+id+".tar.gz", "Extracting the artifact "+id, logPath)
+		}
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			systemutil.WriteLog(logPath, "[ REPO FAILED ] Failed to download artifact: "+err.Error())
+			uploadLog(logPath, taskUUID)
+			return
+		}
 	}
-
-	cmdStr = fmt.Sprintf(`mkdir -p %s/%s && cd %s/%s/ && \
-	%s reprepro -v -v -v --nothingiserror %s --component %s includedsc %s %s/artifacts/%s/*.dsc`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		gnupgDir,
-		ignoreDistribution,
-		raw["component"],
-		irgshConfig.Repo.DistCodename+experimentalSuffix,
-		irgshConfig.Repo.Workdir,
-		taskUUID,
-	)
-
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Injecting the dsc file from artifact to the repository",
-		logPath,
-	)
+	rr := newReprepro(logPath)
+	err = injectArtifacts(rr, build, dist.Codename+experimentalSuffix, artifacts)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
-		systemutil.WriteLog(logPath, "[ REPO FAILED ] Failed to inject dsc file: "+err.Error())
+		systemutil.WriteLog(logPath, "[ REPO FAILED ] "+err.Error())
 		uploadLog(logPath, taskUUID)
 		return
 	}
 
-	cmdStr = fmt.Sprintf("mkdir -p %s/%s && cd %s/%s/ && %s reprepro -v -v -v export",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		gnupgDir,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Re-export and publish the reprepro repository",
-		logPath,
-	)
+	err = rr.Export(dist.Codename)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		systemutil.WriteLog(logPath, "[ REPO FAILED ] Failed to export repository: "+err.Error())
@@ -264,6 +324,8 @@ func Repo(payload string) (err error) {
 		return
 	}
 
+	snapshotAfter(dist.Codename+experimentalSuffix, taskUUID, logPath)
+
 	systemutil.WriteLog(logPath, "[ REPO DONE ]")
 	uploadLog(logPath, taskUUID)
 
@@ -290,16 +352,30 @@ func InitRepo() (err error) {
 		log.Fatalln(err)
 	}
 
-	fmt.Println("##### Initializing new repository for " + irgshConfig.Repo.DistCodename)
+	logPath := irgshConfig.Repo.Workdir + "/init.log"
+	go systemutil.StreamLog(logPath)
+
+	for _, dist := range irgshConfig.Repo.Suites() {
+		fmt.Println("##### Initializing new repository for " + dist.Codename)
+		err = initDistribution(dist, "", logPath)
+		if err != nil {
+			return
+		}
 
-	gnupgDir := "GNUPGHOME=" + irgshConfig.Repo.GnupgDir
-	if irgshConfig.IsDev {
-		gnupgDir = ""
+		fmt.Println("##### Initializing the experimental repository for " + dist.Codename)
+		// With -experimental suffix
+		err = initDistribution(dist, "-experimental", logPath)
+		if err != nil {
+			return
+		}
 	}
 
-	logPath := irgshConfig.Repo.Workdir + "/init.log"
-	go systemutil.StreamLog(logPath)
+	return
+}
 
+// initDistribution creates the reprepro repository of a suite, or of its
+// experimental twin when suffix is "-experimental".
+func initDistribution(dist config.DistributionConfig, suffix string, logPath string) (err error) {
 	repoTemplatePath := "/usr/share/irgsh/reprepro-template"
 	if irgshConfig.IsDev {
 		cwd, _ := os.Getwd()
@@ -311,10 +387,10 @@ func InitRepo() (err error) {
 	cmdStr := fmt.Sprintf("mkdir -p %s && rm -rf %s/%s; cp -R %s %s/%s",
 		irgshConfig.Repo.Workdir,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 		repoTemplatePath,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 	)
 	_, err = systemutil.CmdExec(cmdStr, "Preparing reprepro template", logPath)
 	if err != nil {
@@ -329,14 +405,14 @@ func InitRepo() (err error) {
 		sed 's/DIST_SUPPORTED_ARCHITECTURES/%s/g' | 
 		sed 's/UPSTREAM_DIST_COMPONENTS/%s/g' > updates && rm updates.orig`,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		irgshConfig.Repo.UpstreamName,
-		irgshConfig.Repo.UpstreamDistCodename,
-		strings.Replace(irgshConfig.Repo.UpstreamDistUrl, "/", "\\/", -1),
-		irgshConfig.Repo.DistSupportedArchitectures,
-		irgshConfig.Repo.UpstreamDistComponents,
+		dist.Codename+suffix,
+		dist.UpstreamName,
+		dist.UpstreamDistCodename+suffix,
+		strings.Replace(dist.UpstreamDistUrl, "/", "\\/", -1),
+		dist.SupportedArchitectures,
+		dist.UpstreamDistComponents,
 	)
 	_, err = systemutil.CmdExec(
 		cmdStr,
@@ -359,18 +435,18 @@ func InitRepo() (err error) {
 		sed 's/DIST_SIGNING_KEY/%s/g' |
 		sed 's/UPSTREAM_NAME/%s/g'> distributions && rm distributions.orig`,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		irgshConfig.Repo.DistName,
-		irgshConfig.Repo.DistLabel,
-		irgshConfig.Repo.DistCodename,
-		irgshConfig.Repo.DistComponents,
-		irgshConfig.Repo.DistSupportedArchitectures,
-		irgshConfig.Repo.DistVersionDesc,
-		irgshConfig.Repo.DistVersion,
-		irgshConfig.Repo.DistSigningKey,
-		irgshConfig.Repo.UpstreamName,
+		dist.Codename+suffix,
+		dist.Name,
+		dist.Label,
+		dist.Codename+suffix,
+		dist.Components,
+		dist.SupportedArchitectures,
+		dist.VersionDesc,
+		dist.Version,
+		dist.SigningKey,
+		dist.UpstreamName,
 	)
 	_, err = systemutil.CmdExec(
 		cmdStr,
@@ -383,7 +459,7 @@ func InitRepo() (err error) {
 	}
 
 	repositoryPath := strings.Replace(
-		irgshConfig.Repo.Workdir+"/"+irgshConfig.Repo.DistCodename,
+		irgshConfig.Repo.Workdir+"/"+dist.Codename+suffix,
 		"/",
 		"\\/",
 		-1,
@@ -392,9 +468,9 @@ func InitRepo() (err error) {
 	cat options.orig | sed 's/IRGSH_REPO_WORKDIR/%s/g' > options && \
 	rm options.orig`,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
+		dist.Codename+suffix,
 		repositoryPath,
 	)
 	_, err = systemutil.CmdExec(
@@ -407,127 +483,7 @@ func InitRepo() (err error) {
 		return
 	}
 
-	cmdStr = fmt.Sprintf("cd %s/%s/ && %s reprepro -v -v -v export",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		gnupgDir,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Re-export and publish the reprepro repository",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
-
-	fmt.Println("##### Initializing the experimental repository for " + irgshConfig.Repo.DistCodename)
-	// With -experimental suffix
-
-	cmdStr = fmt.Sprintf("mkdir -p %s && rm -rf %s/%s; cp -R %s %s/%s",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		repoTemplatePath,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-	)
-	_, err = systemutil.CmdExec(cmdStr, "Preparing reprepro template", logPath)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
-
-	cmdStr = fmt.Sprintf(`cd %s/%s/conf && cat updates.orig | 
-		sed 's/UPSTREAM_NAME/%s/g' | 
-		sed 's/UPSTREAM_DIST_CODENAME/%s/g' | 
-		sed 's/UPSTREAM_DIST_URL/%s/g' | 
-		sed 's/DIST_SUPPORTED_ARCHITECTURES/%s/g' | 
-		sed 's/UPSTREAM_DIST_COMPONENTS/%s/g' > updates && rm updates.orig`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		irgshConfig.Repo.UpstreamName,
-		irgshConfig.Repo.UpstreamDistCodename+"-experimental",
-		strings.Replace(irgshConfig.Repo.UpstreamDistUrl, "/", "\\/", -1),
-		irgshConfig.Repo.DistSupportedArchitectures,
-		irgshConfig.Repo.UpstreamDistComponents,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Populate the reprepro's updates config file with values from irgsh's config.yaml",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
-
-	cmdStr = fmt.Sprintf(`cd %s/%s/conf && cat distributions.orig |
-		sed 's/DIST_NAME/%s/g' |
-		sed 's/DIST_LABEL/%s/g' |
-		sed 's/DIST_CODENAME/%s/g' |
-		sed 's/DIST_COMPONENTS/%s/g' |
-		sed 's/DIST_SUPPORTED_ARCHITECTURES/%s/g' |
-		sed 's/DIST_VERSION_DESC/%s/g' |
-		sed 's/DIST_VERSION/%s/g' |
-		sed 's/DIST_SIGNING_KEY/%s/g' |
-		sed 's/UPSTREAM_NAME/%s/g'> distributions && rm distributions.orig`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		irgshConfig.Repo.DistName,
-		irgshConfig.Repo.DistLabel,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		irgshConfig.Repo.DistComponents,
-		irgshConfig.Repo.DistSupportedArchitectures,
-		irgshConfig.Repo.DistVersionDesc,
-		irgshConfig.Repo.DistVersion,
-		irgshConfig.Repo.DistSigningKey,
-		irgshConfig.Repo.UpstreamName,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Populate the reprepro's distributions config file with values from irgsh's config.yaml",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
-
-	repositoryPath = strings.Replace(
-		irgshConfig.Repo.Workdir+"/"+irgshConfig.Repo.DistCodename+"-experimental",
-		"/",
-		"\\/",
-		-1,
-	)
-	cmdStr = fmt.Sprintf(`cd %s/%s/conf && \
-	cat options.orig | sed 's/IRGSH_REPO_WORKDIR/%s/g' > options && \
-	rm options.orig`,
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		repositoryPath,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Populate the reprepro's options config file with values from irgsh's config.yaml",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
-
-	cmdStr = fmt.Sprintf("cd %s/%s/ && %s reprepro -v -v -v export",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename+"-experimental",
-		gnupgDir,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Re-export and publish the reprepro repository",
-		logPath,
-	)
+	err = newReprepro(logPath).Export(dist.Codename + suffix)
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		return
@@ -537,37 +493,28 @@ func InitRepo() (err error) {
 }
 
 func UpdateRepo() (err error) {
-	fmt.Printf("Syncing irgshConfig.Repo.against %s at %s...",
-		irgshConfig.Repo.UpstreamDistCodename,
-		irgshConfig.Repo.UpstreamDistUrl,
-	)
-
 	logPath := irgshConfig.Repo.Workdir + "/update.log"
 	go systemutil.StreamLog(logPath)
 
-	cmdStr := fmt.Sprintf("cd %s/%s/ && reprepro -v -v -v update > %s",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-		logPath,
-	)
-	_, err = systemutil.CmdExec(cmdStr, "Sync the repository against upstream repository", logPath)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
-	}
+	for _, dist := range irgshConfig.Repo.Suites() {
+		fmt.Printf("Syncing %s against %s at %s...",
+			dist.Codename,
+			dist.UpstreamDistCodename,
+			dist.UpstreamDistUrl,
+		)
 
-	cmdStr = fmt.Sprintf("cd %s/%s/ && reprepro -v -v -v export",
-		irgshConfig.Repo.Workdir,
-		irgshConfig.Repo.DistCodename,
-	)
-	_, err = systemutil.CmdExec(
-		cmdStr,
-		"Re-export and publish the reprepro repository",
-		logPath,
-	)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return
+		rr := newReprepro(logPath)
+		err = rr.Update(dist.Codename)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
+
+		err = rr.Export(dist.Codename)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			return
+		}
 	}
 
 	return
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/repo"
+)
+
+// fakeReprepro records the reprepro invocations of the repo worker and
+// answers listfilter with canned output.
+type fakeReprepro struct {
+	calls [][]string
+	lists map[string]string // listfilter output by package type
+}
+
+func (f *fakeReprepro) Run(dir string, env []string, name string, args ...string) ([]byte, []byte, error) {
+	f.calls = append(f.calls, args)
+	if len(args) > 1 && args[0] == "-T" {
+		return []byte(f.lists[args[1]]), nil, nil
+	}
+	for _, a := range args {
+		if a == "listfilter" {
+			return []byte(f.lists[""]), nil, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func (f *fakeReprepro) commands() []string {
+	var cmds []string
+	for _, c := range f.calls {
+		cmds = append(cmds, strings.Join(c, " "))
+	}
+	return cmds
+}
+
+func TestInjectArtifacts(t *testing.T) {
+	workdir := t.TempDir()
+	irgshConfig.Repo = config.RepoConfig{Workdir: workdir}
+	defer func() { irgshConfig.Repo = config.RepoConfig{} }()
+
+	for _, f := range []string{
+		"task.amd64/hello_2.10-3.dsc",
+		"task.amd64/hello_2.10-3_amd64.deb",
+		"task.arm64/hello_2.10-3.dsc",
+		"task.arm64/hello_2.10-3_arm64.deb",
+	} {
+		path := filepath.Join(workdir, "artifacts", f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("Format: 3.0 (quilt)\nSource: hello\n"), 0644))
+	}
+	artifacts := []string{"task.amd64", "task.arm64"}
+	dir := workdir + "/artifacts/"
+
+	fake := &fakeReprepro{}
+	rr := repo.NewReprepro(fake, workdir, "", "")
+	build := payload.Build{IsExperimental: true, Component: "main"}
+	require.NoError(t, injectArtifacts(rr, build, "verbeek-experimental", artifacts))
+	assert.Equal(t, []string{
+		"-v -v -v --nothingiserror remove verbeek-experimental hello",
+		"-v -v -v --nothingiserror --component main includedeb verbeek-experimental " + dir + "task.amd64/hello_2.10-3_amd64.deb",
+		"-v -v -v --nothingiserror --component main includedeb verbeek-experimental " + dir + "task.arm64/hello_2.10-3_arm64.deb",
+		"-v -v -v --nothingiserror --ignore=wrongdistribution --component main includedsc verbeek-experimental " + dir + "task.amd64/hello_2.10-3.dsc",
+	}, fake.commands())
+
+	fake = &fakeReprepro{}
+	rr = repo.NewReprepro(fake, workdir, "", "")
+	build = payload.Build{
+		ForceVersion:           true,
+		Component:              "main",
+		PackageName:            "hello",
+		PackageVersion:         "2.10",
+		PackageExtendedVersion: "3",
+	}
+	require.NoError(t, injectArtifacts(rr, build, "verbeek", artifacts[:1]))
+	assert.Equal(t, []string{
+		"-v -v -v --nothingiserror removesrc verbeek hello 2.10-3",
+		"-v -v -v --nothingiserror remove verbeek hello",
+		"-v -v -v --nothingiserror --component main includedeb verbeek " + dir + "task.amd64/hello_2.10-3_amd64.deb",
+		"-v -v -v --nothingiserror --component main includedsc verbeek " + dir + "task.amd64/hello_2.10-3.dsc",
+	}, fake.commands())
+
+	err := injectArtifacts(rr, build, "verbeek", []string{"missing"})
+	assert.ErrorContains(t, err, "no dsc file in artifact missing")
+
+	// A binary-only rebuild leaves the source package as it is
+	fake = &fakeReprepro{}
+	rr = repo.NewReprepro(fake, workdir, "", "")
+	build = payload.Build{BinNMU: 1, BinNMUReason: "Rebuild", ForceVersion: true, Component: "main", PackageName: "hello"}
+	require.NoError(t, injectArtifacts(rr, build, "verbeek", artifacts))
+	assert.Equal(t, []string{
+		"-v -v -v --nothingiserror --component main includedeb verbeek " + dir + "task.amd64/hello_2.10-3_amd64.deb",
+		"-v -v -v --nothingiserror --component main includedeb verbeek " + dir + "task.arm64/hello_2.10-3_arm64.deb",
+	}, fake.commands())
+}
+
+func TestTasks_RejectInvalidPayloads(t *testing.T) {
+	tasks := map[string]func(string) error{
+		"repo":     Repo,
+		"promote":  Promote,
+		"remove":   Remove,
+		"snapshot": Snapshot,
+		"restore":  Restore,
+	}
+	for name, task := range tasks {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(t, task(`{"taskUUID":`), "malformed payload")
+			assert.ErrorContains(t, task(`{"taskUUID": 42}`), "malformed payload")
+			assert.ErrorContains(t, task(`{"packageName": "hello"}`), "taskUUID is missing")
+			assert.ErrorContains(t, task(`{"schemaVersion": 99, "taskUUID": "task"}`), "unsupported payload schema version 99")
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/notification"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/pkg/systemutil"
+)
+
+// defaultSnapshotKeep is the number of snapshots kept per repository when
+// repo.snapshot_keep is not set.
+const defaultSnapshotKeep = 10
+
+var snapshotIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// snapshot describes the published state of a reprepro repository, captured
+// under <workdir>/snapshots/<ID>/. The published tree (www) is hardlinked,
+// while the reprepro database (db) is copied as it is updated in place.
+type snapshot struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TaskUUID   string    `json:"taskUUID,omitempty"`
+}
+
+func snapshotsDir() string {
+	return irgshConfig.Repo.Workdir + "/snapshots"
+}
+
+func snapshotKeep() int {
+	if irgshConfig.Repo.SnapshotKeep > 0 {
+		return irgshConfig.Repo.SnapshotKeep
+	}
+	return defaultSnapshotKeep
+}
+
+// repositories returns the reprepro repositories served by this instance,
+// every suite along with its experimental twin.
+func repositories() []string {
+	var repos []string
+	for _, dist := range irgshConfig.Repo.Suites() {
+		repos = append(repos, dist.Codename, dist.Codename+"-experimental")
+	}
+	return repos
+}
+
+func isRepository(name string) bool {
+	for _, r := range repositories() {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// createSnapshot captures the current state of a repository and prunes the
+// oldest snapshots beyond the configured retention.
+func createSnapshot(repository, taskUUID, logPath string) (s snapshot, err error) {
+	if !isRepository(repository) {
+		return s, fmt.Errorf("unknown repository %s", repository)
+	}
+	s = snapshot{
+		Repository: repository,
+		CreatedAt:  time.Now().UTC(),
+		TaskUUID:   taskUUID,
+	}
+	// Snapshots taken within the same second keep apart by their suffix
+	s.ID = repository + "_" + s.CreatedAt.Format("20060102-150405") + "-" + uuid.New().String()[:8]
+	dir := snapshotsDir() + "/" + s.ID
+	if _, err := os.Stat(dir); err == nil {
+		return s, fmt.Errorf("snapshot %s already exists", s.ID)
+	}
+
+	cmdStr := fmt.Sprintf("mkdir -p %s && cp -al %s/%s/www %s/www && cp -a %s/%s/db %s/db",
+		dir,
+		irgshConfig.Repo.Workdir, repository, dir,
+		irgshConfig.Repo.Workdir, repository, dir,
+	)
+	_, err = systemutil.CmdExec(cmdStr, "Taking a snapshot of "+repository, logPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return s, err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return s, err
+	}
+	err = os.WriteFile(dir+"/snapshot.json", data, 0644)
+	if err != nil {
+		os.RemoveAll(dir)
+		return s, err
+	}
+	systemutil.WriteLog(logPath, "Snapshot "+s.ID+" created")
+
+	return s, pruneSnapshots(repository, logPath)
+}
+
+// pruneSnapshots removes the oldest snapshots of a repository, keeping the
+// configured number of them.
+func pruneSnapshots(repository, logPath string) error {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, s := range snapshots {
+		if s.Repository != repository {
+			continue
+		}
+		kept++
+		if kept <= snapshotKeep() {
+			continue
+		}
+		systemutil.WriteLog(logPath, "Pruning snapshot "+s.ID)
+		if err := os.RemoveAll(snapshotsDir() + "/" + s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSnapshots returns every snapshot, newest first.
+func listSnapshots() ([]snapshot, error) {
+	entries, err := os.ReadDir(snapshotsDir())
+	if os.IsNotExist(err) {
+		return []snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []snapshot{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(snapshotsDir(), e.Name(), "snapshot.json"))
+		if err != nil {
+			// Incomplete snapshot
+			continue
+		}
+		var s snapshot
+		if err := json.Unmarshal(data, &s); err != nil || s.ID != e.Name() {
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func findSnapshot(id string) (snapshot, error) {
+	if !snapshotIDPattern.MatchString(id) {
+		return snapshot{}, fmt.Errorf("invalid snapshot id %s", id)
+	}
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return snapshot{}, err
+	}
+	for _, s := range snapshots {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return snapshot{}, fmt.Errorf("snapshot %s not found", id)
+}
+
+// restoreSnapshot brings a repository back to the state captured by a
+// snapshot, then re-exports it so the indices are signed again. The
+// snapshot itself is left untouched and can be restored again later on.
+func restoreSnapshot(id, logPath string) (s snapshot, err error) {
+	s, err = findSnapshot(id)
+	if err != nil {
+		return
+	}
+	if !isRepository(s.Repository) {
+		return s, fmt.Errorf("unknown repository %s", s.Repository)
+	}
+	systemutil.WriteLog(logPath, "Restoring "+s.Repository+" to snapshot "+s.ID)
+
+	repoDir := irgshConfig.Repo.Workdir + "/" + s.Repository
+	snapshotDir := snapshotsDir() + "/" + s.ID
+
+	// Stage the copies next to the live tree first, so a failed copy leaves
+	// the repository as it is.
+	cmdStr := fmt.Sprintf(`cd %s && rm -rf db.restore www.restore && \
+	cp -a %s/db db.restore && cp -al %s/www www.restore && \
+	rm -rf db www && mv db.restore db && mv www.restore www`,
+		repoDir,
+		snapshotDir,
+		snapshotDir,
+	)
+	_, err = systemutil.CmdExec(cmdStr, "Restoring the reprepro database and published tree", logPath)
+	if err != nil {
+		return
+	}
+
+	err = newReprepro(logPath).Export(s.Repository)
+	return
+}
+
+// snapshotAfter takes a snapshot of a repository once a task changed it.
+// Failing to do so does not fail the task.
+func snapshotAfter(repository, taskUUID, logPath string) {
+	s, err := createSnapshot(repository, taskUUID, logPath)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		systemutil.WriteLog(logPath, "[ SNAPSHOT FAILED ] "+err.Error())
+		return
+	}
+	fmt.Println("Snapshot " + s.ID + " created")
+}
+
+// Snapshot is the task taking a snapshot of a repository on demand.
+func Snapshot(data string) (err error) {
+	fmt.Println("##### Taking a snapshot of the repository")
+	var task payload.Snapshot
+	if err = payload.Decode(data, &task); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	taskUUID := task.TaskUUID
+	repository := task.Repository
+
+	logDir := irgshConfig.Repo.Workdir + "/artifacts/" + taskUUID
+	logPath := logDir + "/repo.log"
+	os.MkdirAll(logDir, 0755)
+	go systemutil.StreamLog(logPath)
+
+	if err = claimTask(taskUUID, logPath); err != nil {
+		return
+	}
+	// Deferred first, so the end of the task is reported once its log is uploaded
+	reportEnd := reportStart(taskUUID)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	defer func() {
+		status := "SUCCESS"
+		if errors.Is(err, cancel.ErrCancelled) {
+			status = "CANCELLED"
+			systemutil.WriteLog(logPath, "[ SNAPSHOT CANCELLED ]")
+		} else if err != nil {
+			status = "FAILED"
+			systemutil.WriteLog(logPath, "[ SNAPSHOT FAILED ] "+err.Error())
+		} else {
+			systemutil.WriteLog(logPath, "[ SNAPSHOT DONE ]")
+		}
+		uploadLog(logPath, taskUUID)
+		notification.SendJobNotification(
+			irgshConfig.Notification.WebhookURL,
+			"Snapshot",
+			taskUUID,
+			status,
+			notification.JobNotificationInfo{PackageName: repository},
+		)
+	}()
+
+	if isCancelled(taskUUID) {
+		return cancel.ErrCancelled
+	}
+
+	_, err = createSnapshot(repository, taskUUID, logPath)
+	return
+}
+
+// Restore is the task rolling a repository back to a snapshot.
+func Restore(data string) (err error) {
+	fmt.Println("##### Restoring the repository from a snapshot")
+	var task payload.Restore
+	if err = payload.Decode(data, &task); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	taskUUID := task.TaskUUID
+	snapshotID := task.SnapshotID
+
+	logDir := irgshConfig.Repo.Workdir + "/artifacts/" + taskUUID
+	logPath := logDir + "/repo.log"
+	os.MkdirAll(logDir, 0755)
+	go systemutil.StreamLog(logPath)
+
+	if err = claimTask(taskUUID, logPath); err != nil {
+		return
+	}
+	// Deferred first, so the end of the task is reported once its log is uploaded
+	reportEnd := reportStart(taskUUID)
+	defer func() {
+		reportEnd(err)
+	}()
+
+	defer func() {
+		status := "SUCCESS"
+		if errors.Is(err, cancel.ErrCancelled) {
+			status = "CANCELLED"
+			systemutil.WriteLog(logPath, "[ RESTORE CANCELLED ]")
+		} else if err != nil {
+			status = "FAILED"
+			systemutil.WriteLog(logPath, "[ RESTORE FAILED ] "+err.Error())
+		} else {
+			systemutil.WriteLog(logPath, "[ RESTORE DONE ]")
+		}
+		uploadLog(logPath, taskUUID)
+		notification.SendJobNotification(
+			irgshConfig.Notification.WebhookURL,
+			"Restore",
+			taskUUID,
+			status,
+			notification.JobNotificationInfo{PackageName: snapshotID},
+		)
+	}()
+
+	if isCancelled(taskUUID) {
+		return cancel.ErrCancelled
+	}
+
+	if task.Maintainer != "" {
+		systemutil.WriteLog(logPath, "Restore of snapshot "+snapshotID+" requested by "+task.Maintainer)
+	}
+	_, err = restoreSnapshot(snapshotID, logPath)
+	return
+}
+
+// SnapshotsHandler lists the snapshots as JSON on /snapshots/ and serves the
+// published tree of each of them, read-only, under /snapshots/<id>/.
+func SnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "snapshots are read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/snapshots/")
+	if rest == "" {
+		snapshots, err := listSnapshots()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshots)
+		return
+	}
+
+	id, _, found := strings.Cut(rest, "/")
+	if !snapshotIDPattern.MatchString(id) || id == "." || id == ".." {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := os.Stat(snapshotsDir() + "/" + id + "/snapshot.json"); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	prefix := "/snapshots/" + id + "/"
+	if !found {
+		http.Redirect(w, r, prefix, http.StatusMovedPermanently)
+		return
+	}
+	http.StripPrefix(prefix,
+		http.FileServer(http.Dir(snapshotsDir()+"/"+id+"/www")),
+	).ServeHTTP(w, r)
+}
+
+func printSnapshots() error {
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots")
+		return nil
+	}
+	for _, s := range snapshots {
+		fmt.Printf("%s\t%s\t%s\n", s.ID, s.Repository, s.CreatedAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/config"
+)
+
+func setupSnapshotRepo(t *testing.T) string {
+	t.Helper()
+	workdir := t.TempDir()
+	irgshConfig.Repo = config.RepoConfig{
+		Workdir:      workdir,
+		DistCodename: "verbeek",
+		SnapshotKeep: 2,
+	}
+	t.Cleanup(func() { irgshConfig.Repo = config.RepoConfig{} })
+
+	release := filepath.Join(workdir, "verbeek", "www", "dists", "verbeek", "Release")
+	require.NoError(t, os.MkdirAll(filepath.Dir(release), 0755))
+	require.NoError(t, os.WriteFile(release, []byte("Codename: verbeek\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, "verbeek", "db"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "verbeek", "db", "packages.db"), []byte("db"), 0644))
+	return workdir
+}
+
+func writeSnapshot(t *testing.T, s snapshot) {
+	t.Helper()
+	dir := filepath.Join(snapshotsDir(), s.ID)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "www"), 0755))
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snapshot.json"), data, 0644))
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	workdir := setupSnapshotRepo(t)
+
+	s, err := createSnapshot("verbeek", "task-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "verbeek", s.Repository)
+	assert.Equal(t, "task-1", s.TaskUUID)
+
+	data, err := os.ReadFile(filepath.Join(snapshotsDir(), s.ID, "www", "dists", "verbeek", "Release"))
+	require.NoError(t, err)
+	assert.Equal(t, "Codename: verbeek\n", string(data))
+	assert.FileExists(t, filepath.Join(snapshotsDir(), s.ID, "db", "packages.db"))
+
+	// The published tree is hardlinked rather than copied.
+	live, err := os.Stat(filepath.Join(workdir, "verbeek", "www", "dists", "verbeek", "Release"))
+	require.NoError(t, err)
+	snap, err := os.Stat(filepath.Join(snapshotsDir(), s.ID, "www", "dists", "verbeek", "Release"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(live, snap))
+
+	_, err = createSnapshot("unknown", "", "")
+	assert.Error(t, err)
+}
+
+func TestCreateSnapshot_Prunes(t *testing.T) {
+	setupSnapshotRepo(t)
+	now := time.Now().UTC()
+	writeSnapshot(t, snapshot{ID: "verbeek_old", Repository: "verbeek", CreatedAt: now.Add(-2 * time.Hour)})
+	writeSnapshot(t, snapshot{ID: "verbeek_older", Repository: "verbeek", CreatedAt: now.Add(-3 * time.Hour)})
+	writeSnapshot(t, snapshot{ID: "verbeek-experimental_old", Repository: "verbeek-experimental", CreatedAt: now.Add(-4 * time.Hour)})
+
+	s, err := createSnapshot("verbeek", "", "")
+	require.NoError(t, err)
+
+	snapshots, err := listSnapshots()
+	require.NoError(t, err)
+	var ids []string
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{s.ID, "verbeek_old", "verbeek-experimental_old"}, ids)
+}
+
+func TestSnapshotsHandler(t *testing.T) {
+	setupSnapshotRepo(t)
+	s, err := createSnapshot("verbeek", "", "")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	SnapshotsHandler(rec, httptest.NewRequest(http.MethodGet, "/snapshots/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var listed []snapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, s.ID, listed[0].ID)
+
+	rec = httptest.NewRecorder()
+	SnapshotsHandler(rec, httptest.NewRequest(http.MethodGet, "/snapshots/"+s.ID+"/dists/verbeek/Release", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Codename: verbeek\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	SnapshotsHandler(rec, httptest.NewRequest(http.MethodGet, "/snapshots/missing/dists/verbeek/Release", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	SnapshotsHandler(rec, httptest.NewRequest(http.MethodPut, "/snapshots/"+s.ID+"/dists/verbeek/Release", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestCreateSnapshot_SameSecond(t *testing.T) {
+	setupSnapshotRepo(t)
+
+	first, err := createSnapshot("verbeek", "", "")
+	require.NoError(t, err)
+	second, err := createSnapshot("verbeek", "", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	snapshots, err := listSnapshots()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+}
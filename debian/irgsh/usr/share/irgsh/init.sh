@@ -49,7 +49,7 @@ if [ -d "/var/lib/irgsh" ]; then
 			read -p "Do you want to regenerate it? (y/N) " -n 1 -r
 			echo
 			if [[ $REPLY =~ ^[Yy]$ ]]; then
-				if [ ! -f "/var/lib/irgsh/builder/pbocker/base.tgz" ]; then
+				if ! ls /var/lib/irgsh/builder/pbocker/*/base.tgz >/dev/null 2>&1; then
 					OVERWRITE_BASE_TGZ=1
 					OVERWRITE_PBUILDER=1
 				fi
@@ -64,11 +64,11 @@ if [ -d "/var/lib/irgsh" ]; then
 	fi
 fi
 
-if [ ! -f "/var/lib/irgsh/builder/pbocker/base.tgz" ]; then
+if ! ls /var/lib/irgsh/builder/pbocker/*/base.tgz >/dev/null 2>&1; then
 	OVERWRITE_BASE_TGZ=1
 	OVERWRITE_PBUILDER=1
 else
-	if [ "$(docker images | grep pbocker | cut -d ' ' -f 1)" = "pbocker" ]; then
+	if docker images --format '{{.Repository}}' | grep -q '^pbocker-'; then
 		echo
 	else
 		OVERWRITE_PBUILDER=1
@@ -44,7 +44,7 @@ cleanup() {
     fi
     echo "=== Removing Docker image ==="
     docker rmi "$IMAGE_NAME" 2>/dev/null || true
-    docker rmi pbocker 2>/dev/null || true
+    docker images --format '{{.Repository}}' | grep '^pbocker-' | xargs -r docker rmi 2>/dev/null || true
     echo "=== Cleanup complete ==="
 }
 
@@ -0,0 +1,106 @@
+// Package cancel flags cancelled pipelines in Redis. Chief raises the flag
+// of a pipeline, and the workers look it up before running any of its tasks
+// and while building, since machinery cannot revoke a queued task. It also
+// defines the errors of the tasks stopped before they completed.
+package cancel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	keyPrefix = "irgsh:cancelled:"
+
+	// flagTTL outlives any task of a cancelled pipeline still sitting in a
+	// queue.
+	flagTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrCancelled is returned by the tasks of a cancelled pipeline.
+	ErrCancelled = errors.New("pipeline cancelled")
+
+	// ErrTimedOut is returned by the builds that ran out of time. Machinery
+	// only keeps the message of a task error, see IsTimeout.
+	ErrTimedOut = errors.New("build timed out")
+)
+
+// IsTimeout reports whether msg is the error message of a task that
+// returned ErrTimedOut.
+func IsTimeout(msg string) bool {
+	return strings.HasPrefix(msg, ErrTimedOut.Error())
+}
+
+// Checker reports whether a pipeline has been cancelled.
+type Checker interface {
+	IsCancelled(ctx context.Context, taskUUID string) (bool, error)
+}
+
+// Flags reads and writes the cancellation flags.
+type Flags struct {
+	client *redis.Client
+}
+
+// NewFlags returns the cancellation flags kept in the Redis at redisURL.
+func NewFlags(redisURL string) (*Flags, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
+	}
+	return &Flags{client: redis.NewClient(opt)}, nil
+}
+
+// Cancel raises the flag of a pipeline.
+func (f *Flags) Cancel(ctx context.Context, taskUUID string) error {
+	if err := f.client.Set(ctx, keyPrefix+taskUUID, time.Now().UTC().Format(time.RFC3339), flagTTL).Err(); err != nil {
+		return fmt.Errorf("failed to flag %s as cancelled: %w", taskUUID, err)
+	}
+	return nil
+}
+
+// IsCancelled reports whether the flag of a pipeline is raised.
+func (f *Flags) IsCancelled(ctx context.Context, taskUUID string) (bool, error) {
+	n, err := f.client.Exists(ctx, keyPrefix+taskUUID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to look up cancellation of %s: %w", taskUUID, err)
+	}
+	return n > 0, nil
+}
+
+// Close releases the Redis connection.
+func (f *Flags) Close() error {
+	return f.client.Close()
+}
+
+// Watch checks every interval whether a pipeline got cancelled until ctx
+// is done, and calls onCancel once when it did. Lookup errors are logged
+// and the pipeline is assumed to go on.
+func Watch(ctx context.Context, checker Checker, taskUUID string, interval time.Duration, onCancel func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cancelled, err := checker.IsCancelled(ctx, taskUUID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Println(err.Error())
+			}
+			continue
+		}
+		if cancelled {
+			onCancel()
+			return
+		}
+	}
+}
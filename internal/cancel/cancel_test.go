@@ -0,0 +1,63 @@
+package cancel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChecker struct {
+	calls     int32
+	cancelAt  int32
+	failUntil int32
+}
+
+func (f *fakeChecker) IsCancelled(ctx context.Context, taskUUID string) (bool, error) {
+	n := atomic.AddInt32(&f.calls, 1)
+	if n <= f.failUntil {
+		return false, errors.New("redis unavailable")
+	}
+	return f.cancelAt > 0 && n >= f.cancelAt, nil
+}
+
+func TestWatch_Cancelled(t *testing.T) {
+	checker := &fakeChecker{cancelAt: 3, failUntil: 1}
+	var cancelled int32
+	done := make(chan struct{})
+	go func() {
+		Watch(context.Background(), checker, "task", time.Millisecond, func() {
+			atomic.AddInt32(&cancelled, 1)
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not return after the cancellation")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&checker.calls))
+}
+
+func TestWatch_StopsWithContext(t *testing.T) {
+	checker := &fakeChecker{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	called := false
+	Watch(ctx, checker, "task", time.Millisecond, func() { called = true })
+	assert.False(t, called)
+	assert.Greater(t, atomic.LoadInt32(&checker.calls), int32(0))
+}
+
+func TestIsTimeout(t *testing.T) {
+	assert.True(t, IsTimeout(fmt.Errorf("%w after 4h0m0s", ErrTimedOut).Error()))
+	assert.False(t, IsTimeout("exit status 1"))
+	assert.False(t, IsTimeout(""))
+}
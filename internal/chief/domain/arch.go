@@ -0,0 +1,70 @@
+package domain
+
+import "strings"
+
+// DefaultArchitecture is used when no binary architecture is configured.
+const DefaultArchitecture = "amd64"
+
+// BuildTask is a single per-architecture build task within a pipeline.
+type BuildTask struct {
+	TaskUUID     string
+	Architecture string
+	Queue        string // Build queue of the builders able to run the task
+	Payload      []byte
+}
+
+// BuildArchitectures returns the binary architectures a package has to be
+// built for, given a reprepro-style architecture list such as
+// "amd64 arm64 source". The "source" pseudo-architecture is skipped.
+func BuildArchitectures(supported string) []string {
+	var archs []string
+	for _, arch := range strings.Fields(supported) {
+		if arch == "source" {
+			continue
+		}
+		archs = append(archs, arch)
+	}
+	if len(archs) == 0 {
+		archs = []string{DefaultArchitecture}
+	}
+	return archs
+}
+
+// ArchTaskUUID returns the identifier of the build task for arch within the
+// pipeline taskUUID. Artifacts and build logs are stored under this ID.
+func ArchTaskUUID(taskUUID, arch string) string {
+	return taskUUID + "." + arch
+}
+
+// AggregateBuildState folds per-architecture machinery build states into a
+// single build state. Any failure fails the whole build, a build that ran
+// out of time otherwise times the whole build out, a cancelled build
+// otherwise cancels it, and the build only succeeds once every architecture
+// has succeeded.
+func AggregateBuildState(states []string) string {
+	if len(states) == 0 {
+		return ""
+	}
+	seen := map[string]bool{}
+	for _, s := range states {
+		seen[s] = true
+	}
+	switch {
+	case seen["FAILURE"]:
+		return "FAILURE"
+	case seen[StateTimeout]:
+		return StateTimeout
+	case seen[StateCancelled]:
+		return StateCancelled
+	case len(seen) == 1:
+		return states[0]
+	case seen["STARTED"], seen["SUCCESS"]:
+		return "STARTED"
+	case seen["RECEIVED"]:
+		return "RECEIVED"
+	case seen["PENDING"]:
+		return "PENDING"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildArchitectures(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported string
+		want      []string
+	}{
+		{"single arch with source", "amd64 source", []string{"amd64"}},
+		{"multiple archs", "amd64 arm64 source", []string{"amd64", "arm64"}},
+		{"source first", "source amd64 arm64", []string{"amd64", "arm64"}},
+		{"only source", "source", []string{DefaultArchitecture}},
+		{"empty", "", []string{DefaultArchitecture}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, BuildArchitectures(tt.supported))
+		})
+	}
+}
+
+func TestArchTaskUUID(t *testing.T) {
+	id := ArchTaskUUID("2024-01-01-120000_uuid_FP_pkg", "arm64")
+	assert.Equal(t, "2024-01-01-120000_uuid_FP_pkg.arm64", id)
+	assert.True(t, SafeIDPattern.MatchString(id))
+}
+
+func TestAggregateBuildState(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []string
+		want   string
+	}{
+		{"no archs", nil, ""},
+		{"single success", []string{"SUCCESS"}, "SUCCESS"},
+		{"all success", []string{"SUCCESS", "SUCCESS"}, "SUCCESS"},
+		{"one failure", []string{"SUCCESS", "FAILURE"}, "FAILURE"},
+		{"failure while another runs", []string{"STARTED", "FAILURE"}, "FAILURE"},
+		{"one timed out", []string{"SUCCESS", "TIMEOUT"}, "TIMEOUT"},
+		{"failure and timeout", []string{"TIMEOUT", "FAILURE"}, "FAILURE"},
+		{"cancelled while another runs", []string{"STARTED", "CANCELLED"}, "CANCELLED"},
+		{"cancelled after another succeeded", []string{"SUCCESS", "CANCELLED"}, "CANCELLED"},
+		{"cancelled and pending", []string{"CANCELLED", "PENDING"}, "CANCELLED"},
+		{"timeout and cancelled", []string{"CANCELLED", "TIMEOUT"}, "TIMEOUT"},
+		{"failure and cancelled", []string{"CANCELLED", "FAILURE"}, "FAILURE"},
+		{"partially done", []string{"SUCCESS", "PENDING"}, "STARTED"},
+		{"one started", []string{"STARTED", "PENDING"}, "STARTED"},
+		{"received and pending", []string{"RECEIVED", "PENDING"}, "RECEIVED"},
+		{"all pending", []string{"PENDING", "PENDING"}, "PENDING"},
+		{"expired", []string{"", ""}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, AggregateBuildState(tt.states))
+		})
+	}
+}
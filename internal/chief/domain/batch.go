@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// States of a batch, and of its packages besides the states of their
+// pipelines once they are queued.
+const (
+	StateRunning = "RUNNING" // The batch still has packages to build
+	StateWaiting = "WAITING" // The package waits for its dependencies
+	StateSkipped = "SKIPPED" // A dependency of the package was not built
+)
+
+// BatchSubmission submits packages uploaded beforehand, to be built in the
+// order their build dependencies require.
+// The JSON tags must stay in sync with internal/cli/domain/batch.go.
+type BatchSubmission struct {
+	Items []Submission `json:"items"`
+}
+
+// BatchResponse tells the order chief derived for a batch.
+type BatchResponse struct {
+	BatchID  string             `json:"batchId"`
+	Packages []BatchPackageView `json:"packages"`
+}
+
+// BatchPackageView is a package of a batch as reported to the CLI.
+type BatchPackageView struct {
+	PackageName string   `json:"packageName"`
+	PipelineID  string   `json:"pipelineId"`
+	DependsOn   []string `json:"dependsOn,omitempty"`
+}
+
+// BatchPackage is what the scheduler knows of a package of a batch, taken
+// from its .dsc.
+type BatchPackage struct {
+	Name         string   // Source package
+	Binaries     []string // Binary packages it builds
+	BuildDepends []string // Packages it needs to build, alternatives included
+}
+
+// BatchDependencies returns, for every package, the indexes of the packages
+// of the batch it build depends on, in ascending order. A package depends
+// on another one when one of its build dependencies, or one of their
+// alternatives, is a binary package the other one builds. Dependency cycles
+// are rejected, they could never be scheduled.
+func BatchDependencies(pkgs []BatchPackage) ([][]int, error) {
+	builtBy := make(map[string]int)
+	for i, p := range pkgs {
+		for _, bin := range p.Binaries {
+			if other, ok := builtBy[bin]; ok && other != i {
+				return nil, fmt.Errorf("%s and %s both build %s", pkgs[other].Name, p.Name, bin)
+			}
+			builtBy[bin] = i
+		}
+	}
+
+	deps := make([][]int, len(pkgs))
+	for i, p := range pkgs {
+		seen := make(map[int]bool)
+		for _, dep := range p.BuildDepends {
+			j, ok := builtBy[dep]
+			if !ok || j == i || seen[j] {
+				continue
+			}
+			seen[j] = true
+			deps[i] = append(deps[i], j)
+		}
+		sort.Ints(deps[i])
+	}
+
+	if cycle := findCycle(pkgs, deps); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return deps, nil
+}
+
+// findCycle returns the names of the packages of a dependency cycle, the
+// first one repeated at the end, or nil when there is none.
+func findCycle(pkgs []BatchPackage, deps [][]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(pkgs))
+	var stack []int
+	var cycle []string
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		marks[i] = visiting
+		stack = append(stack, i)
+		for _, j := range deps[i] {
+			switch marks[j] {
+			case visiting:
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						for _, n := range stack[k:] {
+							cycle = append(cycle, pkgs[n].Name)
+						}
+						cycle = append(cycle, pkgs[j].Name)
+						break
+					}
+				}
+				return true
+			case unvisited:
+				if visit(j) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		marks[i] = visited
+		return false
+	}
+
+	for i := range pkgs {
+		if marks[i] == unvisited && visit(i) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// IsBatchItemFinished reports whether a package of a batch will not change
+// state anymore.
+func IsBatchItemFinished(state string) bool {
+	switch state {
+	case StateDone, StateFailed, StateTimeout, StateCancelled, StateSkipped:
+		return true
+	}
+	return false
+}
+
+// NextBatchItemState returns what becomes of a package waiting for the
+// packages it depends on, given their states: it is skipped once one of
+// them did not build, and it is ready to be queued once all of them are
+// in the repository.
+func NextBatchItemState(depStates []string) (state string, ready bool) {
+	ready = true
+	for _, s := range depStates {
+		if s == StateDone {
+			continue
+		}
+		if IsBatchItemFinished(s) {
+			return StateSkipped, false
+		}
+		ready = false
+	}
+	return StateWaiting, ready
+}
+
+// BatchState returns the state of a batch given the states of its
+// packages.
+func BatchState(itemStates []string) string {
+	state := StateDone
+	for _, s := range itemStates {
+		if !IsBatchItemFinished(s) {
+			return StateRunning
+		}
+		if s != StateDone {
+			state = StateFailed
+		}
+	}
+	return state
+}
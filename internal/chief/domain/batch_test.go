@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchDependencies(t *testing.T) {
+	pkgs := []BatchPackage{
+		{Name: "app", Binaries: []string{"app"}, BuildDepends: []string{"debhelper-compat", "libfoo-dev", "libbar-dev", "libfoo-dev"}},
+		{Name: "foo", Binaries: []string{"libfoo1", "libfoo-dev"}, BuildDepends: []string{"libbar-dev", "libfoo1"}},
+		{Name: "bar", Binaries: []string{"libbar1", "libbar-dev"}, BuildDepends: []string{"debhelper-compat"}},
+	}
+	deps, err := BatchDependencies(pkgs)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 2}, {2}, nil}, deps)
+}
+
+func TestBatchDependencies_Cycle(t *testing.T) {
+	pkgs := []BatchPackage{
+		{Name: "a", Binaries: []string{"liba-dev"}, BuildDepends: []string{"libb-dev"}},
+		{Name: "b", Binaries: []string{"libb-dev"}, BuildDepends: []string{"libc-dev"}},
+		{Name: "c", Binaries: []string{"libc-dev"}, BuildDepends: []string{"liba-dev"}},
+	}
+	_, err := BatchDependencies(pkgs)
+	require.Error(t, err)
+	assert.Equal(t, "dependency cycle: a -> b -> c -> a", err.Error())
+}
+
+func TestBatchDependencies_SharedBinary(t *testing.T) {
+	_, err := BatchDependencies([]BatchPackage{
+		{Name: "a", Binaries: []string{"libx-dev"}},
+		{Name: "b", Binaries: []string{"libx-dev"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestNextBatchItemState(t *testing.T) {
+	state, ready := NextBatchItemState(nil)
+	assert.Equal(t, StateWaiting, state)
+	assert.True(t, ready)
+
+	state, ready = NextBatchItemState([]string{StateDone, "STARTED"})
+	assert.Equal(t, StateWaiting, state)
+	assert.False(t, ready)
+
+	state, ready = NextBatchItemState([]string{StateWaiting, StateTimeout})
+	assert.Equal(t, StateSkipped, state)
+	assert.False(t, ready)
+
+	state, _ = NextBatchItemState([]string{StateSkipped})
+	assert.Equal(t, StateSkipped, state)
+}
+
+func TestBatchState(t *testing.T) {
+	assert.Equal(t, StateRunning, BatchState([]string{StateDone, StateWaiting}))
+	assert.Equal(t, StateRunning, BatchState([]string{StateFailed, "PENDING"}))
+	assert.Equal(t, StateDone, BatchState([]string{StateDone, StateDone}))
+	assert.Equal(t, StateFailed, BatchState([]string{StateDone, StateSkipped}))
+}
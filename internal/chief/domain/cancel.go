@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// CancelRequest asks for a queued or running pipeline to be stopped.
+// Maintainers send it clearsigned with their GPG key.
+// The JSON tags must stay in sync with internal/cli/domain/cancel.go.
+type CancelRequest struct {
+	PipelineID string    `json:"pipelineId"`
+	Timestamp  time.Time `json:"timestamp"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// StageTiming is when a task of a pipeline started and ended, from the
+// events its worker reported.
+type StageTiming struct {
+	TaskID          string     `json:"taskId"`
+	Stage           string     `json:"stage"`
+	Architecture    string     `json:"architecture,omitempty"`
+	Instance        string     `json:"instance,omitempty"`
+	Result          string     `json:"result,omitempty"` // Event that ended the task, empty while it runs
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"` // So far while the task runs
+}
+
+// DurationStats summarizes the durations of the successful builds of a
+// package on an architecture, or of a builder.
+type DurationStats struct {
+	Name         string  `json:"name"`
+	Architecture string  `json:"architecture,omitempty"` // Empty for builders and for builds reported without it
+	Builds       int     `json:"builds"`
+	P50Seconds   float64 `json:"p50Seconds"`
+	P95Seconds   float64 `json:"p95Seconds"`
+}
+
+// BuildDurationsResponse is the API response listing the build durations
+// per package and architecture and per builder instance, by name.
+type BuildDurationsResponse struct {
+	Packages []DurationStats `json:"packages"`
+	Builders []DurationStats `json:"builders"`
+}
@@ -2,12 +2,18 @@ package domain
 
 import (
 	"fmt"
-	"regexp"
+
+	"github.com/blankon/irgsh-go/internal/payload"
 )
 
 // SafeIDPattern matches strings containing only safe characters for use in
 // file paths and identifiers: alphanumeric, dots, hyphens, underscores, plus.
-var SafeIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._+-]+$`)
+// It is the pattern the workers check the task payloads against.
+var SafeIDPattern = payload.SafeIDPattern
+
+// PackageVersionPattern matches Debian package versions, as the workers
+// check them in the task payloads.
+var PackageVersionPattern = payload.VersionPattern
 
 // ValidateID checks that id matches SafeIDPattern and returns a descriptive
 // error if it does not.
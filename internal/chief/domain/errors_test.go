@@ -54,6 +54,15 @@ func TestSafeIDPattern(t *testing.T) {
 	}
 }
 
+func TestPackageVersionPattern(t *testing.T) {
+	for _, v := range []string{"1.0-1", "1:2.3~rc1-0blankon1", "1.0+dfsg-2"} {
+		assert.True(t, PackageVersionPattern.MatchString(v), "expected %q to match", v)
+	}
+	for _, v := range []string{"", "1.0 2", "1.0;ls", "../1.0", "1.0$x"} {
+		assert.False(t, PackageVersionPattern.MatchString(v), "expected %q to NOT match", v)
+	}
+}
+
 func TestValidateID(t *testing.T) {
 	t.Run("valid id returns nil", func(t *testing.T) {
 		err := ValidateID("valid-id_123", "pipeline")
@@ -0,0 +1,85 @@
+package domain
+
+import "time"
+
+// Events of the tasks of a pipeline. Chief records the tasks it queues,
+// retries and cancels, the workers report the tasks they run.
+const (
+	EventQueued    = "queued"
+	EventStarted   = "started"
+	EventDone      = "done"
+	EventFailed    = "failed"
+	EventCancelled = "cancelled"
+	EventTimeout   = "timeout"
+	EventRetried   = "retried"
+)
+
+// StagePipeline is the stage of the events on a whole pipeline, such as it
+// being retried or cancelled.
+const StagePipeline = "pipeline"
+
+// workerEvents are the events a worker may report on a task it claimed.
+var workerEvents = map[string]bool{
+	EventStarted:   true,
+	EventDone:      true,
+	EventFailed:    true,
+	EventCancelled: true,
+	EventTimeout:   true,
+}
+
+// IsWorkerEvent reports whether a worker may report event.
+func IsWorkerEvent(event string) bool {
+	return workerEvents[event]
+}
+
+// TaskState returns the state of a task a worker reported event on, in the
+// task states of machinery the jobs record. It is empty for the events of
+// chief.
+func TaskState(event string) string {
+	switch event {
+	case EventStarted:
+		return "STARTED"
+	case EventDone:
+		return "SUCCESS"
+	case EventFailed:
+		return "FAILURE"
+	case EventTimeout:
+		return StateTimeout
+	case EventCancelled:
+		return StateCancelled
+	default:
+		return ""
+	}
+}
+
+// JobEvent is an event of a task of a pipeline.
+type JobEvent struct {
+	TaskID          string    `json:"taskId"`
+	Stage           string    `json:"stage"`
+	Architecture    string    `json:"architecture,omitempty"`
+	Event           string    `json:"event"`
+	Instance        string    `json:"instance,omitempty"` // Worker instance, empty for the events of chief
+	Detail          string    `json:"detail,omitempty"`
+	DurationSeconds float64   `json:"durationSeconds,omitempty"` // Time the task ran for, on the events ending it
+	OccurredAt      time.Time `json:"occurredAt"`
+}
+
+// JobEventsResponse is the API response listing the events of a pipeline,
+// oldest first.
+type JobEventsResponse struct {
+	PipelineID string     `json:"pipelineId"`
+	Events     []JobEvent `json:"events"`
+}
+
+// TaskEvent is the event a worker reports on a task it claimed. TaskID and
+// Stage are the identifier and the kind of the claim. The events ending a
+// task carry the time it ran for, and why it failed in Detail.
+type TaskEvent struct {
+	TaskID          string  `json:"taskId"`
+	Stage           string  `json:"stage"`
+	Architecture    string  `json:"architecture,omitempty"`
+	Event           string  `json:"event"`
+	Instance        string  `json:"instance,omitempty"`
+	Detail          string  `json:"detail,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
@@ -6,11 +6,34 @@ type SubmitPayloadResponse struct {
 	Jobs       []string `json:"jobs,omitempty"`
 }
 
+// ArchBuildStatus is the build state of a single architecture in a pipeline,
+// and the state of the tests of its packages.
+type ArchBuildStatus struct {
+	Architecture string `json:"architecture"`
+	BuildStatus  string `json:"buildStatus"`
+	TestStatus   string `json:"testStatus,omitempty"` // Empty when the pipeline runs no tests
+}
+
 // BuildStatusResponse is the API response for package build status queries.
+// BuildStatus is the aggregate of the per-architecture states listed in
+// Architectures.
 type BuildStatusResponse struct {
-	PipelineID  string `json:"pipelineId"`
-	JobStatus   string `json:"jobStatus"`
-	BuildStatus string `json:"buildStatus"`
-	RepoStatus  string `json:"repoStatus"`
-	State       string `json:"state"`
+	PipelineID    string            `json:"pipelineId"`
+	JobStatus     string            `json:"jobStatus"`
+	BuildStatus   string            `json:"buildStatus"`
+	RepoStatus    string            `json:"repoStatus"`
+	TestStatus    string            `json:"testStatus,omitempty"` // Empty when the pipeline runs no tests
+	State         string            `json:"state"`
+	Architectures []ArchBuildStatus `json:"architectures,omitempty"`
+	Lintian       *LintianSummary   `json:"lintian,omitempty"` // Nil until lintian checked a build
+	PackageName   string            `json:"packageName,omitempty"`
+	Stages        []StageTiming     `json:"stages,omitempty"` // Tasks the workers started, in the order they did
+}
+
+// LintianSummary counts the tags lintian reported on the builds of a
+// pipeline, by severity.
+type LintianSummary struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Info     int `json:"info"`
 }
@@ -0,0 +1,40 @@
+package domain
+
+// StateTesting is the pipeline state of a package whose built packages are
+// being tested.
+const StateTesting = "TESTING"
+
+// TestTaskUUID returns the task queue identifier of the test task of the
+// packages built for arch in the pipeline taskUUID. Workers claim it and
+// upload its log under ArchTaskUUID, as the test log.
+func TestTaskUUID(taskUUID, arch string) string {
+	return ArchTaskUUID(taskUUID, arch) + ".test"
+}
+
+// DeriveTestedPipelineState maps the machinery states of a pipeline whose
+// built packages are tested before the repo task runs to a pipeline-level
+// state, see DeriveBuildPipelineState.
+func DeriveTestedPipelineState(buildState, testState, repoState string) string {
+	if buildState != "SUCCESS" {
+		return DeriveBuildPipelineState(buildState, repoState)
+	}
+	switch testState {
+	case "SUCCESS":
+		return DeriveBuildPipelineState(buildState, repoState)
+	case "FAILURE":
+		return StateFailed
+	case StateTimeout:
+		return StateTimeout
+	default:
+		return StateTesting
+	}
+}
+
+// DeriveTestedCurrentStage determines which stage of a tested pipeline is
+// active, see DeriveCurrentStage.
+func DeriveTestedCurrentStage(buildState, testState, repoState string) string {
+	if buildState == "SUCCESS" && testState != "SUCCESS" {
+		return "test"
+	}
+	return DeriveCurrentStage(buildState, repoState)
+}
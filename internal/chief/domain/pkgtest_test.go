@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestTaskUUID(t *testing.T) {
+	assert.Equal(t, "uuid.arm64.test", TestTaskUUID("uuid", "arm64"))
+}
+
+func TestDeriveTestedPipelineState(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildState string
+		testState  string
+		repoState  string
+		want       string
+	}{
+		{"build started", "STARTED", "", "", "STARTED"},
+		{"build failure", "FAILURE", "", "", StateFailed},
+		{"build timeout", StateTimeout, "", "", StateTimeout},
+		{"test pending", "SUCCESS", "PENDING", "", StateTesting},
+		{"test not queued yet", "SUCCESS", "", "", StateTesting},
+		{"test started", "SUCCESS", "STARTED", "", StateTesting},
+		{"test failure", "SUCCESS", "FAILURE", "", StateFailed},
+		{"test timeout", "SUCCESS", StateTimeout, "", StateTimeout},
+		{"repo pending", "SUCCESS", "SUCCESS", "PENDING", StateRepo},
+		{"repo success", "SUCCESS", "SUCCESS", "SUCCESS", StateDone},
+		{"repo failure", "SUCCESS", "SUCCESS", "FAILURE", StateFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeriveTestedPipelineState(tt.buildState, tt.testState, tt.repoState))
+		})
+	}
+}
+
+func TestDeriveTestedCurrentStage(t *testing.T) {
+	assert.Equal(t, "build", DeriveTestedCurrentStage("STARTED", "", ""))
+	assert.Equal(t, "test", DeriveTestedCurrentStage("SUCCESS", "STARTED", ""))
+	assert.Equal(t, "test", DeriveTestedCurrentStage("SUCCESS", "FAILURE", ""))
+	assert.Equal(t, "repo", DeriveTestedCurrentStage("SUCCESS", "SUCCESS", "STARTED"))
+	assert.Equal(t, "completed", DeriveTestedCurrentStage("SUCCESS", "SUCCESS", "SUCCESS"))
+}
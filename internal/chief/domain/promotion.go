@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// PromotionRequest asks for a package version to be copied from the
+// experimental distribution of a suite into the suite itself. Maintainers
+// send it clearsigned with their GPG key.
+// The JSON tags must stay in sync with internal/cli/domain/promotion.go.
+type PromotionRequest struct {
+	PackageName    string    `json:"packageName"`
+	PackageVersion string    `json:"packageVersion"`
+	Suite          string    `json:"suite,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrSourceNotFound is returned when a source package is not published in
+// the repository it is looked up in.
+var ErrSourceNotFound = errors.New("source package not found")
+
+// maxRebuildReasonLength bounds the changelog entry of a rebuild.
+const maxRebuildReasonLength = 200
+
+// RebuildRequest asks for a binary-only rebuild (binNMU) of a source package
+// published in a suite, e.g. to build it against a new version of a library.
+// Maintainers send it clearsigned with their GPG key.
+// The JSON tags must stay in sync with internal/cli/domain/rebuild.go.
+type RebuildRequest struct {
+	PackageName string    `json:"packageName"`
+	Suite       string    `json:"suite,omitempty"`
+	Reason      string    `json:"reason"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PublishedSource is a source package published in a repository, as the
+// repo worker describes it.
+// The JSON tags must stay in sync with cmd/repo/rebuild.go.
+type PublishedSource struct {
+	PackageName string `json:"packageName"`
+	Version     string `json:"version"`   // With its epoch, if any
+	Component   string `json:"component"` // Component of the repository holding it
+	Dsc         string `json:"dsc"`       // Path of its .dsc in the published tree
+	BinNMU      int    `json:"binNMU"`    // Highest rebuild number of its binaries, 0 when never rebuilt
+}
+
+// BinNMUVersion returns the version of the binaries of the nth binary-only
+// rebuild of a source version.
+func BinNMUVersion(version string, n int) string {
+	return fmt.Sprintf("%s+b%d", version, n)
+}
+
+// ValidateRebuildReason checks the reason of a rebuild, which becomes a
+// debian/changelog entry.
+func ValidateRebuildReason(reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("the reason of the rebuild should not be empty")
+	}
+	if len(reason) > maxRebuildReasonLength {
+		return fmt.Errorf("the reason of the rebuild is longer than %d characters", maxRebuildReasonLength)
+	}
+	if strings.ContainsFunc(reason, unicode.IsControl) {
+		return errors.New("the reason of the rebuild must fit on a single line")
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// RemovalRequest asks for a source package and the binaries built from it
+// to be removed from a component of a suite. Maintainers send it clearsigned
+// with their GPG key.
+// The JSON tags must stay in sync with internal/cli/domain/removal.go.
+type RemovalRequest struct {
+	PackageName string    `json:"packageName"`
+	Suite       string    `json:"suite,omitempty"`
+	Component   string    `json:"component"`
+	Timestamp   time.Time `json:"timestamp"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Reproducibility verdicts of the pipelines submitted with a
+// reproducibility check. Pipelines submitted without one have none.
+const (
+	ReproPending        = "PENDING"
+	ReproReproducible   = "REPRODUCIBLE"
+	ReproUnreproducible = "UNREPRODUCIBLE"
+	ReproUnverified     = "UNVERIFIED" // One of the builds did not succeed
+)
+
+// ReproTaskUUID returns the identifier of the second build for arch of a
+// pipeline checked for reproducibility. Its artifact and build log are
+// stored under this ID, next to the ones of the first build.
+func ReproTaskUUID(taskUUID, arch string) string {
+	return ArchTaskUUID(taskUUID, arch) + ".repro"
+}
+
+// DebDifferences lists the .deb files that are not the same in two builds,
+// given the checksums of the .deb files of each by file name. A file built
+// only once differs too.
+func DebDifferences(first, second map[string]string) []string {
+	var diffs []string
+	for name, sum := range first {
+		other, ok := second[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s (missing from the second build)", name))
+		case other != sum:
+			diffs = append(diffs, name)
+		}
+	}
+	for name := range second {
+		if _, ok := first[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s (missing from the first build)", name))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReproTaskUUID(t *testing.T) {
+	assert.Equal(t, "2024-01-01-120000_uuid_FP_pkg.arm64.repro", ReproTaskUUID("2024-01-01-120000_uuid_FP_pkg", "arm64"))
+}
+
+func TestDebDifferences(t *testing.T) {
+	first := map[string]string{"hello_1.0-1_amd64.deb": "aa", "hello-doc_1.0-1_all.deb": "bb", "libhello1_1.0-1_amd64.deb": "cc"}
+	assert.Empty(t, DebDifferences(first, map[string]string{"hello_1.0-1_amd64.deb": "aa", "hello-doc_1.0-1_all.deb": "bb", "libhello1_1.0-1_amd64.deb": "cc"}))
+
+	assert.Equal(t, []string{
+		"hello-dbgsym_1.0-1_amd64.deb (missing from the first build)",
+		"hello_1.0-1_amd64.deb",
+		"libhello1_1.0-1_amd64.deb (missing from the second build)",
+	}, DebDifferences(first, map[string]string{"hello_1.0-1_amd64.deb": "ff", "hello-doc_1.0-1_all.deb": "bb", "hello-dbgsym_1.0-1_amd64.deb": "dd"}))
+}
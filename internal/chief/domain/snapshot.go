@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// SnapshotRequest asks for a snapshot of a repository, a suite or its
+// -experimental twin. The default suite is used when Repository is empty.
+// Maintainers send it clearsigned with their GPG key.
+type SnapshotRequest struct {
+	Repository string    `json:"repository,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RestoreRequest asks for a repository to be rolled back to a snapshot.
+// Maintainers send it clearsigned with their GPG key.
+type RestoreRequest struct {
+	SnapshotID string    `json:"snapshotID"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Snapshot is a captured state of a repository, as listed by the repo
+// worker.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TaskUUID   string    `json:"taskUUID,omitempty"`
+}
@@ -2,23 +2,28 @@ package domain
 
 // Pipeline states returned by the chief API.
 const (
-	StateDone    = "DONE"
-	StateFailed  = "FAILED"
-	StateRepo    = "REPO"
-	StateBuilding = "BUILDING"
-	StateUnknown  = "UNKNOWN"
+	StateDone      = "DONE"
+	StateFailed    = "FAILED"
+	StateRepo      = "REPO"
+	StateBuilding  = "BUILDING"
+	StateUnknown   = "UNKNOWN"
+	StateCancelled = "CANCELLED"
+	StateTimeout   = "TIMEOUT"
 )
 
 // DeriveBuildPipelineState maps machinery build+repo task states to a
 // pipeline-level state for the package build flow.
 //
-// When build succeeds and repo is in-progress, returns "REPO".
+// When build succeeds and repo is in-progress, returns "REPO". A build that
+// ran out of time has the TIMEOUT task state, see TaskQueue.GetTaskState.
 // For all other non-terminal cases, returns the raw buildState string
 // to preserve backward compatibility with existing consumers.
 func DeriveBuildPipelineState(buildState, repoState string) string {
 	switch {
 	case buildState == "FAILURE":
 		return StateFailed
+	case buildState == StateTimeout:
+		return StateTimeout
 	case buildState == "SUCCESS" && repoState == "SUCCESS":
 		return StateDone
 	case buildState == "SUCCESS" && repoState == "FAILURE":
@@ -47,6 +52,23 @@ func DeriveISOPipelineState(isoState string) string {
 	}
 }
 
+// DeriveRepoTaskState maps the state of a pipeline consisting of a single
+// repo task, such as a promotion, to a pipeline-level state.
+func DeriveRepoTaskState(repoState string) string {
+	switch repoState {
+	case "FAILURE":
+		return StateFailed
+	case "SUCCESS":
+		return StateDone
+	case "PENDING", "RECEIVED", "STARTED":
+		return StateRepo
+	case "":
+		return StateUnknown
+	default:
+		return repoState
+	}
+}
+
 // DeriveCurrentStage determines which pipeline stage is active based on
 // the build and repo task states. Used by the dashboard to label jobs.
 func DeriveCurrentStage(buildState, repoState string) string {
@@ -59,3 +81,42 @@ func DeriveCurrentStage(buildState, repoState string) string {
 		return "build"
 	}
 }
+
+// DeriveJobState maps the task states of a package build pipeline to the
+// state recorded on its job: PENDING until one of the tasks fails or the
+// repo task succeeds. A pipeline without tests has no test state.
+func DeriveJobState(buildState, testState, repoState string) string {
+	switch {
+	case buildState == StateCancelled || testState == StateCancelled || repoState == StateCancelled:
+		return StateCancelled
+	case buildState == "FAILURE":
+		return StateFailed
+	case buildState == StateTimeout:
+		return StateTimeout
+	case testState == "FAILURE":
+		return StateFailed
+	case testState == StateTimeout:
+		return StateTimeout
+	case buildState == "SUCCESS" && repoState == "SUCCESS":
+		return StateDone
+	case buildState == "SUCCESS" && repoState == "FAILURE":
+		return StateFailed
+	default:
+		return "PENDING"
+	}
+}
+
+// DeriveRepoJobState maps the state of the task of a pipeline consisting of
+// a single repo task to the state recorded on its job, see DeriveJobState.
+func DeriveRepoJobState(repoState string) string {
+	switch repoState {
+	case "SUCCESS":
+		return StateDone
+	case "FAILURE":
+		return StateFailed
+	case StateCancelled:
+		return StateCancelled
+	default:
+		return "PENDING"
+	}
+}
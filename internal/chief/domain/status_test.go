@@ -19,6 +19,10 @@ func TestDeriveBuildPipelineState(t *testing.T) {
 		{"build failure, repo failure", "FAILURE", "FAILURE", StateFailed},
 		{"build failure, repo pending", "FAILURE", "PENDING", StateFailed},
 
+		// build TIMEOUT => TIMEOUT, the repo task never runs
+		{"build timeout, repo pending", "TIMEOUT", "PENDING", StateTimeout},
+		{"build timeout, repo empty", "TIMEOUT", "", StateTimeout},
+
 		// build SUCCESS + repo terminal
 		{"build success, repo success", "SUCCESS", "SUCCESS", StateDone},
 		{"build success, repo failure", "SUCCESS", "FAILURE", StateFailed},
@@ -72,6 +76,26 @@ func TestDeriveISOPipelineState(t *testing.T) {
 	}
 }
 
+func TestDeriveRepoTaskState(t *testing.T) {
+	tests := []struct {
+		name      string
+		repoState string
+		want      string
+	}{
+		{"failure", "FAILURE", StateFailed},
+		{"success", "SUCCESS", StateDone},
+		{"pending", "PENDING", StateRepo},
+		{"started", "STARTED", StateRepo},
+		{"empty", "", StateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeriveRepoTaskState(tt.repoState))
+		})
+	}
+}
+
 func TestDeriveCurrentStage(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -97,3 +121,40 @@ func TestDeriveCurrentStage(t *testing.T) {
 		})
 	}
 }
+
+func TestDeriveJobState(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildState string
+		testState  string
+		repoState  string
+		want       string
+	}{
+		{"build started", "STARTED", "", "", "PENDING"},
+		{"build failure", "FAILURE", "", "", StateFailed},
+		{"build timeout", StateTimeout, "", "", StateTimeout},
+		{"build cancelled", StateCancelled, "", "", StateCancelled},
+		{"tests started", "SUCCESS", "STARTED", "", "PENDING"},
+		{"tests failure", "SUCCESS", "FAILURE", "", StateFailed},
+		{"tests timeout", "SUCCESS", StateTimeout, "", StateTimeout},
+		{"repo started", "SUCCESS", "SUCCESS", "STARTED", "PENDING"},
+		{"repo failure", "SUCCESS", "", "FAILURE", StateFailed},
+		{"repo cancelled", "SUCCESS", "", StateCancelled, StateCancelled},
+		{"repo success", "SUCCESS", "", "SUCCESS", StateDone},
+		{"tested repo success", "SUCCESS", "SUCCESS", "SUCCESS", StateDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeriveJobState(tt.buildState, tt.testState, tt.repoState))
+		})
+	}
+}
+
+func TestDeriveRepoJobState(t *testing.T) {
+	assert.Equal(t, "PENDING", DeriveRepoJobState(""))
+	assert.Equal(t, "PENDING", DeriveRepoJobState("STARTED"))
+	assert.Equal(t, StateDone, DeriveRepoJobState("SUCCESS"))
+	assert.Equal(t, StateFailed, DeriveRepoJobState("FAILURE"))
+	assert.Equal(t, StateCancelled, DeriveRepoJobState(StateCancelled))
+}
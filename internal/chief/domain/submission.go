@@ -20,6 +20,15 @@ type Submission struct {
 	Tarball                string    `json:"tarball"`
 	PackageBranch          string    `json:"packageBranch"`
 	SourceBranch           string    `json:"sourceBranch"`
+	BuilderLabel           string    `json:"builderLabel,omitempty"`
+	Suite                  string    `json:"suite,omitempty"`
+	UpstreamOnly           bool      `json:"upstreamOnly,omitempty"`
+	CheckReproducibility   bool      `json:"checkReproducibility,omitempty"` // Build twice and compare the binaries
+	RunTests               bool      `json:"runTests,omitempty"`             // Test the built packages before publishing them
+
+	// Set by chief for the rebuilds it queues, never taken from maintainers
+	BinNMU       int    `json:"-"`
+	BinNMUReason string `json:"-"`
 }
 
 // ISOSubmission represents an ISO build request.
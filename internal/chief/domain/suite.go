@@ -0,0 +1,25 @@
+package domain
+
+// Suite is a distribution of the repository that packages can be submitted to.
+type Suite struct {
+	Codename         string   // Target suite, e.g. verbeek
+	UpstreamCodename string   // Upstream distribution the suite's builders track
+	Architectures    []string // Binary architectures packages are built for
+	Components       []string // Repository components, e.g. main restricted
+
+	LintianFailTags map[string][]string // Lintian tags failing the builds, by component
+}
+
+// HasComponent reports whether component is one of the suite's components.
+// Suites without a known component list accept any component.
+func (s Suite) HasComponent(component string) bool {
+	if len(s.Components) == 0 {
+		return true
+	}
+	for _, c := range s.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuiteHasComponent(t *testing.T) {
+	suite := Suite{Codename: "verbeek", Components: []string{"main", "restricted"}}
+	assert.True(t, suite.HasComponent("restricted"))
+	assert.False(t, suite.HasComponent("extras"))
+	assert.True(t, Suite{Codename: "verbeek"}.HasComponent("extras"))
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -59,3 +61,35 @@ func (g *GPG) VerifySignedSubmission(submissionPath string) error {
 func (g *GPG) VerifyFile(filePath string) error {
 	return g.gpgCmd("--verify", filePath).Run()
 }
+
+// VerifySignedMessage verifies a clearsigned message against the maintainer
+// keyring and returns its content along with the fingerprint of the primary
+// key that signed it.
+func (g *GPG) VerifySignedMessage(data []byte) ([]byte, string, error) {
+	cmd := g.gpgCmd("--batch", "--status-fd", "2", "--decrypt")
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", err
+	}
+	fingerprint := parseValidSig(stderr.String())
+	if fingerprint == "" {
+		return nil, "", errors.New("no valid signature found")
+	}
+	return stdout.Bytes(), fingerprint, nil
+}
+
+// parseValidSig returns the primary key fingerprint of the VALIDSIG line of
+// gpg's --status-fd output.
+func parseValidSig(status string) string {
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" || fields[1] != "VALIDSIG" {
+			continue
+		}
+		return fields[len(fields)-1]
+	}
+	return ""
+}
@@ -1,38 +1,111 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/RichardKnop/machinery/v1"
 	"github.com/RichardKnop/machinery/v1/backends/result"
 	"github.com/RichardKnop/machinery/v1/tasks"
+
+	"github.com/blankon/irgsh-go/internal/cancel"
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/queue"
 )
 
 // MachineryTaskQueue adapts *machinery.Server to the usecase.TaskQueue interface.
 type MachineryTaskQueue struct {
 	server *machinery.Server
+	flags  *cancel.Flags
 }
 
-func NewMachineryTaskQueue(server *machinery.Server) *MachineryTaskQueue {
-	return &MachineryTaskQueue{server: server}
+func NewMachineryTaskQueue(server *machinery.Server, flags *cancel.Flags) *MachineryTaskQueue {
+	return &MachineryTaskQueue{server: server, flags: flags}
 }
 
-func (m *MachineryTaskQueue) SendBuildChain(taskUUID string, payload []byte) error {
-	buildSig := tasks.Signature{
-		Name: "build",
-		UUID: taskUUID,
-		Args: []tasks.Arg{{Type: "string", Value: string(payload)}},
+// SendBuildChain sends the per-architecture build tasks as a machinery group
+// with the repo task as its chord callback. Machinery only triggers the
+// callback when every task in the group succeeded.
+func (m *MachineryTaskQueue) SendBuildChain(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+	return m.sendChord(builds, repoSignature(taskUUID, repoPayload))
+}
+
+// SendTestedBuildChain sends the build tasks like SendBuildChain, with the
+// test tasks chained to each other's success as the chord callback and the
+// repo task chained to the success of the last one. Machinery cannot chain
+// a chord to a chord, so the test tasks run one after the other.
+func (m *MachineryTaskQueue) SendTestedBuildChain(taskUUID string, builds, tests []domain.BuildTask, repoPayload []byte) error {
+	if len(tests) == 0 {
+		return m.SendBuildChain(taskUUID, builds, repoPayload)
 	}
-	repoSig := tasks.Signature{
-		Name: "repo",
-		UUID: taskUUID,
+	next := repoSignature(taskUUID, repoPayload)
+	for i := len(tests) - 1; i >= 0; i-- {
+		next = &tasks.Signature{
+			Name:       "test",
+			UUID:       tests[i].TaskUUID,
+			RoutingKey: tests[i].Queue,
+			Args:       []tasks.Arg{{Type: "string", Value: string(tests[i].Payload)}},
+			Immutable:  true,
+			OnSuccess:  []*tasks.Signature{next},
+		}
 	}
-	chain, err := tasks.NewChain(&buildSig, &repoSig)
+	return m.sendChord(builds, next)
+}
+
+// sendChord sends the build tasks as a group triggering callback once they
+// all succeeded.
+func (m *MachineryTaskQueue) sendChord(builds []domain.BuildTask, callback *tasks.Signature) error {
+	buildSigs := make([]*tasks.Signature, 0, len(builds))
+	for _, b := range builds {
+		buildSigs = append(buildSigs, &tasks.Signature{
+			Name:       "build",
+			UUID:       b.TaskUUID,
+			RoutingKey: b.Queue,
+			Args:       []tasks.Arg{{Type: "string", Value: string(b.Payload)}},
+		})
+	}
+	group, err := tasks.NewGroup(buildSigs...)
 	if err != nil {
 		return err
 	}
-	_, err = m.server.SendChain(chain)
+	chord, err := tasks.NewChord(group, callback)
+	if err != nil {
+		return err
+	}
+	_, err = m.server.SendChord(chord, 0)
 	return err
 }
 
+// repoSignature returns the repo task of a pipeline. It takes the pipeline
+// payload rather than the results of the task before it. The task is
+// published by the worker finishing the task before it, so the queue has
+// to be explicit, otherwise it lands in that worker's queue.
+func repoSignature(taskUUID string, repoPayload []byte) *tasks.Signature {
+	return &tasks.Signature{
+		Name:       "repo",
+		UUID:       taskUUID,
+		RoutingKey: queue.Default,
+		Args:       []tasks.Arg{{Type: "string", Value: string(repoPayload)}},
+		Immutable:  true,
+	}
+}
+
+// SendBuildTasks sends each build task on its own to the queue of its
+// builders.
+func (m *MachineryTaskQueue) SendBuildTasks(builds []domain.BuildTask) error {
+	for _, b := range builds {
+		sig := tasks.Signature{
+			Name:       "build",
+			UUID:       b.TaskUUID,
+			RoutingKey: b.Queue,
+			Args:       []tasks.Arg{{Type: "string", Value: string(b.Payload)}},
+		}
+		if _, err := m.server.SendTask(&sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *MachineryTaskQueue) SendISOTask(taskUUID string, payload []byte) error {
 	sig := tasks.Signature{
 		Name: "iso",
@@ -43,6 +116,18 @@ func (m *MachineryTaskQueue) SendISOTask(taskUUID string, payload []byte) error
 	return err
 }
 
+// SendRepoTask sends a task to the repo worker's queue.
+func (m *MachineryTaskQueue) SendRepoTask(taskName, taskUUID string, payload []byte) error {
+	sig := tasks.Signature{
+		Name:       taskName,
+		UUID:       taskUUID,
+		RoutingKey: queue.Default,
+		Args:       []tasks.Arg{{Type: "string", Value: string(payload)}},
+	}
+	_, err := m.server.SendTask(&sig)
+	return err
+}
+
 func (m *MachineryTaskQueue) GetTaskState(taskName, taskUUID string) string {
 	sig := tasks.Signature{
 		Name: taskName,
@@ -50,5 +135,17 @@ func (m *MachineryTaskQueue) GetTaskState(taskName, taskUUID string) string {
 	}
 	r := result.NewAsyncResult(&sig, m.server.GetBackend())
 	r.Touch()
-	return r.GetState().State
+	state := r.GetState()
+	// Machinery has no state of its own for a build that ran out of time
+	if state.State == tasks.StateFailure && cancel.IsTimeout(state.Error) {
+		return domain.StateTimeout
+	}
+	return state.State
+}
+
+// CancelPipeline raises the cancellation flag of a pipeline. Machinery
+// cannot take a task back from the broker, so the workers skip the tasks
+// of a flagged pipeline instead, and builders stop the build they run.
+func (m *MachineryTaskQueue) CancelPipeline(taskUUID string) error {
+	return m.flags.Cancel(context.Background(), taskUUID)
 }
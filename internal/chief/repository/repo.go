@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+)
+
+// RepoClient queries the HTTP server of the repo worker.
+type RepoClient struct {
+	address        string
+	httpClient     *http.Client
+	downloadClient *http.Client // Pool files, such as orig tarballs, may be large
+}
+
+func NewRepoClient(address string) *RepoClient {
+	return &RepoClient{
+		address:        strings.TrimSuffix(address, "/"),
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		downloadClient: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// ListSnapshots returns the snapshots kept by the repo worker, newest first.
+func (c *RepoClient) ListSnapshots() ([]domain.Snapshot, error) {
+	resp, err := c.httpClient.Get(c.address + "/snapshots/")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("repo returned %s", resp.Status)
+	}
+	var snapshots []domain.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetSource describes the source package name published in repository.
+func (c *RepoClient) GetSource(repository, name string) (domain.PublishedSource, error) {
+	resp, err := c.httpClient.Get(c.address + "/sources/" + url.PathEscape(repository) + "/" + url.PathEscape(name))
+	if err != nil {
+		return domain.PublishedSource{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return domain.PublishedSource{}, fmt.Errorf("%w: %s in %s", domain.ErrSourceNotFound, name, repository)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return domain.PublishedSource{}, fmt.Errorf("repo returned %s", resp.Status)
+	}
+	var source domain.PublishedSource
+	if err := json.NewDecoder(resp.Body).Decode(&source); err != nil {
+		return domain.PublishedSource{}, err
+	}
+	return source, nil
+}
+
+// DownloadFile saves a file of the published tree of repository to dest.
+func (c *RepoClient) DownloadFile(repository, path, dest string) error {
+	resp, err := c.downloadClient.Get(c.address + "/" + url.PathEscape(repository) + "/" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("repo returned %s for %s", resp.Status, path)
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -48,6 +48,14 @@ func (s *Storage) ExtractSubmission(taskUUID string) error {
 	return exec.Command("tar", "-xvf", tarball, "-C", dir).Run()
 }
 
+// PackSubmission creates the tarball of a submission from its directory, the
+// reverse of ExtractSubmission.
+func (s *Storage) PackSubmission(taskUUID string) error {
+	tarball := filepath.Join(s.SubmissionsDir(), taskUUID+".tar.gz")
+	dir := filepath.Join(s.SubmissionsDir(), taskUUID)
+	return exec.Command("tar", "-czf", tarball, "-C", dir, ".").Run()
+}
+
 func (s *Storage) CopyFileWithSudo(src, dst string) error {
 	return exec.Command("sudo", "cp", src, dst).Run()
 }
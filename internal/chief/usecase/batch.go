@@ -0,0 +1,363 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// maxBatchSize bounds the number of packages of a batch.
+const maxBatchSize = 100
+
+// BatchService submits batches of packages and queues their pipelines in
+// the order their build dependencies require.
+type BatchService struct {
+	submissions *SubmissionService
+	status      *StatusService
+	store       BatchStore
+
+	// mu serializes the scheduling passes, so a package is never queued
+	// twice by a submission and the scheduler loop.
+	mu sync.Mutex
+}
+
+// NewBatchService creates a BatchService queueing the packages through
+// submissions once status reports their dependencies as built.
+func NewBatchService(submissions *SubmissionService, status *StatusService, store BatchStore) *BatchService {
+	return &BatchService{
+		submissions: submissions,
+		status:      status,
+		store:       store,
+	}
+}
+
+// SubmitBatch accepts every package of a batch, orders them by their build
+// dependencies and queues the packages depending on none of the others.
+// The batch is rejected as a whole when one of its packages is.
+func (bs *BatchService) SubmitBatch(batch domain.BatchSubmission) (domain.BatchResponse, error) {
+	if bs.store == nil {
+		return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusServiceUnavailable, "batch submissions are not available")
+	}
+	if len(batch.Items) == 0 {
+		return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "batch has no package")
+	}
+	if len(batch.Items) > maxBatchSize {
+		return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("batch has more than %d packages", maxBatchSize))
+	}
+	fingerprint := batch.Items[0].MaintainerFingerprint
+	for _, item := range batch.Items {
+		if item.MaintainerFingerprint != fingerprint {
+			return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "packages of a batch must be submitted by the same maintainer")
+		}
+	}
+
+	accepted := make([]domain.Submission, 0, len(batch.Items))
+	suites := make([]domain.Suite, 0, len(batch.Items))
+	recorded := false
+	defer func() {
+		// The packages accepted before the batch was rejected never run
+		if !recorded {
+			for _, submission := range accepted {
+				bs.submissions.removeSubmission(submission.TaskUUID)
+			}
+		}
+	}()
+
+	pkgs := make([]domain.BatchPackage, len(batch.Items))
+	for i, item := range batch.Items {
+		submission, suite, err := bs.submissions.acceptSubmission(item)
+		if err != nil {
+			return domain.BatchResponse{}, prefixHTTPError(item.PackageName, err)
+		}
+		// The suite is resolved now, the default suite may change before
+		// the package is queued.
+		submission.Suite = suite.Codename
+		accepted = append(accepted, submission)
+		suites = append(suites, suite)
+
+		pkg, err := dscRelations(bs.submissions.storage.SubmissionDirPath(submission.TaskUUID))
+		if err != nil {
+			log.Println(err)
+			return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusBadRequest, item.PackageName+": could not read the .dsc")
+		}
+		pkg.Name = submission.PackageName
+		pkgs[i] = pkg
+	}
+
+	deps, err := domain.BatchDependencies(pkgs)
+	if err != nil {
+		return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now()
+	record := storage.Batch{
+		BatchID:     now.Format("2006-01-02-150405") + "_batch_" + uuid.New().String(),
+		Maintainer:  accepted[0].Maintainer,
+		SubmittedAt: now,
+		State:       domain.StateRunning,
+	}
+	resp := domain.BatchResponse{BatchID: record.BatchID}
+	for i, submission := range accepted {
+		data, err := json.Marshal(submission)
+		if err != nil {
+			return domain.BatchResponse{}, err
+		}
+		record.Items = append(record.Items, storage.BatchItem{
+			Position:       i,
+			PackageName:    submission.PackageName,
+			PackageVersion: submission.PackageVersion,
+			TaskUUID:       submission.TaskUUID,
+			DependsOn:      deps[i],
+			State:          domain.StateWaiting,
+			Submission:     string(data),
+		})
+
+		view := domain.BatchPackageView{PackageName: submission.PackageName, PipelineID: submission.TaskUUID}
+		for _, j := range deps[i] {
+			view.DependsOn = append(view.DependsOn, accepted[j].PackageName)
+		}
+		resp.Packages = append(resp.Packages, view)
+	}
+	if err := bs.store.RecordBatch(record); err != nil {
+		log.Printf("Failed to record batch: %v\n", err)
+		return domain.BatchResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	recorded = true
+
+	// The jobs of the packages are known from now on, so that they can be
+	// looked up and cancelled while they wait for their dependencies.
+	if jobStore := bs.submissions.jobStore; jobStore != nil {
+		for i, submission := range accepted {
+			if err := jobStore.RecordJob(pipelineJob(submission, suites[i], domain.StateWaiting)); err != nil {
+				log.Printf("Failed to record job: %v\n", err)
+			}
+		}
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err := bs.scheduleBatch(&record); err != nil {
+		log.Printf("Failed to schedule batch %s: %v\n", record.BatchID, err)
+	}
+
+	return resp, nil
+}
+
+// ScheduleBatches moves every running batch forward. Chief calls it
+// periodically.
+func (bs *BatchService) ScheduleBatches() {
+	if bs.store == nil {
+		return
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	batches, err := bs.store.GetRunningBatches()
+	if err != nil {
+		log.Printf("Failed to list running batches: %v\n", err)
+		return
+	}
+	for _, batch := range batches {
+		if err := bs.scheduleBatch(batch); err != nil {
+			log.Printf("Failed to schedule batch %s: %v\n", batch.BatchID, err)
+		}
+	}
+}
+
+// scheduleBatch refreshes the state of the packages of a batch, queues the
+// packages whose dependencies are in the repository, skips the packages
+// whose dependencies did not build, and finishes the batch once none of
+// its packages can change anymore. It is called with mu held.
+func (bs *BatchService) scheduleBatch(batch *storage.Batch) error {
+	items := batch.Items
+	byPosition := make(map[int]*storage.BatchItem, len(items))
+	for i := range items {
+		byPosition[items[i].Position] = &items[i]
+	}
+
+	for i := range items {
+		item := &items[i]
+		if domain.IsBatchItemFinished(item.State) {
+			continue
+		}
+		status, err := bs.status.BuildStatus(item.TaskUUID)
+		if err != nil || status.State == "" || status.State == item.State {
+			continue
+		}
+		// A package waiting for its dependencies only changes when its
+		// pipeline is cancelled
+		if !item.Queued && status.State != domain.StateCancelled {
+			continue
+		}
+		if err := bs.store.UpdateBatchItem(batch.BatchID, item.Position, status.State, item.Queued); err != nil {
+			return err
+		}
+		item.State = status.State
+	}
+
+	// A package skipped or queued may let the packages after it move on
+	// within the same pass.
+	for changed := true; changed; {
+		changed = false
+		for i := range items {
+			item := &items[i]
+			if item.Queued || item.State != domain.StateWaiting {
+				continue
+			}
+			depStates := make([]string, 0, len(item.DependsOn))
+			for _, p := range item.DependsOn {
+				if dep, ok := byPosition[p]; ok {
+					depStates = append(depStates, dep.State)
+				}
+			}
+			state, ready := domain.NextBatchItemState(depStates)
+			switch {
+			case state == domain.StateSkipped:
+				log.Printf("Batch %s: skipping %s, one of its dependencies was not built\n", batch.BatchID, item.PackageName)
+				if err := bs.store.UpdateBatchItem(batch.BatchID, item.Position, domain.StateSkipped, false); err != nil {
+					return err
+				}
+				if jobStore := bs.submissions.jobStore; jobStore != nil {
+					if err := jobStore.UpdateJobState(item.TaskUUID, domain.StateSkipped); err != nil {
+						log.Printf("Failed to update job state: %v\n", err)
+					}
+				}
+				item.State = domain.StateSkipped
+				changed = true
+			case ready:
+				if err := bs.queueItem(item); err != nil {
+					// Left waiting, the next pass tries again
+					log.Printf("Batch %s: could not queue %s: %v\n", batch.BatchID, item.PackageName, err)
+					continue
+				}
+				if err := bs.store.UpdateBatchItem(batch.BatchID, item.Position, "PENDING", true); err != nil {
+					return err
+				}
+				item.State, item.Queued = "PENDING", true
+				changed = true
+			}
+		}
+	}
+
+	states := make([]string, len(items))
+	for i, item := range items {
+		states[i] = item.State
+	}
+	if state := domain.BatchState(states); state != batch.State {
+		if err := bs.store.UpdateBatchState(batch.BatchID, state); err != nil {
+			return err
+		}
+		batch.State = state
+		log.Printf("Batch %s is %s\n", batch.BatchID, state)
+	}
+	return nil
+}
+
+// queueItem queues the pipeline of a package of a batch.
+func (bs *BatchService) queueItem(item *storage.BatchItem) error {
+	var submission domain.Submission
+	if err := json.Unmarshal([]byte(item.Submission), &submission); err != nil {
+		return err
+	}
+	suite, err := bs.submissions.suite(submission.Suite)
+	if err != nil {
+		return err
+	}
+	return bs.submissions.startPipeline(submission, suite)
+}
+
+// prefixHTTPError prefixes the message of an HTTP error with the package it
+// is about, keeping its status code.
+func prefixHTTPError(packageName string, err error) error {
+	var httpErr httputil.HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+	msg := httpErr.Message
+	if strings.HasPrefix(msg, "{") || msg == "" {
+		msg = http.StatusText(httpErr.Code)
+	}
+	return httputil.NewHTTPError(httpErr.Code, packageName+": "+msg)
+}
+
+// dscRelations reads the binary packages a signed submission builds and the
+// packages it build depends on from its .dsc.
+func dscRelations(submissionPath string) (domain.BatchPackage, error) {
+	matches, err := filepath.Glob(filepath.Join(submissionPath, "signed", "*.dsc"))
+	if err != nil {
+		return domain.BatchPackage{}, err
+	}
+	if len(matches) == 0 {
+		return domain.BatchPackage{}, errors.New("no .dsc file in submission")
+	}
+	content, err := os.ReadFile(matches[0])
+	if err != nil {
+		return domain.BatchPackage{}, err
+	}
+
+	fields := dscFields(string(content))
+	pkg := domain.BatchPackage{Binaries: relationNames(fields["Binary"])}
+	for _, field := range []string{"Build-Depends", "Build-Depends-Arch", "Build-Depends-Indep"} {
+		pkg.BuildDepends = append(pkg.BuildDepends, relationNames(fields[field])...)
+	}
+	return pkg, nil
+}
+
+// dscFields parses the fields of a, possibly clearsigned, .dsc, joining
+// their continuation lines.
+func dscFields(content string) map[string]string {
+	fields := make(map[string]string)
+	var current string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "-----BEGIN PGP SIGNATURE") {
+			break
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if current != "" {
+				fields[current] += " " + strings.TrimSpace(line)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			current = ""
+			continue
+		}
+		current = key
+		fields[key] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+// relationNames returns the package names of a relationship field such as
+// "debhelper-compat (= 13), libfoo-dev (>= 1.2) | libbar-dev [amd64]",
+// alternatives included.
+func relationNames(field string) []string {
+	var names []string
+	for _, relation := range strings.Split(field, ",") {
+		for _, alt := range strings.Split(relation, "|") {
+			name := strings.TrimSpace(alt)
+			if i := strings.IndexAny(name, " \t([<:"); i >= 0 {
+				name = name[:i]
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
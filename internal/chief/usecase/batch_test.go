@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// batchFixture runs batches against fake submissions whose .dsc files
+// declare the given binaries and build dependencies.
+type batchFixture struct {
+	dir    string
+	dscs   map[string]string // Package name to .dsc content
+	queued []string          // Packages whose pipeline was queued, in order
+	states map[string]string // Task UUID to machinery state
+	uuids  map[string]string // Package name to pipeline UUID
+	store  *mockBatchStore
+	jobs   map[string]*monitoring.JobInfo // Recorded jobs, nil without job tracking
+	tq     *mockTaskQueue
+	fs     *mockFileStorage
+	svc    *BatchService
+}
+
+func newBatchFixture(t *testing.T) *batchFixture {
+	f := &batchFixture{
+		dir:    t.TempDir(),
+		dscs:   make(map[string]string),
+		states: make(map[string]string),
+		uuids:  make(map[string]string),
+		store:  &mockBatchStore{},
+	}
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			name := taskUUID[strings.LastIndex(taskUUID, "_")+1:]
+			f.queued = append(f.queued, name)
+			f.uuids[name] = taskUUID
+			return nil
+		},
+		getTaskStateFn: func(taskName, taskUUID string) string {
+			return f.states[taskName+":"+taskUUID]
+		},
+	}
+	fs := &mockFileStorage{
+		submissionsDir: f.dir,
+		extractSubmissionFn: func(taskUUID string) error {
+			name := taskUUID[strings.LastIndex(taskUUID, "_")+1:]
+			signed := filepath.Join(f.dir, taskUUID, "signed")
+			if err := os.MkdirAll(signed, 0755); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(signed, name+"_1.0-1.dsc"), []byte(f.dscs[name]), 0644)
+		},
+	}
+	f.tq, f.fs = tq, fs
+	submissions := newTestSubmissionService(tq, fs, &mockGPGVerifier{}, nil, nil)
+	status := NewStatusService(tq, nil, []string{domain.DefaultArchitecture})
+	f.svc = NewBatchService(submissions, status, f.store)
+	return f
+}
+
+// trackJobs records the jobs of the pipelines in memory.
+func (f *batchFixture) trackJobs() *mockJobStore {
+	f.jobs = make(map[string]*monitoring.JobInfo)
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			f.jobs[job.TaskUUID] = &job
+			return nil
+		},
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			job, ok := f.jobs[taskUUID]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			copied := *job
+			return &copied, nil
+		},
+		updateJobStateFn: func(taskUUID, state string) error {
+			if job, ok := f.jobs[taskUUID]; ok {
+				job.State = state
+			}
+			return nil
+		},
+	}
+	submissions := newTestSubmissionService(f.tq, f.fs, &mockGPGVerifier{}, js, nil)
+	status := NewStatusService(f.tq, js, []string{domain.DefaultArchitecture})
+	f.svc = NewBatchService(submissions, status, f.store)
+	return js
+}
+
+// add uploads a package building binaries and build depending on deps.
+func (f *batchFixture) add(t *testing.T, name, binaries, deps string) domain.Submission {
+	f.dscs[name] = fmt.Sprintf(`-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA512
+
+Format: 3.0 (quilt)
+Source: %s
+Binary: %s
+Version: 1.0-1
+Build-Depends: debhelper-compat (= 13),
+ %s
+-----BEGIN PGP SIGNATURE-----
+
+iQIzBAEBCgAdFiEE
+-----END PGP SIGNATURE-----
+`, name, binaries, deps)
+	tarball := "upload-" + name
+	require.NoError(t, os.WriteFile(filepath.Join(f.dir, tarball+".tar.gz"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(f.dir, tarball+".token"), []byte("sig"), 0644))
+	return domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		Maintainer:            "Test User",
+		PackageName:           name,
+		PackageVersion:        "1.0-1",
+		Tarball:               tarball,
+	}
+}
+
+// finish sets the machinery states of the pipeline of a package.
+func (f *batchFixture) finish(name, buildState, repoState string) {
+	uuid := f.uuids[name]
+	f.states["build:"+domain.ArchTaskUUID(uuid, domain.DefaultArchitecture)] = buildState
+	f.states["repo:"+uuid] = repoState
+}
+
+func (f *batchFixture) itemStates(t *testing.T, batchID string) map[string]string {
+	batch, err := f.store.GetBatch(batchID)
+	require.NoError(t, err)
+	states := make(map[string]string)
+	for _, item := range batch.Items {
+		states[item.PackageName] = item.State
+	}
+	return states
+}
+
+func TestSubmitBatch_SchedulesByBuildDepends(t *testing.T) {
+	f := newBatchFixture(t)
+	items := []domain.Submission{
+		f.add(t, "app", "app", "libfoo-dev (>= 1.0) | libbaz-dev"),
+		f.add(t, "libfoo", "libfoo1, libfoo-dev", "libbar-dev [amd64]"),
+		f.add(t, "libbar", "libbar1, libbar-dev", "zlib1g-dev"),
+		f.add(t, "tool", "tool", "libbar-dev:native"),
+		f.add(t, "docs", "docs", "pandoc"),
+	}
+
+	resp, err := f.svc.SubmitBatch(domain.BatchSubmission{Items: items})
+	require.NoError(t, err)
+	require.Len(t, resp.Packages, 5)
+	assert.Equal(t, []string{"libfoo"}, resp.Packages[0].DependsOn)
+	assert.Equal(t, []string{"libbar"}, resp.Packages[1].DependsOn)
+	assert.Empty(t, resp.Packages[2].DependsOn)
+	assert.NotEmpty(t, resp.Packages[2].PipelineID)
+
+	// Only the packages depending on none of the others are queued
+	assert.Equal(t, []string{"libbar", "docs"}, f.queued)
+
+	// Nothing moves while libbar builds
+	f.finish("libbar", "STARTED", "")
+	f.svc.ScheduleBatches()
+	assert.Equal(t, []string{"libbar", "docs"}, f.queued)
+	assert.Equal(t, "STARTED", f.itemStates(t, resp.BatchID)["libbar"])
+
+	// Its dependants are queued once it is in the repository
+	f.finish("libbar", "SUCCESS", "SUCCESS")
+	f.finish("docs", "SUCCESS", "SUCCESS")
+	f.svc.ScheduleBatches()
+	assert.Equal(t, []string{"libbar", "docs", "libfoo", "tool"}, f.queued)
+
+	// A failed dependency skips its dependants
+	f.finish("libfoo", "FAILURE", "")
+	f.finish("tool", "SUCCESS", "SUCCESS")
+	f.svc.ScheduleBatches()
+	assert.Equal(t, []string{"libbar", "docs", "libfoo", "tool"}, f.queued)
+	assert.Equal(t, map[string]string{
+		"app":    domain.StateSkipped,
+		"libfoo": domain.StateFailed,
+		"libbar": domain.StateDone,
+		"tool":   domain.StateDone,
+		"docs":   domain.StateDone,
+	}, f.itemStates(t, resp.BatchID))
+
+	batch, err := f.store.GetBatch(resp.BatchID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StateFailed, batch.State)
+}
+
+func TestSubmitBatch_Rejections(t *testing.T) {
+	f := newBatchFixture(t)
+
+	_, err := f.svc.SubmitBatch(domain.BatchSubmission{})
+	assert.Error(t, err)
+
+	cycle := []domain.Submission{
+		f.add(t, "a", "liba-dev", "libb-dev"),
+		f.add(t, "b", "libb-dev", "liba-dev"),
+	}
+	_, err = f.svc.SubmitBatch(domain.BatchSubmission{Items: cycle})
+	var httpErr httputil.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, 400, httpErr.Code)
+	assert.Contains(t, httpErr.Message, "dependency cycle")
+	assert.Empty(t, f.queued)
+
+	mixed := []domain.Submission{f.add(t, "c", "c", "d"), f.add(t, "d", "d", "e")}
+	mixed[1].MaintainerFingerprint = "0123456789ABCDEF"
+	_, err = f.svc.SubmitBatch(domain.BatchSubmission{Items: mixed})
+	assert.Error(t, err)
+
+	invalid := []domain.Submission{f.add(t, "e", "e", "f")}
+	invalid[0].PackageName = "bad name"
+	_, err = f.svc.SubmitBatch(domain.BatchSubmission{Items: invalid})
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, "bad name: invalid package name", httpErr.Message)
+
+	noStore := NewBatchService(f.svc.submissions, f.svc.status, nil)
+	_, err = noStore.SubmitBatch(domain.BatchSubmission{Items: []domain.Submission{f.add(t, "g", "g", "h")}})
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, 503, httpErr.Code)
+}
+
+func TestSubmitBatch_RemovesRejectedSubmissions(t *testing.T) {
+	f := newBatchFixture(t)
+
+	items := []domain.Submission{f.add(t, "a", "a", "b"), f.add(t, "b", "b", "c")}
+	items[1].PackageName = "bad name"
+	_, err := f.svc.SubmitBatch(domain.BatchSubmission{Items: items})
+	require.Error(t, err)
+
+	// The files of a, accepted before b was rejected, are gone
+	entries, err := os.ReadDir(f.dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"upload-b.tar.gz", "upload-b.token"}, names)
+}
+
+func TestSubmitBatch_WaitingJobs(t *testing.T) {
+	f := newBatchFixture(t)
+	js := f.trackJobs()
+
+	items := []domain.Submission{
+		f.add(t, "app", "app", "libfoo-dev"),
+		f.add(t, "libfoo", "libfoo-dev", "zlib1g-dev"),
+	}
+	resp, err := f.svc.SubmitBatch(domain.BatchSubmission{Items: items})
+	require.NoError(t, err)
+	require.Equal(t, []string{"libfoo"}, f.queued)
+	app := resp.Packages[0].PipelineID
+
+	// The job of a package waiting for its dependencies is known
+	require.Contains(t, f.jobs, app)
+	assert.Equal(t, domain.StateWaiting, f.jobs[app].State)
+	assert.Equal(t, "PENDING", f.jobs[f.uuids["libfoo"]].State)
+	status, err := f.svc.status.BuildStatus(app)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StateWaiting, status.State)
+
+	// and it can be cancelled before it is queued
+	_, err = NewCancelService(f.tq, signedBy("ABCDEF1234567890"), js).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: app, Timestamp: time.Now()}))
+	require.NoError(t, err)
+	f.jobs[f.uuids["libfoo"]].State = domain.StateDone
+	f.jobs[f.uuids["libfoo"]].BuildState = "SUCCESS"
+	f.jobs[f.uuids["libfoo"]].ArchBuildStates = map[string]string{domain.DefaultArchitecture: "SUCCESS"}
+	f.jobs[f.uuids["libfoo"]].RepoState = "SUCCESS"
+	f.svc.ScheduleBatches()
+	assert.Equal(t, []string{"libfoo"}, f.queued)
+	assert.Equal(t, domain.StateCancelled, f.itemStates(t, resp.BatchID)["app"])
+}
+
+func TestRelationNames(t *testing.T) {
+	assert.Equal(t,
+		[]string{"debhelper-compat", "libfoo-dev", "libbar-dev", "python3", "pkg-config"},
+		relationNames("debhelper-compat (= 13), libfoo-dev (>= 1.2) | libbar-dev [amd64],  python3:any, pkg-config <!nocheck>,"))
+	assert.Nil(t, relationNames(""))
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// CancelService stops pipelines that are queued or running.
+type CancelService struct {
+	taskQueue TaskQueue
+	gpg       GPGVerifier
+	jobStore  JobStore
+}
+
+func NewCancelService(taskQueue TaskQueue, gpg GPGVerifier, jobStore JobStore) *CancelService {
+	return &CancelService{taskQueue: taskQueue, gpg: gpg, jobStore: jobStore}
+}
+
+// CancelPipeline revokes the tasks of the pipeline named by a
+// maintainer-signed domain.CancelRequest that have not run yet and has the
+// workers stop the ones running, then marks its job CANCELLED. Without job
+// tracking, the pipeline is cancelled without being looked up.
+func (c *CancelService) CancelPipeline(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.CancelRequest
+	fingerprint, err := verifySignedRequest(c.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	UUID := req.PipelineID
+	if !domain.SafeIDPattern.MatchString(UUID) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid pipeline identifier")
+	}
+
+	if c.jobStore != nil {
+		job, err := c.jobStore.GetJob(UUID)
+		if err != nil {
+			log.Printf("Job not found for cancellation: %s: %v\n", UUID, err)
+			return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusNotFound, "job not found")
+		}
+		if storage.IsTerminalState(job.State) {
+			return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusConflict, "pipeline already finished as "+job.State)
+		}
+	}
+
+	if err := c.taskQueue.CancelPipeline(UUID); err != nil {
+		log.Printf("Failed to cancel pipeline %s: %v\n", UUID, err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "failed to cancel pipeline")
+	}
+
+	if c.jobStore != nil {
+		if err := c.jobStore.UpdateJobState(UUID, domain.StateCancelled); err != nil {
+			log.Printf("Failed to update job state: %v\n", err)
+		}
+		recordJobEvents(c.jobStore, monitoring.JobEvent{
+			TaskUUID: UUID,
+			TaskID:   UUID,
+			Stage:    domain.StagePipeline,
+			Event:    domain.EventCancelled,
+		})
+	}
+
+	log.Printf("Pipeline %s cancelled by %s\n", UUID, maintainerName(c.gpg, fingerprint))
+	return domain.SubmitPayloadResponse{PipelineID: UUID}, nil
+}
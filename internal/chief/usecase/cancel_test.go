@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cancelRequest returns a request cancelling pipeline, signed now.
+func TestCancelPipeline_Success(t *testing.T) {
+	var cancelled string
+	tq := &mockTaskQueue{
+		cancelPipelineFn: func(taskUUID string) error {
+			cancelled = taskUUID
+			return nil
+		},
+	}
+	var updated string
+	var events []monitoring.JobEvent
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID, State: "PENDING"}, nil
+		},
+		updateJobStateFn: func(taskUUID, state string) error {
+			updated = taskUUID + ":" + state
+			return nil
+		},
+		recordEventFn: func(event monitoring.JobEvent) error {
+			events = append(events, event)
+			return nil
+		},
+	}
+
+	resp, err := NewCancelService(tq, signedBy("FINGERPRINT"), js).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", resp.PipelineID)
+	assert.Equal(t, "task-1", cancelled)
+	assert.Equal(t, "task-1:CANCELLED", updated)
+	require.Len(t, events, 1)
+	assert.Equal(t, "task-1", events[0].TaskUUID)
+	assert.Equal(t, domain.StagePipeline, events[0].Stage)
+	assert.Equal(t, domain.EventCancelled, events[0].Event)
+}
+
+func TestCancelPipeline_WithoutJobTracking(t *testing.T) {
+	var cancelled string
+	tq := &mockTaskQueue{
+		cancelPipelineFn: func(taskUUID string) error {
+			cancelled = taskUUID
+			return nil
+		},
+	}
+
+	_, err := NewCancelService(tq, signedBy("FINGERPRINT"), nil).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", cancelled)
+}
+
+func TestCancelPipeline_Rejected(t *testing.T) {
+	t.Run("invalid id", func(t *testing.T) {
+		_, err := NewCancelService(&mockTaskQueue{}, signedBy("FINGERPRINT"), nil).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "../task", Timestamp: time.Now()}))
+		requireHTTPError(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		tq := &mockTaskQueue{
+			cancelPipelineFn: func(taskUUID string) error {
+				t.Fatal("an unsigned request must not cancel a pipeline")
+				return nil
+			},
+		}
+		gpg := &mockGPGVerifier{
+			verifySignedMessageFn: func(data []byte) ([]byte, string, error) {
+				return nil, "", errors.New("bad signature")
+			},
+		}
+		_, err := NewCancelService(tq, gpg, nil).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+		requireHTTPError(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("expired request", func(t *testing.T) {
+		data, err := json.Marshal(domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now().Add(-time.Hour)})
+		require.NoError(t, err)
+		_, err = NewCancelService(&mockTaskQueue{}, signedBy("FINGERPRINT"), nil).CancelPipeline(data)
+		requireHTTPError(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		js := &mockJobStore{
+			getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+				return nil, errors.New("job not found")
+			},
+		}
+		_, err := NewCancelService(&mockTaskQueue{}, signedBy("FINGERPRINT"), js).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+		requireHTTPError(t, err, http.StatusNotFound)
+	})
+
+	t.Run("finished job", func(t *testing.T) {
+		tq := &mockTaskQueue{
+			cancelPipelineFn: func(taskUUID string) error {
+				t.Fatal("a finished pipeline must not be cancelled")
+				return nil
+			},
+		}
+		js := &mockJobStore{
+			getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+				return &monitoring.JobInfo{TaskUUID: taskUUID, State: "DONE"}, nil
+			},
+		}
+		_, err := NewCancelService(tq, signedBy("FINGERPRINT"), js).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+		httpErr := requireHTTPError(t, err, http.StatusConflict)
+		assert.Equal(t, "pipeline already finished as DONE", httpErr.Message)
+	})
+
+	t.Run("queue failure", func(t *testing.T) {
+		tq := &mockTaskQueue{
+			cancelPipelineFn: func(taskUUID string) error {
+				return errors.New("redis unavailable")
+			},
+		}
+		_, err := NewCancelService(tq, signedBy("FINGERPRINT"), nil).CancelPipeline(signedRequest(t, domain.CancelRequest{PipelineID: "task-1", Timestamp: time.Now()}))
+		requireHTTPError(t, err, http.StatusInternalServerError)
+	})
+}
+
+func TestStatusService_BuildStatus_Cancelled(t *testing.T) {
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID, State: domain.StateCancelled, BuildState: "FAILURE"}, nil
+		},
+	}
+
+	resp, err := NewStatusService(&mockTaskQueue{}, js, nil).BuildStatus("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StateCancelled, resp.State)
+	assert.Equal(t, domain.StateCancelled, resp.JobStatus)
+	assert.Equal(t, "FAILURE", resp.BuildStatus)
+}
@@ -3,11 +3,14 @@ package usecase
 import (
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
 	chiefrepository "github.com/blankon/irgsh-go/internal/chief/repository"
 	"github.com/blankon/irgsh-go/internal/config"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
 )
 
 type ChiefUsecase struct {
@@ -21,6 +24,17 @@ type ChiefUsecase struct {
 	uploadSvc          *UploadService
 	statusSvc          *StatusService
 	submissionSvc      *SubmissionService
+	batchSvc           *BatchService
+	promotionSvc       *PromotionService
+	removalSvc         *RemovalService
+	rebuildSvc         *RebuildService
+	reproSvc           *ReproService
+	lintianSvc         *LintianService
+	eventSvc           *EventService
+	durationSvc        *DurationService
+	snapshotSvc        *SnapshotService
+	cancelSvc          *CancelService
+	workerAuthSvc      *WorkerAuthService
 	dashboardSvc       *DashboardService
 }
 
@@ -30,13 +44,19 @@ func NewChiefUsecase(
 	registry *monitoring.Registry,
 	storage *chiefrepository.Storage,
 	gpg *chiefrepository.GPG,
+	repo *chiefrepository.RepoClient,
+	workers *storage.WorkerStore,
+	batches *storage.BatchStore,
 	version string,
 ) (*ChiefUsecase, error) {
 	maintainerSvc := NewMaintainerService(gpg)
-	dashSvc, err := newDashboardSvc(version, taskQueue, maintainerSvc, registry)
+	suites := configuredSuites(cfg)
+	dashSvc, err := newDashboardSvc(version, maintainerSvc, registry, workers, batches)
 	if err != nil {
 		return nil, fmt.Errorf("init dashboard service: %w", err)
 	}
+	statusSvc := newStatusSvc(taskQueue, registry, suites[0].Architectures)
+	submissionSvc := newSubmissionSvc(taskQueue, storage, gpg, registry, suites)
 	return &ChiefUsecase{
 		config:             cfg,
 		taskQueue:          taskQueue,
@@ -46,34 +66,162 @@ func NewChiefUsecase(
 		version:            version,
 		maintainerSvc:      maintainerSvc,
 		uploadSvc:          NewUploadService(storage, gpg),
-		statusSvc:          NewStatusService(taskQueue),
-		submissionSvc:      newSubmissionSvc(taskQueue, storage, gpg, registry),
+		statusSvc:          statusSvc,
+		submissionSvc:      submissionSvc,
+		batchSvc:           newBatchSvc(submissionSvc, statusSvc, batches),
+		promotionSvc:       newPromotionSvc(taskQueue, gpg, registry, suites),
+		removalSvc:         newRemovalSvc(taskQueue, gpg, registry, suites),
+		rebuildSvc:         NewRebuildService(submissionSvc, gpg, repo),
+		reproSvc:           newReproSvc(registry, storage),
+		lintianSvc:         newLintianSvc(registry, storage),
+		eventSvc:           newEventSvc(registry, taskQueue),
+		durationSvc:        newDurationSvc(registry),
+		snapshotSvc:        newSnapshotSvc(taskQueue, gpg, registry, repo, suites),
+		cancelSvc:          newCancelSvc(taskQueue, gpg, registry),
+		workerAuthSvc:      newWorkerAuthSvc(cfg.Chief, workers),
 		dashboardSvc:       dashSvc,
 	}, nil
 }
 
+// configuredSuites returns the suites of the repository config, in order.
+func configuredSuites(cfg config.IrgshConfig) []domain.Suite {
+	var suites []domain.Suite
+	for _, dist := range cfg.Repo.Suites() {
+		upstream := dist.UpstreamDistCodename
+		if upstream == "" {
+			upstream = cfg.Builder.UpstreamDistCodename
+		}
+		suites = append(suites, domain.Suite{
+			Codename:         dist.Codename,
+			UpstreamCodename: upstream,
+			Architectures:    domain.BuildArchitectures(dist.SupportedArchitectures),
+			Components:       strings.Fields(dist.Components),
+			LintianFailTags:  cfg.Chief.LintianFailTags,
+		})
+	}
+	return suites
+}
+
 // newSubmissionSvc constructs a SubmissionService, avoiding a non-nil
 // interface wrapping a nil *Registry pointer.
-func newSubmissionSvc(tq TaskQueue, st FileStorage, gpg GPGVerifier, reg *monitoring.Registry) *SubmissionService {
+func newSubmissionSvc(tq TaskQueue, st FileStorage, gpg GPGVerifier, reg *monitoring.Registry, suites []domain.Suite) *SubmissionService {
 	var js JobStore
 	var is ISOJobStore
+	var ir InstanceRegistry
 	if reg != nil {
 		js = reg
 		is = reg
+		ir = reg
+	}
+	return NewSubmissionService(tq, st, gpg, js, is, ir, suites)
+}
+
+func newBatchSvc(ss *SubmissionService, st *StatusService, store *storage.BatchStore) *BatchService {
+	var bs BatchStore
+	if store != nil {
+		bs = store
+	}
+	return NewBatchService(ss, st, bs)
+}
+
+func newReproSvc(reg *monitoring.Registry, st FileStorage) *ReproService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewReproService(js, st)
+}
+
+func newLintianSvc(reg *monitoring.Registry, st FileStorage) *LintianService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewLintianService(js, st)
+}
+
+func newEventSvc(reg *monitoring.Registry, tq TaskQueue) *EventService {
+	var js JobStore
+	if reg != nil {
+		js = reg
 	}
-	return NewSubmissionService(tq, st, gpg, js, is)
+	return NewEventService(js, tq)
 }
 
-func newDashboardSvc(version string, tq TaskQueue, ms *MaintainerService, reg *monitoring.Registry) (*DashboardService, error) {
+func newDurationSvc(reg *monitoring.Registry) *DurationService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewDurationService(js)
+}
+
+func newPromotionSvc(tq TaskQueue, gpg GPGVerifier, reg *monitoring.Registry, suites []domain.Suite) *PromotionService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewPromotionService(tq, gpg, js, suites)
+}
+
+func newRemovalSvc(tq TaskQueue, gpg GPGVerifier, reg *monitoring.Registry, suites []domain.Suite) *RemovalService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewRemovalService(tq, gpg, js, suites)
+}
+
+func newSnapshotSvc(tq TaskQueue, gpg GPGVerifier, reg *monitoring.Registry, repo SnapshotLister, suites []domain.Suite) *SnapshotService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewSnapshotService(tq, gpg, js, repo, suites)
+}
+
+func newCancelSvc(tq TaskQueue, gpg GPGVerifier, reg *monitoring.Registry) *CancelService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewCancelService(tq, gpg, js)
+}
+
+func newStatusSvc(tq TaskQueue, reg *monitoring.Registry, archs []string) *StatusService {
+	var js JobStore
+	if reg != nil {
+		js = reg
+	}
+	return NewStatusService(tq, js, archs)
+}
+
+func newWorkerAuthSvc(chief config.ChiefConfig, store *storage.WorkerStore) *WorkerAuthService {
+	var ws WorkerStore
+	if store != nil {
+		ws = store
+	}
+	return NewWorkerAuthService(chief.Workers, ws, time.Duration(chief.ClaimTimeout)*time.Second)
+}
+
+func newDashboardSvc(version string, ms *MaintainerService, reg *monitoring.Registry, workers *storage.WorkerStore, batches *storage.BatchStore) (*DashboardService, error) {
 	var ir InstanceRegistry
 	var js JobStore
 	var is ISOJobStore
+	var ws WorkerStore
+	var bs BatchStore
 	if reg != nil {
 		ir = reg
 		js = reg
 		is = reg
 	}
-	return NewDashboardService(version, tq, ms, ir, js, is)
+	if workers != nil {
+		ws = workers
+	}
+	if batches != nil {
+		bs = batches
+	}
+	return NewDashboardService(version, ms, ir, js, is, ws, bs)
 }
 
 // GetVersion returns the version string for use by handlers.
@@ -93,6 +241,52 @@ func (s *ChiefUsecase) SubmitPackage(submission domain.Submission) (domain.Submi
 	return s.submissionSvc.SubmitPackage(submission)
 }
 
+func (s *ChiefUsecase) SubmitBatch(batch domain.BatchSubmission) (domain.BatchResponse, error) {
+	return s.batchSvc.SubmitBatch(batch)
+}
+
+// ScheduleBatches queues the packages of the running batches whose
+// dependencies have been built.
+func (s *ChiefUsecase) ScheduleBatches() {
+	s.batchSvc.ScheduleBatches()
+}
+
+// ReconcileJobs catches the unfinished jobs up with the outcome of the
+// tasks their workers did not report.
+func (s *ChiefUsecase) ReconcileJobs() {
+	s.eventSvc.ReconcileJobs()
+}
+
+// CheckReproducibility records the verdict of the reproducibility checks
+// whose builds are over.
+func (s *ChiefUsecase) CheckReproducibility() {
+	s.reproSvc.CheckReproducibility()
+}
+
+func (s *ChiefUsecase) PromotePackage(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.promotionSvc.PromotePackage(signedRequest)
+}
+
+func (s *ChiefUsecase) RemovePackage(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.removalSvc.RemovePackage(signedRequest)
+}
+
+func (s *ChiefUsecase) RebuildPackage(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.rebuildSvc.RebuildPackage(signedRequest)
+}
+
+func (s *ChiefUsecase) ListSnapshots() ([]domain.Snapshot, error) {
+	return s.snapshotSvc.ListSnapshots()
+}
+
+func (s *ChiefUsecase) CreateSnapshot(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.snapshotSvc.CreateSnapshot(signedRequest)
+}
+
+func (s *ChiefUsecase) RestoreSnapshot(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.snapshotSvc.RestoreSnapshot(signedRequest)
+}
+
 func (s *ChiefUsecase) BuildStatus(UUID string) (domain.BuildStatusResponse, error) {
 	return s.statusSvc.BuildStatus(UUID)
 }
@@ -105,12 +299,48 @@ func (s *ChiefUsecase) RetryPipeline(oldTaskUUID string) (domain.SubmitPayloadRe
 	return s.submissionSvc.RetryPipeline(oldTaskUUID)
 }
 
-func (s *ChiefUsecase) UploadArtifact(id string, file io.Reader) error {
-	return s.uploadSvc.UploadArtifact(id, file)
+func (s *ChiefUsecase) CancelPipeline(signedRequest []byte) (domain.SubmitPayloadResponse, error) {
+	return s.cancelSvc.CancelPipeline(signedRequest)
+}
+
+func (s *ChiefUsecase) JobEvents(UUID string) (domain.JobEventsResponse, error) {
+	return s.eventSvc.JobEvents(UUID)
+}
+
+func (s *ChiefUsecase) RecordTaskEvent(UUID string, event domain.TaskEvent) error {
+	return s.eventSvc.RecordTaskEvent(UUID, event)
 }
 
+func (s *ChiefUsecase) BuildDurations(packageName string) (domain.BuildDurationsResponse, error) {
+	return s.durationSvc.BuildDurations(packageName)
+}
+
+func (s *ChiefUsecase) UploadArtifact(id string, file io.Reader, checksum string) error {
+	return s.uploadSvc.UploadArtifact(id, file, checksum)
+}
+
+func (s *ChiefUsecase) ClaimTask(token, id, kind, remoteAddr string) error {
+	return s.workerAuthSvc.ClaimTask(token, id, kind, remoteAddr)
+}
+
+func (s *ChiefUsecase) AuthorizeUpload(token, id, kind, remoteAddr string) error {
+	return s.workerAuthSvc.AuthorizeUpload(token, id, kind, remoteAddr)
+}
+
+func (s *ChiefUsecase) AuthorizeEvent(token string, event domain.TaskEvent, remoteAddr string) error {
+	return s.workerAuthSvc.AuthorizeEvent(token, event, remoteAddr)
+}
+
+// UploadLog stores the log of a task. The tags of a lintian log are also
+// recorded on the job of the pipeline.
 func (s *ChiefUsecase) UploadLog(id string, logType string, file io.Reader) error {
-	return s.uploadSvc.UploadLog(id, logType, file)
+	if err := s.uploadSvc.UploadLog(id, logType, file); err != nil {
+		return err
+	}
+	if logType == "lintian" {
+		s.lintianSvc.RecordLintianLog(id)
+	}
+	return nil
 }
 
 func (s *ChiefUsecase) BuildISO(submission domain.ISOSubmission) (domain.SubmitPayloadResponse, error) {
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
@@ -27,6 +28,8 @@ type DashboardData struct {
 	Workers       []WorkerView
 	Jobs          []JobView
 	ISOJobs       []ISOJobView
+	Batches       []BatchView
+	Rejections    []RejectionView
 }
 
 type SummaryView struct {
@@ -50,9 +53,13 @@ type WorkerView struct {
 	Uptime      string
 	ActiveTasks int
 	Concurrency int
+	Arch        string
+	Dist        string
+	Labels      string
 	CPU         string
 	Memory      string
 	Disk        string
+	AptCache    string
 }
 
 type RepoLink struct {
@@ -60,24 +67,45 @@ type RepoLink struct {
 	Label string
 }
 
+type ArchBuildView struct {
+	Architecture  string
+	StageClass    string
+	StateText     string
+	LogURL        string
+	LintianLogURL string // Empty until lintian checked the build
+}
+
 type JobView struct {
-	FilterStatus   string
-	TimeFormatted  string
-	TimeRelative   string
-	PackageName    string
-	PackageVersion string
-	Maintainer     string
-	Component      string
-	IsExperimental bool
-	RepoLinks      []RepoLink
+	FilterStatus    string
+	TimeFormatted   string
+	TimeRelative    string
+	PackageName     string
+	PackageVersion  string
+	Maintainer      string
+	Component       string
+	Suite           string
+	JobType         string
+	IsExperimental  bool
+	RepoLinks       []RepoLink
+	ArchBuilds      []ArchBuildView
+	ArchTests       []ArchBuildView // Empty when the pipeline runs no tests
 	BuildStageClass string
 	BuildStateText  string
+	LintianText     string // Tag counts, empty until lintian checked a build
+	LintianClass    string
+	RunTests        bool
+	TestStageClass  string
+	TestStateText   string
 	RepoStageClass  string
 	RepoStateText   string
-	StatusClass    string
-	StatusText     string
-	ShowSpinner    bool
-	TaskUUID       string
+	DurationText    string // Time the workers spent on the job, empty until one started
+	StatusClass     string
+	StatusText      string
+	ShowSpinner     bool
+	ReproText       string // Reproducibility badge, empty without a check
+	ReproClass      string
+	ReproDetail     string
+	TaskUUID        string
 }
 
 type ISOJobView struct {
@@ -90,24 +118,56 @@ type ISOJobView struct {
 	TaskUUID      string
 }
 
+type BatchView struct {
+	TimeFormatted string
+	TimeRelative  string
+	BatchID       string
+	Maintainer    string
+	State         string
+	StatusClass   string
+	Progress      string
+	Packages      []BatchPackageView
+}
+
+type BatchPackageView struct {
+	PackageName    string
+	PackageVersion string
+	State          string
+	StageClass     string
+	DependsOn      string
+	TaskUUID       string
+}
+
+type RejectionView struct {
+	TimeFormatted string
+	TimeRelative  string
+	Worker        string
+	RemoteAddr    string
+	Kind          string
+	TaskID        string
+	Reason        string
+}
+
 // DashboardService renders the chief dashboard HTML.
 type DashboardService struct {
 	version       string
-	taskQueue     TaskQueue
 	maintainerSvc *MaintainerService
 	registry      InstanceRegistry
 	jobStore      JobStore
 	isoStore      ISOJobStore
+	workerStore   WorkerStore
+	batchStore    BatchStore
 	tmpl          *template.Template
 }
 
 func NewDashboardService(
 	version string,
-	taskQueue TaskQueue,
 	maintainerSvc *MaintainerService,
 	registry InstanceRegistry,
 	jobStore JobStore,
 	isoStore ISOJobStore,
+	workerStore WorkerStore,
+	batchStore BatchStore,
 ) (*DashboardService, error) {
 	tmpl, err := template.New("dashboard").Parse(dashboardTmplStr)
 	if err != nil {
@@ -115,11 +175,12 @@ func NewDashboardService(
 	}
 	return &DashboardService{
 		version:       version,
-		taskQueue:     taskQueue,
 		maintainerSvc: maintainerSvc,
 		registry:      registry,
 		jobStore:      jobStore,
 		isoStore:      isoStore,
+		workerStore:   workerStore,
+		batchStore:    batchStore,
 		tmpl:          tmpl,
 	}, nil
 }
@@ -133,6 +194,8 @@ func (d *DashboardService) buildDashboardData() DashboardData {
 	data := DashboardData{
 		Version:     d.version,
 		Maintainers: d.maintainerSvc.GetMaintainers(),
+		Batches:     d.buildBatchViews(),
+		Rejections:  d.buildRejectionViews(),
 	}
 
 	if d.registry == nil {
@@ -198,6 +261,15 @@ func buildWorkerViews(instances []*monitoring.InstanceInfo) []WorkerView {
 			diskStr += " / " + monitoring.FormatBytes(inst.DiskTotal)
 		}
 
+		aptCacheStr := ""
+		if c := inst.AptCache; c != nil {
+			aptCacheStr = monitoring.FormatBytes(c.Size)
+			if c.Limit > 0 {
+				aptCacheStr += " / " + monitoring.FormatBytes(c.Limit)
+			}
+			aptCacheStr += fmt.Sprintf(", %d packages, %.0f%% hits", c.Packages, c.HitRate()*100)
+		}
+
 		views = append(views, WorkerView{
 			Type:        string(inst.InstanceType),
 			BadgeClass:  badgeClass,
@@ -207,9 +279,13 @@ func buildWorkerViews(instances []*monitoring.InstanceInfo) []WorkerView {
 			Uptime:      formatDuration(time.Since(inst.StartTime)),
 			ActiveTasks: inst.ActiveTasks,
 			Concurrency: inst.Concurrency,
+			Arch:        strings.Join(inst.Capabilities.Architectures, " "),
+			Dist:        inst.Capabilities.DistCodename,
+			Labels:      strings.Join(inst.Capabilities.Labels, " "),
 			CPU:         fmt.Sprintf("%.1f", inst.CPUUsage),
 			Memory:      memStr,
 			Disk:        diskStr,
+			AptCache:    aptCacheStr,
 		})
 	}
 	return views
@@ -228,8 +304,6 @@ func (d *DashboardService) buildJobViews() []JobView {
 		return nil
 	}
 
-	d.resolveJobStates(jobs)
-
 	jakartaLoc, locErr := time.LoadLocation("Asia/Jakarta")
 	if locErr != nil {
 		jakartaLoc = time.UTC
@@ -237,48 +311,35 @@ func (d *DashboardService) buildJobViews() []JobView {
 
 	views := make([]JobView, 0, len(jobs))
 	for _, job := range jobs {
-		views = append(views, buildJobView(job, jakartaLoc))
+		view := buildJobView(job, jakartaLoc)
+		if job.IsBuild() {
+			addLintianView(&view, lintianSummary(d.jobStore, job.TaskUUID))
+		}
+		if timings := jobStageTimings(d.jobStore, job.TaskUUID, time.Now()); len(timings) > 0 {
+			view.DurationText = formatDuration(jobDuration(timings))
+		}
+		views = append(views, view)
 	}
 	return views
 }
 
-func (d *DashboardService) resolveJobStates(jobs []*storage.JobInfo) {
-	for _, job := range jobs {
-		if storage.IsTerminalState(job.State) || job.State == "UNKNOWN" {
-			continue
-		}
-
-		buildState := d.taskQueue.GetTaskState("build", job.TaskUUID)
-		repoState := d.taskQueue.GetTaskState("repo", job.TaskUUID)
-
-		// If machinery returns empty for both, data has expired
-		if buildState == "" && repoState == "" {
-			continue
-		}
-
-		currentStage := domain.DeriveCurrentStage(buildState, repoState)
-		job.BuildState = buildState
-		job.RepoState = repoState
-		job.CurrentStage = currentStage
-
-		var overallState string
-		switch {
-		case buildState == "FAILURE":
-			overallState = "FAILED"
-		case buildState == "SUCCESS" && repoState == "SUCCESS":
-			overallState = "DONE"
-		case buildState == "SUCCESS" && repoState == "FAILURE":
-			overallState = "FAILED"
-		default:
-			overallState = "PENDING"
-		}
-
-		job.State = overallState
-
-		if storage.IsTerminalState(overallState) {
-			d.jobStore.UpdateJobStages(job.TaskUUID, buildState, repoState, currentStage)
-			d.jobStore.UpdateJobState(job.TaskUUID, overallState)
-		}
+// addLintianView shows the tags lintian reported on the builds of a job,
+// along with the links to its logs.
+func addLintianView(view *JobView, summary *domain.LintianSummary) {
+	if summary == nil {
+		return
+	}
+	view.LintianText = fmt.Sprintf("lintian: %d E, %d W, %d I", summary.Errors, summary.Warnings, summary.Info)
+	switch {
+	case summary.Errors > 0:
+		view.LintianClass = "status-offline"
+	case summary.Warnings > 0:
+		view.LintianClass = "status-warning"
+	default:
+		view.LintianClass = "status-online"
+	}
+	for i, build := range view.ArchBuilds {
+		view.ArchBuilds[i].LintianLogURL = "/logs/" + domain.ArchTaskUUID(view.TaskUUID, build.Architecture) + ".lintian.log"
 	}
 }
 
@@ -295,6 +356,8 @@ func buildJobView(job *storage.JobInfo, loc *time.Location) JobView {
 		statusClass = "status-offline"
 		if job.BuildState == "FAILURE" {
 			statusText = "FAILED (build)"
+		} else if job.TestState == "FAILURE" {
+			statusText = "FAILED (test)"
 		} else if job.RepoState == "FAILURE" {
 			statusText = "FAILED (repo)"
 		}
@@ -309,6 +372,10 @@ func buildJobView(job *storage.JobInfo, loc *time.Location) JobView {
 	case "UNKNOWN":
 		statusClass = "status-offline"
 		statusText = "UNKNOWN"
+	case "CANCELLED":
+		statusClass = "status-offline"
+	case "TIMEOUT":
+		statusClass = "status-offline"
 	default:
 		showSpinner = true
 		filterStatus = "PENDING"
@@ -322,6 +389,40 @@ func buildJobView(job *storage.JobInfo, loc *time.Location) JobView {
 	if repoStateText == "" {
 		repoStateText = "-"
 	}
+	testStateText := job.TestState
+	if testStateText == "" {
+		testStateText = "-"
+	}
+	var archBuilds []ArchBuildView
+	for _, arch := range job.Architectures {
+		state := job.ArchBuildStates[arch]
+		stateText := state
+		if stateText == "" {
+			stateText = "-"
+		}
+		archBuilds = append(archBuilds, ArchBuildView{
+			Architecture: arch,
+			StageClass:   stageClass(state),
+			StateText:    stateText,
+			LogURL:       "/logs/" + domain.ArchTaskUUID(job.TaskUUID, arch) + ".build.log",
+		})
+	}
+	var archTests []ArchBuildView
+	if job.RunTests {
+		for _, arch := range job.Architectures {
+			state := job.ArchTestStates[arch]
+			stateText := state
+			if stateText == "" {
+				stateText = "-"
+			}
+			archTests = append(archTests, ArchBuildView{
+				Architecture: arch,
+				StageClass:   stageClass(state),
+				StateText:    stateText,
+				LogURL:       "/logs/" + domain.ArchTaskUUID(job.TaskUUID, arch) + ".test.log",
+			})
+		}
+	}
 
 	var repoLinks []RepoLink
 	if job.SourceURL != "" {
@@ -345,6 +446,13 @@ func buildJobView(job *storage.JobInfo, loc *time.Location) JobView {
 		})
 	}
 
+	jobType := job.JobType
+	if jobType == "" {
+		jobType = storage.JobTypeBuild
+	}
+
+	reproText, reproClass := reproBadge(job.Reproducibility)
+
 	jakartaTime := job.SubmittedAt.In(loc)
 
 	return JobView{
@@ -355,19 +463,46 @@ func buildJobView(job *storage.JobInfo, loc *time.Location) JobView {
 		PackageVersion:  job.PackageVersion,
 		Maintainer:      job.Maintainer,
 		Component:       job.Component,
+		Suite:           job.Suite,
+		JobType:         jobType,
 		IsExperimental:  job.IsExperimental,
 		RepoLinks:       repoLinks,
+		ArchBuilds:      archBuilds,
+		ArchTests:       archTests,
 		BuildStageClass: stageClass(job.BuildState),
 		BuildStateText:  buildStateText,
+		RunTests:        job.RunTests,
+		TestStageClass:  stageClass(job.TestState),
+		TestStateText:   testStateText,
 		RepoStageClass:  stageClass(job.RepoState),
 		RepoStateText:   repoStateText,
 		StatusClass:     statusClass,
 		StatusText:      statusText,
 		ShowSpinner:     showSpinner,
+		ReproText:       reproText,
+		ReproClass:      reproClass,
+		ReproDetail:     job.ReproducibilityDetail,
 		TaskUUID:        job.TaskUUID,
 	}
 }
 
+// reproBadge returns the text and class of the badge showing a
+// reproducibility verdict.
+func reproBadge(verdict string) (string, string) {
+	switch verdict {
+	case "":
+		return "", ""
+	case domain.ReproReproducible:
+		return "reproducible", "badge-reproducible"
+	case domain.ReproUnreproducible:
+		return "not reproducible", "badge-unreproducible"
+	case domain.ReproPending:
+		return "checking reproducibility", "badge-repro-neutral"
+	default:
+		return "reproducibility unverified", "badge-repro-neutral"
+	}
+}
+
 func (d *DashboardService) buildISOJobViews() []ISOJobView {
 	if d.isoStore == nil {
 		return nil
@@ -412,11 +547,114 @@ func (d *DashboardService) buildISOJobViews() []ISOJobView {
 	return views
 }
 
+// buildBatchViews lists the latest batches with the state of every package.
+// The batch scheduler keeps the states up to date.
+func (d *DashboardService) buildBatchViews() []BatchView {
+	if d.batchStore == nil {
+		return nil
+	}
+	batches, err := d.batchStore.GetRecentBatches(10)
+	if err != nil {
+		log.Printf("Failed to list batches: %v\n", err)
+		return nil
+	}
+
+	jakartaLoc, locErr := time.LoadLocation("Asia/Jakarta")
+	if locErr != nil {
+		jakartaLoc = time.UTC
+	}
+
+	views := make([]BatchView, 0, len(batches))
+	for _, b := range batches {
+		view := BatchView{
+			TimeFormatted: b.SubmittedAt.In(jakartaLoc).Format("2006-01-02 15:04:05 MST"),
+			TimeRelative:  formatRelativeTime(b.SubmittedAt),
+			BatchID:       b.BatchID,
+			Maintainer:    b.Maintainer,
+			State:         b.State,
+			StatusClass:   batchStateClass(b.State),
+		}
+		names := make(map[int]string, len(b.Items))
+		for _, item := range b.Items {
+			names[item.Position] = item.PackageName
+		}
+		done := 0
+		for _, item := range b.Items {
+			if item.State == domain.StateDone {
+				done++
+			}
+			var deps []string
+			for _, p := range item.DependsOn {
+				deps = append(deps, names[p])
+			}
+			view.Packages = append(view.Packages, BatchPackageView{
+				PackageName:    item.PackageName,
+				PackageVersion: item.PackageVersion,
+				State:          item.State,
+				StageClass:     batchStateClass(item.State),
+				DependsOn:      strings.Join(deps, ", "),
+				TaskUUID:       item.TaskUUID,
+			})
+		}
+		view.Progress = fmt.Sprintf("%d/%d built", done, len(b.Items))
+		views = append(views, view)
+	}
+	return views
+}
+
+func batchStateClass(state string) string {
+	switch state {
+	case domain.StateDone:
+		return "status-online"
+	case domain.StateFailed, domain.StateTimeout, domain.StateCancelled, domain.StateSkipped:
+		return "status-offline"
+	case domain.StateWaiting:
+		return ""
+	default:
+		return "status-warning"
+	}
+}
+
+// buildRejectionViews lists the latest worker requests chief turned down.
+func (d *DashboardService) buildRejectionViews() []RejectionView {
+	if d.workerStore == nil {
+		return nil
+	}
+	rejections, err := d.workerStore.GetRecentRejections(20)
+	if err != nil {
+		log.Printf("Failed to list rejected worker requests: %v\n", err)
+		return nil
+	}
+
+	jakartaLoc, locErr := time.LoadLocation("Asia/Jakarta")
+	if locErr != nil {
+		jakartaLoc = time.UTC
+	}
+
+	views := make([]RejectionView, 0, len(rejections))
+	for _, r := range rejections {
+		worker := r.Worker
+		if worker == "" {
+			worker = "-"
+		}
+		views = append(views, RejectionView{
+			TimeFormatted: r.RejectedAt.In(jakartaLoc).Format("2006-01-02 15:04:05 MST"),
+			TimeRelative:  formatRelativeTime(r.RejectedAt),
+			Worker:        worker,
+			RemoteAddr:    r.RemoteAddr,
+			Kind:          r.Kind,
+			TaskID:        r.TaskID,
+			Reason:        r.Reason,
+		})
+	}
+	return views
+}
+
 func stageClass(state string) string {
 	switch state {
 	case "SUCCESS":
 		return "status-online"
-	case "FAILURE":
+	case "FAILURE", "TIMEOUT":
 		return "status-offline"
 	case "STARTED", "RECEIVED":
 		return "status-warning"
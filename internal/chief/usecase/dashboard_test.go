@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/blankon/irgsh-go/internal/chief/domain"
 	"github.com/blankon/irgsh-go/internal/monitoring"
 	"github.com/blankon/irgsh-go/internal/storage"
 	"github.com/stretchr/testify/assert"
@@ -50,6 +51,13 @@ func TestBuildWorkerViews(t *testing.T) {
 			MemoryTotal:  1024 * 1024 * 1024 * 4,
 			DiskUsage:    1024 * 1024 * 1024 * 10,
 			DiskTotal:    1024 * 1024 * 1024 * 100,
+			AptCache: &monitoring.AptCacheStats{
+				Packages:  120,
+				Size:      1024 * 1024 * 1024,
+				Limit:     1024 * 1024 * 1024 * 4,
+				HitBytes:  750,
+				MissBytes: 250,
+			},
 		},
 		{
 			InstanceType: monitoring.InstanceTypeRepo,
@@ -75,10 +83,12 @@ func TestBuildWorkerViews(t *testing.T) {
 	assert.Equal(t, "55.5", views[0].CPU)
 	assert.Equal(t, 1, views[0].ActiveTasks)
 	assert.Equal(t, 4, views[0].Concurrency)
+	assert.Equal(t, "1.0 GB / 4.0 GB, 120 packages, 75% hits", views[0].AptCache)
 
 	// repo
 	assert.Equal(t, "badge-repo", views[1].BadgeClass)
 	assert.Equal(t, "status-offline", views[1].StatusClass)
+	assert.Empty(t, views[1].AptCache)
 
 	// iso
 	assert.Equal(t, "badge-iso", views[2].BadgeClass)
@@ -196,6 +206,40 @@ func TestBuildJobView(t *testing.T) {
 		assert.Contains(t, v.RepoLinks[0].Label, "default")
 		assert.Contains(t, v.RepoLinks[1].Label, "default")
 	})
+
+	t.Run("reproducibility badge", func(t *testing.T) {
+		v := buildJobView(&storage.JobInfo{State: "DONE", SubmittedAt: now}, loc)
+		assert.Empty(t, v.ReproText)
+
+		job := &storage.JobInfo{
+			State:                 "DONE",
+			SubmittedAt:           now,
+			Reproducibility:       domain.ReproUnreproducible,
+			ReproducibilityDetail: "amd64: pkg_1.0_amd64.deb",
+		}
+		v = buildJobView(job, loc)
+		assert.Equal(t, "not reproducible", v.ReproText)
+		assert.Equal(t, "badge-unreproducible", v.ReproClass)
+		assert.Equal(t, "amd64: pkg_1.0_amd64.deb", v.ReproDetail)
+	})
+}
+
+func TestAddLintianView(t *testing.T) {
+	job := &storage.JobInfo{TaskUUID: "uuid", State: "DONE", SubmittedAt: time.Now(), Architectures: []string{"amd64", "arm64"}}
+
+	v := buildJobView(job, time.UTC)
+	addLintianView(&v, nil)
+	assert.Empty(t, v.LintianText)
+	assert.Empty(t, v.ArchBuilds[0].LintianLogURL)
+
+	addLintianView(&v, &domain.LintianSummary{Errors: 1, Warnings: 3})
+	assert.Equal(t, "lintian: 1 E, 3 W, 0 I", v.LintianText)
+	assert.Equal(t, "status-offline", v.LintianClass)
+	assert.Equal(t, "/logs/uuid.arm64.lintian.log", v.ArchBuilds[1].LintianLogURL)
+
+	v = buildJobView(job, time.UTC)
+	addLintianView(&v, &domain.LintianSummary{})
+	assert.Equal(t, "status-online", v.LintianClass)
 }
 
 func TestStageClass(t *testing.T) {
@@ -228,65 +272,6 @@ func TestFormatRelativeTime(t *testing.T) {
 	assert.Equal(t, "7 days ago", formatRelativeTime(now.Add(-7*24*time.Hour)))
 }
 
-func TestResolveJobStates(t *testing.T) {
-	tq := &mockTaskQueue{
-		getTaskStateFn: func(taskName, taskUUID string) string {
-			states := map[string]map[string]string{
-				"active-job": {"build": "SUCCESS", "repo": "SUCCESS"},
-				"stale-job":  {"build": "", "repo": ""},
-			}
-			if m, ok := states[taskUUID]; ok {
-				return m[taskName]
-			}
-			return ""
-		},
-	}
-
-	var updatedStages []string
-	var updatedStates []string
-	js := &mockJobStore{
-		updateJobStagesFn: func(taskUUID, buildState, repoState, currentStage string) error {
-			updatedStages = append(updatedStages, taskUUID)
-			return nil
-		},
-		updateJobStateFn: func(taskUUID, state string) error {
-			updatedStates = append(updatedStates, taskUUID+":"+state)
-			return nil
-		},
-	}
-
-	ds := &DashboardService{
-		taskQueue: tq,
-		jobStore:  js,
-	}
-
-	jobs := []*storage.JobInfo{
-		{TaskUUID: "done-job", State: "DONE"},         // terminal, skip
-		{TaskUUID: "unknown-job", State: "UNKNOWN"},    // UNKNOWN, skip
-		{TaskUUID: "active-job", State: "PENDING"},     // should resolve to DONE
-		{TaskUUID: "stale-job", State: "PENDING"},      // both empty, skip
-	}
-
-	ds.resolveJobStates(jobs)
-
-	// done-job and unknown-job should be unchanged
-	assert.Equal(t, "DONE", jobs[0].State)
-	assert.Equal(t, "UNKNOWN", jobs[1].State)
-
-	// active-job should be resolved to DONE
-	assert.Equal(t, "DONE", jobs[2].State)
-	assert.Equal(t, "SUCCESS", jobs[2].BuildState)
-	assert.Equal(t, "SUCCESS", jobs[2].RepoState)
-	assert.Equal(t, "completed", jobs[2].CurrentStage)
-
-	// stale-job stays PENDING (both empty)
-	assert.Equal(t, "PENDING", jobs[3].State)
-
-	// Only active-job should have been persisted
-	assert.Equal(t, []string{"active-job"}, updatedStages)
-	assert.Equal(t, []string{"active-job:DONE"}, updatedStates)
-}
-
 func TestDashboardService_RenderIndexHTML(t *testing.T) {
 	gpg := &mockGPGVerifier{
 		listKeysWithColonsFn: func() (string, error) {
@@ -295,7 +280,7 @@ func TestDashboardService_RenderIndexHTML(t *testing.T) {
 	}
 	maintainerSvc := NewMaintainerService(gpg)
 
-	ds, err := NewDashboardService("1.0.0", &mockTaskQueue{}, maintainerSvc, nil, nil, nil)
+	ds, err := NewDashboardService("1.0.0", maintainerSvc, nil, nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	var buf bytes.Buffer
@@ -310,6 +295,35 @@ func TestDashboardService_BuildJobViews_NilJobStore(t *testing.T) {
 	assert.Nil(t, views)
 }
 
+func TestDashboardService_BuildJobViews_Duration(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour)
+	finishedAt := startedAt.Add(3 * time.Minute)
+	js := &mockJobStore{
+		getRecentJobsFn: func(limit int) ([]*monitoring.JobInfo, error) {
+			return []*monitoring.JobInfo{
+				{TaskUUID: "done-uuid", PackageName: "hello", State: "DONE", SubmittedAt: startedAt},
+				{TaskUUID: "queued-uuid", PackageName: "world", State: "PENDING", SubmittedAt: startedAt},
+			}, nil
+		},
+		getEventsFn: func(taskUUID string) ([]*monitoring.JobEvent, error) {
+			if taskUUID != "done-uuid" {
+				return nil, nil
+			}
+			return []*monitoring.JobEvent{
+				{TaskUUID: taskUUID, TaskID: taskUUID, Stage: "build", Event: domain.EventStarted, OccurredAt: startedAt},
+				{TaskUUID: taskUUID, TaskID: taskUUID, Stage: "build", Event: domain.EventDone, Duration: 120, OccurredAt: startedAt.Add(2 * time.Minute)},
+				{TaskUUID: taskUUID, TaskID: taskUUID, Stage: "repo", Event: domain.EventDone, Duration: 30, OccurredAt: finishedAt},
+			}, nil
+		},
+	}
+
+	ds := &DashboardService{jobStore: js}
+	views := ds.buildJobViews()
+	require.Len(t, views, 2)
+	assert.Equal(t, "3m 0s", views[0].DurationText)
+	assert.Empty(t, views[1].DurationText)
+}
+
 func TestDashboardService_BuildISOJobViews_NilISOStore(t *testing.T) {
 	ds := &DashboardService{isoStore: nil}
 	views := ds.buildISOJobViews()
@@ -337,3 +351,72 @@ func TestDashboardService_BuildISOJobViews(t *testing.T) {
 	assert.Equal(t, "status-warning", views[2].StatusClass)
 	assert.Equal(t, "", views[3].StatusClass)
 }
+
+func TestDashboardService_RenderIndexHTML_Rejections(t *testing.T) {
+	gpg := &mockGPGVerifier{
+		listKeysWithColonsFn: func() (string, error) {
+			return "", nil
+		},
+	}
+	ws := &mockWorkerStore{
+		getRecentRejectionsFn: func(limit int) ([]*storage.UploadRejection, error) {
+			return []*storage.UploadRejection{
+				{TaskID: "task.amd64", Kind: "build", Worker: "builder-2", RemoteAddr: "10.0.0.2:1234", Reason: "task claimed by builder-1", RejectedAt: time.Now()},
+				{TaskID: "task", Kind: "repo", RemoteAddr: "10.0.0.3:1234", Reason: "invalid worker token", RejectedAt: time.Now()},
+			}, nil
+		},
+	}
+
+	// Rejections are shown without monitoring
+	ds, err := NewDashboardService("1.0.0", NewMaintainerService(gpg), nil, nil, nil, ws, nil)
+	require.NoError(t, err)
+
+	views := ds.buildRejectionViews()
+	require.Len(t, views, 2)
+	assert.Equal(t, "builder-2", views[0].Worker)
+	assert.Equal(t, "-", views[1].Worker)
+
+	var buf bytes.Buffer
+	require.NoError(t, ds.RenderIndexHTML(&buf))
+	assert.Contains(t, buf.String(), "Rejected Worker Requests")
+	assert.Contains(t, buf.String(), "task claimed by builder-1")
+	assert.Contains(t, buf.String(), "10.0.0.3:1234")
+}
+
+func TestBuildBatchViews(t *testing.T) {
+	store := &mockBatchStore{}
+	require.NoError(t, store.RecordBatch(storage.Batch{
+		BatchID:     "2024-01-01-120000_batch_1",
+		Maintainer:  "Test User",
+		SubmittedAt: time.Now(),
+		State:       "RUNNING",
+		Items: []storage.BatchItem{
+			{Position: 0, PackageName: "libbar", PackageVersion: "1.0-1", State: "DONE", Queued: true},
+			{Position: 1, PackageName: "libfoo", PackageVersion: "2.0-1", DependsOn: []int{0}, State: "STARTED", Queued: true},
+			{Position: 2, PackageName: "app", PackageVersion: "3.0-1", DependsOn: []int{0, 1}, State: "WAITING"},
+		},
+	}))
+	gpg := &mockGPGVerifier{
+		listKeysWithColonsFn: func() (string, error) {
+			return "", nil
+		},
+	}
+
+	// Batches are shown without monitoring
+	ds, err := NewDashboardService("1.0.0", NewMaintainerService(gpg), nil, nil, nil, nil, store)
+	require.NoError(t, err)
+
+	views := ds.buildBatchViews()
+	require.Len(t, views, 1)
+	assert.Equal(t, "1/3 built", views[0].Progress)
+	assert.Equal(t, "status-warning", views[0].StatusClass)
+	require.Len(t, views[0].Packages, 3)
+	assert.Equal(t, "libbar, libfoo", views[0].Packages[2].DependsOn)
+	assert.Equal(t, "", views[0].Packages[2].StageClass)
+	assert.Equal(t, "status-online", views[0].Packages[0].StageClass)
+
+	var buf bytes.Buffer
+	require.NoError(t, ds.RenderIndexHTML(&buf))
+	assert.Contains(t, buf.String(), "2024-01-01-120000_batch_1")
+	assert.Contains(t, buf.String(), "(after libbar, libfoo)")
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// DurationService reports how long the builds take, from the durations the
+// builders reported on the builds they completed.
+type DurationService struct {
+	jobStore JobStore
+}
+
+func NewDurationService(jobStore JobStore) *DurationService {
+	return &DurationService{jobStore: jobStore}
+}
+
+// BuildDurations returns the p50 and p95 durations of the successful builds,
+// per package and architecture and per builder. An empty packageName covers
+// every package. The builds are those of the jobs still recorded, less the
+// second builds of the reproducibility checks.
+func (ds *DurationService) BuildDurations(packageName string) (domain.BuildDurationsResponse, error) {
+	if ds.jobStore == nil {
+		return domain.BuildDurationsResponse{}, httputil.NewHTTPError(http.StatusServiceUnavailable, "monitoring is not enabled")
+	}
+	durations, err := ds.jobStore.GetStageDurations("build", packageName)
+	if err != nil {
+		log.Printf("Failed to get the build durations: %v\n", err)
+		return domain.BuildDurationsResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "failed to get build durations")
+	}
+
+	byPackage := make(map[durationGroup][]float64)
+	byBuilder := make(map[durationGroup][]float64)
+	for _, d := range durations {
+		pkg := durationGroup{name: d.PackageName, architecture: d.Architecture}
+		byPackage[pkg] = append(byPackage[pkg], d.Duration)
+		if d.Instance != "" {
+			builder := durationGroup{name: d.Instance}
+			byBuilder[builder] = append(byBuilder[builder], d.Duration)
+		}
+	}
+	return domain.BuildDurationsResponse{
+		Packages: durationStats(byPackage),
+		Builders: durationStats(byBuilder),
+	}, nil
+}
+
+// durationGroup is the package and architecture, or the builder, a build
+// duration is summarized with.
+type durationGroup struct {
+	name         string
+	architecture string
+}
+
+// durationStats summarizes the durations of every group, by name and
+// architecture.
+func durationStats(groups map[durationGroup][]float64) []domain.DurationStats {
+	stats := make([]domain.DurationStats, 0, len(groups))
+	for group, durations := range groups {
+		slices.Sort(durations)
+		stats = append(stats, domain.DurationStats{
+			Name:         group.name,
+			Architecture: group.architecture,
+			Builds:       len(durations),
+			P50Seconds:   percentile(durations, 50),
+			P95Seconds:   percentile(durations, 95),
+		})
+	}
+	slices.SortFunc(stats, func(a, b domain.DurationStats) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Architecture, b.Architecture)
+	})
+	return stats
+}
+
+// percentile returns the p-th percentile of the sorted values, by the
+// nearest-rank method.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// jobStageTimings returns the stage timings of the pipeline taskUUID, nil
+// when job tracking is disabled or its events cannot be read.
+func jobStageTimings(js JobStore, taskUUID string, now time.Time) []domain.StageTiming {
+	if js == nil {
+		return nil
+	}
+	events, err := js.GetJobEvents(taskUUID)
+	if err != nil {
+		log.Printf("Failed to get the events of %s: %v\n", taskUUID, err)
+		return nil
+	}
+	return stageTimings(events, now)
+}
+
+// stageTimings returns when the tasks the workers reported on started and
+// ended, in the order they started. A task whose start was not reported
+// started the time it ran for before it ended; a task still running has run
+// until now.
+func stageTimings(events []*monitoring.JobEvent, now time.Time) []domain.StageTiming {
+	var timings []domain.StageTiming
+	tasks := make(map[string]int)
+	for _, event := range events {
+		if !domain.IsWorkerEvent(event.Event) {
+			continue
+		}
+		key := event.Stage + " " + event.TaskID
+		i, seen := tasks[key]
+		if !seen {
+			i = len(timings)
+			tasks[key] = i
+			timings = append(timings, domain.StageTiming{
+				TaskID:       event.TaskID,
+				Stage:        event.Stage,
+				Architecture: event.Architecture,
+			})
+		}
+		timing := &timings[i]
+		timing.Instance = event.Instance
+		if event.Event == domain.EventStarted {
+			timing.StartedAt = event.OccurredAt
+			timing.Result = ""
+			timing.FinishedAt = nil
+			continue
+		}
+		finishedAt := event.OccurredAt
+		timing.Result = event.Event
+		timing.FinishedAt = &finishedAt
+		timing.DurationSeconds = event.Duration
+		if timing.StartedAt.IsZero() {
+			timing.StartedAt = finishedAt.Add(-seconds(event.Duration))
+		}
+	}
+
+	for i := range timings {
+		if timings[i].FinishedAt == nil {
+			timings[i].DurationSeconds = now.Sub(timings[i].StartedAt).Seconds()
+		}
+	}
+	slices.SortStableFunc(timings, func(a, b domain.StageTiming) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return timings
+}
+
+// jobDuration returns the time the workers spent on a pipeline, from the
+// start of its first task to the end of its last one.
+func jobDuration(timings []domain.StageTiming) time.Duration {
+	var start, end time.Time
+	for _, timing := range timings {
+		if start.IsZero() || timing.StartedAt.Before(start) {
+			start = timing.StartedAt
+		}
+		finishedAt := timing.StartedAt.Add(seconds(timing.DurationSeconds))
+		if timing.FinishedAt != nil {
+			finishedAt = *timing.FinishedAt
+		}
+		if finishedAt.After(end) {
+			end = finishedAt
+		}
+	}
+	return end.Sub(start)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
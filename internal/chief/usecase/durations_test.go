@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+func TestBuildDurations(t *testing.T) {
+	var queried string
+	js := &mockJobStore{
+		getDurationsFn: func(stage, packageName string) ([]*monitoring.StageDuration, error) {
+			queried = stage + " " + packageName
+			var durations []*monitoring.StageDuration
+			for i := 1; i <= 20; i++ {
+				durations = append(durations, &monitoring.StageDuration{PackageName: "hello", Architecture: "amd64", Instance: "b1-builder", Duration: float64(i * 10)})
+			}
+			durations = append(durations,
+				&monitoring.StageDuration{PackageName: "world", Architecture: "arm64", Instance: "b2-builder", Duration: 300},
+				&monitoring.StageDuration{PackageName: "world", Architecture: "arm64", Duration: 500},
+				&monitoring.StageDuration{PackageName: "world", Architecture: "amd64", Instance: "b1-builder", Duration: 60},
+			)
+			return durations, nil
+		},
+	}
+	svc := NewDurationService(js)
+
+	res, err := svc.BuildDurations("")
+	require.NoError(t, err)
+	assert.Equal(t, "build ", queried)
+	assert.Equal(t, []domain.DurationStats{
+		{Name: "hello", Architecture: "amd64", Builds: 20, P50Seconds: 100, P95Seconds: 190},
+		{Name: "world", Architecture: "amd64", Builds: 1, P50Seconds: 60, P95Seconds: 60},
+		{Name: "world", Architecture: "arm64", Builds: 2, P50Seconds: 300, P95Seconds: 500},
+	}, res.Packages)
+	// Builds reported without their builder only count for their package
+	assert.Equal(t, []domain.DurationStats{
+		{Name: "b1-builder", Builds: 21, P50Seconds: 100, P95Seconds: 190},
+		{Name: "b2-builder", Builds: 1, P50Seconds: 300, P95Seconds: 300},
+	}, res.Builders)
+
+	_, err = svc.BuildDurations("hello")
+	require.NoError(t, err)
+	assert.Equal(t, "build hello", queried)
+}
+
+func TestBuildDurations_Empty(t *testing.T) {
+	res, err := NewDurationService(&mockJobStore{}).BuildDurations("hello")
+	require.NoError(t, err)
+	assert.Empty(t, res.Packages)
+	assert.NotNil(t, res.Packages)
+	assert.Empty(t, res.Builders)
+}
+
+func TestBuildDurations_MonitoringDisabled(t *testing.T) {
+	_, err := NewDurationService(nil).BuildDurations("")
+	var httpErr httputil.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+}
+
+func TestStageTimings(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedAt := at.Add(2 * time.Minute)
+	repoFinishedAt := at.Add(4 * time.Minute)
+	events := []*monitoring.JobEvent{
+		{TaskID: "uuid.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventQueued, OccurredAt: at.Add(-time.Minute)},
+		{TaskID: "uuid.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventStarted, Instance: "b1-builder", OccurredAt: at},
+		{TaskID: "uuid.arm64", Stage: "build", Architecture: "arm64", Event: domain.EventStarted, Instance: "b2-builder", OccurredAt: at.Add(time.Second)},
+		{TaskID: "uuid.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventDone, Instance: "b1-builder", Duration: 119.5, OccurredAt: finishedAt},
+		// The start of the repo task was not reported
+		{TaskID: "uuid", Stage: "repo", Event: domain.EventFailed, Instance: "r1-repo", Detail: "exit status 1", Duration: 30, OccurredAt: repoFinishedAt},
+	}
+
+	timings := stageTimings(events, at.Add(5*time.Minute))
+	assert.Equal(t, []domain.StageTiming{
+		{TaskID: "uuid.amd64", Stage: "build", Architecture: "amd64", Instance: "b1-builder", Result: domain.EventDone, StartedAt: at, FinishedAt: &finishedAt, DurationSeconds: 119.5},
+		{TaskID: "uuid.arm64", Stage: "build", Architecture: "arm64", Instance: "b2-builder", StartedAt: at.Add(time.Second), DurationSeconds: 299},
+		{TaskID: "uuid", Stage: "repo", Instance: "r1-repo", Result: domain.EventFailed, StartedAt: repoFinishedAt.Add(-30 * time.Second), FinishedAt: &repoFinishedAt, DurationSeconds: 30},
+	}, timings)
+	// The arm64 build still runs
+	assert.Equal(t, 5*time.Minute, jobDuration(timings))
+}
+
+func TestJobDuration(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	buildFinishedAt := at.Add(2 * time.Minute)
+	repoFinishedAt := at.Add(3 * time.Minute)
+	assert.Equal(t, 3*time.Minute, jobDuration([]domain.StageTiming{
+		{Stage: "build", StartedAt: at, FinishedAt: &buildFinishedAt, DurationSeconds: 120},
+		{Stage: "repo", StartedAt: at.Add(150 * time.Second), FinishedAt: &repoFinishedAt, DurationSeconds: 30},
+	}))
+	assert.Zero(t, jobDuration(nil))
+}
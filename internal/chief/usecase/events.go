@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// EventService keeps the history of the tasks of the pipelines: chief
+// records the tasks it queues, retries and cancels, the workers report the
+// tasks they run. The events of the workers drive the state of the jobs,
+// the task queue catches them up on the events that never came.
+type EventService struct {
+	jobStore  JobStore
+	taskQueue TaskQueue
+	now       func() time.Time
+	mu        sync.Mutex // Serializes the job updates of concurrent events
+}
+
+func NewEventService(jobStore JobStore, taskQueue TaskQueue) *EventService {
+	return &EventService{jobStore: jobStore, taskQueue: taskQueue, now: time.Now}
+}
+
+// JobEvents lists the events of the pipeline UUID, oldest first.
+func (es *EventService) JobEvents(UUID string) (domain.JobEventsResponse, error) {
+	if !domain.SafeIDPattern.MatchString(UUID) {
+		return domain.JobEventsResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid pipeline identifier")
+	}
+	if es.jobStore == nil {
+		return domain.JobEventsResponse{}, httputil.NewHTTPError(http.StatusServiceUnavailable, "monitoring is not enabled")
+	}
+	if _, err := es.jobStore.GetJob(UUID); err != nil {
+		return domain.JobEventsResponse{}, httputil.NewHTTPError(http.StatusNotFound, "job not found")
+	}
+
+	events, err := es.jobStore.GetJobEvents(UUID)
+	if err != nil {
+		log.Printf("Failed to get the events of %s: %v\n", UUID, err)
+		return domain.JobEventsResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "failed to get job events")
+	}
+	res := domain.JobEventsResponse{PipelineID: UUID, Events: []domain.JobEvent{}}
+	for _, event := range events {
+		res.Events = append(res.Events, domain.JobEvent{
+			TaskID:          event.TaskID,
+			Stage:           event.Stage,
+			Architecture:    event.Architecture,
+			Event:           event.Event,
+			Instance:        event.Instance,
+			Detail:          event.Detail,
+			DurationSeconds: event.Duration,
+			OccurredAt:      event.OccurredAt,
+		})
+	}
+	return res, nil
+}
+
+// RecordTaskEvent records the event a worker reported on a task of the
+// pipeline UUID and updates the state of its job. The worker is expected to
+// have been authorized on the claim of the task already.
+func (es *EventService) RecordTaskEvent(UUID string, event domain.TaskEvent) error {
+	if !domain.SafeIDPattern.MatchString(UUID) {
+		return httputil.NewHTTPError(http.StatusBadRequest, "invalid pipeline identifier")
+	}
+	if event.TaskID != UUID && !strings.HasPrefix(event.TaskID, UUID+".") {
+		return httputil.NewHTTPError(http.StatusBadRequest, "task "+event.TaskID+" is not a task of the pipeline")
+	}
+	if !domain.IsWorkerEvent(event.Event) {
+		return httputil.NewHTTPError(http.StatusBadRequest, "invalid event "+event.Event)
+	}
+	if es.jobStore == nil {
+		return nil
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	job, err := es.jobStore.GetJob(UUID)
+	if err != nil {
+		return httputil.NewHTTPError(http.StatusNotFound, "job not found")
+	}
+
+	err = es.jobStore.RecordJobEvent(monitoring.JobEvent{
+		TaskUUID:     UUID,
+		TaskID:       event.TaskID,
+		Stage:        event.Stage,
+		Architecture: event.Architecture,
+		Event:        event.Event,
+		Instance:     event.Instance,
+		Detail:       event.Detail,
+		Duration:     event.DurationSeconds,
+		OccurredAt:   es.now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to record the event of %s: %v\n", event.TaskID, err)
+		return httputil.NewHTTPError(http.StatusInternalServerError, "failed to record job event")
+	}
+
+	if storage.IsTerminalState(job.State) || !applyTaskEvent(job, event) {
+		return nil
+	}
+	if err := saveJobState(es.jobStore, job); err != nil {
+		log.Printf("Failed to update the state of job %s: %v\n", UUID, err)
+		return httputil.NewHTTPError(http.StatusInternalServerError, "failed to update job state")
+	}
+	return nil
+}
+
+// reconcileDetail marks the events chief derived from the task queue.
+const reconcileDetail = "reported by the task queue, the worker did not report it"
+
+// ReconcileJobs applies the outcome the task queue holds for the tasks of
+// the unfinished jobs that no worker reported, e.g. as the worker died or
+// could not reach chief. The jobs of a batch waiting for their
+// dependencies have no task queued yet and are left as they are.
+func (es *EventService) ReconcileJobs() {
+	if es.jobStore == nil || es.taskQueue == nil {
+		return
+	}
+	jobs, err := es.jobStore.GetUnfinishedJobs()
+	if err != nil {
+		log.Printf("Failed to list the unfinished jobs: %v\n", err)
+		return
+	}
+	for _, job := range jobs {
+		if job.State == domain.StateWaiting {
+			continue
+		}
+		es.reconcileJob(job.TaskUUID)
+	}
+}
+
+// reconcileJob applies the outcome of the tasks of the job UUID that the
+// task queue knows of and the job does not.
+func (es *EventService) reconcileJob(UUID string) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	job, err := es.jobStore.GetJob(UUID)
+	if err != nil || storage.IsTerminalState(job.State) || job.State == domain.StateWaiting {
+		return
+	}
+
+	changed := false
+	for _, task := range jobTasks(job) {
+		event := domain.TaskEvent{TaskID: task.id, Stage: task.stage, Architecture: task.arch, Detail: reconcileDetail}
+		switch es.taskQueue.GetTaskState(task.queueName, task.queueUUID) {
+		case "SUCCESS":
+			event.Event = domain.EventDone
+		case "FAILURE":
+			// A worker whose claim was refused as another one runs the
+			// task fails it in the task queue too, only the tasks no
+			// worker reported on are failed
+			if task.state != "" {
+				continue
+			}
+			event.Event = domain.EventFailed
+		case domain.StateTimeout:
+			event.Event = domain.EventTimeout
+		default:
+			continue
+		}
+		if isTaskOver(task.state) || !applyTaskEvent(job, event) {
+			continue
+		}
+		log.Printf("Task %s of %s ended as %s without its worker reporting it\n", task.stage, task.id, event.Event)
+		recordJobEvents(es.jobStore, monitoring.JobEvent{
+			TaskUUID:     UUID,
+			TaskID:       event.TaskID,
+			Stage:        event.Stage,
+			Architecture: event.Architecture,
+			Event:        event.Event,
+			Detail:       event.Detail,
+		})
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if err := saveJobState(es.jobStore, job); err != nil {
+		log.Printf("Failed to update the state of job %s: %v\n", UUID, err)
+	}
+}
+
+// jobTask is a task of a job: how workers report it, how the task queue
+// knows it, and the state the job holds for it.
+type jobTask struct {
+	id, stage, arch      string
+	queueName, queueUUID string
+	state                string
+}
+
+// jobTasks lists the tasks of job, the second builds of a reproducibility
+// check aside.
+func jobTasks(job *monitoring.JobInfo) []jobTask {
+	if !job.IsBuild() {
+		return []jobTask{{id: job.TaskUUID, stage: "repo", queueName: "repo", queueUUID: job.TaskUUID, state: job.RepoState}}
+	}
+
+	var tasks []jobTask
+	if len(job.Architectures) == 0 {
+		tasks = append(tasks, jobTask{id: job.TaskUUID, stage: "build", queueName: "build", queueUUID: job.TaskUUID, state: job.BuildState})
+	}
+	for _, arch := range job.Architectures {
+		id := domain.ArchTaskUUID(job.TaskUUID, arch)
+		tasks = append(tasks, jobTask{id: id, stage: "build", arch: arch, queueName: "build", queueUUID: id, state: job.ArchBuildStates[arch]})
+	}
+	if job.RunTests {
+		for _, arch := range job.Architectures {
+			id := domain.ArchTaskUUID(job.TaskUUID, arch)
+			tasks = append(tasks, jobTask{id: id, stage: "test", arch: arch, queueName: "test", queueUUID: domain.TestTaskUUID(job.TaskUUID, arch), state: job.ArchTestStates[arch]})
+		}
+	}
+	return append(tasks, jobTask{id: job.TaskUUID, stage: "repo", queueName: "repo", queueUUID: job.TaskUUID, state: job.RepoState})
+}
+
+// isTaskOver reports whether a task state recorded on a job is final.
+func isTaskOver(state string) bool {
+	switch state {
+	case "SUCCESS", "FAILURE", domain.StateTimeout, domain.StateCancelled:
+		return true
+	}
+	return false
+}
+
+// applyTaskEvent updates the task states of job with the event a worker
+// reported, and the job state along with them. It reports whether the
+// event concerns the job: the second builds of a reproducibility check do
+// not make the pipeline.
+func applyTaskEvent(job *monitoring.JobInfo, event domain.TaskEvent) bool {
+	state := domain.TaskState(event.Event)
+	if !job.IsBuild() {
+		if event.Stage != "repo" {
+			return false
+		}
+		job.RepoState = state
+		job.State = domain.DeriveRepoJobState(state)
+		if job.State == domain.StateDone {
+			job.CurrentStage = "completed"
+		}
+		return true
+	}
+
+	switch event.Stage {
+	case "build":
+		if len(job.Architectures) == 0 {
+			// A pipeline predating per-architecture builds has a single
+			// build task
+			job.BuildState = state
+			break
+		}
+		if !isArchTask(job, event) {
+			return false
+		}
+		job.ArchBuildStates = setArchState(job.ArchBuildStates, job.Architectures, event.Architecture, state)
+		job.BuildState = aggregateArchStates(job.ArchBuildStates, job.Architectures)
+	case "test":
+		// Each architecture built is tested by its own task
+		if !job.RunTests || !isArchTask(job, event) {
+			return false
+		}
+		job.ArchTestStates = setArchState(job.ArchTestStates, job.Architectures, event.Architecture, state)
+		job.TestState = aggregateArchStates(job.ArchTestStates, job.Architectures)
+	case "repo":
+		job.RepoState = state
+	default:
+		return false
+	}
+
+	job.CurrentStage = domain.DeriveCurrentStage(job.BuildState, job.RepoState)
+	if job.RunTests {
+		job.CurrentStage = domain.DeriveTestedCurrentStage(job.BuildState, job.TestState, job.RepoState)
+	}
+	job.State = domain.DeriveJobState(job.BuildState, job.TestState, job.RepoState)
+	return true
+}
+
+// isArchTask reports whether event is about the task of an architecture of
+// job, which workers report under its per-architecture UUID.
+func isArchTask(job *monitoring.JobInfo, event domain.TaskEvent) bool {
+	return slices.Contains(job.Architectures, event.Architecture) &&
+		event.TaskID == domain.ArchTaskUUID(job.TaskUUID, event.Architecture)
+}
+
+// setArchState records the state of the task of arch in archStates, which
+// it allocates for archs if needed.
+func setArchState(archStates map[string]string, archs []string, arch, state string) map[string]string {
+	if archStates == nil {
+		archStates = make(map[string]string, len(archs))
+	}
+	archStates[arch] = state
+	return archStates
+}
+
+// aggregateArchStates folds the states of the tasks of archs into a single
+// state, see domain.AggregateBuildState. The tasks no worker reported on
+// yet are pending.
+func aggregateArchStates(archStates map[string]string, archs []string) string {
+	states := make([]string, 0, len(archs))
+	for _, arch := range archs {
+		archState := archStates[arch]
+		if archState == "" {
+			archState = "PENDING"
+		}
+		states = append(states, archState)
+	}
+	return domain.AggregateBuildState(states)
+}
+
+// saveJobState records the task states of job and then its state, which
+// the other states cannot be updated past once it is terminal.
+func saveJobState(js JobStore, job *monitoring.JobInfo) error {
+	if err := js.UpdateJobStages(job.TaskUUID, job.BuildState, job.RepoState, job.CurrentStage); err != nil {
+		return err
+	}
+	if job.ArchBuildStates != nil {
+		if err := js.UpdateJobArchStates(job.TaskUUID, job.ArchBuildStates); err != nil {
+			return err
+		}
+	}
+	if job.RunTests {
+		if err := js.UpdateJobTestState(job.TaskUUID, job.TestState, job.ArchTestStates); err != nil {
+			return err
+		}
+	}
+	return js.UpdateJobState(job.TaskUUID, job.State)
+}
+
+// recordJobEvents records the events chief produced on a pipeline. They
+// only add to its history, failing to record them is logged.
+func recordJobEvents(js JobStore, events ...monitoring.JobEvent) {
+	if js == nil {
+		return
+	}
+	now := time.Now().UTC()
+	for _, event := range events {
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = now
+		}
+		if err := js.RecordJobEvent(event); err != nil {
+			log.Printf("Failed to record the %s event of %s: %v\n", event.Event, event.TaskID, err)
+		}
+	}
+}
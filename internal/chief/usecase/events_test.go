@@ -0,0 +1,388 @@
+package usecase
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+func TestJobEvents(t *testing.T) {
+	queuedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			if taskUUID != "task-1" {
+				return nil, assert.AnError
+			}
+			return &monitoring.JobInfo{TaskUUID: taskUUID}, nil
+		},
+		getEventsFn: func(taskUUID string) ([]*monitoring.JobEvent, error) {
+			return []*monitoring.JobEvent{
+				{TaskUUID: taskUUID, TaskID: "task-1.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventQueued, OccurredAt: queuedAt},
+				{TaskUUID: taskUUID, TaskID: "task-1.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventStarted, Instance: "host-builder", OccurredAt: queuedAt.Add(time.Minute)},
+			}, nil
+		},
+	}
+	svc := NewEventService(js, &mockTaskQueue{})
+
+	res, err := svc.JobEvents("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", res.PipelineID)
+	assert.Equal(t, []domain.JobEvent{
+		{TaskID: "task-1.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventQueued, OccurredAt: queuedAt},
+		{TaskID: "task-1.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventStarted, Instance: "host-builder", OccurredAt: queuedAt.Add(time.Minute)},
+	}, res.Events)
+
+	_, err = svc.JobEvents("missing")
+	var httpErr httputil.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+
+	_, err = svc.JobEvents("../etc")
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestRecordTaskEvent(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var recorded []monitoring.JobEvent
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID}, nil
+		},
+		recordEventFn: func(event monitoring.JobEvent) error {
+			recorded = append(recorded, event)
+			return nil
+		},
+	}
+	svc := NewEventService(js, &mockTaskQueue{})
+	svc.now = func() time.Time { return now }
+
+	require.NoError(t, svc.RecordTaskEvent("task-1", domain.TaskEvent{
+		TaskID:       "task-1.amd64",
+		Stage:        "build",
+		Architecture: "amd64",
+		Event:        domain.EventFailed,
+		Instance:     "host-builder",
+		Detail:       "exit status 1",
+	}))
+	require.NoError(t, svc.RecordTaskEvent("task-1", domain.TaskEvent{TaskID: "task-1", Stage: "repo", Event: domain.EventDone}))
+	assert.Equal(t, []monitoring.JobEvent{
+		{TaskUUID: "task-1", TaskID: "task-1.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventFailed, Instance: "host-builder", Detail: "exit status 1", OccurredAt: now},
+		{TaskUUID: "task-1", TaskID: "task-1", Stage: "repo", Event: domain.EventDone, OccurredAt: now},
+	}, recorded)
+
+	tests := []struct {
+		name  string
+		event domain.TaskEvent
+	}{
+		{"task of another pipeline", domain.TaskEvent{TaskID: "task-10.amd64", Stage: "build", Event: domain.EventStarted}},
+		{"event of chief", domain.TaskEvent{TaskID: "task-1", Stage: "repo", Event: domain.EventQueued}},
+		{"unknown event", domain.TaskEvent{TaskID: "task-1", Stage: "repo", Event: "exploded"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.RecordTaskEvent("task-1", tt.event)
+			var httpErr httputil.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+	assert.Len(t, recorded, 2)
+}
+
+func TestSubmitPackage_RecordsQueuedEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	tarballName := "test-tarball"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".tar.gz"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".token"), []byte("sig"), 0644))
+
+	var jobRecorded bool
+	var events []monitoring.JobEvent
+	jobStore := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			jobRecorded = true
+			return nil
+		},
+		recordEventFn: func(event monitoring.JobEvent) error {
+			// The events of a job are cleaned up along with it, it has to
+			// be recorded first
+			assert.True(t, jobRecorded)
+			events = append(events, event)
+			return nil
+		},
+	}
+	tq := &mockTaskQueue{}
+	storage := &mockFileStorage{
+		submissionsDir: tmpDir,
+		submissionTarballPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".tar.gz")
+		},
+		submissionDirPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID)
+		},
+		submissionSignaturePathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".sig")
+		},
+	}
+	svc := NewSubmissionService(tq, storage, &mockGPGVerifier{}, jobStore, nil, nil, []domain.Suite{
+		{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64", "arm64"}},
+	})
+
+	resp, err := svc.SubmitPackage(domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		PackageName:           "testpkg",
+		PackageVersion:        "1.0",
+		Maintainer:            "Test User",
+		Tarball:               tarballName,
+		RunTests:              true,
+		CheckReproducibility:  true,
+	})
+	require.NoError(t, err)
+
+	uuid := resp.PipelineID
+	var got []string
+	for _, event := range events {
+		assert.Equal(t, uuid, event.TaskUUID)
+		assert.Equal(t, domain.EventQueued, event.Event)
+		assert.False(t, event.OccurredAt.IsZero())
+		got = append(got, event.Stage+" "+event.TaskID+" "+event.Architecture)
+	}
+	assert.Equal(t, []string{
+		"build " + uuid + ".amd64 amd64",
+		"build " + uuid + ".arm64 arm64",
+		"test " + uuid + ".amd64 amd64",
+		"test " + uuid + ".arm64 arm64",
+		"repo " + uuid + " ",
+		"build " + uuid + ".amd64.repro amd64",
+		"build " + uuid + ".arm64.repro arm64",
+	}, got)
+}
+
+func TestApplyTaskEvent(t *testing.T) {
+	job := &monitoring.JobInfo{TaskUUID: "task-1", State: "PENDING", Architectures: []string{"amd64", "arm64"}}
+	build := func(arch, event string) domain.TaskEvent {
+		return domain.TaskEvent{TaskID: domain.ArchTaskUUID("task-1", arch), Stage: "build", Architecture: arch, Event: event}
+	}
+
+	require.True(t, applyTaskEvent(job, build("amd64", domain.EventStarted)))
+	assert.Equal(t, "STARTED", job.BuildState)
+	assert.Equal(t, "build", job.CurrentStage)
+	assert.Equal(t, "PENDING", job.State)
+
+	// The other architecture is still building
+	require.True(t, applyTaskEvent(job, build("amd64", domain.EventDone)))
+	assert.Equal(t, map[string]string{"amd64": "SUCCESS"}, job.ArchBuildStates)
+	assert.Equal(t, "STARTED", job.BuildState)
+	assert.Equal(t, "PENDING", job.State)
+
+	require.True(t, applyTaskEvent(job, build("arm64", domain.EventDone)))
+	assert.Equal(t, "SUCCESS", job.BuildState)
+	assert.Equal(t, "repo", job.CurrentStage)
+
+	// The second builds of a reproducibility check and unknown
+	// architectures leave the job as it is
+	assert.False(t, applyTaskEvent(job, domain.TaskEvent{TaskID: domain.ReproTaskUUID("task-1", "amd64"), Stage: "build", Architecture: "amd64", Event: domain.EventFailed}))
+	assert.False(t, applyTaskEvent(job, build("riscv64", domain.EventFailed)))
+	assert.Equal(t, "SUCCESS", job.BuildState)
+
+	require.True(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "task-1", Stage: "repo", Event: domain.EventDone}))
+	assert.Equal(t, "SUCCESS", job.RepoState)
+	assert.Equal(t, "completed", job.CurrentStage)
+	assert.Equal(t, domain.StateDone, job.State)
+}
+
+func TestApplyTaskEvent_Failures(t *testing.T) {
+	job := &monitoring.JobInfo{TaskUUID: "task-1", State: "PENDING", Architectures: []string{"amd64", "arm64"}}
+	applyTaskEvent(job, domain.TaskEvent{TaskID: "task-1.arm64", Stage: "build", Architecture: "arm64", Event: domain.EventTimeout})
+	assert.Equal(t, domain.StateTimeout, job.BuildState)
+	assert.Equal(t, domain.StateTimeout, job.State)
+
+	// A cancelled architecture ends the pipeline while another still builds
+	job = &monitoring.JobInfo{TaskUUID: "task-3", State: "PENDING", Architectures: []string{"amd64", "arm64"}}
+	applyTaskEvent(job, domain.TaskEvent{TaskID: "task-3.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventStarted})
+	applyTaskEvent(job, domain.TaskEvent{TaskID: "task-3.arm64", Stage: "build", Architecture: "arm64", Event: domain.EventCancelled})
+	assert.Equal(t, domain.StateCancelled, job.BuildState)
+	assert.Equal(t, domain.StateCancelled, job.State)
+
+	// Pipelines predating per-architecture builds have a single build task
+	legacy := &monitoring.JobInfo{TaskUUID: "task-2", State: "PENDING"}
+	applyTaskEvent(legacy, domain.TaskEvent{TaskID: "task-2", Stage: "build", Architecture: "amd64", Event: domain.EventFailed})
+	assert.Equal(t, "FAILURE", legacy.BuildState)
+	assert.Equal(t, domain.StateFailed, legacy.State)
+}
+
+func TestApplyTaskEvent_TestTask(t *testing.T) {
+	job := &monitoring.JobInfo{TaskUUID: "tested-job", State: "PENDING", Architectures: []string{"amd64", "arm64"}, RunTests: true}
+	applyTaskEvent(job, domain.TaskEvent{TaskID: "tested-job.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventDone})
+	applyTaskEvent(job, domain.TaskEvent{TaskID: "tested-job.arm64", Stage: "build", Architecture: "arm64", Event: domain.EventDone})
+	assert.Equal(t, "test", job.CurrentStage)
+	assert.Equal(t, "PENDING", job.State)
+
+	// Each architecture is tested by its own task
+	assert.False(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "tested-job", Stage: "test", Architecture: "amd64", Event: domain.EventDone}))
+	require.True(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "tested-job.amd64", Stage: "test", Architecture: "amd64", Event: domain.EventDone}))
+	assert.Equal(t, map[string]string{"amd64": "SUCCESS"}, job.ArchTestStates)
+	assert.NotEqual(t, "SUCCESS", job.TestState)
+	assert.Equal(t, "test", job.CurrentStage)
+
+	require.True(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "tested-job.arm64", Stage: "test", Architecture: "arm64", Event: domain.EventFailed}))
+	assert.Equal(t, "FAILURE", job.TestState)
+	assert.Equal(t, "test", job.CurrentStage)
+	assert.Equal(t, domain.StateFailed, job.State)
+
+	v := buildJobView(job, time.UTC)
+	assert.Equal(t, "FAILED (test)", v.StatusText)
+	assert.True(t, v.RunTests)
+	assert.Equal(t, "FAILURE", v.TestStateText)
+	require.Len(t, v.ArchTests, 2)
+	assert.Equal(t, "SUCCESS", v.ArchTests[0].StateText)
+	assert.Equal(t, "FAILURE", v.ArchTests[1].StateText)
+	assert.Equal(t, "/logs/tested-job.arm64.test.log", v.ArchTests[1].LogURL)
+
+	// Both test states are recorded
+	var recorded map[string]string
+	js := &mockJobStore{updateJobTestFn: func(taskUUID, testState string, archTestStates map[string]string) error {
+		recorded = archTestStates
+		return nil
+	}}
+	require.NoError(t, saveJobState(js, job))
+	assert.Equal(t, map[string]string{"amd64": "SUCCESS", "arm64": "FAILURE"}, recorded)
+}
+
+func TestApplyTaskEvent_RepoTask(t *testing.T) {
+	job := &monitoring.JobInfo{TaskUUID: "promote-job", State: "PENDING", JobType: storage.JobTypePromote}
+	assert.False(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "promote-job", Stage: "build", Event: domain.EventDone}))
+
+	require.True(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "promote-job", Stage: "repo", Event: domain.EventStarted}))
+	assert.Equal(t, "STARTED", job.RepoState)
+	assert.Equal(t, "PENDING", job.State)
+
+	require.True(t, applyTaskEvent(job, domain.TaskEvent{TaskID: "promote-job", Stage: "repo", Event: domain.EventFailed}))
+	assert.Equal(t, "FAILURE", job.RepoState)
+	assert.Equal(t, domain.StateFailed, job.State)
+}
+
+func TestRecordTaskEvent_UpdatesJob(t *testing.T) {
+	jobs := map[string]*monitoring.JobInfo{
+		"running": {TaskUUID: "running", State: "PENDING", Architectures: []string{"amd64"}},
+		"done":    {TaskUUID: "done", State: domain.StateDone, Architectures: []string{"amd64"}},
+	}
+	var updates []string
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			job := *jobs[taskUUID]
+			return &job, nil
+		},
+		updateJobStagesFn: func(taskUUID, buildState, repoState, currentStage string) error {
+			updates = append(updates, "stages "+taskUUID+" "+buildState+" "+currentStage)
+			return nil
+		},
+		updateJobArchFn: func(taskUUID string, archStates map[string]string) error {
+			updates = append(updates, "arch "+taskUUID+" "+archStates["amd64"])
+			return nil
+		},
+		updateJobStateFn: func(taskUUID, state string) error {
+			updates = append(updates, "state "+taskUUID+" "+state)
+			return nil
+		},
+	}
+	svc := NewEventService(js, &mockTaskQueue{})
+
+	require.NoError(t, svc.RecordTaskEvent("running", domain.TaskEvent{TaskID: "running.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventFailed, DurationSeconds: 12}))
+	// Late events leave a finished job as it is
+	require.NoError(t, svc.RecordTaskEvent("done", domain.TaskEvent{TaskID: "done.amd64", Stage: "build", Architecture: "amd64", Event: domain.EventFailed}))
+	assert.Equal(t, []string{
+		"stages running FAILURE build",
+		"arch running FAILURE",
+		"state running FAILED",
+	}, updates)
+}
+
+func TestReconcileJobs(t *testing.T) {
+	jobs := map[string]*monitoring.JobInfo{
+		// The end events of arm64 and of the repo task were lost
+		"lost": {TaskUUID: "lost", State: "STARTED", Architectures: []string{"amd64", "arm64"},
+			ArchBuildStates: map[string]string{"amd64": "SUCCESS", "arm64": "STARTED"}, BuildState: "STARTED"},
+		// The builder was refused the claim and failed the task
+		"refused": {TaskUUID: "refused", State: "PENDING", Architectures: []string{"amd64"}},
+		// Another builder runs the task its claim was refused for
+		"running": {TaskUUID: "running", State: "STARTED", Architectures: []string{"amd64"},
+			ArchBuildStates: map[string]string{"amd64": "STARTED"}, BuildState: "STARTED"},
+		"promote": {TaskUUID: "promote", State: "PENDING", JobType: storage.JobTypePromote},
+		"waiting": {TaskUUID: "waiting", State: domain.StateWaiting, Architectures: []string{"amd64"}},
+		// The end events of the arm64 tests and of the repo task were lost
+		"tested": {TaskUUID: "tested", State: domain.StateTesting, Architectures: []string{"amd64", "arm64"}, RunTests: true,
+			ArchBuildStates: map[string]string{"amd64": "SUCCESS", "arm64": "SUCCESS"}, BuildState: "SUCCESS",
+			ArchTestStates: map[string]string{"amd64": "SUCCESS", "arm64": "STARTED"}, TestState: "STARTED"},
+	}
+	queued := map[string]string{
+		"build:lost.arm64":       "SUCCESS",
+		"repo:lost":              "SUCCESS",
+		"build:refused.amd64":    "FAILURE",
+		"build:running.amd64":    "FAILURE",
+		"repo:promote":           "SUCCESS",
+		"build:waiting.amd64":    "FAILURE",
+		"test:tested.arm64.test": "SUCCESS",
+		"repo:tested":            "SUCCESS",
+	}
+	states := make(map[string]string)
+	var recorded []monitoring.JobEvent
+	js := &mockJobStore{
+		getUnfinishedFn: func() ([]*monitoring.JobInfo, error) {
+			var unfinished []*monitoring.JobInfo
+			for _, uuid := range []string{"lost", "refused", "running", "promote", "waiting", "tested"} {
+				job := *jobs[uuid]
+				unfinished = append(unfinished, &job)
+			}
+			return unfinished, nil
+		},
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			job := *jobs[taskUUID]
+			return &job, nil
+		},
+		updateJobStateFn: func(taskUUID, state string) error {
+			states[taskUUID] = state
+			return nil
+		},
+		recordEventFn: func(event monitoring.JobEvent) error {
+			recorded = append(recorded, event)
+			return nil
+		},
+	}
+	tq := &mockTaskQueue{
+		getTaskStateFn: func(taskName, taskUUID string) string {
+			return queued[taskName+":"+taskUUID]
+		},
+	}
+
+	NewEventService(js, tq).ReconcileJobs()
+
+	assert.Equal(t, map[string]string{
+		"lost":    domain.StateDone,
+		"refused": domain.StateFailed,
+		"promote": domain.StateDone,
+		"tested":  domain.StateDone,
+	}, states)
+	require.Len(t, recorded, 6)
+	assert.Equal(t, "lost.arm64", recorded[0].TaskID)
+	assert.Equal(t, domain.EventDone, recorded[0].Event)
+	assert.Equal(t, reconcileDetail, recorded[0].Detail)
+	assert.Equal(t, "repo", recorded[1].Stage)
+	assert.Equal(t, "refused.amd64", recorded[2].TaskID)
+	assert.Equal(t, domain.EventFailed, recorded[2].Event)
+	assert.Equal(t, "promote", recorded[3].TaskID)
+	assert.Equal(t, "tested.arm64", recorded[4].TaskID)
+	assert.Equal(t, "test", recorded[4].Stage)
+	assert.Equal(t, domain.EventDone, recorded[4].Event)
+	assert.Equal(t, "repo", recorded[5].Stage)
+}
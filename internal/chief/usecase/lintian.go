@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/lintian"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+// LintianService records the tags lintian reported on the builds, from the
+// lintian logs the builders upload.
+type LintianService struct {
+	jobStore JobStore
+	storage  FileStorage
+	now      func() time.Time
+}
+
+func NewLintianService(jobStore JobStore, storage FileStorage) *LintianService {
+	return &LintianService{jobStore: jobStore, storage: storage, now: time.Now}
+}
+
+// RecordLintianLog parses the uploaded lintian log of the build id and
+// records its tags on the job of the pipeline.
+func (ls *LintianService) RecordLintianLog(id string) {
+	if ls.jobStore == nil {
+		return
+	}
+	f, err := os.Open(filepath.Join(ls.storage.LogsDir(), id+".lintian.log"))
+	if err != nil {
+		log.Printf("Failed to read the lintian log of %s: %v\n", id, err)
+		return
+	}
+	defer f.Close()
+
+	tags, err := lintian.Parse(f)
+	if err != nil {
+		log.Printf("Failed to parse the lintian log of %s: %v\n", id, err)
+		return
+	}
+	taskUUID, arch := ls.splitBuildID(id)
+	err = ls.jobStore.RecordLintianResult(monitoring.LintianResult{
+		TaskUUID:     taskUUID,
+		Architecture: arch,
+		Tags:         tags,
+		RecordedAt:   ls.now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Failed to record the lintian tags of %s: %v\n", id, err)
+	}
+}
+
+// splitBuildID returns the pipeline and the architecture of the build id,
+// see domain.ArchTaskUUID. Package names may hold dots too, so the
+// architecture has to be one of the job.
+func (ls *LintianService) splitBuildID(id string) (string, string) {
+	if i := strings.LastIndex(id, "."); i > 0 {
+		taskUUID, arch := id[:i], id[i+1:]
+		job, err := ls.jobStore.GetJob(taskUUID)
+		if err == nil && slices.Contains(job.Architectures, arch) {
+			return taskUUID, arch
+		}
+	}
+	return id, ""
+}
+
+// lintianSummary counts the tags lintian reported on the builds of a
+// pipeline. It returns nil when lintian checked none of them.
+func lintianSummary(js JobStore, taskUUID string) *domain.LintianSummary {
+	if js == nil {
+		return nil
+	}
+	results, err := js.GetLintianResults(taskUUID)
+	if err != nil {
+		log.Printf("Failed to get the lintian tags of %s: %v\n", taskUUID, err)
+		return nil
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	summary := &domain.LintianSummary{}
+	for _, result := range results {
+		for _, tag := range result.Tags {
+			switch tag.Severity {
+			case lintian.Error:
+				summary.Errors++
+			case lintian.Warning:
+				summary.Warnings++
+			case lintian.Info:
+				summary.Info++
+			}
+		}
+	}
+	return summary
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/lintian"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+func TestRecordLintianLog(t *testing.T) {
+	dir := t.TempDir()
+	output := "##### RUN lintian\nE: python3.12: no-copyright-file\nW: python3.12 source: out-of-date-standards-version 4.1.0\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "uuid_python3.12.arm64.lintian.log"), []byte(output), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "uuid_python3.12.lintian.log"), []byte(output), 0644))
+
+	var recorded []monitoring.LintianResult
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			if taskUUID != "uuid_python3.12" {
+				return nil, assert.AnError
+			}
+			return &monitoring.JobInfo{TaskUUID: taskUUID, Architectures: []string{"amd64", "arm64"}}, nil
+		},
+		recordLintianFn: func(result monitoring.LintianResult) error {
+			recorded = append(recorded, result)
+			return nil
+		},
+	}
+	svc := NewLintianService(js, &mockFileStorage{logsDir: dir})
+
+	svc.RecordLintianLog("uuid_python3.12.arm64")
+	// A pipeline predating per-architecture builds has no architecture
+	svc.RecordLintianLog("uuid_python3.12")
+	// A missing log records nothing
+	svc.RecordLintianLog("uuid_python3.12.amd64")
+
+	tags := []lintian.Tag{
+		{Severity: lintian.Error, Package: "python3.12", Name: "no-copyright-file"},
+		{Severity: lintian.Warning, Package: "python3.12 source", Name: "out-of-date-standards-version", Info: "4.1.0"},
+	}
+	require.Len(t, recorded, 2)
+	assert.Equal(t, "uuid_python3.12", recorded[0].TaskUUID)
+	assert.Equal(t, "arm64", recorded[0].Architecture)
+	assert.Equal(t, tags, recorded[0].Tags)
+	assert.Equal(t, "uuid_python3.12", recorded[1].TaskUUID)
+	assert.Equal(t, "", recorded[1].Architecture)
+}
+
+func TestLintianSummary(t *testing.T) {
+	js := &mockJobStore{
+		getLintianFn: func(taskUUID string) ([]*monitoring.LintianResult, error) {
+			if taskUUID != "checked" {
+				return nil, nil
+			}
+			return []*monitoring.LintianResult{
+				{Architecture: "amd64", Tags: []lintian.Tag{{Severity: lintian.Error}, {Severity: lintian.Warning}, {Severity: lintian.Info}}},
+				{Architecture: "arm64", Tags: []lintian.Tag{{Severity: lintian.Error}, {Severity: lintian.Pedantic}}},
+			}, nil
+		},
+	}
+
+	assert.Equal(t, &domain.LintianSummary{Errors: 2, Warnings: 1, Info: 1}, lintianSummary(js, "checked"))
+	assert.Nil(t, lintianSummary(js, "unchecked"))
+	assert.Nil(t, lintianSummary(nil, "checked"))
+}
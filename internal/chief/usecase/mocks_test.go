@@ -2,20 +2,41 @@ package usecase
 
 import (
 	"errors"
+	"time"
 
+	"github.com/blankon/irgsh-go/internal/chief/domain"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
 )
 
 // mockTaskQueue implements TaskQueue for testing.
 type mockTaskQueue struct {
-	sendBuildChainFn func(taskUUID string, payload []byte) error
+	sendBuildChainFn func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error
+	sendTestedFn     func(taskUUID string, builds, tests []domain.BuildTask, repoPayload []byte) error
+	sendBuildTasksFn func(builds []domain.BuildTask) error
 	sendISOTaskFn    func(taskUUID string, payload []byte) error
+	sendRepoTaskFn   func(taskName, taskUUID string, payload []byte) error
 	getTaskStateFn   func(taskName, taskUUID string) string
+	cancelPipelineFn func(taskUUID string) error
 }
 
-func (m *mockTaskQueue) SendBuildChain(taskUUID string, payload []byte) error {
+func (m *mockTaskQueue) SendBuildChain(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
 	if m.sendBuildChainFn != nil {
-		return m.sendBuildChainFn(taskUUID, payload)
+		return m.sendBuildChainFn(taskUUID, builds, repoPayload)
+	}
+	return nil
+}
+
+func (m *mockTaskQueue) SendTestedBuildChain(taskUUID string, builds, tests []domain.BuildTask, repoPayload []byte) error {
+	if m.sendTestedFn != nil {
+		return m.sendTestedFn(taskUUID, builds, tests, repoPayload)
+	}
+	return nil
+}
+
+func (m *mockTaskQueue) SendBuildTasks(builds []domain.BuildTask) error {
+	if m.sendBuildTasksFn != nil {
+		return m.sendBuildTasksFn(builds)
 	}
 	return nil
 }
@@ -27,6 +48,13 @@ func (m *mockTaskQueue) SendISOTask(taskUUID string, payload []byte) error {
 	return nil
 }
 
+func (m *mockTaskQueue) SendRepoTask(taskName, taskUUID string, payload []byte) error {
+	if m.sendRepoTaskFn != nil {
+		return m.sendRepoTaskFn(taskName, taskUUID, payload)
+	}
+	return nil
+}
+
 func (m *mockTaskQueue) GetTaskState(taskName, taskUUID string) string {
 	if m.getTaskStateFn != nil {
 		return m.getTaskStateFn(taskName, taskUUID)
@@ -34,12 +62,20 @@ func (m *mockTaskQueue) GetTaskState(taskName, taskUUID string) string {
 	return ""
 }
 
+func (m *mockTaskQueue) CancelPipeline(taskUUID string) error {
+	if m.cancelPipelineFn != nil {
+		return m.cancelPipelineFn(taskUUID)
+	}
+	return nil
+}
+
 // mockGPGVerifier implements GPGVerifier for testing.
 type mockGPGVerifier struct {
 	listKeysWithColonsFn      func() (string, error)
 	listKeysFn                func() (string, error)
 	verifySignedSubmissionFn  func(submissionPath string) error
 	verifyFileFn              func(filePath string) error
+	verifySignedMessageFn     func(data []byte) ([]byte, string, error)
 }
 
 func (m *mockGPGVerifier) ListKeysWithColons() (string, error) {
@@ -70,6 +106,13 @@ func (m *mockGPGVerifier) VerifyFile(filePath string) error {
 	return nil
 }
 
+func (m *mockGPGVerifier) VerifySignedMessage(data []byte) ([]byte, string, error) {
+	if m.verifySignedMessageFn != nil {
+		return m.verifySignedMessageFn(data)
+	}
+	return data, "", nil
+}
+
 // mockFileStorage implements FileStorage for testing.
 type mockFileStorage struct {
 	artifactsDir   string
@@ -81,6 +124,7 @@ type mockFileStorage struct {
 	submissionDirPathFn      func(taskUUID string) string
 	submissionSignaturePathFn func(taskUUID string) string
 	extractSubmissionFn      func(taskUUID string) error
+	packSubmissionFn         func(taskUUID string) error
 	copyFileWithSudoFn       func(src, dst string) error
 	copyDirWithSudoFn        func(src, dst string) error
 	chownWithSudoFn          func(path string) error
@@ -126,6 +170,13 @@ func (m *mockFileStorage) ExtractSubmission(taskUUID string) error {
 	return nil
 }
 
+func (m *mockFileStorage) PackSubmission(taskUUID string) error {
+	if m.packSubmissionFn != nil {
+		return m.packSubmissionFn(taskUUID)
+	}
+	return nil
+}
+
 func (m *mockFileStorage) CopyFileWithSudo(src, dst string) error {
 	if m.copyFileWithSudoFn != nil {
 		return m.copyFileWithSudoFn(src, dst)
@@ -161,6 +212,16 @@ type mockJobStore struct {
 	getJobFn          func(taskUUID string) (*monitoring.JobInfo, error)
 	updateJobStateFn  func(taskUUID string, state string) error
 	updateJobStagesFn func(taskUUID, buildState, repoState, currentStage string) error
+	updateJobArchFn   func(taskUUID string, archStates map[string]string) error
+	updateJobTestFn   func(taskUUID, testState string, archTestStates map[string]string) error
+	getJobsByReproFn  func(verdict string) ([]*monitoring.JobInfo, error)
+	getUnfinishedFn   func() ([]*monitoring.JobInfo, error)
+	updateJobReproFn  func(taskUUID, verdict, detail string) error
+	recordLintianFn   func(result monitoring.LintianResult) error
+	getLintianFn      func(taskUUID string) ([]*monitoring.LintianResult, error)
+	recordEventFn     func(event monitoring.JobEvent) error
+	getEventsFn       func(taskUUID string) ([]*monitoring.JobEvent, error)
+	getDurationsFn    func(stage, packageName string) ([]*monitoring.StageDuration, error)
 }
 
 func (m *mockJobStore) RecordJob(job monitoring.JobInfo) error {
@@ -198,6 +259,76 @@ func (m *mockJobStore) UpdateJobStages(taskUUID, buildState, repoState, currentS
 	return nil
 }
 
+func (m *mockJobStore) UpdateJobArchStates(taskUUID string, archStates map[string]string) error {
+	if m.updateJobArchFn != nil {
+		return m.updateJobArchFn(taskUUID, archStates)
+	}
+	return nil
+}
+
+func (m *mockJobStore) UpdateJobTestState(taskUUID, testState string, archTestStates map[string]string) error {
+	if m.updateJobTestFn != nil {
+		return m.updateJobTestFn(taskUUID, testState, archTestStates)
+	}
+	return nil
+}
+
+func (m *mockJobStore) GetJobsByReproducibility(verdict string) ([]*monitoring.JobInfo, error) {
+	if m.getJobsByReproFn != nil {
+		return m.getJobsByReproFn(verdict)
+	}
+	return nil, nil
+}
+
+func (m *mockJobStore) GetUnfinishedJobs() ([]*monitoring.JobInfo, error) {
+	if m.getUnfinishedFn != nil {
+		return m.getUnfinishedFn()
+	}
+	return nil, nil
+}
+
+func (m *mockJobStore) UpdateJobReproducibility(taskUUID, verdict, detail string) error {
+	if m.updateJobReproFn != nil {
+		return m.updateJobReproFn(taskUUID, verdict, detail)
+	}
+	return nil
+}
+
+func (m *mockJobStore) RecordLintianResult(result monitoring.LintianResult) error {
+	if m.recordLintianFn != nil {
+		return m.recordLintianFn(result)
+	}
+	return nil
+}
+
+func (m *mockJobStore) GetLintianResults(taskUUID string) ([]*monitoring.LintianResult, error) {
+	if m.getLintianFn != nil {
+		return m.getLintianFn(taskUUID)
+	}
+	return nil, nil
+}
+
+func (m *mockJobStore) RecordJobEvent(event monitoring.JobEvent) error {
+	if m.recordEventFn != nil {
+		return m.recordEventFn(event)
+	}
+	return nil
+}
+
+func (m *mockJobStore) GetJobEvents(taskUUID string) ([]*monitoring.JobEvent, error) {
+	if m.getEventsFn != nil {
+		return m.getEventsFn(taskUUID)
+	}
+	return nil, nil
+}
+
+func (m *mockJobStore) GetStageDurations(stage, packageName string) ([]*monitoring.StageDuration, error) {
+	if m.getDurationsFn != nil {
+		return m.getDurationsFn(stage, packageName)
+	}
+	return nil, nil
+}
+
 // mockISOJobStore implements ISOJobStore for testing.
 type mockISOJobStore struct {
 	recordISOJobFn     func(job monitoring.ISOJobInfo) error
@@ -237,3 +368,141 @@ func (m *mockInstanceRegistry) GetSummary() (monitoring.InstanceSummary, error)
 	}
 	return monitoring.InstanceSummary{}, nil
 }
+
+// mockSnapshotLister implements SnapshotLister for testing.
+type mockSnapshotLister struct {
+	listSnapshotsFn func() ([]domain.Snapshot, error)
+}
+
+func (m *mockSnapshotLister) ListSnapshots() ([]domain.Snapshot, error) {
+	if m.listSnapshotsFn != nil {
+		return m.listSnapshotsFn()
+	}
+	return nil, nil
+}
+
+// mockSourceRepository implements SourceRepository for testing.
+type mockSourceRepository struct {
+	getSourceFn    func(repository, name string) (domain.PublishedSource, error)
+	downloadFileFn func(repository, path, dest string) error
+}
+
+func (m *mockSourceRepository) GetSource(repository, name string) (domain.PublishedSource, error) {
+	if m.getSourceFn != nil {
+		return m.getSourceFn(repository, name)
+	}
+	return domain.PublishedSource{}, nil
+}
+
+func (m *mockSourceRepository) DownloadFile(repository, path, dest string) error {
+	if m.downloadFileFn != nil {
+		return m.downloadFileFn(repository, path, dest)
+	}
+	return nil
+}
+
+// mockWorkerStore implements WorkerStore for testing.
+type mockWorkerStore struct {
+	claimTaskFn           func(claim storage.TaskClaim, staleBefore time.Time) (*storage.TaskClaim, error)
+	getTaskClaimFn        func(taskID, kind string) (*storage.TaskClaim, error)
+	recordRejectionFn     func(r storage.UploadRejection) error
+	getRecentRejectionsFn func(limit int) ([]*storage.UploadRejection, error)
+}
+
+func (m *mockWorkerStore) ClaimTask(claim storage.TaskClaim, staleBefore time.Time) (*storage.TaskClaim, error) {
+	if m.claimTaskFn != nil {
+		return m.claimTaskFn(claim, staleBefore)
+	}
+	return &claim, nil
+}
+
+func (m *mockWorkerStore) GetTaskClaim(taskID, kind string) (*storage.TaskClaim, error) {
+	if m.getTaskClaimFn != nil {
+		return m.getTaskClaimFn(taskID, kind)
+	}
+	return nil, nil
+}
+
+func (m *mockWorkerStore) RecordRejection(r storage.UploadRejection) error {
+	if m.recordRejectionFn != nil {
+		return m.recordRejectionFn(r)
+	}
+	return nil
+}
+
+func (m *mockWorkerStore) GetRecentRejections(limit int) ([]*storage.UploadRejection, error) {
+	if m.getRecentRejectionsFn != nil {
+		return m.getRecentRejectionsFn(limit)
+	}
+	return nil, nil
+}
+
+// mockBatchStore implements BatchStore in memory for testing.
+type mockBatchStore struct {
+	batches map[string]*storage.Batch
+	order   []string
+}
+
+func (m *mockBatchStore) RecordBatch(batch storage.Batch) error {
+	if m.batches == nil {
+		m.batches = make(map[string]*storage.Batch)
+	}
+	batch.Items = append([]storage.BatchItem(nil), batch.Items...)
+	m.batches[batch.BatchID] = &batch
+	m.order = append(m.order, batch.BatchID)
+	return nil
+}
+
+func (m *mockBatchStore) GetBatch(batchID string) (*storage.Batch, error) {
+	b, ok := m.batches[batchID]
+	if !ok {
+		return nil, errors.New("batch not found")
+	}
+	copied := *b
+	copied.Items = append([]storage.BatchItem(nil), b.Items...)
+	return &copied, nil
+}
+
+func (m *mockBatchStore) GetRunningBatches() ([]*storage.Batch, error) {
+	var batches []*storage.Batch
+	for _, id := range m.order {
+		if m.batches[id].State == domain.StateRunning {
+			b, _ := m.GetBatch(id)
+			batches = append(batches, b)
+		}
+	}
+	return batches, nil
+}
+
+func (m *mockBatchStore) GetRecentBatches(limit int) ([]*storage.Batch, error) {
+	var batches []*storage.Batch
+	for i := len(m.order) - 1; i >= 0 && len(batches) < limit; i-- {
+		b, _ := m.GetBatch(m.order[i])
+		batches = append(batches, b)
+	}
+	return batches, nil
+}
+
+func (m *mockBatchStore) UpdateBatchItem(batchID string, position int, state string, queued bool) error {
+	b, ok := m.batches[batchID]
+	if !ok {
+		return errors.New("batch not found")
+	}
+	for i := range b.Items {
+		if b.Items[i].Position == position {
+			b.Items[i].State = state
+			b.Items[i].Queued = queued
+			return nil
+		}
+	}
+	return errors.New("batch item not found")
+}
+
+func (m *mockBatchStore) UpdateBatchState(batchID, state string) error {
+	b, ok := m.batches[batchID]
+	if !ok {
+		return errors.New("batch not found")
+	}
+	b.State = state
+	return nil
+}
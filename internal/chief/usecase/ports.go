@@ -3,18 +3,39 @@ package usecase
 // Ports (interfaces) consumed by the chief usecase layer.
 
 import (
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/storage"
 )
 
 // TaskQueue abstracts the distributed task queue (machinery).
 type TaskQueue interface {
-	// SendBuildChain queues a build -> repo task chain.
-	SendBuildChain(taskUUID string, payload []byte) error
+	// SendBuildChain queues one build task per architecture, followed by
+	// a repo task that only runs once every build has succeeded.
+	SendBuildChain(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error
+	// SendTestedBuildChain queues the build tasks like SendBuildChain, with
+	// the test tasks, one per architecture, between them and the repo task.
+	// The repo task only runs once every test task has succeeded.
+	SendTestedBuildChain(taskUUID string, builds, tests []domain.BuildTask, repoPayload []byte) error
+	// SendBuildTasks queues build tasks outside of any pipeline chord, so
+	// their outcome does not hold back a repo task.
+	SendBuildTasks(builds []domain.BuildTask) error
 	// SendISOTask queues a single ISO build task.
 	SendISOTask(taskUUID string, payload []byte) error
-	// GetTaskState returns the current state string for a task.
-	// taskName is "build", "repo", or "iso".
+	// SendRepoTask queues a standalone task for the repo worker, such as
+	// "promote".
+	SendRepoTask(taskName, taskUUID string, payload []byte) error
+	// GetTaskState returns the current state string for a task, TIMEOUT
+	// for a build that ran out of time.
+	// taskName is "build", "test", "repo", "iso", or a repo task name. Build tasks are addressed by
+	// their per-architecture UUID (see domain.ArchTaskUUID), test tasks by
+	// theirs (see domain.TestTaskUUID).
 	GetTaskState(taskName, taskUUID string) string
+	// CancelPipeline keeps the tasks of a pipeline that have not run yet
+	// from running, and has the workers stop the running ones.
+	CancelPipeline(taskUUID string) error
 }
 
 // GPGVerifier handles GPG key listing and signature verification.
@@ -23,6 +44,9 @@ type GPGVerifier interface {
 	ListKeys() (string, error)
 	VerifySignedSubmission(submissionPath string) error
 	VerifyFile(filePath string) error
+	// VerifySignedMessage returns the content of a clearsigned message and
+	// the fingerprint of the key that signed it.
+	VerifySignedMessage(data []byte) ([]byte, string, error)
 }
 
 // FileStorage manages the on-disk layout for submissions, artifacts, and logs.
@@ -35,6 +59,7 @@ type FileStorage interface {
 	SubmissionDirPath(taskUUID string) string
 	SubmissionSignaturePath(taskUUID string) string
 	ExtractSubmission(taskUUID string) error
+	PackSubmission(taskUUID string) error
 	CopyFileWithSudo(src, dst string) error
 	CopyDirWithSudo(src, dst string) error
 	ChownWithSudo(path string) error
@@ -48,6 +73,41 @@ type JobStore interface {
 	GetJob(taskUUID string) (*monitoring.JobInfo, error)
 	UpdateJobState(taskUUID string, state string) error
 	UpdateJobStages(taskUUID, buildState, repoState, currentStage string) error
+	UpdateJobArchStates(taskUUID string, archStates map[string]string) error
+	UpdateJobTestState(taskUUID, testState string, archTestStates map[string]string) error
+	GetJobsByReproducibility(verdict string) ([]*monitoring.JobInfo, error)
+	GetUnfinishedJobs() ([]*monitoring.JobInfo, error)
+	UpdateJobReproducibility(taskUUID, verdict, detail string) error
+	RecordLintianResult(result monitoring.LintianResult) error
+	GetLintianResults(taskUUID string) ([]*monitoring.LintianResult, error)
+	RecordJobEvent(event monitoring.JobEvent) error
+	GetJobEvents(taskUUID string) ([]*monitoring.JobEvent, error)
+	GetStageDurations(stage, packageName string) ([]*monitoring.StageDuration, error)
+}
+
+// BatchStore persists the batches of packages and the state of their
+// packages.
+type BatchStore interface {
+	RecordBatch(batch storage.Batch) error
+	GetBatch(batchID string) (*storage.Batch, error)
+	GetRunningBatches() ([]*storage.Batch, error)
+	GetRecentBatches(limit int) ([]*storage.Batch, error)
+	UpdateBatchItem(batchID string, position int, state string, queued bool) error
+	UpdateBatchState(batchID, state string) error
+}
+
+// SnapshotLister lists the repository snapshots kept by the repo worker.
+type SnapshotLister interface {
+	ListSnapshots() ([]domain.Snapshot, error)
+}
+
+// SourceRepository fetches the source packages published by the repo
+// worker.
+type SourceRepository interface {
+	// GetSource describes the source package name published in repository.
+	GetSource(repository, name string) (domain.PublishedSource, error)
+	// DownloadFile saves a file of the published tree of repository to dest.
+	DownloadFile(repository, path, dest string) error
 }
 
 // ISOJobStore tracks ISO build job state.
@@ -61,3 +121,16 @@ type InstanceRegistry interface {
 	ListInstances(instanceType monitoring.InstanceType, status monitoring.InstanceStatus) ([]*monitoring.InstanceInfo, error)
 	GetSummary() (monitoring.InstanceSummary, error)
 }
+
+// WorkerStore records which worker claimed each task and the worker
+// requests chief rejected.
+type WorkerStore interface {
+	// ClaimTask records claim unless the task is already claimed by
+	// another worker since staleBefore, and returns the claim holding the
+	// task afterwards.
+	ClaimTask(claim storage.TaskClaim, staleBefore time.Time) (*storage.TaskClaim, error)
+	// GetTaskClaim returns nil when the task is unclaimed.
+	GetTaskClaim(taskID, kind string) (*storage.TaskClaim, error)
+	RecordRejection(r storage.UploadRejection) error
+	GetRecentRejections(limit int) ([]*storage.UploadRejection, error)
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// PromotionService promotes already built packages from the experimental
+// distribution of a suite into the suite itself.
+type PromotionService struct {
+	taskQueue TaskQueue
+	gpg       GPGVerifier
+	jobStore  JobStore
+	suites    []domain.Suite
+}
+
+func NewPromotionService(taskQueue TaskQueue, gpg GPGVerifier, jobStore JobStore, suites []domain.Suite) *PromotionService {
+	return &PromotionService{
+		taskQueue: taskQueue,
+		gpg:       gpg,
+		jobStore:  jobStore,
+		suites:    suites,
+	}
+}
+
+// PromotePackage queues the promotion described by a maintainer-signed
+// domain.PromotionRequest.
+func (ps *PromotionService) PromotePackage(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.PromotionRequest
+	fingerprint, err := verifySignedRequest(ps.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	if !domain.SafeIDPattern.MatchString(req.PackageName) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package name")
+	}
+	if !domain.PackageVersionPattern.MatchString(req.PackageVersion) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package version")
+	}
+	suite, err := findSuite(ps.suites, req.Suite)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	promotion := payload.Promote{
+		Timestamp:             time.Now(),
+		PackageName:           req.PackageName,
+		PackageVersion:        req.PackageVersion,
+		Suite:                 suite.Codename,
+		Maintainer:            maintainerName(ps.gpg, fingerprint),
+		MaintainerFingerprint: fingerprint,
+	}
+	promotion.TaskUUID = promotion.Timestamp.Format("2006-01-02-150405") + "_" + uuid.New().String() + "_" + fingerprint + "_" + promotion.PackageName
+
+	data, err := payload.Encode(&promotion)
+	if err != nil {
+		log.Println(err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	if err := ps.taskQueue.SendRepoTask(storage.JobTypePromote, promotion.TaskUUID, data); err != nil {
+		log.Printf("Could not send promote task: %v\n", err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+
+	if ps.jobStore != nil {
+		job := monitoring.JobInfo{
+			TaskUUID:       promotion.TaskUUID,
+			PackageName:    promotion.PackageName,
+			PackageVersion: promotion.PackageVersion,
+			Maintainer:     promotion.Maintainer,
+			SubmittedAt:    promotion.Timestamp,
+			State:          "PENDING",
+			CurrentStage:   "repo",
+			Suite:          suite.Codename,
+			JobType:        storage.JobTypePromote,
+		}
+		if err := ps.jobStore.RecordJob(job); err != nil {
+			log.Printf("Failed to record promotion job: %v\n", err)
+		}
+		recordJobEvents(ps.jobStore, queuedEvent(promotion.TaskUUID, promotion.TaskUUID, "repo", ""))
+	}
+
+	return domain.SubmitPayloadResponse{PipelineID: promotion.TaskUUID}, nil
+}
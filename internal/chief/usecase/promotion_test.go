@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSuites = []domain.Suite{
+	{Codename: "verbeek", UpstreamCodename: "sid"},
+	{Codename: "tambora", UpstreamCodename: "bookworm"},
+}
+
+// signedBy returns a GPG verifier accepting any message as signed by
+// fingerprint.
+func signedBy(fingerprint string) *mockGPGVerifier {
+	return &mockGPGVerifier{
+		verifySignedMessageFn: func(data []byte) ([]byte, string, error) {
+			return data, fingerprint, nil
+		},
+		listKeysWithColonsFn: func() (string, error) {
+			return "pub:u:4096:1:0123456789ABCDEF:1700000000:::u:::scESC:\n" +
+				"uid:u::::1700000000::HASH::Jane Doe <jane@example.com>:\n", nil
+		},
+	}
+}
+
+// signedRequest encodes a request to chief, which signedBy accepts as
+// signed.
+func signedRequest(t *testing.T, req any) []byte {
+	t.Helper()
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	return data
+}
+
+func requireHTTPError(t *testing.T, err error, code int) httputil.HTTPError {
+	t.Helper()
+	require.Error(t, err)
+	var httpErr httputil.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, code, httpErr.Code)
+	return httpErr
+}
+
+func TestPromotePackage_Success(t *testing.T) {
+	var taskName, taskUUID string
+	var queued []byte
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			taskName, taskUUID, queued = name, uuid, p
+			return nil
+		},
+	}
+	var recorded monitoring.JobInfo
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recorded = job
+			return nil
+		},
+	}
+	svc := NewPromotionService(tq, signedBy("FFFF0123456789ABCDEF"), js, testSuites)
+
+	resp, err := svc.PromotePackage(signedRequest(t, domain.PromotionRequest{
+		PackageName:    "hello",
+		PackageVersion: "1:2.10-3",
+		Suite:          "tambora",
+		Timestamp:      time.Now(),
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "promote", taskName)
+	assert.Equal(t, resp.PipelineID, taskUUID)
+	assert.True(t, strings.HasSuffix(taskUUID, "_FFFF0123456789ABCDEF_hello"))
+	assert.True(t, domain.SafeIDPattern.MatchString(taskUUID))
+
+	var promotion payload.Promote
+	require.NoError(t, payload.Decode(string(queued), &promotion))
+	assert.Equal(t, "hello", promotion.PackageName)
+	assert.Equal(t, "1:2.10-3", promotion.PackageVersion)
+	assert.Equal(t, "tambora", promotion.Suite)
+	assert.Equal(t, "Jane Doe <jane@example.com>", promotion.Maintainer)
+
+	assert.Equal(t, taskUUID, recorded.TaskUUID)
+	assert.Equal(t, storage.JobTypePromote, recorded.JobType)
+	assert.Equal(t, "tambora", recorded.Suite)
+	assert.Equal(t, "PENDING", recorded.State)
+}
+
+func TestPromotePackage_DefaultSuite(t *testing.T) {
+	var queued []byte
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			queued = p
+			return nil
+		},
+	}
+	svc := NewPromotionService(tq, signedBy("0123456789ABCDEF"), nil, testSuites)
+
+	_, err := svc.PromotePackage(signedRequest(t, domain.PromotionRequest{
+		PackageName:    "hello",
+		PackageVersion: "2.10-3",
+		Timestamp:      time.Now(),
+	}))
+	require.NoError(t, err)
+
+	var promotion payload.Promote
+	require.NoError(t, payload.Decode(string(queued), &promotion))
+	assert.Equal(t, "verbeek", promotion.Suite)
+}
+
+func TestPromotePackage_Rejected(t *testing.T) {
+	valid := domain.PromotionRequest{PackageName: "hello", PackageVersion: "2.10-3", Timestamp: time.Now()}
+
+	t.Run("bad signature", func(t *testing.T) {
+		gpg := &mockGPGVerifier{
+			verifySignedMessageFn: func(data []byte) ([]byte, string, error) {
+				return nil, "", errors.New("bad signature")
+			},
+		}
+		svc := NewPromotionService(&mockTaskQueue{}, gpg, nil, testSuites)
+		_, err := svc.PromotePackage(signedRequest(t, valid))
+		requireHTTPError(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("expired request", func(t *testing.T) {
+		req := valid
+		req.Timestamp = time.Now().Add(-time.Hour)
+		svc := NewPromotionService(&mockTaskQueue{}, signedBy("0123456789ABCDEF"), nil, testSuites)
+		_, err := svc.PromotePackage(signedRequest(t, req))
+		requireHTTPError(t, err, http.StatusUnauthorized)
+	})
+
+	tests := []struct {
+		name    string
+		mutate  func(*domain.PromotionRequest)
+		wantMsg string
+	}{
+		{"invalid package name", func(r *domain.PromotionRequest) { r.PackageName = "../etc" }, "invalid package name"},
+		{"invalid version", func(r *domain.PromotionRequest) { r.PackageVersion = "1.0;ls" }, "invalid package version"},
+		{"unknown suite", func(r *domain.PromotionRequest) { r.Suite = "nosuch" }, "unknown suite nosuch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.mutate(&req)
+			tq := &mockTaskQueue{
+				sendRepoTaskFn: func(name, uuid string, p []byte) error {
+					t.Fatal("task must not be queued")
+					return nil
+				},
+			}
+			svc := NewPromotionService(tq, signedBy("0123456789ABCDEF"), nil, testSuites)
+			_, err := svc.PromotePackage(signedRequest(t, req))
+			httpErr := requireHTTPError(t, err, http.StatusBadRequest)
+			assert.Contains(t, httpErr.Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestPromotePackage_QueueFailure(t *testing.T) {
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			return errors.New("broker down")
+		},
+	}
+	svc := NewPromotionService(tq, signedBy("0123456789ABCDEF"), nil, testSuites)
+	_, err := svc.PromotePackage(signedRequest(t, domain.PromotionRequest{
+		PackageName:    "hello",
+		PackageVersion: "2.10-3",
+		Timestamp:      time.Now(),
+	}))
+	requireHTTPError(t, err, http.StatusInternalServerError)
+}
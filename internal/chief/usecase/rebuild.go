@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// RebuildService queues binary-only rebuilds (binNMUs) of the source packages
+// published in the repository, so they pick up new versions of their build
+// dependencies without their maintainers uploading them again.
+type RebuildService struct {
+	submissions *SubmissionService
+	gpg         GPGVerifier
+	sources     SourceRepository
+}
+
+// NewRebuildService creates a RebuildService fetching the published sources
+// from sources and queueing their pipelines through submissions.
+func NewRebuildService(submissions *SubmissionService, gpg GPGVerifier, sources SourceRepository) *RebuildService {
+	return &RebuildService{
+		submissions: submissions,
+		gpg:         gpg,
+		sources:     sources,
+	}
+}
+
+// RebuildPackage queues the rebuild described by a maintainer-signed
+// domain.RebuildRequest. The published source is copied into a submission
+// of its own, which the builders download like any other, and only the
+// rebuilt architecture-dependent binaries are injected into the repository.
+func (rs *RebuildService) RebuildPackage(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.RebuildRequest
+	fingerprint, err := verifySignedRequest(rs.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	if !domain.SafeIDPattern.MatchString(req.PackageName) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package name")
+	}
+	if err := domain.ValidateRebuildReason(req.Reason); err != nil {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	suite, err := rs.submissions.suite(req.Suite)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := rs.submissions.checkBuilders(suite, ""); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	source, err := rs.sources.GetSource(suite.Codename, req.PackageName)
+	if errors.Is(err, domain.ErrSourceNotFound) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusNotFound, req.PackageName+" is not published in "+suite.Codename)
+	}
+	if err != nil {
+		log.Printf("Could not look %s up in %s: %v\n", req.PackageName, suite.Codename, err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadGateway, "could not reach the repository")
+	}
+	if !domain.PackageVersionPattern.MatchString(source.Version) || !domain.SafeIDPattern.MatchString(source.Component) {
+		log.Printf("Repository described %s with version %q in component %q\n", req.PackageName, source.Version, source.Component)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadGateway, "could not reach the repository")
+	}
+
+	now := time.Now()
+	binNMU := source.BinNMU + 1
+	submission := domain.Submission{
+		TaskUUID:              now.Format("2006-01-02-150405") + "_" + uuid.New().String() + "_" + fingerprint + "_" + req.PackageName,
+		Timestamp:             now,
+		PackageName:           req.PackageName,
+		PackageVersion:        domain.BinNMUVersion(source.Version, binNMU),
+		Maintainer:            maintainerName(rs.gpg, fingerprint),
+		MaintainerFingerprint: fingerprint,
+		Component:             source.Component,
+		Suite:                 suite.Codename,
+		BinNMU:                binNMU,
+		BinNMUReason:          req.Reason,
+	}
+
+	if err := rs.fetchSource(suite.Codename, source, submission.TaskUUID); err != nil {
+		os.RemoveAll(rs.submissions.storage.SubmissionDirPath(submission.TaskUUID))
+		var httpErr httputil.HTTPError
+		if errors.As(err, &httpErr) {
+			return domain.SubmitPayloadResponse{}, err
+		}
+		log.Printf("Could not fetch %s %s from %s: %v\n", req.PackageName, source.Version, suite.Codename, err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadGateway, "could not fetch the source package from the repository")
+	}
+
+	if err := rs.submissions.startPipeline(submission, suite); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	log.Printf("Rebuilding %s %s as %s: %s\n", req.PackageName, source.Version, submission.PackageVersion, req.Reason)
+
+	return domain.SubmitPayloadResponse{PipelineID: submission.TaskUUID}, nil
+}
+
+// fetchSource downloads the files of a published source package into the
+// signed directory of a submission, checking them against the checksums of
+// its .dsc, and packs the submission the way irgsh-cli uploads it.
+func (rs *RebuildService) fetchSource(repository string, source domain.PublishedSource, taskUUID string) error {
+	dscPath := path.Clean(source.Dsc)
+	if !strings.HasPrefix(dscPath, "pool/") || !strings.HasSuffix(dscPath, ".dsc") {
+		return fmt.Errorf("unexpected .dsc path %q", source.Dsc)
+	}
+
+	st := rs.submissions.storage
+	signed := filepath.Join(st.SubmissionDirPath(taskUUID), "signed")
+	if err := st.EnsureDir(signed); err != nil {
+		return err
+	}
+	dsc := filepath.Join(signed, path.Base(dscPath))
+	if err := rs.sources.DownloadFile(repository, dscPath, dsc); err != nil {
+		return err
+	}
+	content, err := os.ReadFile(dsc)
+	if err != nil {
+		return err
+	}
+
+	fields := dscFields(string(content))
+	if !hasArchDependentBinaries(fields["Architecture"]) {
+		return httputil.NewHTTPError(http.StatusBadRequest, source.PackageName+" only builds architecture-independent packages, there is nothing to rebuild")
+	}
+	files, err := dscChecksums(fields["Checksums-Sha256"])
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		dest := filepath.Join(signed, f.name)
+		if err := rs.sources.DownloadFile(repository, path.Dir(dscPath)+"/"+f.name, dest); err != nil {
+			return err
+		}
+		if err := checkSHA256(dest, f.sha256); err != nil {
+			return err
+		}
+	}
+
+	if err := st.PackSubmission(taskUUID); err != nil {
+		return fmt.Errorf("failed to pack submission: %w", err)
+	}
+	if err := writeFileChecksum(st.SubmissionTarballPath(taskUUID)); err != nil {
+		log.Printf("Failed to write the checksum of %s: %v\n", st.SubmissionTarballPath(taskUUID), err)
+	}
+	return nil
+}
+
+// dscFile is a file a .dsc references.
+type dscFile struct {
+	name   string
+	sha256 string
+}
+
+// dscChecksums parses the Checksums-Sha256 field of a .dsc, as joined by
+// dscFields.
+func dscChecksums(field string) ([]dscFile, error) {
+	parts := strings.Fields(field)
+	if len(parts) == 0 || len(parts)%3 != 0 {
+		return nil, errors.New("invalid Checksums-Sha256 field")
+	}
+	var files []dscFile
+	for i := 0; i < len(parts); i += 3 {
+		name := parts[i+2]
+		if !domain.SafeIDPattern.MatchString(name) || name == "." || name == ".." {
+			return nil, fmt.Errorf("invalid file name %q", name)
+		}
+		files = append(files, dscFile{name: name, sha256: parts[i]})
+	}
+	return files, nil
+}
+
+// hasArchDependentBinaries reports whether the Architecture field of a .dsc
+// names more than arch:all packages.
+func hasArchDependentBinaries(architecture string) bool {
+	for _, arch := range strings.Fields(architecture) {
+		if arch != "all" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSHA256(path, want string) error {
+	got, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch for %s", filepath.Base(path))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+)
+
+// rebuildFixture serves a published source package from a fake repository.
+type rebuildFixture struct {
+	dir    string
+	files  map[string]string // Published tree path to content
+	source domain.PublishedSource
+	builds []domain.BuildTask
+	job    monitoring.JobInfo
+	svc    *RebuildService
+}
+
+func newRebuildFixture(t *testing.T, architecture string) *rebuildFixture {
+	orig := "upstream source"
+	sum := sha256.Sum256([]byte(orig))
+	f := &rebuildFixture{
+		dir: t.TempDir(),
+		files: map[string]string{
+			"pool/main/h/hello/hello_2.10.orig.tar.gz": orig,
+			"pool/main/h/hello/hello_2.10-3.dsc": fmt.Sprintf(`Format: 3.0 (quilt)
+Source: hello
+Binary: hello
+Architecture: %s
+Version: 2.10-3
+Checksums-Sha256:
+ %s %d hello_2.10.orig.tar.gz
+`, architecture, hex.EncodeToString(sum[:]), len(orig)),
+		},
+		source: domain.PublishedSource{
+			PackageName: "hello",
+			Version:     "2.10-3",
+			Component:   "main",
+			Dsc:         "pool/main/h/hello/hello_2.10-3.dsc",
+			BinNMU:      1,
+		},
+	}
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			f.builds = builds
+			return nil
+		},
+	}
+	fs := &mockFileStorage{
+		submissionsDir: f.dir,
+		ensureDirFn: func(path string) error {
+			return os.MkdirAll(path, 0755)
+		},
+	}
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			f.job = job
+			return nil
+		},
+	}
+	sources := &mockSourceRepository{
+		getSourceFn: func(repository, name string) (domain.PublishedSource, error) {
+			if repository != "verbeek" || name != f.source.PackageName {
+				return domain.PublishedSource{}, domain.ErrSourceNotFound
+			}
+			return f.source, nil
+		},
+		downloadFileFn: func(repository, path, dest string) error {
+			content, ok := f.files[path]
+			if !ok {
+				return fmt.Errorf("%s not found", path)
+			}
+			return os.WriteFile(dest, []byte(content), 0644)
+		},
+	}
+	suites := []domain.Suite{{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64", "arm64"}}}
+	submissions := NewSubmissionService(tq, fs, &mockGPGVerifier{}, js, nil, nil, suites)
+	f.svc = NewRebuildService(submissions, signedBy("FFFF0123456789ABCDEF"), sources)
+	return f
+}
+
+func TestRebuildPackage_Success(t *testing.T) {
+	f := newRebuildFixture(t, "any")
+
+	resp, err := f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{
+		PackageName: "hello",
+		Reason:      "Rebuild against libfoo2",
+		Timestamp:   time.Now(),
+	}))
+	require.NoError(t, err)
+
+	signed := filepath.Join(f.dir, resp.PipelineID, "signed")
+	for _, name := range []string{"hello_2.10-3.dsc", "hello_2.10.orig.tar.gz"} {
+		assert.FileExists(t, filepath.Join(signed, name))
+	}
+
+	require.Len(t, f.builds, 2)
+	for _, task := range f.builds {
+		var build payload.Build
+		require.NoError(t, payload.Decode(string(task.Payload), &build))
+		assert.Equal(t, 2, build.BinNMU)
+		assert.Equal(t, "Rebuild against libfoo2", build.BinNMUReason)
+		assert.Equal(t, "2.10-3+b2", build.PackageVersion)
+		assert.Equal(t, "main", build.Component)
+		assert.False(t, build.BuildArchIndep)
+	}
+
+	assert.Equal(t, resp.PipelineID, f.job.TaskUUID)
+	assert.Equal(t, storage.JobTypeRebuild, f.job.JobType)
+	assert.Equal(t, "Jane Doe <jane@example.com>", f.job.Maintainer)
+}
+
+func TestRebuildPackage_Rejections(t *testing.T) {
+	f := newRebuildFixture(t, "any")
+
+	_, err := f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "hello", Timestamp: time.Now()}))
+	requireHTTPError(t, err, http.StatusBadRequest)
+
+	_, err = f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "hello", Reason: "a\n * b", Timestamp: time.Now()}))
+	requireHTTPError(t, err, http.StatusBadRequest)
+
+	_, err = f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "missing", Reason: "rebuild", Timestamp: time.Now()}))
+	httpErr := requireHTTPError(t, err, http.StatusNotFound)
+	assert.Equal(t, "missing is not published in verbeek", httpErr.Message)
+
+	_, err = f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "hello", Suite: "tambora", Reason: "rebuild", Timestamp: time.Now()}))
+	requireHTTPError(t, err, http.StatusBadRequest)
+
+	_, err = f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{
+		PackageName: "hello",
+		Reason:      "rebuild",
+		Timestamp:   time.Now().Add(-time.Hour),
+	}))
+	requireHTTPError(t, err, http.StatusUnauthorized)
+	assert.Empty(t, f.builds)
+}
+
+func TestRebuildPackage_ArchIndependentOnly(t *testing.T) {
+	f := newRebuildFixture(t, "all")
+
+	_, err := f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "hello", Reason: "rebuild", Timestamp: time.Now()}))
+	requireHTTPError(t, err, http.StatusBadRequest)
+	assert.Empty(t, f.builds)
+}
+
+func TestRebuildPackage_ChecksumMismatch(t *testing.T) {
+	f := newRebuildFixture(t, "any")
+	f.files["pool/main/h/hello/hello_2.10.orig.tar.gz"] = "tampered"
+
+	_, err := f.svc.RebuildPackage(signedRequest(t, domain.RebuildRequest{PackageName: "hello", Reason: "rebuild", Timestamp: time.Now()}))
+	requireHTTPError(t, err, http.StatusBadGateway)
+	assert.Empty(t, f.builds)
+
+	entries, err := os.ReadDir(f.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the submission of a failed rebuild is removed")
+}
+
+func TestDscChecksums(t *testing.T) {
+	files, err := dscChecksums("abc 10 hello_2.10-3.debian.tar.xz def 20 hello_2.10.orig.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, []dscFile{
+		{name: "hello_2.10-3.debian.tar.xz", sha256: "abc"},
+		{name: "hello_2.10.orig.tar.gz", sha256: "def"},
+	}, files)
+
+	_, err = dscChecksums("abc 10 ../etc/passwd")
+	assert.Error(t, err)
+	_, err = dscChecksums("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// RemovalService removes source packages, along with their binaries, from
+// the repository. Removals run on the repo worker, serialized with the
+// package injections.
+type RemovalService struct {
+	taskQueue TaskQueue
+	gpg       GPGVerifier
+	jobStore  JobStore
+	suites    []domain.Suite
+}
+
+func NewRemovalService(taskQueue TaskQueue, gpg GPGVerifier, jobStore JobStore, suites []domain.Suite) *RemovalService {
+	return &RemovalService{
+		taskQueue: taskQueue,
+		gpg:       gpg,
+		jobStore:  jobStore,
+		suites:    suites,
+	}
+}
+
+// RemovePackage queues the removal described by a maintainer-signed
+// domain.RemovalRequest.
+func (rs *RemovalService) RemovePackage(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.RemovalRequest
+	fingerprint, err := verifySignedRequest(rs.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	if !domain.SafeIDPattern.MatchString(req.PackageName) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package name")
+	}
+	if !domain.SafeIDPattern.MatchString(req.Component) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid component")
+	}
+	suite, err := findSuite(rs.suites, req.Suite)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if !suite.HasComponent(req.Component) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest,
+			"unknown component "+req.Component+" in suite "+suite.Codename)
+	}
+
+	removal := payload.Remove{
+		Timestamp:             time.Now(),
+		PackageName:           req.PackageName,
+		Suite:                 suite.Codename,
+		Component:             req.Component,
+		Maintainer:            maintainerName(rs.gpg, fingerprint),
+		MaintainerFingerprint: fingerprint,
+	}
+	removal.TaskUUID = removal.Timestamp.Format("2006-01-02-150405") + "_" + uuid.New().String() + "_" + fingerprint + "_" + removal.PackageName
+
+	data, err := payload.Encode(&removal)
+	if err != nil {
+		log.Println(err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	if err := rs.taskQueue.SendRepoTask(storage.JobTypeRemove, removal.TaskUUID, data); err != nil {
+		log.Printf("Could not send remove task: %v\n", err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+
+	log.Printf("Removal of %s from %s/%s requested by %s (%s)\n",
+		removal.PackageName, removal.Suite, removal.Component, removal.Maintainer, removal.TaskUUID)
+
+	if rs.jobStore != nil {
+		job := monitoring.JobInfo{
+			TaskUUID:     removal.TaskUUID,
+			PackageName:  removal.PackageName,
+			Maintainer:   removal.Maintainer,
+			Component:    removal.Component,
+			SubmittedAt:  removal.Timestamp,
+			State:        "PENDING",
+			CurrentStage: "repo",
+			Suite:        suite.Codename,
+			JobType:      storage.JobTypeRemove,
+		}
+		if err := rs.jobStore.RecordJob(job); err != nil {
+			log.Printf("Failed to record removal job: %v\n", err)
+		}
+		recordJobEvents(rs.jobStore, queuedEvent(removal.TaskUUID, removal.TaskUUID, "repo", ""))
+	}
+
+	return domain.SubmitPayloadResponse{PipelineID: removal.TaskUUID}, nil
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemovePackage_Success(t *testing.T) {
+	var taskName string
+	var queued []byte
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			taskName, queued = name, p
+			return nil
+		},
+	}
+	var recorded monitoring.JobInfo
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recorded = job
+			return nil
+		},
+	}
+	suites := []domain.Suite{{Codename: "verbeek", Components: []string{"main", "restricted"}}}
+	svc := NewRemovalService(tq, signedBy("0123456789ABCDEF"), js, suites)
+
+	resp, err := svc.RemovePackage(signedRequest(t, domain.RemovalRequest{
+		PackageName: "hello",
+		Component:   "restricted",
+		Timestamp:   time.Now(),
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "remove", taskName)
+	var removal payload.Remove
+	require.NoError(t, payload.Decode(string(queued), &removal))
+	assert.Equal(t, resp.PipelineID, removal.TaskUUID)
+	assert.Equal(t, "hello", removal.PackageName)
+	assert.Equal(t, "verbeek", removal.Suite)
+	assert.Equal(t, "restricted", removal.Component)
+	assert.Equal(t, "0123456789ABCDEF", removal.MaintainerFingerprint)
+	assert.Equal(t, "Jane Doe <jane@example.com>", removal.Maintainer)
+
+	assert.Equal(t, storage.JobTypeRemove, recorded.JobType)
+	assert.Equal(t, "restricted", recorded.Component)
+	assert.Equal(t, "Jane Doe <jane@example.com>", recorded.Maintainer)
+}
+
+func TestRemovePackage_Rejected(t *testing.T) {
+	suites := []domain.Suite{{Codename: "verbeek", Components: []string{"main"}}}
+	valid := domain.RemovalRequest{PackageName: "hello", Component: "main", Timestamp: time.Now()}
+
+	tests := []struct {
+		name     string
+		mutate   func(*domain.RemovalRequest)
+		wantCode int
+		wantMsg  string
+	}{
+		{"invalid package name", func(r *domain.RemovalRequest) { r.PackageName = "hello world" }, http.StatusBadRequest, "invalid package name"},
+		{"missing component", func(r *domain.RemovalRequest) { r.Component = "" }, http.StatusBadRequest, "invalid component"},
+		{"unknown component", func(r *domain.RemovalRequest) { r.Component = "extras" }, http.StatusBadRequest, "unknown component extras"},
+		{"unknown suite", func(r *domain.RemovalRequest) { r.Suite = "nosuch" }, http.StatusBadRequest, "unknown suite nosuch"},
+		{"expired request", func(r *domain.RemovalRequest) { r.Timestamp = time.Now().Add(-time.Hour) }, http.StatusUnauthorized, "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.mutate(&req)
+			tq := &mockTaskQueue{
+				sendRepoTaskFn: func(name, uuid string, p []byte) error {
+					t.Fatal("task must not be queued")
+					return nil
+				},
+			}
+			svc := NewRemovalService(tq, signedBy("0123456789ABCDEF"), nil, suites)
+			_, err := svc.RemovePackage(signedRequest(t, req))
+			httpErr := requireHTTPError(t, err, tt.wantCode)
+			assert.Contains(t, httpErr.Message, tt.wantMsg)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+// reproCheckExpiry bounds the time the second builds of a pipeline have to
+// finish before its reproducibility is left unverified.
+const reproCheckExpiry = 48 * time.Hour
+
+// ReproService compares the binaries of the two builds of the pipelines
+// checked for reproducibility, and records the verdict on their job.
+type ReproService struct {
+	jobStore JobStore
+	storage  FileStorage
+	now      func() time.Time
+}
+
+func NewReproService(jobStore JobStore, storage FileStorage) *ReproService {
+	return &ReproService{jobStore: jobStore, storage: storage, now: time.Now}
+}
+
+// CheckReproducibility gives a verdict to the pending pipelines whose
+// builds are over.
+func (rs *ReproService) CheckReproducibility() {
+	if rs.jobStore == nil {
+		return
+	}
+	jobs, err := rs.jobStore.GetJobsByReproducibility(domain.ReproPending)
+	if err != nil {
+		log.Printf("Failed to list the jobs checked for reproducibility: %v\n", err)
+		return
+	}
+	for _, job := range jobs {
+		verdict, detail, done := rs.verdict(job)
+		if !done {
+			continue
+		}
+		if err := rs.jobStore.UpdateJobReproducibility(job.TaskUUID, verdict, detail); err != nil {
+			log.Printf("Failed to record the reproducibility of %s: %v\n", job.TaskUUID, err)
+		}
+	}
+}
+
+// verdict compares the builds of each architecture of job. It returns
+// false while a build still has to finish. The state of the first builds
+// is the one recorded on the job, the second builds only have their events.
+func (rs *ReproService) verdict(job *monitoring.JobInfo) (string, string, bool) {
+	if job.State == domain.StateCancelled {
+		return domain.ReproUnverified, "the pipeline was cancelled", true
+	}
+	expired := rs.now().Sub(job.SubmittedAt) > reproCheckExpiry
+
+	archs := job.Architectures
+	if len(archs) == 0 {
+		archs = []string{domain.DefaultArchitecture}
+	}
+	var secondStates map[string]string // Loaded once a build lacks its artifact
+	var unverified, diffs []string
+	for _, arch := range archs {
+		first := domain.ArchTaskUUID(job.TaskUUID, arch)
+		second := domain.ReproTaskUUID(job.TaskUUID, arch)
+		if !rs.artifactStored(first) || !rs.artifactStored(second) {
+			firstState := job.ArchBuildStates[arch]
+			if len(job.Architectures) == 0 {
+				firstState = job.BuildState
+			}
+			if secondStates == nil {
+				secondStates = rs.taskStates(job.TaskUUID)
+			}
+			switch {
+			case buildOver(firstState):
+				unverified = append(unverified, arch+": the first build failed")
+			case buildOver(secondStates[second]):
+				unverified = append(unverified, arch+": the second build failed")
+			case expired:
+				unverified = append(unverified, arch+": the builds did not finish in time")
+			default:
+				return "", "", false
+			}
+			continue
+		}
+
+		firstSums, err := debChecksums(rs.artifactPath(first))
+		if err != nil {
+			log.Printf("Failed to read the artifact %s: %v\n", first, err)
+			unverified = append(unverified, arch+": the first build could not be read")
+			continue
+		}
+		secondSums, err := debChecksums(rs.artifactPath(second))
+		if err != nil {
+			log.Printf("Failed to read the artifact %s: %v\n", second, err)
+			unverified = append(unverified, arch+": the second build could not be read")
+			continue
+		}
+		for _, diff := range domain.DebDifferences(firstSums, secondSums) {
+			diffs = append(diffs, arch+": "+diff)
+		}
+	}
+
+	switch {
+	case len(unverified) > 0:
+		return domain.ReproUnverified, strings.Join(unverified, ", "), true
+	case len(diffs) > 0:
+		return domain.ReproUnreproducible, strings.Join(diffs, ", "), true
+	default:
+		return domain.ReproReproducible, "", true
+	}
+}
+
+// taskStates returns the state of the tasks of the pipeline UUID, by task
+// id, after the last event the workers reported on them.
+func (rs *ReproService) taskStates(UUID string) map[string]string {
+	states := make(map[string]string)
+	events, err := rs.jobStore.GetJobEvents(UUID)
+	if err != nil {
+		log.Printf("Failed to get the events of %s: %v\n", UUID, err)
+		return states
+	}
+	for _, event := range events {
+		if state := domain.TaskState(event.Event); state != "" {
+			states[event.TaskID] = state
+		}
+	}
+	return states
+}
+
+// buildOver reports whether a build ended without an artifact.
+func buildOver(state string) bool {
+	return state == "FAILURE" || state == domain.StateTimeout
+}
+
+func (rs *ReproService) artifactPath(id string) string {
+	return filepath.Join(rs.storage.ArtifactsDir(), id+".tar.gz")
+}
+
+// artifactStored reports whether the artifact id has been uploaded in
+// full. Its checksum is only written once the upload is over.
+func (rs *ReproService) artifactStored(id string) bool {
+	_, err := os.Stat(rs.artifactPath(id) + chiefclient.ChecksumSuffix)
+	return err == nil
+}
+
+// debChecksums returns the SHA-256 checksums of the .deb files of a build
+// artifact, by file name.
+func debChecksums(artifact string) (map[string]string, error) {
+	f, err := os.Open(artifact)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	sums := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".deb") {
+			continue
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		sums[path.Base(hdr.Name)] = hex.EncodeToString(h.Sum(nil))
+	}
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+)
+
+// writeArtifact stores a build artifact holding files the way the builders
+// pack them, along with its checksum sidecar.
+func writeArtifact(t *testing.T, dir, id string, files map[string]string) {
+	path := filepath.Join(dir, id+".tar.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: id + "/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: id + "/" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+	require.NoError(t, writeFileChecksum(path))
+}
+
+func TestCheckReproducibility(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	debs := map[string]string{"hello_1.0_amd64.deb": "hello", "hello_1.0_amd64.buildinfo": "first"}
+
+	// Both builds produced the same binaries
+	writeArtifact(t, dir, "same.amd64", debs)
+	writeArtifact(t, dir, "same.amd64.repro", map[string]string{"hello_1.0_amd64.deb": "hello", "hello_1.0_amd64.buildinfo": "second"})
+	// The second build produced different binaries
+	writeArtifact(t, dir, "differ.amd64", debs)
+	writeArtifact(t, dir, "differ.amd64.repro", map[string]string{"hello_1.0_amd64.deb": "hullo"})
+	// The second build of arm64 is still running
+	writeArtifact(t, dir, "waiting.amd64", debs)
+	writeArtifact(t, dir, "waiting.amd64.repro", debs)
+	writeArtifact(t, dir, "waiting.arm64", debs)
+	// The second build failed
+	writeArtifact(t, dir, "failed.amd64", debs)
+
+	// The first build of arm64 failed
+	writeArtifact(t, dir, "broken.amd64", debs)
+	writeArtifact(t, dir, "broken.amd64.repro", debs)
+
+	jobs := []*monitoring.JobInfo{
+		{TaskUUID: "same", SubmittedAt: now},
+		{TaskUUID: "differ", SubmittedAt: now, Architectures: []string{"amd64"}},
+		{TaskUUID: "waiting", SubmittedAt: now, Architectures: []string{"amd64", "arm64"}},
+		{TaskUUID: "failed", SubmittedAt: now},
+		{TaskUUID: "broken", SubmittedAt: now, Architectures: []string{"amd64", "arm64"},
+			ArchBuildStates: map[string]string{"amd64": "SUCCESS", "arm64": "FAILURE"}},
+		{TaskUUID: "cancelled", SubmittedAt: now, State: domain.StateCancelled},
+		{TaskUUID: "expired", SubmittedAt: now.Add(-reproCheckExpiry - time.Minute)},
+	}
+	verdicts := make(map[string][2]string)
+	js := &mockJobStore{
+		getJobsByReproFn: func(verdict string) ([]*monitoring.JobInfo, error) {
+			assert.Equal(t, domain.ReproPending, verdict)
+			return jobs, nil
+		},
+		updateJobReproFn: func(taskUUID, verdict, detail string) error {
+			verdicts[taskUUID] = [2]string{verdict, detail}
+			return nil
+		},
+	}
+	js.getEventsFn = func(taskUUID string) ([]*monitoring.JobEvent, error) {
+		switch taskUUID {
+		case "failed":
+			return []*monitoring.JobEvent{
+				{TaskID: "failed.amd64.repro", Stage: "build", Event: domain.EventQueued},
+				{TaskID: "failed.amd64.repro", Stage: "build", Event: domain.EventStarted},
+				{TaskID: "failed.amd64.repro", Stage: "build", Event: domain.EventFailed},
+			}, nil
+		case "waiting":
+			return []*monitoring.JobEvent{
+				{TaskID: "waiting.arm64.repro", Stage: "build", Event: domain.EventStarted},
+			}, nil
+		}
+		return nil, nil
+	}
+
+	svc := NewReproService(js, &mockFileStorage{artifactsDir: dir})
+	svc.now = func() time.Time { return now }
+	svc.CheckReproducibility()
+
+	assert.Equal(t, map[string][2]string{
+		"same":      {domain.ReproReproducible, ""},
+		"differ":    {domain.ReproUnreproducible, "amd64: hello_1.0_amd64.deb"},
+		"failed":    {domain.ReproUnverified, "amd64: the second build failed"},
+		"broken":    {domain.ReproUnverified, "arm64: the first build failed"},
+		"cancelled": {domain.ReproUnverified, "the pipeline was cancelled"},
+		"expired":   {domain.ReproUnverified, "amd64: the builds did not finish in time"},
+	}, verdicts)
+}
+
+func TestCheckReproducibility_NoJobStore(t *testing.T) {
+	svc := NewReproService(nil, &mockFileStorage{})
+	assert.NotPanics(t, svc.CheckReproducibility)
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// signedRequestMaxAge bounds how long a signed request stays valid, so a
+// captured request cannot be replayed later on.
+const signedRequestMaxAge = 10 * time.Minute
+
+// verifySignedRequest checks the maintainer signature of a clearsigned JSON
+// request, decodes it into v and returns the signer's fingerprint.
+func verifySignedRequest(gpg GPGVerifier, signed []byte, v any) (string, error) {
+	content, fingerprint, err := gpg.VerifySignedMessage(signed)
+	if err != nil {
+		log.Println(err)
+		return "", httputil.NewHTTPError(http.StatusUnauthorized, "401 Unauthorized")
+	}
+	if !domain.SafeIDPattern.MatchString(fingerprint) {
+		return "", httputil.NewHTTPError(http.StatusUnauthorized, "401 Unauthorized")
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return "", httputil.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	return fingerprint, nil
+}
+
+// checkSignedAt rejects signed requests created too long ago, or too far in
+// the future.
+func checkSignedAt(timestamp time.Time) error {
+	age := time.Since(timestamp)
+	if age > signedRequestMaxAge || age < -signedRequestMaxAge {
+		return httputil.NewHTTPError(http.StatusUnauthorized, "signed request has expired")
+	}
+	return nil
+}
+
+// maintainerName returns the identity of the maintainer key with the given
+// fingerprint, or the fingerprint itself when it cannot be found.
+func maintainerName(gpg GPGVerifier, fingerprint string) string {
+	output, err := gpg.ListKeysWithColons()
+	if err != nil {
+		return fingerprint
+	}
+	for _, m := range parseGPGKeys(output) {
+		if m.KeyID == "" || !strings.HasSuffix(fingerprint, m.KeyID) {
+			continue
+		}
+		if m.Email != "" {
+			return m.Name + " <" + m.Email + ">"
+		}
+		return m.Name
+	}
+	return fingerprint
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// SnapshotService lists the repository snapshots kept by the repo worker,
+// and queues snapshots and rollbacks. Both run on the repo worker,
+// serialized with the package injections.
+type SnapshotService struct {
+	taskQueue TaskQueue
+	gpg       GPGVerifier
+	jobStore  JobStore
+	snapshots SnapshotLister
+	suites    []domain.Suite
+}
+
+func NewSnapshotService(taskQueue TaskQueue, gpg GPGVerifier, jobStore JobStore, snapshots SnapshotLister, suites []domain.Suite) *SnapshotService {
+	return &SnapshotService{
+		taskQueue: taskQueue,
+		gpg:       gpg,
+		jobStore:  jobStore,
+		snapshots: snapshots,
+		suites:    suites,
+	}
+}
+
+// ListSnapshots returns the snapshots kept by the repo worker, newest first.
+func (ss *SnapshotService) ListSnapshots() ([]domain.Snapshot, error) {
+	snapshots, err := ss.snapshots.ListSnapshots()
+	if err != nil {
+		log.Printf("Could not list snapshots: %v\n", err)
+		return nil, httputil.NewHTTPError(http.StatusBadGateway, "could not reach the repository")
+	}
+	if snapshots == nil {
+		snapshots = []domain.Snapshot{}
+	}
+	return snapshots, nil
+}
+
+// CreateSnapshot queues a snapshot of the repository named by a
+// maintainer-signed domain.SnapshotRequest.
+func (ss *SnapshotService) CreateSnapshot(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.SnapshotRequest
+	fingerprint, err := verifySignedRequest(ss.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	repository, suite, err := ss.repository(req.Repository)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	now := time.Now()
+	task := payload.Snapshot{
+		Header:                payload.Header{TaskUUID: snapshotTaskUUID(now, fingerprint, storage.JobTypeSnapshot)},
+		Timestamp:             now,
+		Repository:            repository,
+		Maintainer:            maintainerName(ss.gpg, fingerprint),
+		MaintainerFingerprint: fingerprint,
+	}
+	return ss.send(storage.JobTypeSnapshot, &task, monitoring.JobInfo{
+		TaskUUID:    task.TaskUUID,
+		PackageName: repository,
+		Maintainer:  task.Maintainer,
+		SubmittedAt: now,
+		Suite:       suite.Codename,
+	})
+}
+
+// RestoreSnapshot queues the rollback described by a maintainer-signed
+// domain.RestoreRequest.
+func (ss *SnapshotService) RestoreSnapshot(signed []byte) (domain.SubmitPayloadResponse, error) {
+	var req domain.RestoreRequest
+	fingerprint, err := verifySignedRequest(ss.gpg, signed, &req)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := checkSignedAt(req.Timestamp); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	if !domain.SafeIDPattern.MatchString(req.SnapshotID) {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid snapshot id")
+	}
+	snapshots, err := ss.ListSnapshots()
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	var snapshot *domain.Snapshot
+	for i := range snapshots {
+		if snapshots[i].ID == req.SnapshotID {
+			snapshot = &snapshots[i]
+			break
+		}
+	}
+	if snapshot == nil {
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusNotFound, "snapshot "+req.SnapshotID+" not found")
+	}
+	_, suite, err := ss.repository(snapshot.Repository)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+
+	now := time.Now()
+	task := payload.Restore{
+		Header:                payload.Header{TaskUUID: snapshotTaskUUID(now, fingerprint, storage.JobTypeRestore)},
+		Timestamp:             now,
+		SnapshotID:            snapshot.ID,
+		Repository:            snapshot.Repository,
+		Maintainer:            maintainerName(ss.gpg, fingerprint),
+		MaintainerFingerprint: fingerprint,
+	}
+	return ss.send(storage.JobTypeRestore, &task, monitoring.JobInfo{
+		TaskUUID:    task.TaskUUID,
+		PackageName: snapshot.ID,
+		Maintainer:  task.Maintainer,
+		SubmittedAt: now,
+		Suite:       suite.Codename,
+	})
+}
+
+// repository resolves a repository name, a suite codename or its
+// -experimental twin, to the suite it belongs to.
+func (ss *SnapshotService) repository(name string) (string, domain.Suite, error) {
+	if name == "" {
+		suite, err := findSuite(ss.suites, "")
+		return suite.Codename, suite, err
+	}
+	for _, s := range ss.suites {
+		if name == s.Codename || name == s.Codename+"-experimental" {
+			return name, s, nil
+		}
+	}
+	return "", domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "unknown repository "+name)
+}
+
+func snapshotTaskUUID(now time.Time, fingerprint, taskName string) string {
+	return now.Format("2006-01-02-150405") + "_" + uuid.New().String() + "_" + fingerprint + "_" + taskName
+}
+
+// send queues a snapshot or restore task and records it as job.
+func (ss *SnapshotService) send(taskName string, task payload.Payload, job monitoring.JobInfo) (domain.SubmitPayloadResponse, error) {
+	data, err := payload.Encode(task)
+	if err != nil {
+		log.Println(err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	if err := ss.taskQueue.SendRepoTask(taskName, job.TaskUUID, data); err != nil {
+		log.Printf("Could not send %s task: %v\n", taskName, err)
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+
+	log.Printf("%s of %s requested by %s (%s)\n", taskName, job.PackageName, job.Maintainer, job.TaskUUID)
+
+	if ss.jobStore != nil {
+		job.State = "PENDING"
+		job.CurrentStage = "repo"
+		job.JobType = taskName
+		if err := ss.jobStore.RecordJob(job); err != nil {
+			log.Printf("Failed to record %s job: %v\n", taskName, err)
+		}
+		recordJobEvents(ss.jobStore, queuedEvent(job.TaskUUID, job.TaskUUID, "repo", ""))
+	}
+
+	return domain.SubmitPayloadResponse{PipelineID: job.TaskUUID}, nil
+}
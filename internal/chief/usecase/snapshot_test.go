@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSnapshots = &mockSnapshotLister{
+	listSnapshotsFn: func() ([]domain.Snapshot, error) {
+		return []domain.Snapshot{
+			{ID: "tambora-experimental_20240102-120000", Repository: "tambora-experimental"},
+			{ID: "verbeek_20240101-120000", Repository: "verbeek"},
+		}, nil
+	},
+}
+
+func TestListSnapshots(t *testing.T) {
+	svc := NewSnapshotService(&mockTaskQueue{}, signedBy("0123456789ABCDEF"), nil, testSnapshots, testSuites)
+	snapshots, err := svc.ListSnapshots()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	svc = NewSnapshotService(&mockTaskQueue{}, signedBy("0123456789ABCDEF"), nil, &mockSnapshotLister{}, testSuites)
+	snapshots, err = svc.ListSnapshots()
+	require.NoError(t, err)
+	assert.NotNil(t, snapshots)
+
+	failing := &mockSnapshotLister{
+		listSnapshotsFn: func() ([]domain.Snapshot, error) { return nil, errors.New("connection refused") },
+	}
+	svc = NewSnapshotService(&mockTaskQueue{}, signedBy("0123456789ABCDEF"), nil, failing, testSuites)
+	_, err = svc.ListSnapshots()
+	requireHTTPError(t, err, http.StatusBadGateway)
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	var taskName string
+	var queued []byte
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			taskName, queued = name, p
+			return nil
+		},
+	}
+	var recorded monitoring.JobInfo
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recorded = job
+			return nil
+		},
+	}
+	svc := NewSnapshotService(tq, signedBy("0123456789ABCDEF"), js, testSnapshots, testSuites)
+
+	resp, err := svc.CreateSnapshot(signedRequest(t, domain.SnapshotRequest{
+		Repository: "tambora-experimental",
+		Timestamp:  time.Now(),
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "snapshot", taskName)
+	var task payload.Snapshot
+	require.NoError(t, payload.Decode(string(queued), &task))
+	assert.Equal(t, resp.PipelineID, task.TaskUUID)
+	assert.Equal(t, "tambora-experimental", task.Repository)
+	assert.Equal(t, "Jane Doe <jane@example.com>", task.Maintainer)
+
+	assert.Equal(t, storage.JobTypeSnapshot, recorded.JobType)
+	assert.Equal(t, "tambora-experimental", recorded.PackageName)
+	assert.Equal(t, "tambora", recorded.Suite)
+
+	// The default suite is used when no repository is given.
+	_, err = svc.CreateSnapshot(signedRequest(t, domain.SnapshotRequest{Timestamp: time.Now()}))
+	require.NoError(t, err)
+	require.NoError(t, payload.Decode(string(queued), &task))
+	assert.Equal(t, "verbeek", task.Repository)
+
+	_, err = svc.CreateSnapshot(signedRequest(t, domain.SnapshotRequest{Repository: "nosuch", Timestamp: time.Now()}))
+	httpErr := requireHTTPError(t, err, http.StatusBadRequest)
+	assert.Contains(t, httpErr.Message, "unknown repository nosuch")
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	var taskName string
+	var queued []byte
+	tq := &mockTaskQueue{
+		sendRepoTaskFn: func(name, uuid string, p []byte) error {
+			taskName, queued = name, p
+			return nil
+		},
+	}
+	var recorded monitoring.JobInfo
+	js := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recorded = job
+			return nil
+		},
+	}
+	svc := NewSnapshotService(tq, signedBy("0123456789ABCDEF"), js, testSnapshots, testSuites)
+
+	resp, err := svc.RestoreSnapshot(signedRequest(t, domain.RestoreRequest{
+		SnapshotID: "verbeek_20240101-120000",
+		Timestamp:  time.Now(),
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "restore", taskName)
+	var task payload.Restore
+	require.NoError(t, payload.Decode(string(queued), &task))
+	assert.Equal(t, resp.PipelineID, task.TaskUUID)
+	assert.Equal(t, "verbeek_20240101-120000", task.SnapshotID)
+	assert.Equal(t, "verbeek", task.Repository)
+
+	assert.Equal(t, storage.JobTypeRestore, recorded.JobType)
+	assert.Equal(t, "verbeek_20240101-120000", recorded.PackageName)
+}
+
+func TestRestoreSnapshot_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      domain.RestoreRequest
+		wantCode int
+	}{
+		{"invalid id", domain.RestoreRequest{SnapshotID: "../verbeek", Timestamp: time.Now()}, http.StatusBadRequest},
+		{"unknown snapshot", domain.RestoreRequest{SnapshotID: "verbeek_20230101-120000", Timestamp: time.Now()}, http.StatusNotFound},
+		{"expired request", domain.RestoreRequest{SnapshotID: "verbeek_20240101-120000", Timestamp: time.Now().Add(-time.Hour)}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tq := &mockTaskQueue{
+				sendRepoTaskFn: func(name, uuid string, p []byte) error {
+					t.Fatal("task must not be queued")
+					return nil
+				},
+			}
+			svc := NewSnapshotService(tq, signedBy("0123456789ABCDEF"), nil, testSnapshots, testSuites)
+			_, err := svc.RestoreSnapshot(signedRequest(t, tt.req))
+			requireHTTPError(t, err, tt.wantCode)
+		})
+	}
+}
@@ -1,34 +1,184 @@
 package usecase
 
 import (
+	"time"
+
 	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/monitoring"
 )
 
 // StatusService handles build and ISO status queries.
 type StatusService struct {
-	taskQueue TaskQueue
+	taskQueue     TaskQueue
+	jobStore      JobStore
+	architectures []string
+	now           func() time.Time
 }
 
-func NewStatusService(taskQueue TaskQueue) *StatusService {
-	return &StatusService{taskQueue: taskQueue}
+// NewStatusService creates a StatusService. The architectures are used for
+// pipelines that cannot be looked up in jobStore.
+func NewStatusService(taskQueue TaskQueue, jobStore JobStore, architectures []string) *StatusService {
+	return &StatusService{
+		taskQueue:     taskQueue,
+		jobStore:      jobStore,
+		architectures: architectures,
+		now:           time.Now,
+	}
 }
 
+// BuildStatus returns the state of a pipeline. The state of a recorded job
+// is the one the workers reported, along with when they ran its tasks; the
+// task queue is only looked up for pipelines without one.
 func (st *StatusService) BuildStatus(UUID string) (domain.BuildStatusResponse, error) {
-	buildState := st.taskQueue.GetTaskState("build", UUID)
+	job := st.lookupJob(UUID)
+	if job != nil {
+		res := jobBuildStatus(job, lintianSummary(st.jobStore, UUID))
+		res.Stages = jobStageTimings(st.jobStore, UUID, st.now())
+		return res, nil
+	}
+
+	buildState, archStatuses := resolveBuildState(st.taskQueue, UUID, st.architectures)
 	repoState := st.taskQueue.GetTaskState("repo", UUID)
 	pipelineState := domain.DeriveBuildPipelineState(buildState, repoState)
+	// Without its job, a pipeline is known to run tests once its test tasks
+	// have been queued
+	testState := resolveTestState(st.taskQueue, UUID, archStatuses)
+	if testState != "" {
+		pipelineState = domain.DeriveTestedPipelineState(buildState, testState, repoState)
+	}
 
 	return domain.BuildStatusResponse{
-		PipelineID:  UUID,
-		JobStatus:   pipelineState,
-		BuildStatus: buildState,
-		RepoStatus:  repoState,
-		State:       pipelineState,
+		PipelineID:    UUID,
+		JobStatus:     pipelineState,
+		BuildStatus:   buildState,
+		RepoStatus:    repoState,
+		TestStatus:    testState,
+		State:         pipelineState,
+		Architectures: archStatuses,
 	}, nil
 }
 
+// jobBuildStatus returns the state of a pipeline from its job. The tasks no
+// worker reported on yet are pending.
+func jobBuildStatus(job *monitoring.JobInfo, lintian *domain.LintianSummary) domain.BuildStatusResponse {
+	if !job.IsBuild() {
+		repoState := reportedState(job.RepoState)
+		state := domain.DeriveRepoTaskState(repoState)
+		if job.State == domain.StateCancelled {
+			state = domain.StateCancelled
+		}
+		return domain.BuildStatusResponse{
+			PipelineID:  job.TaskUUID,
+			JobStatus:   state,
+			RepoStatus:  repoState,
+			State:       state,
+			PackageName: job.PackageName,
+		}
+	}
+
+	buildState := reportedState(job.BuildState)
+	var archStatuses []domain.ArchBuildStatus
+	for _, arch := range job.Architectures {
+		archStatus := domain.ArchBuildStatus{
+			Architecture: arch,
+			BuildStatus:  reportedState(job.ArchBuildStates[arch]),
+		}
+		if job.RunTests {
+			archStatus.TestStatus = reportedState(job.ArchTestStates[arch])
+		}
+		archStatuses = append(archStatuses, archStatus)
+	}
+	repoState := reportedState(job.RepoState)
+	pipelineState := domain.DeriveBuildPipelineState(buildState, repoState)
+	var testState string
+	if job.RunTests {
+		testState = reportedState(job.TestState)
+		pipelineState = domain.DeriveTestedPipelineState(buildState, testState, repoState)
+	}
+	switch job.State {
+	case domain.StateCancelled, domain.StateWaiting, domain.StateSkipped:
+		// Set by chief, no worker reports on these pipelines anymore or yet
+		pipelineState = job.State
+	}
+
+	return domain.BuildStatusResponse{
+		PipelineID:    job.TaskUUID,
+		JobStatus:     pipelineState,
+		BuildStatus:   buildState,
+		RepoStatus:    repoState,
+		TestStatus:    testState,
+		State:         pipelineState,
+		Architectures: archStatuses,
+		Lintian:       lintian,
+		PackageName:   job.PackageName,
+	}
+}
+
+// reportedState returns the state of a task of a job, PENDING until a
+// worker reported on it.
+func reportedState(state string) string {
+	if state == "" {
+		return "PENDING"
+	}
+	return state
+}
+
 func (st *StatusService) ISOStatus(UUID string) (string, string, error) {
 	isoStatusStr := st.taskQueue.GetTaskState("iso", UUID)
 	jobStatus := domain.DeriveISOPipelineState(isoStatusStr)
 	return jobStatus, isoStatusStr, nil
 }
+
+// lookupJob returns the recorded job of a pipeline, or nil when job
+// tracking is disabled or the job is unknown. Jobs recorded before
+// per-architecture builds have no architectures.
+func (st *StatusService) lookupJob(UUID string) *monitoring.JobInfo {
+	if st.jobStore == nil {
+		return nil
+	}
+	job, err := st.jobStore.GetJob(UUID)
+	if err != nil {
+		return nil
+	}
+	return job
+}
+
+// resolveBuildState queries the build task of every architecture and
+// returns their aggregate state. Without architectures the pipeline
+// predates per-architecture builds and has a single build task sharing
+// the pipeline UUID.
+func resolveBuildState(tq TaskQueue, taskUUID string, archs []string) (string, []domain.ArchBuildStatus) {
+	if len(archs) == 0 {
+		return tq.GetTaskState("build", taskUUID), nil
+	}
+
+	states := make([]string, 0, len(archs))
+	statuses := make([]domain.ArchBuildStatus, 0, len(archs))
+	for _, arch := range archs {
+		state := tq.GetTaskState("build", domain.ArchTaskUUID(taskUUID, arch))
+		states = append(states, state)
+		statuses = append(statuses, domain.ArchBuildStatus{
+			Architecture: arch,
+			BuildStatus:  state,
+		})
+	}
+	return domain.AggregateBuildState(states), statuses
+}
+
+// resolveTestState looks up the states of the test tasks of a pipeline in
+// the task queue, one per architecture of archStatuses, and records them
+// there. It returns their aggregate, empty when none has been queued.
+func resolveTestState(tq TaskQueue, taskUUID string, archStatuses []domain.ArchBuildStatus) string {
+	var queued bool
+	states := make([]string, 0, len(archStatuses))
+	for i, archStatus := range archStatuses {
+		state := tq.GetTaskState("test", domain.TestTaskUUID(taskUUID, archStatus.Architecture))
+		queued = queued || state != ""
+		archStatuses[i].TestStatus = state
+		states = append(states, state)
+	}
+	if !queued {
+		return ""
+	}
+	return domain.AggregateBuildState(states)
+}
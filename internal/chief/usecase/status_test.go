@@ -2,7 +2,11 @@ package usecase
 
 import (
 	"testing"
+	"time"
 
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/lintian"
+	"github.com/blankon/irgsh-go/internal/monitoring"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,7 +41,7 @@ func TestStatusService_BuildStatus(t *testing.T) {
 				},
 			}
 
-			svc := NewStatusService(tq)
+			svc := NewStatusService(tq, nil, nil)
 			resp, err := svc.BuildStatus("test-uuid")
 			require.NoError(t, err)
 			assert.Equal(t, "test-uuid", resp.PipelineID)
@@ -71,7 +75,7 @@ func TestStatusService_ISOStatus(t *testing.T) {
 				},
 			}
 
-			svc := NewStatusService(tq)
+			svc := NewStatusService(tq, nil, nil)
 			jobStatus, rawState, err := svc.ISOStatus("iso-uuid")
 			require.NoError(t, err)
 			assert.Equal(t, tt.wantJobStatus, jobStatus)
@@ -79,3 +83,194 @@ func TestStatusService_ISOStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusService_BuildStatusPerArch(t *testing.T) {
+	archStates := map[string]string{
+		"test-uuid.amd64": "SUCCESS",
+		"test-uuid.arm64": "FAILURE",
+	}
+	tq := &mockTaskQueue{
+		getTaskStateFn: func(taskName, taskUUID string) string {
+			if taskName == "build" {
+				return archStates[taskUUID]
+			}
+			return ""
+		},
+	}
+
+	t.Run("architectures from job store", func(t *testing.T) {
+		js := &mockJobStore{
+			getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+				return &monitoring.JobInfo{
+					TaskUUID:        taskUUID,
+					Architectures:   []string{"amd64", "arm64", "riscv64"},
+					ArchBuildStates: map[string]string{"amd64": "SUCCESS", "arm64": "FAILURE"},
+					BuildState:      "FAILURE",
+				}, nil
+			},
+		}
+		// The workers reported the states of the job, the task queue is
+		// not looked up
+		svc := NewStatusService(&mockTaskQueue{getTaskStateFn: func(taskName, taskUUID string) string {
+			t.Errorf("unexpected task state lookup of %s %s", taskName, taskUUID)
+			return ""
+		}}, js, []string{"amd64"})
+		resp, err := svc.BuildStatus("test-uuid")
+		require.NoError(t, err)
+		assert.Equal(t, "FAILURE", resp.BuildStatus)
+		assert.Equal(t, "FAILED", resp.State)
+		assert.Equal(t, []domain.ArchBuildStatus{
+			{Architecture: "amd64", BuildStatus: "SUCCESS"},
+			{Architecture: "arm64", BuildStatus: "FAILURE"},
+			{Architecture: "riscv64", BuildStatus: "PENDING"},
+		}, resp.Architectures)
+	})
+
+	t.Run("configured architectures without job store", func(t *testing.T) {
+		svc := NewStatusService(tq, nil, []string{"amd64"})
+		resp, err := svc.BuildStatus("test-uuid")
+		require.NoError(t, err)
+		assert.Equal(t, "SUCCESS", resp.BuildStatus)
+		require.Len(t, resp.Architectures, 1)
+		assert.Equal(t, "amd64", resp.Architectures[0].Architecture)
+	})
+
+	t.Run("legacy job without architectures", func(t *testing.T) {
+		js := &mockJobStore{
+			getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+				return &monitoring.JobInfo{TaskUUID: taskUUID, State: "DONE", BuildState: "SUCCESS", RepoState: "SUCCESS"}, nil
+			},
+		}
+		svc := NewStatusService(&mockTaskQueue{}, js, []string{"amd64"})
+		resp, err := svc.BuildStatus("test-uuid")
+		require.NoError(t, err)
+		assert.Equal(t, "DONE", resp.State)
+		assert.Empty(t, resp.Architectures)
+	})
+}
+
+func TestStatusService_BuildStatusTested(t *testing.T) {
+	job := &monitoring.JobInfo{
+		Architectures:   []string{"amd64", "arm64"},
+		ArchBuildStates: map[string]string{"amd64": "SUCCESS", "arm64": "SUCCESS"},
+		BuildState:      "SUCCESS",
+		ArchTestStates:  map[string]string{"amd64": "SUCCESS", "arm64": "STARTED"},
+		TestState:       "STARTED",
+		RunTests:        true,
+	}
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			job.TaskUUID = taskUUID
+			return job, nil
+		},
+	}
+	svc := NewStatusService(&mockTaskQueue{}, js, []string{"amd64"})
+
+	resp, err := svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "STARTED", resp.TestStatus)
+	assert.Equal(t, domain.StateTesting, resp.State)
+	assert.Equal(t, []domain.ArchBuildStatus{
+		{Architecture: "amd64", BuildStatus: "SUCCESS", TestStatus: "SUCCESS"},
+		{Architecture: "arm64", BuildStatus: "SUCCESS", TestStatus: "STARTED"},
+	}, resp.Architectures)
+
+	// The repo task never runs after failed tests
+	job.TestState = "FAILURE"
+	resp, err = svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StateFailed, resp.State)
+
+	// Without its job, the test tasks tell the pipeline runs tests
+	states := map[string]string{
+		"build:test-uuid.amd64":     "SUCCESS",
+		"build:test-uuid.arm64":     "SUCCESS",
+		"test:test-uuid.amd64.test": "SUCCESS",
+		"test:test-uuid.arm64.test": "SUCCESS",
+		"repo:test-uuid":            "STARTED",
+	}
+	tq := &mockTaskQueue{
+		getTaskStateFn: func(taskName, taskUUID string) string {
+			return states[taskName+":"+taskUUID]
+		},
+	}
+	svc = NewStatusService(tq, nil, []string{"amd64", "arm64"})
+	resp, err = svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.TestStatus)
+	assert.Equal(t, domain.StateRepo, resp.State)
+
+	// The packages of every architecture are tested
+	states["test:test-uuid.arm64.test"] = "FAILURE"
+	delete(states, "repo:test-uuid")
+	resp, err = svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.TestStatus)
+	assert.Equal(t, "FAILURE", resp.Architectures[1].TestStatus)
+	assert.Equal(t, domain.StateFailed, resp.State)
+}
+
+func TestStatusService_BuildStatusLintian(t *testing.T) {
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID, Architectures: []string{"amd64"}}, nil
+		},
+		getLintianFn: func(taskUUID string) ([]*monitoring.LintianResult, error) {
+			return []*monitoring.LintianResult{
+				{TaskUUID: taskUUID, Architecture: "amd64", Tags: []lintian.Tag{{Severity: lintian.Warning, Package: "pkg", Name: "binary-without-manpage"}}},
+			}, nil
+		},
+	}
+	svc := NewStatusService(&mockTaskQueue{}, js, []string{"amd64"})
+
+	resp, err := svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.LintianSummary{Warnings: 1}, resp.Lintian)
+}
+
+func TestStatusService_BuildStatusRepoTask(t *testing.T) {
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID, JobType: "promote", RepoState: "STARTED"}, nil
+		},
+	}
+	svc := NewStatusService(&mockTaskQueue{}, js, []string{"amd64"})
+	resp, err := svc.BuildStatus("promote-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "STARTED", resp.RepoStatus)
+	assert.Equal(t, domain.StateRepo, resp.State)
+	assert.Empty(t, resp.BuildStatus)
+	assert.Empty(t, resp.Architectures)
+
+	// A task no worker reported on yet is queued
+	js.getJobFn = func(taskUUID string) (*monitoring.JobInfo, error) {
+		return &monitoring.JobInfo{TaskUUID: taskUUID, JobType: "promote"}, nil
+	}
+	resp, err = svc.BuildStatus("promote-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "PENDING", resp.RepoStatus)
+	assert.Equal(t, domain.StateRepo, resp.State)
+}
+
+func TestStatusService_BuildStatusStages(t *testing.T) {
+	startedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{TaskUUID: taskUUID, PackageName: "hello", Architectures: []string{"amd64"}}, nil
+		},
+		getEventsFn: func(taskUUID string) ([]*monitoring.JobEvent, error) {
+			return []*monitoring.JobEvent{
+				{TaskUUID: taskUUID, TaskID: taskUUID + ".amd64", Stage: "build", Architecture: "amd64", Event: domain.EventStarted, Instance: "host-builder", OccurredAt: startedAt},
+			}, nil
+		},
+	}
+	svc := NewStatusService(&mockTaskQueue{}, js, []string{"amd64"})
+	svc.now = func() time.Time { return startedAt.Add(90 * time.Second) }
+
+	resp, err := svc.BuildStatus("test-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.PackageName)
+	assert.Equal(t, []domain.StageTiming{
+		{TaskID: "test-uuid.amd64", Stage: "build", Architecture: "amd64", Instance: "host-builder", StartedAt: startedAt, DurationSeconds: 90},
+	}, resp.Stages)
+}
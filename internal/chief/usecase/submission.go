@@ -1,7 +1,7 @@
 package usecase
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -12,7 +12,11 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
+	"github.com/blankon/irgsh-go/internal/queue"
+	"github.com/blankon/irgsh-go/internal/storage"
 	"github.com/blankon/irgsh-go/pkg/httputil"
 	"github.com/blankon/irgsh-go/pkg/systemutil"
 )
@@ -24,33 +28,289 @@ type SubmissionService struct {
 	gpg       GPGVerifier
 	jobStore  JobStore
 	isoStore  ISOJobStore
+	instances InstanceRegistry
+	suites    []domain.Suite
 }
 
+// NewSubmissionService creates a SubmissionService for the given suites, the
+// first of which is the default target. Every package is built once per
+// architecture of its suite; the first one also builds the
+// architecture-independent packages. When instances is set, submissions no
+// online builder can serve are rejected up front.
 func NewSubmissionService(
 	taskQueue TaskQueue,
 	storage FileStorage,
 	gpg GPGVerifier,
 	jobStore JobStore,
 	isoStore ISOJobStore,
+	instances InstanceRegistry,
+	suites []domain.Suite,
 ) *SubmissionService {
+	for i := range suites {
+		if len(suites[i].Architectures) == 0 {
+			suites[i].Architectures = []string{domain.DefaultArchitecture}
+		}
+	}
 	return &SubmissionService{
 		taskQueue: taskQueue,
 		storage:   storage,
 		gpg:       gpg,
 		jobStore:  jobStore,
 		isoStore:  isoStore,
+		instances: instances,
+		suites:    suites,
+	}
+}
+
+// findSuite returns the suite with the given codename, or the default
+// (first) suite when codename is empty.
+func findSuite(suites []domain.Suite, codename string) (domain.Suite, error) {
+	if len(suites) == 0 {
+		return domain.Suite{}, httputil.NewHTTPError(http.StatusInternalServerError, "no suite configured")
+	}
+	if codename == "" {
+		return suites[0], nil
+	}
+	for _, s := range suites {
+		if s.Codename == codename {
+			return s, nil
+		}
+	}
+	return domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "unknown suite "+codename)
+}
+
+func (ss *SubmissionService) suite(codename string) (domain.Suite, error) {
+	return findSuite(ss.suites, codename)
+}
+
+// changesDistribution returns the Distribution field of the signed .changes
+// file of a submission, which dpkg-genchanges takes from debian/changelog.
+func changesDistribution(submissionPath string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(submissionPath, "signed", "*.changes"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", errors.New("no .changes file in submission")
+	}
+	content, err := os.ReadFile(matches[0])
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if dist, ok := strings.CutPrefix(line, "Distribution:"); ok {
+			return strings.TrimSpace(dist), nil
+		}
+	}
+	return "", errors.New("no Distribution field in " + filepath.Base(matches[0]))
+}
+
+// checkBuilders returns an error naming the first architecture of suite that
+// no online builder can build the submission for.
+func (ss *SubmissionService) checkBuilders(suite domain.Suite, label string) error {
+	if ss.instances == nil {
+		return nil
+	}
+	builders, err := ss.instances.ListInstances(monitoring.InstanceTypeBuilder, monitoring.StatusOnline)
+	if err != nil {
+		log.Printf("Failed to list builders: %v\n", err)
+		return httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	for _, arch := range suite.Architectures {
+		found := false
+		for _, b := range builders {
+			if b.Capabilities.CanBuild(suite.UpstreamCodename, arch, label) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msg := "no online builder for " + suite.UpstreamCodename + "/" + arch
+			if label != "" {
+				msg += " with label " + label
+			}
+			return httputil.NewHTTPError(http.StatusServiceUnavailable, msg)
+		}
+	}
+	return nil
+}
+
+// buildPayload converts a submission into the payload of its build and repo
+// tasks.
+func buildPayload(submission domain.Submission) payload.Build {
+	return payload.Build{
+		Header:                 payload.Header{TaskUUID: submission.TaskUUID},
+		Timestamp:              submission.Timestamp,
+		PackageName:            submission.PackageName,
+		PackageVersion:         submission.PackageVersion,
+		PackageExtendedVersion: submission.PackageExtendedVersion,
+		PackageURL:             submission.PackageURL,
+		SourceURL:              submission.SourceURL,
+		Maintainer:             submission.Maintainer,
+		MaintainerFingerprint:  submission.MaintainerFingerprint,
+		Component:              submission.Component,
+		IsExperimental:         submission.IsExperimental,
+		ForceVersion:           submission.ForceVersion,
+		Tarball:                submission.Tarball,
+		PackageBranch:          submission.PackageBranch,
+		SourceBranch:           submission.SourceBranch,
+		BuilderLabel:           submission.BuilderLabel,
+		Suite:                  submission.Suite,
+		UpstreamOnly:           submission.UpstreamOnly,
+		BinNMU:                 submission.BinNMU,
+		BinNMUReason:           submission.BinNMUReason,
+	}
+}
+
+// queueBuildPipeline fans the submission out to one build task per
+// architecture of its suite and queues the repo task behind them, behind
+// one test task per architecture when the submission asks for tests. A
+// submission checked for reproducibility gets a second build task per
+// architecture. It returns the queued events of the tasks, to be recorded
+// along with the job.
+func (ss *SubmissionService) queueBuildPipeline(submission domain.Submission, suite domain.Suite) ([]monitoring.JobEvent, error) {
+	submission.Suite = suite.Codename
+	builds := make([]domain.BuildTask, 0, len(suite.Architectures))
+	var repros []domain.BuildTask // Second builds of a reproducibility check
+	for i, arch := range suite.Architectures {
+		build := buildPayload(submission)
+		build.Architecture = arch
+		build.Architectures = suite.Architectures
+		// Only one builder produces the arch:all packages, otherwise
+		// reprepro would be handed several differing copies of them. A
+		// rebuild leaves them as they are.
+		build.BuildArchIndep = i == 0 && submission.BinNMU == 0
+		build.LintianFailTags = suite.LintianFailTags[submission.Component]
+		data, err := payload.Encode(&build)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, domain.BuildTask{
+			TaskUUID:     domain.ArchTaskUUID(submission.TaskUUID, arch),
+			Architecture: arch,
+			Queue:        queue.Build(suite.UpstreamCodename, arch, submission.BuilderLabel),
+			Payload:      data,
+		})
+
+		if !submission.CheckReproducibility {
+			continue
+		}
+		build.ReproCheck = true
+		data, err = payload.Encode(&build)
+		if err != nil {
+			return nil, err
+		}
+		repros = append(repros, domain.BuildTask{
+			TaskUUID:     domain.ReproTaskUUID(submission.TaskUUID, arch),
+			Architecture: arch,
+			Queue:        queue.Build(suite.UpstreamCodename, arch, submission.BuilderLabel),
+			Payload:      data,
+		})
+	}
+
+	repo := buildPayload(submission)
+	repo.Architectures = suite.Architectures
+	repoPayload, err := payload.Encode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if submission.RunTests {
+		// The packages of each architecture are tested on its builders.
+		// The arch:all ones are only built for the first architecture,
+		// the other test tasks fetch them from its build.
+		tests := make([]domain.BuildTask, 0, len(builds))
+		for _, build := range builds {
+			test := buildPayload(submission)
+			test.Architecture = build.Architecture
+			test.Architectures = suite.Architectures
+			data, err := payload.Encode(&test)
+			if err != nil {
+				return nil, err
+			}
+			tests = append(tests, domain.BuildTask{
+				TaskUUID:     domain.TestTaskUUID(submission.TaskUUID, build.Architecture),
+				Architecture: build.Architecture,
+				Queue:        build.Queue,
+				Payload:      data,
+			})
+		}
+		if err := ss.taskQueue.SendTestedBuildChain(submission.TaskUUID, builds, tests, repoPayload); err != nil {
+			return nil, err
+		}
+	} else if err := ss.taskQueue.SendBuildChain(submission.TaskUUID, builds, repoPayload); err != nil {
+		return nil, err
+	}
+
+	var events []monitoring.JobEvent
+	for _, build := range builds {
+		events = append(events, queuedEvent(submission.TaskUUID, build.TaskUUID, "build", build.Architecture))
+	}
+	if submission.RunTests {
+		for _, build := range builds {
+			events = append(events, queuedEvent(submission.TaskUUID, build.TaskUUID, "test", build.Architecture))
+		}
+	}
+	events = append(events, queuedEvent(submission.TaskUUID, submission.TaskUUID, "repo", ""))
+
+	// The second builds are left out of the chord, publishing the package
+	// does not wait for them. Not queueing them leaves the check unverified.
+	if len(repros) > 0 {
+		if err := ss.taskQueue.SendBuildTasks(repros); err != nil {
+			log.Printf("Could not send the second builds of %s: %v\n", submission.TaskUUID, err)
+		} else {
+			for _, build := range repros {
+				events = append(events, queuedEvent(submission.TaskUUID, build.TaskUUID, "build", build.Architecture))
+			}
+		}
+	}
+	return events, nil
+}
+
+// queuedEvent returns the event of a task of the pipeline taskUUID being
+// queued. taskID is the identifier the worker claims the task under.
+func queuedEvent(taskUUID, taskID, stage, arch string) monitoring.JobEvent {
+	return monitoring.JobEvent{
+		TaskUUID:     taskUUID,
+		TaskID:       taskID,
+		Stage:        stage,
+		Architecture: arch,
+		Event:        domain.EventQueued,
 	}
 }
 
 func (ss *SubmissionService) SubmitPackage(submission domain.Submission) (domain.SubmitPayloadResponse, error) {
+	submission, suite, err := ss.acceptSubmission(submission)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := ss.startPipeline(submission, suite); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	return domain.SubmitPayloadResponse{PipelineID: submission.TaskUUID}, nil
+}
+
+// acceptSubmission validates a submission, gives it its pipeline UUID and
+// moves its uploaded files in place once their signatures are verified.
+func (ss *SubmissionService) acceptSubmission(submission domain.Submission) (domain.Submission, domain.Suite, error) {
 	if !domain.SafeIDPattern.MatchString(submission.MaintainerFingerprint) {
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid maintainer fingerprint")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid maintainer fingerprint")
 	}
 	if !domain.SafeIDPattern.MatchString(submission.PackageName) {
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package name")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid package name")
 	}
 	if !domain.SafeIDPattern.MatchString(submission.Tarball) {
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid tarball identifier")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid tarball identifier")
+	}
+	if submission.BuilderLabel != "" && !domain.SafeIDPattern.MatchString(submission.BuilderLabel) {
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "invalid builder label")
+	}
+	suite, err := ss.suite(submission.Suite)
+	if err != nil {
+		return domain.Submission{}, domain.Suite{}, err
+	}
+	if err := ss.checkBuilders(suite, submission.BuilderLabel); err != nil {
+		return domain.Submission{}, domain.Suite{}, err
 	}
 
 	submission.Timestamp = time.Now()
@@ -58,65 +318,117 @@ func (ss *SubmissionService) SubmitPackage(submission domain.Submission) (domain
 
 	if err := ss.storage.EnsureDir(filepath.Join(ss.storage.SubmissionsDir(), submission.TaskUUID)); err != nil {
 		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
 	}
 
 	src := filepath.Join(ss.storage.SubmissionsDir(), submission.Tarball+".tar.gz")
 	path := ss.storage.SubmissionTarballPath(submission.TaskUUID)
 	if err := systemutil.MoveFile(src, path); err != nil {
 		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+	}
+	if err := writeFileChecksum(path); err != nil {
+		log.Printf("Failed to write the checksum of %s: %v\n", path, err)
 	}
 
 	if err := ss.storage.ExtractSubmission(submission.TaskUUID); err != nil {
 		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
 	}
 
 	src = filepath.Join(ss.storage.SubmissionsDir(), submission.Tarball+".token")
 	path = ss.storage.SubmissionSignaturePath(submission.TaskUUID)
 	if err := systemutil.MoveFile(src, path); err != nil {
 		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
 	}
 
 	if err := ss.gpg.VerifySignedSubmission(ss.storage.SubmissionDirPath(submission.TaskUUID)); err != nil {
 		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusUnauthorized, "401 Unauthorized")
+		return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusUnauthorized, "401 Unauthorized")
 	}
 
-	jsonStr, err := json.Marshal(submission)
-	if err != nil {
-		log.Println(err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "400")
+	// A package explicitly targeting a suite must have been prepared for it.
+	if submission.Suite != "" {
+		dist, err := changesDistribution(ss.storage.SubmissionDirPath(submission.TaskUUID))
+		if err != nil {
+			log.Println(err)
+			return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest, "could not read the changelog distribution")
+		}
+		if dist != submission.Suite {
+			return domain.Submission{}, domain.Suite{}, httputil.NewHTTPError(http.StatusBadRequest,
+				"suite "+submission.Suite+" does not match the changelog distribution "+dist)
+		}
 	}
 
-	if err := ss.taskQueue.SendBuildChain(submission.TaskUUID, jsonStr); err != nil {
+	return submission, suite, nil
+}
+
+// startPipeline queues the build pipeline of an accepted submission and
+// records its job.
+func (ss *SubmissionService) startPipeline(submission domain.Submission, suite domain.Suite) error {
+	events, err := ss.queueBuildPipeline(submission, suite)
+	if err != nil {
 		log.Printf("Could not send build chain: %v\n", err)
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, "500")
+		return httputil.NewHTTPError(http.StatusInternalServerError, "500")
 	}
 
 	if ss.jobStore != nil {
-		job := monitoring.JobInfo{
-			TaskUUID:       submission.TaskUUID,
-			PackageName:    submission.PackageName,
-			PackageVersion: submission.PackageVersion,
-			Maintainer:     submission.Maintainer,
-			Component:      submission.Component,
-			IsExperimental: submission.IsExperimental,
-			SubmittedAt:    submission.Timestamp,
-			State:          "PENDING",
-			PackageURL:     submission.PackageURL,
-			SourceURL:      submission.SourceURL,
-			PackageBranch:  submission.PackageBranch,
-			SourceBranch:   submission.SourceBranch,
-		}
-		if err := ss.jobStore.RecordJob(job); err != nil {
+		if err := ss.jobStore.RecordJob(pipelineJob(submission, suite, "PENDING")); err != nil {
 			log.Printf("Failed to record job: %v\n", err)
 		}
+		recordJobEvents(ss.jobStore, events...)
 	}
 
-	return domain.SubmitPayloadResponse{PipelineID: submission.TaskUUID}, nil
+	return nil
+}
+
+// pipelineJob returns the job of the build pipeline of an accepted
+// submission, in state.
+func pipelineJob(submission domain.Submission, suite domain.Suite, state string) monitoring.JobInfo {
+	job := monitoring.JobInfo{
+		TaskUUID:       submission.TaskUUID,
+		PackageName:    submission.PackageName,
+		PackageVersion: submission.PackageVersion,
+		Maintainer:     submission.Maintainer,
+		Component:      submission.Component,
+		IsExperimental: submission.IsExperimental,
+		SubmittedAt:    submission.Timestamp,
+		State:          state,
+		PackageURL:     submission.PackageURL,
+		SourceURL:      submission.SourceURL,
+		PackageBranch:  submission.PackageBranch,
+		SourceBranch:   submission.SourceBranch,
+		Architectures:  suite.Architectures,
+		Suite:          suite.Codename,
+		RunTests:       submission.RunTests,
+		BuilderLabel:   submission.BuilderLabel,
+		UpstreamOnly:   submission.UpstreamOnly,
+	}
+	if submission.BinNMU > 0 {
+		job.JobType = storage.JobTypeRebuild
+	}
+	if submission.CheckReproducibility {
+		job.Reproducibility = domain.ReproPending
+	}
+	return job
+}
+
+// removeSubmission deletes the files of an accepted submission whose
+// pipeline will never run.
+func (ss *SubmissionService) removeSubmission(taskUUID string) {
+	tarball := ss.storage.SubmissionTarballPath(taskUUID)
+	paths := []string{
+		ss.storage.SubmissionDirPath(taskUUID),
+		tarball,
+		tarball + chiefclient.ChecksumSuffix,
+		ss.storage.SubmissionSignaturePath(taskUUID),
+	}
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			log.Printf("Failed to remove %s: %v\n", path, err)
+		}
+	}
 }
 
 func (ss *SubmissionService) RetryPipeline(oldTaskUUID string) (domain.SubmitPayloadResponse, error) {
@@ -132,6 +444,18 @@ func (ss *SubmissionService) RetryPipeline(oldTaskUUID string) (domain.SubmitPay
 		log.Printf("Job not found for retry: %s: %v\n", oldTaskUUID, err)
 		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusNotFound, `{"error": "job not found"}`)
 	}
+	if job.JobType == storage.JobTypeRebuild {
+		// The job does not keep the rebuild number and reason
+		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, `{"error": "rebuilds cannot be retried, request a new rebuild"}`)
+	}
+
+	suite, err := ss.suite(job.Suite)
+	if err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
+	if err := ss.checkBuilders(suite, job.BuilderLabel); err != nil {
+		return domain.SubmitPayloadResponse{}, err
+	}
 
 	parts := strings.Split(oldTaskUUID, "_")
 	var maintainerFingerprint string
@@ -170,6 +494,9 @@ func (ss *SubmissionService) RetryPipeline(oldTaskUUID string) (domain.SubmitPay
 	if err := ss.storage.ChownWithSudo(newTarball); err != nil {
 		log.Printf("Failed to chown tarball: %v\n", err)
 	}
+	if err := writeFileChecksum(newTarball); err != nil {
+		log.Printf("Failed to write the checksum of %s: %v\n", newTarball, err)
+	}
 
 	if err := ss.storage.ChownRecursiveWithSudo(newDir); err != nil {
 		log.Printf("Failed to chown submission directory: %v\n", err)
@@ -190,36 +517,29 @@ func (ss *SubmissionService) RetryPipeline(oldTaskUUID string) (domain.SubmitPay
 		IsExperimental:        job.IsExperimental,
 		PackageBranch:         job.PackageBranch,
 		SourceBranch:          job.SourceBranch,
+		CheckReproducibility:  job.Reproducibility != "",
+		RunTests:              job.RunTests,
+		BuilderLabel:          job.BuilderLabel,
+		UpstreamOnly:          job.UpstreamOnly,
 	}
 
-	jsonStr, err := json.Marshal(submission)
+	events, err := ss.queueBuildPipeline(submission, suite)
 	if err != nil {
-		log.Println(err.Error())
-		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, `{"error": "failed to marshal submission"}`)
-	}
-
-	if err := ss.taskQueue.SendBuildChain(submission.TaskUUID, jsonStr); err != nil {
 		log.Printf("Could not send retry build chain: %v\n", err)
 		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusInternalServerError, `{"error": "failed to queue retry task"}`)
 	}
 
-	newJob := monitoring.JobInfo{
-		TaskUUID:       newTaskUUID,
-		PackageName:    job.PackageName,
-		PackageVersion: job.PackageVersion,
-		Maintainer:     job.Maintainer,
-		Component:      job.Component,
-		IsExperimental: job.IsExperimental,
-		SubmittedAt:    newTimestamp,
-		State:          "PENDING",
-		PackageURL:     job.PackageURL,
-		SourceURL:      job.SourceURL,
-		PackageBranch:  job.PackageBranch,
-		SourceBranch:   job.SourceBranch,
-	}
-	if err := ss.jobStore.RecordJob(newJob); err != nil {
+	if err := ss.jobStore.RecordJob(pipelineJob(submission, suite, "PENDING")); err != nil {
 		log.Printf("Failed to record retry job: %v\n", err)
 	}
+	recordJobEvents(ss.jobStore, events...)
+	recordJobEvents(ss.jobStore, monitoring.JobEvent{
+		TaskUUID: oldTaskUUID,
+		TaskID:   oldTaskUUID,
+		Stage:    domain.StagePipeline,
+		Event:    domain.EventRetried,
+		Detail:   "retried as " + newTaskUUID,
+	})
 
 	log.Printf("Job %s retried as new pipeline %s\n", oldTaskUUID, newTaskUUID)
 
@@ -237,7 +557,12 @@ func (ss *SubmissionService) BuildISO(submission domain.ISOSubmission) (domain.S
 	submission.Timestamp = time.Now()
 	submission.TaskUUID = submission.Timestamp.Format("2006-01-02-150405") + "_" + uuid.New().String() + "_iso"
 
-	jsonStr, err := json.Marshal(submission)
+	jsonStr, err := payload.Encode(&payload.ISO{
+		Header:    payload.Header{TaskUUID: submission.TaskUUID},
+		Timestamp: submission.Timestamp,
+		RepoURL:   submission.RepoURL,
+		Branch:    submission.Branch,
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return domain.SubmitPayloadResponse{}, httputil.NewHTTPError(http.StatusBadRequest, "400")
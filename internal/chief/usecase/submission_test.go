@@ -9,13 +9,14 @@ import (
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
 	"github.com/blankon/irgsh-go/internal/monitoring"
+	"github.com/blankon/irgsh-go/internal/payload"
 	"github.com/blankon/irgsh-go/pkg/httputil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func newTestSubmissionService(tq TaskQueue, fs FileStorage, gpg GPGVerifier, js JobStore, iso ISOJobStore) *SubmissionService {
-	return NewSubmissionService(tq, fs, gpg, js, iso)
+	return NewSubmissionService(tq, fs, gpg, js, iso, nil, []domain.Suite{{Codename: "verbeek", UpstreamCodename: "sid"}})
 }
 
 func TestSubmitPackage_ValidationErrors(t *testing.T) {
@@ -102,7 +103,7 @@ func TestSubmitPackage_QueueFailure(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".token"), []byte("sig"), 0644))
 
 	tq := &mockTaskQueue{
-		sendBuildChainFn: func(taskUUID string, payload []byte) error {
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
 			return errors.New("queue down")
 		},
 	}
@@ -149,7 +150,7 @@ func TestSubmitPackage_Success(t *testing.T) {
 
 	var queuedUUID string
 	tq := &mockTaskQueue{
-		sendBuildChainFn: func(taskUUID string, payload []byte) error {
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
 			queuedUUID = taskUUID
 			return nil
 		},
@@ -185,6 +186,340 @@ func TestSubmitPackage_Success(t *testing.T) {
 	assert.Equal(t, "PENDING", recordedJob.State)
 }
 
+func TestSubmitPackage_FansOutPerArchitecture(t *testing.T) {
+	tmpDir := t.TempDir()
+	tarballName := "test-tarball"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".tar.gz"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".token"), []byte("sig"), 0644))
+
+	var recordedJob monitoring.JobInfo
+	jobStore := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recordedJob = job
+			return nil
+		},
+	}
+
+	var queuedBuilds []domain.BuildTask
+	var queuedRepoPayload []byte
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			queuedBuilds = builds
+			queuedRepoPayload = repoPayload
+			return nil
+		},
+	}
+
+	storage := &mockFileStorage{
+		submissionsDir: tmpDir,
+		submissionTarballPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".tar.gz")
+		},
+		submissionDirPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID)
+		},
+		submissionSignaturePathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".sig")
+		},
+	}
+
+	svc := NewSubmissionService(tq, storage, &mockGPGVerifier{}, jobStore, nil, nil, []domain.Suite{
+		{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64", "arm64"},
+			LintianFailTags: map[string][]string{"main": {"no-copyright-file"}, "extras": {"binary-without-manpage"}}},
+	})
+
+	resp, err := svc.SubmitPackage(domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		PackageName:           "testpkg",
+		PackageVersion:        "1.0",
+		Component:             "main",
+		Tarball:               tarballName,
+		UpstreamOnly:          true,
+	})
+	require.NoError(t, err)
+
+	// The builders verify the tarball they download against its sidecar.
+	assert.FileExists(t, filepath.Join(tmpDir, resp.PipelineID+".tar.gz.sha256"))
+
+	require.Len(t, queuedBuilds, 2)
+	for i, arch := range []string{"amd64", "arm64"} {
+		build := queuedBuilds[i]
+		assert.Equal(t, arch, build.Architecture)
+		assert.Equal(t, resp.PipelineID+"."+arch, build.TaskUUID)
+		assert.Equal(t, "irgsh.build.sid."+arch, build.Queue)
+
+		var task payload.Build
+		require.NoError(t, payload.Decode(string(build.Payload), &task))
+		assert.Equal(t, payload.SchemaVersion, task.SchemaVersion)
+		assert.Equal(t, resp.PipelineID, task.TaskUUID)
+		assert.Equal(t, arch, task.Architecture)
+		assert.Equal(t, i == 0, task.BuildArchIndep)
+		assert.Equal(t, "verbeek", task.Suite)
+		assert.True(t, task.UpstreamOnly)
+		// The builders fail the build on the lintian tags of its component
+		assert.Equal(t, []string{"no-copyright-file"}, task.LintianFailTags)
+	}
+
+	var repoPayload payload.Build
+	require.NoError(t, payload.Decode(string(queuedRepoPayload), &repoPayload))
+	assert.Equal(t, []string{"amd64", "arm64"}, repoPayload.Architectures)
+	assert.Empty(t, repoPayload.Architecture)
+
+	assert.Equal(t, []string{"amd64", "arm64"}, recordedJob.Architectures)
+	assert.Equal(t, "verbeek", recordedJob.Suite)
+	assert.Empty(t, recordedJob.Reproducibility)
+}
+
+func TestSubmitPackage_CheckReproducibility(t *testing.T) {
+	tmpDir := t.TempDir()
+	tarballName := "test-tarball"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".tar.gz"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".token"), []byte("sig"), 0644))
+
+	var recordedJob monitoring.JobInfo
+	jobStore := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recordedJob = job
+			return nil
+		},
+	}
+	var chained, repros []domain.BuildTask
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			chained = builds
+			return nil
+		},
+		sendBuildTasksFn: func(builds []domain.BuildTask) error {
+			repros = builds
+			return nil
+		},
+	}
+	storage := &mockFileStorage{
+		submissionsDir: tmpDir,
+		submissionTarballPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".tar.gz")
+		},
+		submissionDirPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID)
+		},
+		submissionSignaturePathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".sig")
+		},
+	}
+	svc := NewSubmissionService(tq, storage, &mockGPGVerifier{}, jobStore, nil, nil, []domain.Suite{
+		{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64", "arm64"}},
+	})
+
+	resp, err := svc.SubmitPackage(domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		PackageName:           "testpkg",
+		PackageVersion:        "1.0",
+		Tarball:               tarballName,
+		CheckReproducibility:  true,
+	})
+	require.NoError(t, err)
+
+	// The second builds run the same builds outside of the chord
+	require.Len(t, chained, 2)
+	require.Len(t, repros, 2)
+	for i, arch := range []string{"amd64", "arm64"} {
+		repro := repros[i]
+		assert.Equal(t, resp.PipelineID+"."+arch+".repro", repro.TaskUUID)
+		assert.Equal(t, chained[i].Queue, repro.Queue)
+
+		var first, second payload.Build
+		require.NoError(t, payload.Decode(string(chained[i].Payload), &first))
+		require.NoError(t, payload.Decode(string(repro.Payload), &second))
+		assert.False(t, first.ReproCheck)
+		assert.True(t, second.ReproCheck)
+		second.ReproCheck = false
+		assert.Equal(t, first, second)
+	}
+	assert.Equal(t, domain.ReproPending, recordedJob.Reproducibility)
+}
+
+func TestSubmitPackage_RunTests(t *testing.T) {
+	tmpDir := t.TempDir()
+	tarballName := "test-tarball"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".tar.gz"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, tarballName+".token"), []byte("sig"), 0644))
+
+	var recordedJob monitoring.JobInfo
+	jobStore := &mockJobStore{
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recordedJob = job
+			return nil
+		},
+	}
+	var builds, tests []domain.BuildTask
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			t.Error("a tested pipeline has its repo task behind the test task")
+			return nil
+		},
+		sendTestedFn: func(taskUUID string, b, tt []domain.BuildTask, repoPayload []byte) error {
+			builds, tests = b, tt
+			return nil
+		},
+	}
+	storage := &mockFileStorage{
+		submissionsDir: tmpDir,
+		submissionTarballPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".tar.gz")
+		},
+		submissionDirPathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID)
+		},
+		submissionSignaturePathFn: func(taskUUID string) string {
+			return filepath.Join(tmpDir, taskUUID+".sig")
+		},
+	}
+	svc := NewSubmissionService(tq, storage, &mockGPGVerifier{}, jobStore, nil, nil, []domain.Suite{
+		{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64", "arm64"}},
+	})
+
+	resp, err := svc.SubmitPackage(domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		PackageName:           "testpkg",
+		PackageVersion:        "1.0",
+		Tarball:               tarballName,
+		RunTests:              true,
+	})
+	require.NoError(t, err)
+
+	// The packages of each architecture are tested on its builders
+	require.Len(t, builds, 2)
+	require.Len(t, tests, 2)
+	for i, arch := range []string{"amd64", "arm64"} {
+		assert.Equal(t, resp.PipelineID+"."+arch+".test", tests[i].TaskUUID)
+		assert.Equal(t, arch, tests[i].Architecture)
+		assert.Equal(t, "irgsh.build.sid."+arch, tests[i].Queue)
+
+		var task payload.Build
+		require.NoError(t, payload.Decode(string(tests[i].Payload), &task))
+		assert.Equal(t, resp.PipelineID, task.TaskUUID)
+		assert.Equal(t, arch, task.Architecture)
+		assert.Equal(t, []string{"amd64", "arm64"}, task.Architectures)
+	}
+
+	assert.True(t, recordedJob.RunTests)
+}
+
+func TestSubmitPackage_Suite(t *testing.T) {
+	suites := []domain.Suite{
+		{Codename: "verbeek", UpstreamCodename: "sid"},
+		{Codename: "uluwatu", UpstreamCodename: "bookworm"},
+	}
+
+	tests := []struct {
+		name      string
+		suite     string
+		changes   string
+		wantCode  int
+		wantQueue string
+	}{
+		{"unknown suite", "tambora", "Distribution: tambora\n", http.StatusBadRequest, ""},
+		{"changelog mismatch", "uluwatu", "Distribution: verbeek\n", http.StatusBadRequest, ""},
+		{"matching suite", "uluwatu", "Format: 1.8\nDistribution: uluwatu\n", 0, "irgsh.build.bookworm.amd64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tarball.tar.gz"), []byte("data"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "tarball.token"), []byte("sig"), 0644))
+
+			var queuedBuilds []domain.BuildTask
+			tq := &mockTaskQueue{
+				sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+					queuedBuilds = builds
+					return nil
+				},
+			}
+			storage := &mockFileStorage{
+				submissionsDir: tmpDir,
+				extractSubmissionFn: func(taskUUID string) error {
+					signed := filepath.Join(tmpDir, taskUUID, "signed")
+					if err := os.MkdirAll(signed, 0755); err != nil {
+						return err
+					}
+					return os.WriteFile(filepath.Join(signed, "pkg_1.0_source.changes"), []byte(tt.changes), 0644)
+				},
+			}
+
+			svc := NewSubmissionService(tq, storage, &mockGPGVerifier{}, nil, nil, nil, suites)
+			_, err := svc.SubmitPackage(domain.Submission{
+				MaintainerFingerprint: "ABCDEF1234567890",
+				PackageName:           "testpkg",
+				Tarball:               "tarball",
+				Suite:                 tt.suite,
+			})
+
+			if tt.wantCode != 0 {
+				require.Error(t, err)
+				var httpErr httputil.HTTPError
+				require.True(t, errors.As(err, &httpErr))
+				assert.Equal(t, tt.wantCode, httpErr.Code)
+				assert.Empty(t, queuedBuilds)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, queuedBuilds, 1)
+			assert.Equal(t, tt.wantQueue, queuedBuilds[0].Queue)
+		})
+	}
+}
+
+func TestSubmitPackage_RequiresMatchingBuilder(t *testing.T) {
+	builders := &mockInstanceRegistry{
+		listInstancesFn: func(instanceType monitoring.InstanceType, status monitoring.InstanceStatus) ([]*monitoring.InstanceInfo, error) {
+			assert.Equal(t, monitoring.InstanceTypeBuilder, instanceType)
+			assert.Equal(t, monitoring.StatusOnline, status)
+			return []*monitoring.InstanceInfo{
+				{Capabilities: monitoring.Capabilities{Architectures: []string{"amd64"}, DistCodename: "sid"}},
+				{Capabilities: monitoring.Capabilities{Architectures: []string{"arm64"}, DistCodename: "bookworm"}},
+			}, nil
+		},
+	}
+	queued := false
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			queued = true
+			return nil
+		},
+	}
+	submission := domain.Submission{
+		MaintainerFingerprint: "ABCDEF1234567890",
+		PackageName:           "testpkg",
+		Tarball:               "tarball",
+	}
+
+	tests := []struct {
+		name  string
+		archs []string
+		label string
+		want  string
+	}{
+		{"arch without builder", []string{"amd64", "arm64"}, "", "no online builder for sid/arm64"},
+		{"label without builder", []string{"amd64"}, "big-memory", "no online builder for sid/amd64 with label big-memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suites := []domain.Suite{{Codename: "verbeek", UpstreamCodename: "sid", Architectures: tt.archs}}
+			svc := NewSubmissionService(tq, &mockFileStorage{}, &mockGPGVerifier{}, nil, nil, builders, suites)
+			sub := submission
+			sub.BuilderLabel = tt.label
+			_, err := svc.SubmitPackage(sub)
+			require.Error(t, err)
+			var httpErr httputil.HTTPError
+			require.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+			assert.Equal(t, tt.want, httpErr.Message)
+		})
+	}
+	assert.False(t, queued)
+}
+
 func TestRetryPipeline_ValidationErrors(t *testing.T) {
 	svc := newTestSubmissionService(&mockTaskQueue{}, &mockFileStorage{}, &mockGPGVerifier{}, &mockJobStore{}, nil)
 
@@ -241,6 +576,58 @@ func TestRetryPipeline_MissingTarball(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, httpErr.Code)
 }
 
+func TestRetryPipeline_KeepsSubmissionOptions(t *testing.T) {
+	oldUUID := "2024-01-01-120000_uuid_FINGERPRINT_testpkg"
+	submissionsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(submissionsDir, oldUUID+".tar.gz"), []byte("tarball"), 0644))
+
+	var recorded monitoring.JobInfo
+	js := &mockJobStore{
+		getJobFn: func(taskUUID string) (*monitoring.JobInfo, error) {
+			return &monitoring.JobInfo{
+				TaskUUID:     taskUUID,
+				PackageName:  "testpkg",
+				Suite:        "verbeek",
+				BuilderLabel: "big-memory",
+				UpstreamOnly: true,
+			}, nil
+		},
+		recordJobFn: func(job monitoring.JobInfo) error {
+			recorded = job
+			return nil
+		},
+	}
+	// Only a labeled builder is online
+	builders := &mockInstanceRegistry{
+		listInstancesFn: func(instanceType monitoring.InstanceType, status monitoring.InstanceStatus) ([]*monitoring.InstanceInfo, error) {
+			return []*monitoring.InstanceInfo{
+				{Capabilities: monitoring.Capabilities{Architectures: []string{"amd64"}, DistCodename: "sid", Labels: []string{"big-memory"}}},
+			}, nil
+		},
+	}
+	var queuedBuilds []domain.BuildTask
+	tq := &mockTaskQueue{
+		sendBuildChainFn: func(taskUUID string, builds []domain.BuildTask, repoPayload []byte) error {
+			queuedBuilds = builds
+			return nil
+		},
+	}
+	suites := []domain.Suite{{Codename: "verbeek", UpstreamCodename: "sid", Architectures: []string{"amd64"}}}
+	svc := NewSubmissionService(tq, &mockFileStorage{submissionsDir: submissionsDir}, &mockGPGVerifier{}, js, nil, builders, suites)
+
+	res, err := svc.RetryPipeline(oldUUID)
+	require.NoError(t, err)
+	require.Len(t, queuedBuilds, 1)
+	assert.Equal(t, "irgsh.build.sid.amd64.big-memory", queuedBuilds[0].Queue)
+	var build payload.Build
+	require.NoError(t, payload.Decode(string(queuedBuilds[0].Payload), &build))
+	assert.Equal(t, "big-memory", build.BuilderLabel)
+	assert.True(t, build.UpstreamOnly)
+	assert.Equal(t, res.PipelineID, recorded.TaskUUID)
+	assert.Equal(t, "big-memory", recorded.BuilderLabel)
+	assert.True(t, recorded.UpstreamOnly)
+}
+
 func TestBuildISO_ValidationErrors(t *testing.T) {
 	svc := newTestSubmissionService(&mockTaskQueue{}, &mockFileStorage{}, &mockGPGVerifier{}, nil, nil)
 
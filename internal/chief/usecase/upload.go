@@ -1,6 +1,8 @@
 package usecase
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"log"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/chiefclient"
 	"github.com/blankon/irgsh-go/pkg/httputil"
 )
 
@@ -24,7 +27,10 @@ func NewUploadService(storage FileStorage, gpg GPGVerifier) *UploadService {
 	return &UploadService{storage: storage, gpg: gpg}
 }
 
-func (u *UploadService) UploadArtifact(id string, file io.Reader) error {
+// UploadArtifact stores the build artifact id along with its checksum
+// sidecar. When the uploader sent a checksum, the artifact is rejected if it
+// does not match.
+func (u *UploadService) UploadArtifact(id string, file io.Reader, checksum string) error {
 	if !domain.SafeIDPattern.MatchString(id) {
 		return httputil.NewHTTPError(http.StatusBadRequest, "invalid artifact id")
 	}
@@ -63,13 +69,29 @@ func (u *UploadService) UploadArtifact(id string, file io.Reader) error {
 		return httputil.NewHTTPError(http.StatusBadRequest, "")
 	}
 
-	if _, err := newFile.Write(header); err != nil {
+	h := sha256.New()
+	w := io.MultiWriter(newFile, h)
+	if _, err := w.Write(header); err != nil {
 		log.Println(err.Error())
 		os.Remove(newPath)
 		return httputil.NewHTTPError(http.StatusInternalServerError, "")
 	}
 
-	if _, err := io.Copy(newFile, file); err != nil {
+	if _, err := io.Copy(w, file); err != nil {
+		log.Println(err.Error())
+		os.Remove(newPath)
+		return httputil.NewHTTPError(http.StatusInternalServerError, "")
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if checksum != "" && !strings.EqualFold(checksum, sum) {
+		log.Printf("File upload rejected: checksum of %s is %s, expected %s\n", fileName, sum, checksum)
+		os.Remove(newPath)
+		os.Remove(newPath + chiefclient.ChecksumSuffix)
+		return httputil.NewHTTPError(http.StatusBadRequest, "checksum mismatch")
+	}
+
+	if err := writeChecksum(newPath, sum); err != nil {
 		log.Println(err.Error())
 		os.Remove(newPath)
 		return httputil.NewHTTPError(http.StatusInternalServerError, "")
@@ -197,3 +219,34 @@ func (u *UploadService) UploadSubmission(tokenData []byte, blob io.Reader) (stri
 
 	return id, nil
 }
+
+// writeChecksum writes the sha256sum(1) style checksum sidecar of the file
+// at path, which the workers verify their downloads against.
+func writeChecksum(path, sum string) error {
+	line := sum + "  " + filepath.Base(path) + "\n"
+	return os.WriteFile(path+chiefclient.ChecksumSuffix, []byte(line), 0644)
+}
+
+// writeFileChecksum computes the checksum of the file at path and writes its
+// sidecar.
+func writeFileChecksum(path string) error {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	return writeChecksum(path, sum)
+}
+
+// fileSHA256 returns the hex encoded sha256 checksum of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
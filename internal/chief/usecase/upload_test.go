@@ -3,10 +3,13 @@ package usecase
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/blankon/irgsh-go/pkg/httputil"
@@ -26,7 +29,7 @@ func gzipBytes(t *testing.T, data []byte) []byte {
 
 func TestUploadArtifact_InvalidID(t *testing.T) {
 	svc := NewUploadService(&mockFileStorage{artifactsDir: t.TempDir()}, &mockGPGVerifier{})
-	err := svc.UploadArtifact("../bad", bytes.NewReader(nil))
+	err := svc.UploadArtifact("../bad", bytes.NewReader(nil), "")
 	require.Error(t, err)
 	var httpErr httputil.HTTPError
 	require.True(t, errors.As(err, &httpErr))
@@ -38,7 +41,7 @@ func TestUploadArtifact_InvalidContentType(t *testing.T) {
 	svc := NewUploadService(&mockFileStorage{artifactsDir: dir}, &mockGPGVerifier{})
 
 	// Plain text is not gzip
-	err := svc.UploadArtifact("valid-id", bytes.NewReader([]byte("not a gzip file")))
+	err := svc.UploadArtifact("valid-id", bytes.NewReader([]byte("not a gzip file")), "")
 	require.Error(t, err)
 	var httpErr httputil.HTTPError
 	require.True(t, errors.As(err, &httpErr))
@@ -54,12 +57,34 @@ func TestUploadArtifact_Success(t *testing.T) {
 	svc := NewUploadService(&mockFileStorage{artifactsDir: dir}, &mockGPGVerifier{})
 
 	content := gzipBytes(t, []byte("hello world"))
-	err := svc.UploadArtifact("my-artifact", bytes.NewReader(content))
+	err := svc.UploadArtifact("my-artifact", bytes.NewReader(content), "")
 	require.NoError(t, err)
 
 	written, err := os.ReadFile(filepath.Join(dir, "my-artifact.tar.gz"))
 	require.NoError(t, err)
 	assert.Equal(t, content, written)
+
+	sum := sha256.Sum256(content)
+	sidecar, err := os.ReadFile(filepath.Join(dir, "my-artifact.tar.gz.sha256"))
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:])+"  my-artifact.tar.gz\n", string(sidecar))
+}
+
+func TestUploadArtifact_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewUploadService(&mockFileStorage{artifactsDir: dir}, &mockGPGVerifier{})
+
+	content := gzipBytes(t, []byte("hello world"))
+	sum := sha256.Sum256(content)
+	require.NoError(t, svc.UploadArtifact("my-artifact", bytes.NewReader(content), strings.ToUpper(hex.EncodeToString(sum[:]))))
+
+	err := svc.UploadArtifact("my-artifact", bytes.NewReader(content), "0000")
+	require.Error(t, err)
+	var httpErr httputil.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, "checksum mismatch", httpErr.Message)
+	assert.NoFileExists(t, filepath.Join(dir, "my-artifact.tar.gz"))
 }
 
 func TestUploadLog_InvalidID(t *testing.T) {
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+// taskKinds are the kinds of task a worker can claim. They match the log
+// types the workers upload.
+var taskKinds = map[string]bool{"build": true, "lintian": true, "test": true, "repo": true, "iso": true}
+
+// WorkerAuthService authenticates workers by their token and makes sure
+// only the worker that claimed a task uploads its artifacts and logs.
+// Without configured workers, every worker request is let through.
+type WorkerAuthService struct {
+	workers      []config.WorkerCredential
+	store        WorkerStore
+	claimTimeout time.Duration
+	now          func() time.Time
+}
+
+// NewWorkerAuthService creates the service. A claim older than claimTimeout
+// may be taken over by another worker.
+func NewWorkerAuthService(workers []config.WorkerCredential, store WorkerStore, claimTimeout time.Duration) *WorkerAuthService {
+	if len(workers) == 0 {
+		log.Println("WARNING: no workers are listed in chief.workers, worker requests are NOT authenticated")
+	}
+	return &WorkerAuthService{workers: workers, store: store, claimTimeout: claimTimeout, now: time.Now}
+}
+
+func (s *WorkerAuthService) enabled() bool {
+	return len(s.workers) > 0
+}
+
+// authenticate returns the worker token belongs to.
+func (s *WorkerAuthService) authenticate(token string) (config.WorkerCredential, bool) {
+	var worker config.WorkerCredential
+	found := false
+	for _, w := range s.workers {
+		// Compare against every worker so the time taken does not tell
+		// which one matched.
+		if subtle.ConstantTimeCompare([]byte(token), []byte(w.Token)) == 1 {
+			worker, found = w, true
+		}
+	}
+	return worker, found && token != ""
+}
+
+// ClaimTask records that the worker owning token took the kind task of id.
+// Claiming a task again is fine for the worker holding it, and refused for
+// any other until the claim is older than the claim timeout. Workers only
+// claim the kinds of task their role allows.
+func (s *WorkerAuthService) ClaimTask(token, id, kind, remoteAddr string) error {
+	if err := checkTask(id, kind); err != nil {
+		return err
+	}
+	if !s.enabled() {
+		return nil
+	}
+
+	attempt := storage.UploadRejection{TaskID: id, Kind: kind, RemoteAddr: remoteAddr}
+	worker, ok := s.authenticate(token)
+	if !ok {
+		return s.reject(attempt, http.StatusUnauthorized, "invalid worker token")
+	}
+	attempt.Worker = worker.Name
+	if !worker.MayClaim(kind) {
+		return s.reject(attempt, http.StatusForbidden, fmt.Sprintf("%s workers may not claim %s tasks", worker.Role, kind))
+	}
+
+	now := s.now().UTC()
+	holder, err := s.store.ClaimTask(storage.TaskClaim{
+		TaskID:    id,
+		Kind:      kind,
+		Worker:    worker.Name,
+		ClaimedAt: now,
+	}, now.Add(-s.claimTimeout))
+	if err != nil {
+		log.Println(err.Error())
+		return httputil.NewHTTPError(http.StatusInternalServerError, "")
+	}
+	if holder.Worker != worker.Name {
+		return s.reject(attempt, http.StatusConflict, "task claimed by "+holder.Worker)
+	}
+	return nil
+}
+
+// AuthorizeUpload checks that the worker owning token claimed the kind task
+// of id before it uploads an artifact or a log for it.
+func (s *WorkerAuthService) AuthorizeUpload(token, id, kind, remoteAddr string) error {
+	return s.authorizeClaimed(token, id, kind, remoteAddr, false)
+}
+
+// AuthorizeEvent checks that the worker owning token may report event on
+// the task it is about: a task it claimed, or the failure of a task nobody
+// claimed, which is how a worker whose claim was refused reports it.
+func (s *WorkerAuthService) AuthorizeEvent(token string, event domain.TaskEvent, remoteAddr string) error {
+	return s.authorizeClaimed(token, event.TaskID, event.Stage, remoteAddr, event.Event == domain.EventFailed)
+}
+
+// authorizeClaimed checks that the worker owning token holds the claim on
+// the kind task of id, or that nobody does when unclaimed is true.
+func (s *WorkerAuthService) authorizeClaimed(token, id, kind, remoteAddr string, unclaimed bool) error {
+	if err := checkTask(id, kind); err != nil {
+		return err
+	}
+	if !s.enabled() {
+		return nil
+	}
+
+	attempt := storage.UploadRejection{TaskID: id, Kind: kind, RemoteAddr: remoteAddr}
+	worker, ok := s.authenticate(token)
+	if !ok {
+		return s.reject(attempt, http.StatusUnauthorized, "invalid worker token")
+	}
+	attempt.Worker = worker.Name
+
+	claim, err := s.store.GetTaskClaim(id, kind)
+	if err != nil {
+		log.Println(err.Error())
+		return httputil.NewHTTPError(http.StatusInternalServerError, "")
+	}
+	if claim == nil {
+		if unclaimed {
+			return nil
+		}
+		return s.reject(attempt, http.StatusForbidden, "task not claimed")
+	}
+	if claim.Worker != worker.Name {
+		return s.reject(attempt, http.StatusForbidden, "task claimed by "+claim.Worker)
+	}
+	return nil
+}
+
+// reject logs and records a refused worker request, and returns the error
+// answering it.
+func (s *WorkerAuthService) reject(attempt storage.UploadRejection, code int, reason string) error {
+	attempt.Reason = reason
+	attempt.RejectedAt = s.now().UTC()
+	worker := attempt.Worker
+	if worker == "" {
+		worker = "unauthenticated worker"
+	}
+	log.Printf("Rejected %s request for %s from %s (%s): %s\n", attempt.Kind, attempt.TaskID, worker, attempt.RemoteAddr, reason)
+	if err := s.store.RecordRejection(attempt); err != nil {
+		log.Printf("Failed to record rejection: %v\n", err)
+	}
+	return httputil.NewHTTPError(code, reason)
+}
+
+func checkTask(id, kind string) error {
+	if !domain.SafeIDPattern.MatchString(id) {
+		return httputil.NewHTTPError(http.StatusBadRequest, "invalid task id")
+	}
+	if !taskKinds[kind] {
+		return httputil.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid task kind %q", kind))
+	}
+	return nil
+}
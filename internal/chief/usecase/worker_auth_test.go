@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/chief/domain"
+	"github.com/blankon/irgsh-go/internal/config"
+	"github.com/blankon/irgsh-go/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testWorkers = []config.WorkerCredential{
+	{Name: "builder-1", Token: "token-1", Role: "builder"},
+	{Name: "builder-2", Token: "token-2", Role: "builder"},
+	{Name: "repo-1", Token: "token-4", Role: "repo"},
+}
+
+// claimingStore returns a mockWorkerStore keeping claims in memory and
+// collecting rejections.
+func claimingStore(rejections *[]storage.UploadRejection) *mockWorkerStore {
+	claims := map[string]*storage.TaskClaim{}
+	return &mockWorkerStore{
+		claimTaskFn: func(claim storage.TaskClaim, staleBefore time.Time) (*storage.TaskClaim, error) {
+			key := claim.TaskID + "/" + claim.Kind
+			held := claims[key]
+			if held == nil || held.Worker == claim.Worker || held.ClaimedAt.Before(staleBefore) {
+				claims[key] = &claim
+			}
+			return claims[key], nil
+		},
+		getTaskClaimFn: func(taskID, kind string) (*storage.TaskClaim, error) {
+			return claims[taskID+"/"+kind], nil
+		},
+		recordRejectionFn: func(r storage.UploadRejection) error {
+			*rejections = append(*rejections, r)
+			return nil
+		},
+	}
+}
+
+func TestWorkerAuth_ClaimAndUpload(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+
+	require.NoError(t, svc.ClaimTask("token-1", "task.amd64", "build", "10.0.0.1:1234"))
+	// Claiming again is fine for the same worker
+	require.NoError(t, svc.ClaimTask("token-1", "task.amd64", "build", "10.0.0.1:1234"))
+	require.NoError(t, svc.AuthorizeUpload("token-1", "task.amd64", "build", "10.0.0.1:1234"))
+	assert.Empty(t, rejections)
+
+	err := svc.ClaimTask("token-2", "task.amd64", "build", "10.0.0.2:1234")
+	requireHTTPError(t, err, http.StatusConflict)
+
+	err = svc.AuthorizeUpload("token-2", "task.amd64", "build", "10.0.0.2:1234")
+	httpErr := requireHTTPError(t, err, http.StatusForbidden)
+	assert.Equal(t, "task claimed by builder-1", httpErr.Message)
+
+	err = svc.AuthorizeUpload("token-2", "task.arm64", "build", "10.0.0.2:1234")
+	httpErr = requireHTTPError(t, err, http.StatusForbidden)
+	assert.Equal(t, "task not claimed", httpErr.Message)
+
+	require.Len(t, rejections, 3)
+	assert.Equal(t, "builder-2", rejections[0].Worker)
+	assert.Equal(t, "task claimed by builder-1", rejections[0].Reason)
+	assert.Equal(t, "10.0.0.2:1234", rejections[1].RemoteAddr)
+	assert.Equal(t, "task.arm64", rejections[2].TaskID)
+	assert.False(t, rejections[2].RejectedAt.IsZero())
+}
+
+func TestWorkerAuth_AuthorizeEvent(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+	event := func(id, name string) domain.TaskEvent {
+		return domain.TaskEvent{TaskID: id, Stage: "build", Event: name}
+	}
+
+	require.NoError(t, svc.ClaimTask("token-1", "task.amd64", "build", "10.0.0.1:1234"))
+	require.NoError(t, svc.AuthorizeEvent("token-1", event("task.amd64", domain.EventStarted), "10.0.0.1:1234"))
+
+	// A worker whose claim was refused reports the failure of the task,
+	// unless another worker runs it
+	require.NoError(t, svc.AuthorizeEvent("token-2", event("task.arm64", domain.EventFailed), "10.0.0.2:1234"))
+	requireHTTPError(t, svc.AuthorizeEvent("token-2", event("task.arm64", domain.EventStarted), "10.0.0.2:1234"), http.StatusForbidden)
+	requireHTTPError(t, svc.AuthorizeEvent("token-2", event("task.amd64", domain.EventFailed), "10.0.0.2:1234"), http.StatusForbidden)
+	requireHTTPError(t, svc.AuthorizeEvent("token-3", event("task.arm64", domain.EventFailed), "10.0.0.3:1234"), http.StatusUnauthorized)
+	assert.Len(t, rejections, 3)
+}
+
+func TestWorkerAuth_ClaimTakenOverOnceStale(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	require.NoError(t, svc.ClaimTask("token-1", "task.amd64", "build", "10.0.0.1:1234"))
+	requireHTTPError(t, svc.ClaimTask("token-2", "task.amd64", "build", "10.0.0.2:1234"), http.StatusConflict)
+
+	// builder-1 died and the task was redelivered to builder-2
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, svc.ClaimTask("token-2", "task.amd64", "build", "10.0.0.2:1234"))
+	require.NoError(t, svc.AuthorizeUpload("token-2", "task.amd64", "build", "10.0.0.2:1234"))
+	requireHTTPError(t, svc.AuthorizeUpload("token-1", "task.amd64", "build", "10.0.0.1:1234"), http.StatusForbidden)
+}
+
+func TestWorkerAuth_ClaimRestrictedByRole(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+
+	for _, kind := range []string{"repo", "iso"} {
+		err := svc.ClaimTask("token-1", "task", kind, "10.0.0.1:1234")
+		httpErr := requireHTTPError(t, err, http.StatusForbidden)
+		assert.Equal(t, "builder workers may not claim "+kind+" tasks", httpErr.Message)
+	}
+	requireHTTPError(t, svc.ClaimTask("token-4", "task.amd64", "build", "10.0.0.4:1234"), http.StatusForbidden)
+	require.NoError(t, svc.ClaimTask("token-4", "task", "repo", "10.0.0.4:1234"))
+
+	require.Len(t, rejections, 3)
+	assert.Equal(t, "repo-1", rejections[2].Worker)
+}
+
+func TestWorkerAuth_InvalidToken(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+
+	for _, token := range []string{"", "token-3"} {
+		err := svc.ClaimTask(token, "task", "repo", "10.0.0.3:1234")
+		requireHTTPError(t, err, http.StatusUnauthorized)
+		err = svc.AuthorizeUpload(token, "task", "repo", "10.0.0.3:1234")
+		requireHTTPError(t, err, http.StatusUnauthorized)
+	}
+
+	require.Len(t, rejections, 4)
+	assert.Empty(t, rejections[0].Worker)
+	assert.Equal(t, "invalid worker token", rejections[0].Reason)
+}
+
+func TestWorkerAuth_InvalidTask(t *testing.T) {
+	var rejections []storage.UploadRejection
+	svc := NewWorkerAuthService(testWorkers, claimingStore(&rejections), time.Hour)
+
+	requireHTTPError(t, svc.ClaimTask("token-1", "../task", "build", ""), http.StatusBadRequest)
+	requireHTTPError(t, svc.AuthorizeUpload("token-1", "task", "promote", ""), http.StatusBadRequest)
+	assert.Empty(t, rejections)
+}
+
+func TestWorkerAuth_Disabled(t *testing.T) {
+	svc := NewWorkerAuthService(nil, nil, time.Hour)
+
+	assert.NoError(t, svc.ClaimTask("", "task", "build", ""))
+	assert.NoError(t, svc.AuthorizeUpload("", "task", "build", ""))
+	assert.NoError(t, svc.AuthorizeUpload("anything", "task", "iso", ""))
+}
@@ -0,0 +1,373 @@
+// Package chiefclient is the HTTP client the workers use to claim their
+// tasks, report their events and exchange submissions, artifacts and logs
+// with chief.
+//
+// Transfers are retried with an exponential backoff and streamed from and to
+// disk. Every file chief serves to the workers has a SHA-256 sidecar next to
+// it, named after the file with ChecksumSuffix appended, which downloads are
+// verified against. Uploaded artifacts carry their digest in ChecksumHeader
+// so chief can verify them in turn.
+package chiefclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+const (
+	// ChecksumHeader carries the hex SHA-256 digest of an uploaded file.
+	ChecksumHeader = "X-Checksum-Sha256"
+	// ChecksumSuffix is appended to the name of a file served by chief to
+	// get its checksum sidecar, in the format of sha256sum(1).
+	ChecksumSuffix = ".sha256"
+
+	defaultAttempts = 5
+	defaultBackoff  = 2 * time.Second
+)
+
+// ErrChecksumMismatch is returned when a downloaded file does not match its
+// checksum sidecar.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Client talks to chief on behalf of a worker.
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+	attempts   int
+	backoff    time.Duration
+}
+
+// New returns a client for the chief at address. Requests carry token as a
+// bearer token when it is not empty.
+func New(address, token string) *Client {
+	return &Client{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		httpClient: &http.Client{},
+		attempts:   defaultAttempts,
+		backoff:    defaultBackoff,
+	}
+}
+
+// Claim tells chief this worker took the kind task of id: "build", "repo"
+// or "iso". Chief only accepts the artifacts and logs of a task from the
+// worker that claimed it.
+func (c *Client) Claim(ctx context.Context, id, kind string) error {
+	query := url.Values{"id": {id}, "kind": {kind}}
+	return c.retry(ctx, "claim of "+kind+" task "+id, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"/api/v1/claim?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil
+	})
+}
+
+// Events a worker reports on the tasks it claimed.
+const (
+	EventStarted   = "started"
+	EventDone      = "done"
+	EventFailed    = "failed"
+	EventCancelled = "cancelled"
+	EventTimeout   = "timeout"
+)
+
+// TaskEvent is an event of a task the worker claimed. TaskID and Stage are
+// the id and the kind of the claim. The events ending a task carry the time
+// it ran for, and why it failed in Detail.
+type TaskEvent struct {
+	TaskID          string  `json:"taskId"`
+	Stage           string  `json:"stage"`
+	Architecture    string  `json:"architecture,omitempty"`
+	Event           string  `json:"event"`
+	Instance        string  `json:"instance,omitempty"`
+	Detail          string  `json:"detail,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+// ReportEvent records event in the history of the pipeline taskUUID, which
+// chief derives the state of the pipeline from. Chief only accepts the
+// events of a task from the worker that claimed it.
+func (c *Client) ReportEvent(ctx context.Context, taskUUID string, event TaskEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.retry(ctx, event.Event+" event of "+event.Stage+" task "+event.TaskID, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"/api/v1/jobs/"+url.PathEscape(taskUUID)+"/events", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil
+	})
+}
+
+// TaskInstance returns the worker instance that last started the task id of
+// the pipeline taskUUID, according to the events chief recorded. It is
+// empty while no worker started the task.
+func (c *Client) TaskInstance(ctx context.Context, taskUUID, id string) (string, error) {
+	var instance string
+	err := c.retry(ctx, "lookup of task "+id, func() error {
+		resp, err := c.get(ctx, "/api/v1/jobs/"+url.PathEscape(taskUUID)+"/events")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		var events struct {
+			Events []TaskEvent `json:"events"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+			return err
+		}
+		instance = ""
+		for _, event := range events.Events {
+			if event.TaskID == id && event.Event == EventStarted {
+				instance = event.Instance
+			}
+		}
+		return nil
+	})
+	return instance, err
+}
+
+// DownloadSubmission downloads the submission tarball of a pipeline to dest.
+func (c *Client) DownloadSubmission(ctx context.Context, taskUUID, dest string) error {
+	return c.download(ctx, "/submissions/"+url.PathEscape(taskUUID)+".tar.gz", dest)
+}
+
+// DownloadArtifact downloads the build artifact id to dest.
+func (c *Client) DownloadArtifact(ctx context.Context, id, dest string) error {
+	return c.download(ctx, "/artifacts/"+url.PathEscape(id)+".tar.gz", dest)
+}
+
+// UploadArtifact uploads the build artifact tarball at path as id.
+func (c *Client) UploadArtifact(ctx context.Context, id, path string) error {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"id": {id}}
+	return c.upload(ctx, "/api/v1/artifact-upload?"+query.Encode(), path, sum)
+}
+
+// UploadLog uploads the log at path as the logType log of id.
+func (c *Client) UploadLog(ctx context.Context, id, logType, path string) error {
+	query := url.Values{"id": {id}, "type": {logType}}
+	return c.upload(ctx, "/api/v1/log-upload?"+query.Encode(), path, "")
+}
+
+func (c *Client) download(ctx context.Context, path, dest string) error {
+	return c.retry(ctx, "download of "+path, func() error {
+		expected, err := c.fetchChecksum(ctx, path+ChecksumSuffix)
+		if err != nil {
+			return err
+		}
+		return c.fetch(ctx, path, dest, expected)
+	})
+}
+
+// fetchChecksum returns the digest in the checksum sidecar at path. Files
+// stored before chief wrote sidecars have none, which is not an error.
+func (c *Client) fetchChecksum(ctx context.Context, path string) (string, error) {
+	resp, err := c.get(ctx, path)
+	var statusErr httputil.HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file %s", path)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// fetch streams the file at path into dest. The file is written next to
+// dest first, and only moved in place once its checksum has been verified.
+func (c *Client) fetch(ctx context.Context, path, dest, expected string) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	partial := dest + ".part"
+	f, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if expected != "" {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+			return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, path, expected, actual)
+		}
+	}
+	return os.Rename(partial, dest)
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.address+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// upload streams the file at path as the uploadFile field of a multipart
+// form, without buffering it in memory.
+func (c *Client) upload(ctx context.Context, path, file, sum string) error {
+	return c.retry(ctx, "upload of "+file, func() error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		pr, pw := io.Pipe()
+		form := multipart.NewWriter(pw)
+		go func() {
+			part, err := form.CreateFormFile("uploadFile", filepath.Base(file))
+			if err == nil {
+				_, err = io.Copy(part, f)
+			}
+			if err == nil {
+				err = form.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+path, pr)
+		if err != nil {
+			pr.Close()
+			return err
+		}
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		if sum != "" {
+			req.Header.Set(ChecksumHeader, sum)
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil
+	})
+}
+
+// do sends req and turns non-2xx responses into an
+// httputil.HTTPStatusError.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, httputil.HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return resp, nil
+}
+
+// retry runs fn until it succeeds, fails permanently or runs out of
+// attempts, doubling the delay between attempts.
+func (c *Client) retry(ctx context.Context, what string, fn func() error) error {
+	delay := c.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) || attempt >= c.attempts {
+			if err != nil {
+				err = fmt.Errorf("%s failed: %w", what, err)
+			}
+			return err
+		}
+		log.Printf("%s failed (attempt %d/%d), retrying in %s: %v\n", what, attempt, c.attempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("%s failed: %w", what, ctx.Err())
+		}
+		delay *= 2
+	}
+}
+
+// retryable reports whether err might go away on another attempt: transport
+// errors, server errors and corrupted transfers are, while client errors and
+// local file errors are not.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr httputil.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var pathErr *fs.PathError
+	return !errors.As(err, &pathErr)
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,270 @@
+package chiefclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/pkg/httputil"
+)
+
+func testClient(address string) *Client {
+	c := New(address, "secret")
+	c.attempts = 3
+	c.backoff = time.Millisecond
+	return c
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	content := []byte("artifact")
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifacts/task.amd64.tar.gz.sha256":
+			io.WriteString(w, checksum(content)+"  task.amd64.tar.gz\n")
+		case "/artifacts/task.amd64.tar.gz":
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "task.amd64.tar.gz")
+	require.NoError(t, testClient(srv.URL).DownloadArtifact(context.Background(), "task.amd64", dest))
+
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.NoFileExists(t, dest+".part")
+}
+
+func TestDownloadSubmission_WithoutChecksum(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/submissions/task.tar.gz" {
+			io.WriteString(w, "submission")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "debuild.tar.gz")
+	require.NoError(t, testClient(srv.URL).DownloadSubmission(context.Background(), "task", dest))
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "submission", string(data))
+}
+
+func TestDownload_ChecksumMismatch(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/artifacts/task.tar.gz.sha256" {
+			io.WriteString(w, checksum([]byte("expected"))+"  task.tar.gz\n")
+			return
+		}
+		atomic.AddInt32(&attempts, 1)
+		io.WriteString(w, "corrupted")
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "task.tar.gz")
+	err := testClient(srv.URL).DownloadArtifact(context.Background(), "task", dest)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.NoFileExists(t, dest)
+	assert.NoFileExists(t, dest+".part")
+}
+
+func TestDownload_NotFound(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv.URL).DownloadArtifact(context.Background(), "task", filepath.Join(t.TempDir(), "task.tar.gz"))
+	var statusErr httputil.HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	// The sidecar and the file itself, no retries.
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestUploadArtifact(t *testing.T) {
+	content := []byte("artifact")
+	path := filepath.Join(t.TempDir(), "task.amd64.tar.gz")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		assert.Equal(t, "/api/v1/artifact-upload", r.URL.Path)
+		assert.Equal(t, "task.amd64", r.URL.Query().Get("id"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, checksum(content), r.Header.Get(ChecksumHeader))
+
+		file, header, err := r.FormFile("uploadFile")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer file.Close()
+		assert.Equal(t, "task.amd64.tar.gz", header.Filename)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, content, data)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, testClient(srv.URL).UploadArtifact(context.Background(), "task.amd64", path))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	err := testClient(srv.URL).UploadArtifact(context.Background(), "task.amd64", filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestUploadLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "build.log")
+	require.NoError(t, os.WriteFile(path, []byte("log"), 0644))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/log-upload", r.URL.Path)
+		assert.Equal(t, "task", r.URL.Query().Get("id"))
+		assert.Equal(t, "build", r.URL.Query().Get("type"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv.URL).UploadLog(context.Background(), "task", "build", path)
+	var statusErr httputil.HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+}
+
+func TestRetry_ContextCancellation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := testClient(srv.URL)
+	c.backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := c.DownloadArtifact(ctx, "task", filepath.Join(t.TempDir(), "task.tar.gz"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClaim(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/claim", r.URL.Path)
+		assert.Equal(t, "task.amd64", r.URL.Query().Get("id"))
+		assert.Equal(t, "build", r.URL.Query().Get("kind"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, testClient(srv.URL).Claim(context.Background(), "task.amd64", "build"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestClaim_Conflict(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, `{"error":"task claimed by builder-1"}`, http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv.URL).Claim(context.Background(), "task", "repo")
+	var statusErr httputil.HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusConflict, statusErr.StatusCode)
+	assert.Contains(t, statusErr.Body, "task claimed by builder-1")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestReportEvent(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/jobs/task/events", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var event TaskEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		assert.Equal(t, TaskEvent{TaskID: "task.amd64", Stage: "build", Architecture: "amd64", Event: EventFailed, Instance: "host-builder", Detail: "exit status 1"}, event)
+	}))
+	defer srv.Close()
+
+	err := testClient(srv.URL).ReportEvent(context.Background(), "task", TaskEvent{
+		TaskID:       "task.amd64",
+		Stage:        "build",
+		Architecture: "amd64",
+		Event:        EventFailed,
+		Instance:     "host-builder",
+		Detail:       "exit status 1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestTaskInstance(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/v1/jobs/task/events", r.URL.Path)
+		w.Write([]byte(`{"pipelineId":"task","events":[
+			{"taskId":"task","stage":"pipeline","event":"queued"},
+			{"taskId":"task.amd64","stage":"build","event":"started","instance":"host1-builder"},
+			{"taskId":"task.amd64","stage":"build","event":"failed","instance":"host1-builder"},
+			{"taskId":"task.amd64","stage":"build","event":"started","instance":"host2-builder"},
+			{"taskId":"task.arm64","stage":"build","event":"started","instance":"host3-builder"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	instance, err := testClient(srv.URL).TaskInstance(context.Background(), "task", "task.amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "host2-builder", instance)
+
+	instance, err = testClient(srv.URL).TaskInstance(context.Background(), "task", "task.amd64.repro")
+	require.NoError(t, err)
+	assert.Empty(t, instance)
+}
@@ -0,0 +1,40 @@
+package domain
+
+// BatchFile describes the packages of a batch submission, as written by the
+// maintainer in YAML. The settings at the top apply to every package.
+type BatchFile struct {
+	Suite          string         `json:"suite"`
+	IsExperimental bool           `json:"experimental"`
+	ForceVersion   bool           `json:"force-version"`
+	BuilderLabel   string         `json:"builder-label"`
+	Packages       []BatchPackage `json:"packages"`
+}
+
+// BatchPackage is a package of a batch file.
+type BatchPackage struct {
+	PackageURL    string `json:"package"`
+	SourceURL     string `json:"source"`
+	PackageBranch string `json:"package-branch"`
+	SourceBranch  string `json:"source-branch"`
+	Component     string `json:"component"`
+}
+
+// BatchSubmission is the wire format of a batch sent to the chief API.
+// The JSON tags must stay in sync with internal/chief/domain/batch.go.
+type BatchSubmission struct {
+	Items []Submission `json:"items"`
+}
+
+type BatchResponse struct {
+	BatchID  string             `json:"batchId"`
+	Packages []BatchPackageView `json:"packages"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// BatchPackageView is a package of a submitted batch and the packages of
+// the batch it waits for.
+type BatchPackageView struct {
+	PackageName string   `json:"packageName"`
+	PipelineID  string   `json:"pipelineId"`
+	DependsOn   []string `json:"dependsOn,omitempty"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// CancelRequest asks chief to stop a queued or running pipeline. It is sent
+// clearsigned with the maintainer's key.
+// The JSON tags must stay in sync with internal/chief/domain/cancel.go.
+type CancelRequest struct {
+	PipelineID string    `json:"pipelineId"`
+	Timestamp  time.Time `json:"timestamp"`
+}
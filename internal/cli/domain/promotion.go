@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// PromotionRequest asks chief to copy a package version from the
+// experimental distribution of a suite into the suite itself. It is sent
+// clearsigned with the maintainer's key.
+// The JSON tags must stay in sync with internal/chief/domain/promotion.go.
+type PromotionRequest struct {
+	PackageName    string    `json:"packageName"`
+	PackageVersion string    `json:"packageVersion"`
+	Suite          string    `json:"suite,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// RebuildRequest asks chief for a binary-only rebuild (binNMU) of a source
+// package published in a suite. It is sent clearsigned with the
+// maintainer's key.
+// The JSON tags must stay in sync with internal/chief/domain/rebuild.go.
+type RebuildRequest struct {
+	PackageName string    `json:"packageName"`
+	Suite       string    `json:"suite,omitempty"`
+	Reason      string    `json:"reason"`
+	Timestamp   time.Time `json:"timestamp"`
+}
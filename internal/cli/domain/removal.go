@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// RemovalRequest asks chief to remove a source package, along with the
+// binaries built from it, from a component of a suite. It is sent
+// clearsigned with the maintainer's key.
+// The JSON tags must stay in sync with internal/chief/domain/removal.go.
+type RemovalRequest struct {
+	PackageName string    `json:"packageName"`
+	Suite       string    `json:"suite,omitempty"`
+	Component   string    `json:"component"`
+	Timestamp   time.Time `json:"timestamp"`
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// SnapshotRequest asks chief for a snapshot of a repository, a suite or its
+// -experimental twin. It is sent clearsigned with the maintainer's key.
+// The JSON tags must stay in sync with internal/chief/domain/snapshot.go.
+type SnapshotRequest struct {
+	Repository string    `json:"repository,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RestoreRequest asks chief to roll a repository back to a snapshot. It is
+// sent clearsigned with the maintainer's key.
+type RestoreRequest struct {
+	SnapshotID string    `json:"snapshotID"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Snapshot is a captured state of a repository.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	Repository string    `json:"repository"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TaskUUID   string    `json:"taskUUID,omitempty"`
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type VersionResponse struct {
 	Version string `json:"version"`
 }
@@ -18,12 +20,76 @@ type RetryResponse struct {
 	Error      string `json:"error,omitempty"`
 }
 
+type CancelResponse struct {
+	PipelineID string `json:"pipelineId"`
+	Error      string `json:"error,omitempty"`
+}
+
+type ArchStatus struct {
+	Architecture string `json:"architecture"`
+	BuildStatus  string `json:"buildStatus"`
+	TestStatus   string `json:"testStatus,omitempty"`
+}
+
 type PackageStatus struct {
-	PipelineID  string `json:"pipelineId"`
-	JobStatus   string `json:"jobStatus"`
-	BuildStatus string `json:"buildStatus"`
-	RepoStatus  string `json:"repoStatus"`
-	State       string `json:"state"`
+	PipelineID    string        `json:"pipelineId"`
+	JobStatus     string        `json:"jobStatus"`
+	BuildStatus   string        `json:"buildStatus"`
+	RepoStatus    string        `json:"repoStatus"`
+	TestStatus    string        `json:"testStatus,omitempty"`
+	State         string        `json:"state"`
+	Architectures []ArchStatus  `json:"architectures,omitempty"`
+	Lintian       *LintianTags  `json:"lintian,omitempty"`
+	PackageName   string        `json:"packageName,omitempty"`
+	Stages        []StageTiming `json:"stages,omitempty"`
+
+	// Estimated by irgsh-cli, chief does not send it
+	Estimate *BuildEstimate `json:"-"`
+}
+
+// LintianTags counts the tags lintian reported on the builds of a
+// pipeline, by severity.
+type LintianTags struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Info     int `json:"info"`
+}
+
+// StageTiming is when a task of a pipeline started and ended. The duration
+// of a task still running is the time it ran for so far.
+type StageTiming struct {
+	TaskID          string     `json:"taskId"`
+	Stage           string     `json:"stage"`
+	Architecture    string     `json:"architecture,omitempty"`
+	Instance        string     `json:"instance,omitempty"`
+	Result          string     `json:"result,omitempty"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+}
+
+// DurationStats summarizes the durations of the successful builds of a
+// package on an architecture, or of a builder.
+type DurationStats struct {
+	Name         string  `json:"name"`
+	Architecture string  `json:"architecture,omitempty"` // Empty for builders and for builds reported without it
+	Builds       int     `json:"builds"`
+	P50Seconds   float64 `json:"p50Seconds"`
+	P95Seconds   float64 `json:"p95Seconds"`
+}
+
+type BuildDurations struct {
+	Packages []DurationStats `json:"packages"`
+	Builders []DurationStats `json:"builders"`
+}
+
+// BuildEstimate is the time the builds of a pipeline are left to run,
+// estimated from the past builds of its package.
+type BuildEstimate struct {
+	Builds    int // Past builds the estimate is drawn from
+	P50       time.Duration
+	P95       time.Duration
+	Remaining time.Duration // Until the builds ran for as long as P50
 }
 
 type ISOStatus struct {
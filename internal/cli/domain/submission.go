@@ -16,16 +16,26 @@ type Submission struct {
 	Tarball                string `json:"tarball"`
 	PackageBranch          string `json:"packageBranch"`
 	SourceBranch           string `json:"sourceBranch"`
+	BuilderLabel           string `json:"builderLabel,omitempty"`
+	Suite                  string `json:"suite,omitempty"`
+	UpstreamOnly           bool   `json:"upstreamOnly,omitempty"`
+	CheckReproducibility   bool   `json:"checkReproducibility,omitempty"`
+	RunTests               bool   `json:"runTests,omitempty"`
 }
 
 // SubmitParams holds the CLI input parameters for a package submission.
 type SubmitParams struct {
-	PackageURL     string
-	SourceURL      string
-	Component      string
-	PackageBranch  string
-	SourceBranch   string
-	IsExperimental bool
-	IgnoreChecks   bool
-	ForceVersion   bool
+	PackageURL           string
+	SourceURL            string
+	Component            string
+	PackageBranch        string
+	SourceBranch         string
+	IsExperimental       bool
+	IgnoreChecks         bool
+	ForceVersion         bool
+	BuilderLabel         string
+	Suite                string
+	UpstreamOnly         bool
+	CheckReproducibility bool
+	RunTests             bool
 }
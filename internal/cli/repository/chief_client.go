@@ -203,6 +203,41 @@ func (c *HTTPChiefClient) SubmitPackage(ctx context.Context, submission domain.S
 	return sr, nil
 }
 
+func (c *HTTPChiefClient) SubmitBatch(ctx context.Context, batch domain.BatchSubmission) (domain.BatchResponse, error) {
+	base, err := c.baseURL()
+	if err != nil {
+		return domain.BatchResponse{}, err
+	}
+
+	jsonBytes, err := json.Marshal(batch)
+	if err != nil {
+		return domain.BatchResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/v1/batch", bytes.NewReader(jsonBytes))
+	if err != nil {
+		return domain.BatchResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return domain.BatchResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return domain.BatchResponse{}, err
+	}
+
+	var br domain.BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		return domain.BatchResponse{}, err
+	}
+	return br, nil
+}
+
 func (c *HTTPChiefClient) SubmitISO(ctx context.Context, submission domain.ISOSubmission) (domain.SubmitResponse, error) {
 	base, err := c.baseURL()
 	if err != nil {
@@ -265,6 +300,34 @@ func (c *HTTPChiefClient) GetPackageStatus(ctx context.Context, pipelineID strin
 	return ps, nil
 }
 
+func (c *HTTPChiefClient) GetBuildDurations(ctx context.Context, packageName string) (domain.BuildDurations, error) {
+	base, err := c.baseURL()
+	if err != nil {
+		return domain.BuildDurations{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/api/v1/stats/build-durations?package="+url.QueryEscape(packageName), nil)
+	if err != nil {
+		return domain.BuildDurations{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return domain.BuildDurations{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return domain.BuildDurations{}, err
+	}
+
+	var bd domain.BuildDurations
+	if err := json.NewDecoder(resp.Body).Decode(&bd); err != nil {
+		return domain.BuildDurations{}, err
+	}
+	return bd, nil
+}
+
 func (c *HTTPChiefClient) GetISOStatus(ctx context.Context, pipelineID string) (domain.ISOStatus, error) {
 	base, err := c.baseURL()
 	if err != nil {
@@ -321,6 +384,115 @@ func (c *HTTPChiefClient) Retry(ctx context.Context, pipelineID string) (domain.
 	return rr, nil
 }
 
+func (c *HTTPChiefClient) Cancel(ctx context.Context, signedRequest []byte) (domain.CancelResponse, error) {
+	base, err := c.baseURL()
+	if err != nil {
+		return domain.CancelResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/v1/cancel", bytes.NewReader(signedRequest))
+	if err != nil {
+		return domain.CancelResponse{}, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return domain.CancelResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return domain.CancelResponse{}, err
+	}
+
+	var cr domain.CancelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&cr); err != nil {
+		return domain.CancelResponse{}, err
+	}
+	return cr, nil
+}
+
+// postSignedRequest posts a clearsigned request to a chief API endpoint.
+func (c *HTTPChiefClient) postSignedRequest(ctx context.Context, endpoint string, signedRequest []byte) (domain.SubmitResponse, error) {
+	base, err := c.baseURL()
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+endpoint, bytes.NewReader(signedRequest))
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	var sr domain.SubmitResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	return sr, nil
+}
+
+func (c *HTTPChiefClient) Promote(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	return c.postSignedRequest(ctx, "/api/v1/promote", signedRequest)
+}
+
+func (c *HTTPChiefClient) Remove(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	return c.postSignedRequest(ctx, "/api/v1/remove", signedRequest)
+}
+
+func (c *HTTPChiefClient) Rebuild(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	return c.postSignedRequest(ctx, "/api/v1/rebuild", signedRequest)
+}
+
+func (c *HTTPChiefClient) ListSnapshots(ctx context.Context) ([]domain.Snapshot, error) {
+	base, err := c.baseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/api/v1/snapshots", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	var snapshots []domain.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (c *HTTPChiefClient) CreateSnapshot(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	return c.postSignedRequest(ctx, "/api/v1/snapshot-create", signedRequest)
+}
+
+func (c *HTTPChiefClient) RestoreSnapshot(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	return c.postSignedRequest(ctx, "/api/v1/snapshot-restore", signedRequest)
+}
+
 func (c *HTTPChiefClient) FetchLog(ctx context.Context, logPath string) (string, error) {
 	base, err := c.baseURL()
 	if err != nil {
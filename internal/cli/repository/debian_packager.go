@@ -85,6 +85,14 @@ func (d *ShellDebianPackager) ExtractExtendedVersion(changelogPath string) (stri
 	return "", nil
 }
 
+func (d *ShellDebianPackager) ExtractDistribution(changelogPath string) (string, error) {
+	firstLine, err := readFirstLine(changelogPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get package distribution: %w", err)
+	}
+	return parseChangelogDistribution(firstLine), nil
+}
+
 func (d *ShellDebianPackager) ExtractChangelogMaintainer(changelogPath string) (string, error) {
 	f, err := os.Open(changelogPath)
 	if err != nil {
@@ -183,3 +191,22 @@ func parseChangelogVersion(line string) string {
 	}
 	return ""
 }
+
+// parseChangelogDistribution extracts the first target distribution from a
+// debian/changelog first line.
+// Format: "package (version) distribution; urgency=level"
+func parseChangelogDistribution(line string) string {
+	end := strings.Index(line, ")")
+	if end < 0 {
+		return ""
+	}
+	rest := line[end+1:]
+	if idx := strings.Index(rest, ";"); idx >= 0 {
+		rest = rest[:idx]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
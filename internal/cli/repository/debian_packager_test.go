@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChangelogDistribution(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"hello (2.10-3) verbeek; urgency=medium", "verbeek"},
+		{"hello (1:2.0-1) uluwatu uluwatu-security; urgency=low", "uluwatu"},
+		{"hello (2.10-3) ; urgency=medium", ""},
+		{"not a changelog line", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseChangelogDistribution(tt.line), tt.line)
+	}
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// SubmitBatch uploads every package of a batch file and submits them to
+// chief as one batch. Chief derives the build order from the Build-Depends
+// of the packages, a package is only built once the packages of the batch
+// it depends on are in the repository.
+func (u *CLIUsecase) SubmitBatch(ctx context.Context, batchPath string, ignoreChecks bool) (domain.BatchResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.BatchResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	if batchPath == "" {
+		return domain.BatchResponse{}, ErrBatchFileMissing
+	}
+
+	content, err := os.ReadFile(batchPath)
+	if err != nil {
+		return domain.BatchResponse{}, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	var batch domain.BatchFile
+	if err := yaml.Unmarshal(content, &batch); err != nil {
+		return domain.BatchResponse{}, fmt.Errorf("failed to parse batch file: %w", err)
+	}
+	if len(batch.Packages) == 0 {
+		return domain.BatchResponse{}, errors.New("the batch file lists no package")
+	}
+
+	params := make([]domain.SubmitParams, len(batch.Packages))
+	for i, pkg := range batch.Packages {
+		params[i] = domain.SubmitParams{
+			PackageURL:     pkg.PackageURL,
+			SourceURL:      pkg.SourceURL,
+			Component:      pkg.Component,
+			PackageBranch:  pkg.PackageBranch,
+			SourceBranch:   pkg.SourceBranch,
+			IsExperimental: batch.IsExperimental,
+			IgnoreChecks:   ignoreChecks,
+			ForceVersion:   batch.ForceVersion,
+			BuilderLabel:   batch.BuilderLabel,
+			Suite:          batch.Suite,
+		}
+		if err := validatePackageURLs(params[i]); err != nil {
+			return domain.BatchResponse{}, fmt.Errorf("package %d of the batch: %w", i+1, err)
+		}
+	}
+
+	if !ignoreChecks {
+		if err := u.checkChiefVersion(ctx); err != nil {
+			return domain.BatchResponse{}, err
+		}
+	}
+
+	if !batch.IsExperimental {
+		if err := u.confirmOfficialSubmission("batch"); err != nil {
+			return domain.BatchResponse{}, err
+		}
+	}
+
+	var submission domain.BatchSubmission
+	for i, p := range params {
+		log.Printf("Preparing package %d/%d: %s\n", i+1, len(params), p.PackageURL)
+		item, err := u.uploadPackage(ctx, cfg, p)
+		if err != nil {
+			return domain.BatchResponse{}, fmt.Errorf("%s: %w", p.PackageURL, err)
+		}
+		submission.Items = append(submission.Items, item)
+	}
+
+	log.Println("Submitting batch...")
+	resp, err := u.chief.SubmitBatch(ctx, submission)
+	if err != nil {
+		return domain.BatchResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.BatchResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Batch submission succeeded. Batch ID:")
+	fmt.Println(resp.BatchID)
+	fmt.Println("Pipelines:")
+	for _, pkg := range resp.Packages {
+		line := "  " + pkg.PackageName + "  " + pkg.PipelineID
+		if len(pkg.DependsOn) > 0 {
+			line += "  (after " + strings.Join(pkg.DependsOn, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,88 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBatchFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "batch.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func newBatchUsecase(chief *mockChiefAPI, prompter *mockPrompter) *usecase.CLIUsecase {
+	return usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		nil, chief, nil, nil, nil, nil, nil, nil, prompter, "1.0.0",
+	)
+}
+
+func TestSubmitBatch_ConfigMissing(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{err: errors.New("no config")},
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, "",
+	)
+	_, err := svc.SubmitBatch(context.Background(), "batch.yaml", false)
+	assert.ErrorIs(t, err, usecase.ErrConfigMissing)
+}
+
+func TestSubmitBatch_FileMissing(t *testing.T) {
+	svc := newBatchUsecase(&mockChiefAPI{}, nil)
+	_, err := svc.SubmitBatch(context.Background(), "", false)
+	assert.ErrorIs(t, err, usecase.ErrBatchFileMissing)
+
+	_, err = svc.SubmitBatch(context.Background(), filepath.Join(t.TempDir(), "missing.yaml"), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read batch file")
+}
+
+func TestSubmitBatch_NoPackage(t *testing.T) {
+	svc := newBatchUsecase(&mockChiefAPI{}, nil)
+	_, err := svc.SubmitBatch(context.Background(), writeBatchFile(t, "suite: verbeek\n"), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "lists no package")
+}
+
+func TestSubmitBatch_InvalidPackageURL(t *testing.T) {
+	svc := newBatchUsecase(&mockChiefAPI{}, nil)
+	path := writeBatchFile(t, `packages:
+  - package: https://git.example.com/libfoo
+  - package: not-a-url
+`)
+	_, err := svc.SubmitBatch(context.Background(), path, true)
+	require.Error(t, err)
+	assert.Equal(t, "package 2 of the batch: --package must be a valid http or https URL", err.Error())
+}
+
+func TestSubmitBatch_VersionMismatch(t *testing.T) {
+	svc := newBatchUsecase(&mockChiefAPI{version: domain.VersionResponse{Version: "2.0.0"}}, nil)
+	path := writeBatchFile(t, "packages:\n  - package: https://git.example.com/libfoo\n")
+	_, err := svc.SubmitBatch(context.Background(), path, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "version mismatch")
+}
+
+func TestSubmitBatch_UserCancelled(t *testing.T) {
+	chief := &mockChiefAPI{}
+	svc := newBatchUsecase(chief, &mockPrompter{confirmed: false})
+	path := writeBatchFile(t, `suite: verbeek
+packages:
+  - package: https://git.example.com/libfoo
+    source: https://git.example.com/libfoo-src
+    package-branch: verbeek
+`)
+	_, err := svc.SubmitBatch(context.Background(), path, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cancelled by user")
+	assert.Nil(t, chief.batch)
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// CancelPipeline asks chief to stop a pipeline, with a request signed by the
+// maintainer's key.
+func (u *CLIUsecase) CancelPipeline(ctx context.Context, pipelineID string) (domain.CancelResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.CancelResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+
+	if pipelineID == "" {
+		pipelineID, err = u.pipelines.LoadPackageID()
+		if err != nil || pipelineID == "" {
+			return domain.CancelResponse{}, ErrPipelineIDMissing
+		}
+	}
+
+	log.Println("Signing cancel request...")
+	signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.CancelRequest{
+		PipelineID: pipelineID,
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return domain.CancelResponse{}, err
+	}
+
+	fmt.Println("Cancelling pipeline " + pipelineID + " ...")
+
+	resp, err := u.chief.Cancel(ctx, signed)
+	if err != nil {
+		return domain.CancelResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.CancelResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Pipeline " + resp.PipelineID + " has been cancelled")
+	return resp, nil
+}
@@ -0,0 +1,68 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelPipeline_Success(t *testing.T) {
+	chief := &mockChiefAPI{cancelResp: domain.CancelResponse{PipelineID: "pkg-123"}}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+	resp, err := svc.CancelPipeline(context.Background(), "pkg-123")
+	assert.NoError(t, err)
+	assert.Equal(t, "pkg-123", resp.PipelineID)
+
+	var req domain.CancelRequest
+	require.NoError(t, json.Unmarshal(chief.cancelled, &req))
+	assert.Equal(t, "pkg-123", req.PipelineID)
+	assert.False(t, req.Timestamp.IsZero())
+}
+
+func TestCancelPipeline_LoadFromStore(t *testing.T) {
+	chief := &mockChiefAPI{cancelResp: domain.CancelResponse{PipelineID: "stored-pkg"}}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{packageID: "stored-pkg"},
+		chief,
+		nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+	_, err := svc.CancelPipeline(context.Background(), "")
+	assert.NoError(t, err)
+
+	var req domain.CancelRequest
+	require.NoError(t, json.Unmarshal(chief.cancelled, &req))
+	assert.Equal(t, "stored-pkg", req.PipelineID)
+}
+
+func TestCancelPipeline_PipelineIDMissing(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		nil, nil, nil, nil, nil, nil, nil, nil, "",
+	)
+	_, err := svc.CancelPipeline(context.Background(), "")
+	assert.ErrorIs(t, err, usecase.ErrPipelineIDMissing)
+}
+
+func TestCancelPipeline_ServerError(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		&mockChiefAPI{cancelErr: errors.New("pipeline already finished as DONE")},
+		nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+	_, err := svc.CancelPipeline(context.Background(), "pkg-123")
+	assert.EqualError(t, err, "pipeline already finished as DONE")
+}
@@ -7,8 +7,13 @@ import (
 )
 
 var (
-	ErrConfigMissing     = errors.New("irgsh-cli configuration missing")
-	ErrPipelineIDMissing = errors.New("pipeline ID should not be empty")
+	ErrConfigMissing      = errors.New("irgsh-cli configuration missing")
+	ErrBatchFileMissing   = errors.New("batch file should not be empty")
+	ErrPipelineIDMissing  = errors.New("pipeline ID should not be empty")
+	ErrPromoteArgsMissing = errors.New("package name and version should not be empty")
+	ErrRemoveArgsMissing  = errors.New("package name and component should not be empty")
+	ErrRebuildArgsMissing = errors.New("package names and reason should not be empty")
+	ErrSnapshotIDMissing  = errors.New("snapshot ID should not be empty")
 )
 
 // isHTTPNotFound checks whether the error represents an HTTP 404 response.
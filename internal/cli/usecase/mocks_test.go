@@ -3,6 +3,7 @@ package usecase_test
 import (
 	"context"
 	"io"
+	"os"
 
 	"github.com/blankon/irgsh-go/internal/cli/domain"
 )
@@ -67,16 +68,40 @@ type mockChiefAPI struct {
 	uploadErr    error
 	submitResp   domain.SubmitResponse
 	submitErr    error
+	batchResp    domain.BatchResponse
+	batchErr     error
+	batch        *domain.BatchSubmission
 	isoResp      domain.SubmitResponse
 	isoErr       error
 	pkgStatus    domain.PackageStatus
 	pkgStatusErr error
+	durations    domain.BuildDurations
+	durationsErr error
 	isoStatus    domain.ISOStatus
 	isoStatusErr error
 	retryResp    domain.RetryResponse
 	retryErr     error
+	cancelResp   domain.CancelResponse
+	cancelErr    error
+	cancelled    []byte
+	promoteResp  domain.SubmitResponse
+	promoteErr   error
+	promoted     []byte
+	removeResp   domain.SubmitResponse
+	removeErr    error
+	removed      []byte
+	rebuildResp  domain.SubmitResponse
+	rebuildErr   error
+	rebuilt      [][]byte
+	snapshots    []domain.Snapshot
+	snapshotsErr error
+	snapshotResp domain.SubmitResponse
+	snapshotted  []byte
+	restoreResp  domain.SubmitResponse
+	restored     []byte
 	fetchLogResp string
 	fetchLogErr  error
+	fetchedLogs  []string
 }
 
 func (m *mockChiefAPI) GetVersion(_ context.Context) (domain.VersionResponse, error) {
@@ -91,6 +116,11 @@ func (m *mockChiefAPI) SubmitPackage(_ context.Context, _ domain.Submission) (do
 	return m.submitResp, m.submitErr
 }
 
+func (m *mockChiefAPI) SubmitBatch(_ context.Context, batch domain.BatchSubmission) (domain.BatchResponse, error) {
+	m.batch = &batch
+	return m.batchResp, m.batchErr
+}
+
 func (m *mockChiefAPI) SubmitISO(_ context.Context, _ domain.ISOSubmission) (domain.SubmitResponse, error) {
 	return m.isoResp, m.isoErr
 }
@@ -99,6 +129,10 @@ func (m *mockChiefAPI) GetPackageStatus(_ context.Context, _ string) (domain.Pac
 	return m.pkgStatus, m.pkgStatusErr
 }
 
+func (m *mockChiefAPI) GetBuildDurations(_ context.Context, _ string) (domain.BuildDurations, error) {
+	return m.durations, m.durationsErr
+}
+
 func (m *mockChiefAPI) GetISOStatus(_ context.Context, _ string) (domain.ISOStatus, error) {
 	return m.isoStatus, m.isoStatusErr
 }
@@ -107,7 +141,42 @@ func (m *mockChiefAPI) Retry(_ context.Context, _ string) (domain.RetryResponse,
 	return m.retryResp, m.retryErr
 }
 
-func (m *mockChiefAPI) FetchLog(_ context.Context, _ string) (string, error) {
+func (m *mockChiefAPI) Cancel(_ context.Context, signedRequest []byte) (domain.CancelResponse, error) {
+	m.cancelled = signedRequest
+	return m.cancelResp, m.cancelErr
+}
+
+func (m *mockChiefAPI) Promote(_ context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	m.promoted = signedRequest
+	return m.promoteResp, m.promoteErr
+}
+
+func (m *mockChiefAPI) Remove(_ context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	m.removed = signedRequest
+	return m.removeResp, m.removeErr
+}
+
+func (m *mockChiefAPI) Rebuild(_ context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	m.rebuilt = append(m.rebuilt, signedRequest)
+	return m.rebuildResp, m.rebuildErr
+}
+
+func (m *mockChiefAPI) ListSnapshots(_ context.Context) ([]domain.Snapshot, error) {
+	return m.snapshots, m.snapshotsErr
+}
+
+func (m *mockChiefAPI) CreateSnapshot(_ context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	m.snapshotted = signedRequest
+	return m.snapshotResp, nil
+}
+
+func (m *mockChiefAPI) RestoreSnapshot(_ context.Context, signedRequest []byte) (domain.SubmitResponse, error) {
+	m.restored = signedRequest
+	return m.restoreResp, nil
+}
+
+func (m *mockChiefAPI) FetchLog(_ context.Context, name string) (string, error) {
+	m.fetchedLogs = append(m.fetchedLogs, name)
 	return m.fetchLogResp, m.fetchLogErr
 }
 
@@ -143,6 +212,7 @@ type mockDebianPackager struct {
 	packageName     string
 	version         string
 	extendedVersion string
+	distribution    string
 	maintainer      string
 	uploaders       string
 	err             error
@@ -160,6 +230,10 @@ func (m *mockDebianPackager) ExtractExtendedVersion(_ string) (string, error) {
 	return m.extendedVersion, m.err
 }
 
+func (m *mockDebianPackager) ExtractDistribution(_ string) (string, error) {
+	return m.distribution, m.err
+}
+
 func (m *mockDebianPackager) ExtractChangelogMaintainer(_ string) (string, error) {
 	return m.maintainer, m.err
 }
@@ -190,8 +264,16 @@ func (m *mockGPGSigner) GetIdentity(_ string) (string, error) {
 	return m.identity, m.err
 }
 
-func (m *mockGPGSigner) ClearSign(_, _, _ string) error {
-	return m.err
+// ClearSign copies the input unsigned.
+func (m *mockGPGSigner) ClearSign(inputPath, outputPath, _ string) error {
+	if m.err != nil {
+		return m.err
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0600)
 }
 
 // mockReleaseFetcher implements usecase.ReleaseFetcher for testing.
@@ -7,12 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -38,59 +40,112 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 
 	// Validate chief connectivity (unless ignoring checks)
 	if !params.IgnoreChecks {
-		versionResp, err := u.chief.GetVersion(ctx)
-		if err != nil {
-			return domain.SubmitResponse{}, fmt.Errorf("failed to connect to chief: %w", err)
+		if err := u.checkChiefVersion(ctx); err != nil {
+			return domain.SubmitResponse{}, err
 		}
-		if versionResp.Version != u.version {
-			return domain.SubmitResponse{}, fmt.Errorf("client version mismatch: local=%s, chief=%s. Please update your irgsh-cli", u.version, versionResp.Version)
+	}
+
+	if err := validatePackageURLs(params); err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	// Experimental prompt
+	if !params.IsExperimental {
+		if err := u.confirmOfficialSubmission("package"); err != nil {
+			return domain.SubmitResponse{}, err
 		}
 	}
 
-	// Defaults
-	component := params.Component
-	if component == "" {
-		component = "main"
+	submission, err := u.uploadPackage(ctx, cfg, params)
+	if err != nil {
+		return domain.SubmitResponse{}, err
 	}
-	packageBranch := params.PackageBranch
-	if packageBranch == "" {
-		packageBranch = "master"
+
+	// Submit
+	log.Println("Submitting...")
+	submitResp, err := u.chief.SubmitPackage(ctx, submission)
+	if err != nil {
+		return domain.SubmitResponse{}, err
 	}
-	sourceBranch := params.SourceBranch
-	if sourceBranch == "" {
-		sourceBranch = "master"
+	if submitResp.Error != "" {
+		return domain.SubmitResponse{}, errors.New(submitResp.Error)
+	}
+
+	fmt.Println("Submission succeeded. Pipeline ID:")
+	fmt.Println(submitResp.PipelineID)
+
+	// Persist pipeline ID
+	if err := u.pipelines.SavePackageID(submitResp.PipelineID); err != nil {
+		log.Printf("warning: failed to save pipeline ID: %v", err)
 	}
 
-	// Validate URLs
+	return submitResp, nil
+}
+
+// checkChiefVersion makes sure chief is reachable and runs the same version
+// as this irgsh-cli.
+func (u *CLIUsecase) checkChiefVersion(ctx context.Context) error {
+	versionResp, err := u.chief.GetVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to chief: %w", err)
+	}
+	if versionResp.Version != u.version {
+		return fmt.Errorf("client version mismatch: local=%s, chief=%s. Please update your irgsh-cli", u.version, versionResp.Version)
+	}
+	return nil
+}
+
+func validatePackageURLs(params domain.SubmitParams) error {
 	if params.SourceURL != "" {
 		srcURL, err := url.Parse(params.SourceURL)
 		if err != nil || srcURL.Host == "" || (srcURL.Scheme != "http" && srcURL.Scheme != "https") {
-			return domain.SubmitResponse{}, errors.New("--source must be a valid http or https URL")
+			return errors.New("--source must be a valid http or https URL")
 		}
 	}
 	if params.PackageURL == "" {
-		return domain.SubmitResponse{}, errors.New("--package should not be empty")
+		return errors.New("--package should not be empty")
 	}
 	if pkgURL, err := url.Parse(params.PackageURL); err != nil || pkgURL.Host == "" || (pkgURL.Scheme != "http" && pkgURL.Scheme != "https") {
-		return domain.SubmitResponse{}, errors.New("--package must be a valid http or https URL")
+		return errors.New("--package must be a valid http or https URL")
 	}
+	return nil
+}
 
-	// Experimental prompt
-	isExperimental := params.IsExperimental
-	if !isExperimental {
-		confirmed, err := u.prompter.Confirm("Experimental flag is not set which means the package will be injected to official dev repository. Are you sure you want to continue to submit and build this package?")
-		if err != nil {
-			return domain.SubmitResponse{}, err
-		}
-		if !confirmed {
-			return domain.SubmitResponse{}, errors.New("submission cancelled by user")
-		}
+// confirmOfficialSubmission asks the maintainer to confirm a submission
+// without the experimental flag, what is either "package" or "batch".
+func (u *CLIUsecase) confirmOfficialSubmission(what string) error {
+	confirmed, err := u.prompter.Confirm("Experimental flag is not set which means the " + what + " will be injected to official dev repository. Are you sure you want to continue to submit and build this " + what + "?")
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errors.New("submission cancelled by user")
+	}
+	return nil
+}
+
+// uploadPackage builds and signs the source package described by params,
+// then uploads it to chief. The returned submission references the upload
+// and is ready to be submitted.
+func (u *CLIUsecase) uploadPackage(ctx context.Context, cfg domain.Config, params domain.SubmitParams) (domain.Submission, error) {
+	// Defaults
+	component := params.Component
+	if component == "" {
+		component = "main"
+	}
+	packageBranch := params.PackageBranch
+	if packageBranch == "" {
+		packageBranch = "master"
+	}
+	sourceBranch := params.SourceBranch
+	if sourceBranch == "" {
+		sourceBranch = "master"
 	}
 
 	tmpID := uuid.New().String()
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	tmpBase := filepath.Join(homeDir, ".irgsh", "tmp")
 	tmpDir := filepath.Join(tmpBase, tmpID)
@@ -105,7 +160,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 			// Only fall back to tarball download if the repo/branch was not found.
 			// Other errors (e.g. network, permission) should propagate immediately.
 			if !errors.Is(err, domain.ErrRepoOrBranchNotFound) {
-				return domain.SubmitResponse{}, err
+				return domain.Submission{}, err
 			}
 			log.Println(err)
 			// Try as downloadable tarball
@@ -113,27 +168,27 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 			log.Println("Downloading the tarball " + downloadableTarballURL)
 			dlReq, dlErr := http.NewRequestWithContext(ctx, http.MethodGet, downloadableTarballURL, nil)
 			if dlErr != nil {
-				return domain.SubmitResponse{}, dlErr
+				return domain.Submission{}, dlErr
 			}
 			dlClient := &http.Client{Timeout: 5 * time.Minute}
 			resp, dlErr := dlClient.Do(dlReq)
 			if dlErr != nil {
-				return domain.SubmitResponse{}, dlErr
+				return domain.Submission{}, dlErr
 			}
 			defer resp.Body.Close()
 
 			if mkErr := os.MkdirAll(tmpDir, 0755); mkErr != nil {
-				return domain.SubmitResponse{}, mkErr
+				return domain.Submission{}, mkErr
 			}
 
 			tarballName := path.Base(downloadableTarballURL)
 			out, createErr := os.Create(filepath.Join(tmpDir, tarballName))
 			if createErr != nil {
-				return domain.SubmitResponse{}, createErr
+				return domain.Submission{}, createErr
 			}
 			defer out.Close()
 			if _, cpErr := out.ReadFrom(resp.Body); cpErr != nil {
-				return domain.SubmitResponse{}, cpErr
+				return domain.Submission{}, cpErr
 			}
 		}
 	}
@@ -142,7 +197,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	// Clone package repo
 	err = u.repoSync.Sync(params.PackageURL, packageBranch, filepath.Join(tmpDir, "package"))
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 
 	packageDir := filepath.Join(tmpDir, "package")
@@ -153,57 +208,69 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	log.Println("Getting package name...")
 	packageName, err := u.debian.ExtractPackageName(controlPath)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	if packageName == "" {
-		return domain.SubmitResponse{}, errors.New("repository does not contain debian spec directory")
+		return domain.Submission{}, errors.New("repository does not contain debian spec directory")
 	}
 	if !safeDebianName.MatchString(packageName) {
-		return domain.SubmitResponse{}, fmt.Errorf("invalid package name %q: contains unsafe characters", packageName)
+		return domain.Submission{}, fmt.Errorf("invalid package name %q: contains unsafe characters", packageName)
 	}
 	log.Println("Package name: " + packageName)
 
 	log.Println("Getting package version...")
 	packageVersion, err := u.debian.ExtractVersion(changelogPath)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	if !safeDebianName.MatchString(packageVersion) {
-		return domain.SubmitResponse{}, fmt.Errorf("invalid package version %q: contains unsafe characters", packageVersion)
+		return domain.Submission{}, fmt.Errorf("invalid package version %q: contains unsafe characters", packageVersion)
 	}
 	log.Println("Package version: " + packageVersion)
 
 	log.Println("Getting package extended version...")
 	packageExtendedVersion, err := u.debian.ExtractExtendedVersion(changelogPath)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	if packageExtendedVersion == packageVersion {
 		packageExtendedVersion = ""
 	}
 	if packageExtendedVersion != "" && !safeDebianName.MatchString(packageExtendedVersion) {
-		return domain.SubmitResponse{}, fmt.Errorf("invalid package extended version %q: contains unsafe characters", packageExtendedVersion)
+		return domain.Submission{}, fmt.Errorf("invalid package extended version %q: contains unsafe characters", packageExtendedVersion)
 	}
 	log.Println("Package extended version: " + packageExtendedVersion)
 
+	if params.Suite != "" {
+		log.Println("Getting package distribution...")
+		distribution, err := u.debian.ExtractDistribution(changelogPath)
+		if err != nil {
+			return domain.Submission{}, err
+		}
+		// Chief rejects the mismatch anyway, so --ignore-checks does not skip it
+		if distribution != params.Suite {
+			return domain.Submission{}, fmt.Errorf("the distribution in the debian/changelog (%s) does not match the target suite %s", distribution, params.Suite)
+		}
+	}
+
 	log.Println("Getting package last maintainer...")
 	packageLastMaintainer, err := u.debian.ExtractChangelogMaintainer(changelogPath)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	log.Println(packageLastMaintainer)
 
 	log.Println("Getting uploaders...")
 	uploaders, err := u.debian.ExtractUploaders(controlPath)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 
 	// Get maintainer identity from GPG key
 	log.Println("Getting maintainer identity...")
 	maintainerIdentity, err := u.gpg.GetIdentity(cfg.MaintainerSigningKey)
 	if err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 
 	// Validate identity matches
@@ -211,12 +278,12 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 		if strings.TrimSpace(uploaders) != strings.TrimSpace(maintainerIdentity) {
 			log.Println("The uploader in the debian/control: " + uploaders)
 			log.Println("Your signing key identity: " + maintainerIdentity)
-			return domain.SubmitResponse{}, errors.New("the uploaders value in the debian/control does not matched with your identity")
+			return domain.Submission{}, errors.New("the uploaders value in the debian/control does not matched with your identity")
 		}
 		if strings.TrimSpace(packageLastMaintainer) != strings.TrimSpace(maintainerIdentity) {
 			log.Println("The last maintainer in the debian/changelog: " + packageLastMaintainer)
 			log.Println("Your signing key identity: " + maintainerIdentity)
-			return domain.SubmitResponse{}, errors.New("the last maintainer in the debian/changelog does not matched with your identity")
+			return domain.Submission{}, errors.New("the last maintainer in the debian/changelog does not matched with your identity")
 		}
 	}
 
@@ -237,7 +304,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 			packageName, packageVersion,
 		)
 		if _, shellErr := u.shell.Output(cmdStr); shellErr != nil {
-			return domain.SubmitResponse{}, fmt.Errorf("failed to create orig tarball: %w", shellErr)
+			return domain.Submission{}, fmt.Errorf("failed to create orig tarball: %w", shellErr)
 		}
 	}
 
@@ -245,7 +312,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	log.Println("Renaming workdir...")
 	renameCmd := fmt.Sprintf("cd %s && mv package %s", sq(tmpDir), packageNameVersion)
 	if err := u.shell.Run(renameCmd); err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("failed to rename workdir: %w", err)
+		return domain.Submission{}, fmt.Errorf("failed to rename workdir: %w", err)
 	}
 
 	workDir := filepath.Join(tmpDir, packageNameVersion)
@@ -253,13 +320,13 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	// dpkg-source --build
 	log.Println("Building source package...")
 	if err := u.debian.BuildSource(workDir); err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("dpkg-source failed: %w", err)
+		return domain.Submission{}, fmt.Errorf("dpkg-source failed: %w", err)
 	}
 
 	// debsign
 	log.Println("Signing the dsc file...")
 	if err := u.debian.Sign(tmpDir, cfg.MaintainerSigningKey); err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("debsign failed: %w", err)
+		return domain.Submission{}, fmt.Errorf("debsign failed: %w", err)
 	}
 
 	// dpkg-genbuildinfo
@@ -269,7 +336,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 		log.Println("Trying debuild before dpkg-genbuildinfo...")
 		debuildCmd := fmt.Sprintf("cd %s && debuild -us -uc -b && dpkg-genbuildinfo", sq(workDir))
 		if shellErr := u.shell.RunInteractive(debuildCmd); shellErr != nil {
-			return domain.SubmitResponse{}, fmt.Errorf("dpkg-genbuildinfo failed (debuild fallback also failed: %w): %w", shellErr, err)
+			return domain.Submission{}, fmt.Errorf("dpkg-genbuildinfo failed (debuild fallback also failed: %w): %w", shellErr, err)
 		}
 	}
 
@@ -277,15 +344,15 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	log.Println("Generating changes file...")
 	dscMatches, err := filepath.Glob(filepath.Join(tmpDir, "*.dsc"))
 	if err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("failed to find .dsc file: %w", err)
+		return domain.Submission{}, fmt.Errorf("failed to find .dsc file: %w", err)
 	}
 	if len(dscMatches) == 0 {
-		return domain.SubmitResponse{}, errors.New("no .dsc file found after dpkg-source")
+		return domain.Submission{}, errors.New("no .dsc file found after dpkg-source")
 	}
 	dscBase := strings.TrimSuffix(filepath.Base(dscMatches[0]), ".dsc")
 	genchangesCmd := fmt.Sprintf("cd %s && dpkg-genchanges > %s", sq(workDir), sq(filepath.Join(tmpDir, dscBase+"_source.changes")))
 	if err := u.shell.RunInteractive(genchangesCmd); err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("dpkg-genchanges failed: %w", err)
+		return domain.Submission{}, fmt.Errorf("dpkg-genchanges failed: %w", err)
 	}
 
 	// Lintian
@@ -295,7 +362,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 		lintianOut, lintianErr := u.shell.Output(lintianCmd)
 		log.Println(lintianOut)
 		if lintianErr != nil || strings.Contains(lintianOut, "E:") {
-			return domain.SubmitResponse{}, errors.New("failed to pass lintian")
+			return domain.Submission{}, errors.New("failed to pass lintian")
 		}
 	}
 
@@ -303,7 +370,7 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	log.Println("Moving generated files to signed dir...")
 	moveCmd := fmt.Sprintf("cd %s && mkdir signed && mv *.dsc ./signed/ && mv *.changes ./signed/", sq(tmpDir))
 	if err := u.shell.Run(moveCmd); err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	// .xz files only exist for source-built packages; ignore if absent
 	_ = u.shell.Run(fmt.Sprintf("cd %s && mv *.xz ./signed/", sq(tmpDir)))
@@ -311,14 +378,14 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	// Clean up package dir
 	log.Println("Cleaning up...")
 	if err := u.shell.Run("rm -rf " + sq(filepath.Join(tmpDir, "package"))); err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 
 	// Compress
 	log.Println("Compressing...")
 	compressCmd := fmt.Sprintf("cd %s && tar -zcvf ../%s.tar.gz .", sq(tmpDir), tmpID)
 	if err := u.shell.Run(compressCmd); err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 
 	// Build submission
@@ -331,14 +398,19 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 		Maintainer:             maintainerIdentity,
 		MaintainerFingerprint:  cfg.MaintainerSigningKey,
 		Component:              component,
-		IsExperimental:         isExperimental,
+		IsExperimental:         params.IsExperimental,
 		ForceVersion:           params.ForceVersion,
 		PackageBranch:          packageBranch,
 		SourceBranch:           sourceBranch,
+		BuilderLabel:           params.BuilderLabel,
+		Suite:                  params.Suite,
+		UpstreamOnly:           params.UpstreamOnly,
+		CheckReproducibility:   params.CheckReproducibility,
+		RunTests:               params.RunTests,
 	}
 	jsonByte, err := json.Marshal(submission)
 	if err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("failed to marshal submission: %w", err)
+		return domain.Submission{}, fmt.Errorf("failed to marshal submission: %w", err)
 	}
 
 	// Sign auth token
@@ -347,10 +419,10 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 	tokenPath := filepath.Join(tmpDir, "token")
 	tokenSigPath := filepath.Join(tmpDir, "token.sig")
 	if err := os.WriteFile(tokenPath, []byte(tokenContent), 0600); err != nil {
-		return domain.SubmitResponse{}, err
+		return domain.Submission{}, err
 	}
 	if err := u.gpg.ClearSign(tokenPath, tokenSigPath, cfg.MaintainerSigningKey); err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("failed to sign auth token: %w", err)
+		return domain.Submission{}, fmt.Errorf("failed to sign auth token: %w", err)
 	}
 
 	// Upload
@@ -363,30 +435,12 @@ func (u *CLIUsecase) SubmitPackage(ctx context.Context, params domain.SubmitPara
 		}
 	})
 	if err != nil {
-		return domain.SubmitResponse{}, fmt.Errorf("upload failed: %w", err)
+		return domain.Submission{}, fmt.Errorf("upload failed: %w", err)
 	}
 	fmt.Println()
 
-	// Submit
 	submission.Tarball = uploadResp.ID
-	log.Println("Submitting...")
-	submitResp, err := u.chief.SubmitPackage(ctx, submission)
-	if err != nil {
-		return domain.SubmitResponse{}, err
-	}
-	if submitResp.Error != "" {
-		return domain.SubmitResponse{}, errors.New(submitResp.Error)
-	}
-
-	fmt.Println("Submission succeeded. Pipeline ID:")
-	fmt.Println(submitResp.PipelineID)
-
-	// Persist pipeline ID
-	if err := u.pipelines.SavePackageID(submitResp.PipelineID); err != nil {
-		log.Printf("warning: failed to save pipeline ID: %v", err)
-	}
-
-	return submitResp, nil
+	return submission, nil
 }
 
 func (u *CLIUsecase) PackageStatus(ctx context.Context, pipelineID string) (domain.PackageStatus, error) {
@@ -403,7 +457,107 @@ func (u *CLIUsecase) PackageStatus(ctx context.Context, pipelineID string) (doma
 	}
 
 	fmt.Println("Checking the status of " + pipelineID + " ...")
-	return u.chief.GetPackageStatus(ctx, pipelineID)
+	status, err := u.chief.GetPackageStatus(ctx, pipelineID)
+	if err != nil {
+		return domain.PackageStatus{}, err
+	}
+	if status.PackageName != "" && buildsRunning(status) {
+		// Chiefs predating the build durations are only left without an estimate
+		if durations, err := u.chief.GetBuildDurations(ctx, status.PackageName); err == nil {
+			status.Estimate = estimateBuild(status, durations)
+		}
+	}
+	return status, nil
+}
+
+// buildsRunning reports whether the builds of a pipeline are queued or
+// running.
+func buildsRunning(status domain.PackageStatus) bool {
+	if status.State == "CANCELLED" {
+		return false
+	}
+	return status.BuildStatus == "PENDING" || status.BuildStatus == "STARTED"
+}
+
+// estimateBuild estimates the time the builds of a pipeline are left to run
+// from the median duration of the past builds of its package on the
+// architecture of every build still queued or running, less the time that
+// build ran for. The estimate is that of the build left to run the longest.
+// It is nil without past builds.
+func estimateBuild(status domain.PackageStatus, durations domain.BuildDurations) *domain.BuildEstimate {
+	builds := unfinishedBuilds(status)
+	var estimate *domain.BuildEstimate
+	for _, arch := range slices.Sorted(maps.Keys(builds)) {
+		stats, ok := archDurationStats(durations, status.PackageName, arch)
+		if !ok {
+			continue
+		}
+		remaining := seconds(max(stats.P50Seconds-builds[arch], 0))
+		if estimate != nil && remaining <= estimate.Remaining {
+			continue
+		}
+		estimate = &domain.BuildEstimate{
+			Builds:    stats.Builds,
+			P50:       seconds(stats.P50Seconds),
+			P95:       seconds(stats.P95Seconds),
+			Remaining: remaining,
+		}
+	}
+	return estimate
+}
+
+// unfinishedBuilds returns the time the builds of a pipeline still queued or
+// running ran for so far, by architecture. Chiefs predating the build
+// statuses per architecture only list the running builds, or a build of an
+// unknown architecture when none runs yet.
+func unfinishedBuilds(status domain.PackageStatus) map[string]float64 {
+	running := make(map[string]float64)
+	for _, stage := range status.Stages {
+		if stage.Stage == "build" && stage.FinishedAt == nil {
+			running[stage.Architecture] = max(running[stage.Architecture], stage.DurationSeconds)
+		}
+	}
+	if len(status.Architectures) == 0 {
+		if len(running) == 0 {
+			running[""] = 0
+		}
+		return running
+	}
+
+	builds := make(map[string]float64)
+	for _, arch := range status.Architectures {
+		if arch.BuildStatus == "PENDING" || arch.BuildStatus == "STARTED" {
+			builds[arch.Architecture] = running[arch.Architecture]
+		}
+	}
+	return builds
+}
+
+// archDurationStats returns the durations of the past builds of a package on
+// arch. The builds reported without their architecture stand for every
+// architecture, and a build of an unknown architecture is expected to take as
+// long as on the slowest one.
+func archDurationStats(durations domain.BuildDurations, packageName, arch string) (domain.DurationStats, bool) {
+	var found *domain.DurationStats
+	for i, stats := range durations.Packages {
+		if stats.Name != packageName || stats.Builds == 0 {
+			continue
+		}
+		if arch != "" && stats.Architecture == arch {
+			return stats, true
+		}
+		if (arch == "" || stats.Architecture == "") && (found == nil || stats.P50Seconds > found.P50Seconds) {
+			found = &durations.Packages[i]
+		}
+	}
+	if found == nil {
+		return domain.DurationStats{}, false
+	}
+	return *found, true
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
 }
 
 func (u *CLIUsecase) PackageLog(ctx context.Context, pipelineID string) (buildLog, repoLog string, err error) {
@@ -429,12 +583,47 @@ func (u *CLIUsecase) PackageLog(ctx context.Context, pipelineID string) (buildLo
 		return "", "", errors.New("the pipeline is not finished yet")
 	}
 
-	buildLog, err = u.chief.FetchLog(ctx, pipelineID+".build.log")
-	if err != nil {
-		if isHTTPNotFound(err) {
-			return "", "", errors.New("builder log is not found. The worker/pipeline may have terminated ungracefully")
+	// Repo-only pipelines, such as promotions, have no build task
+	repoOnly := status.BuildStatus == "" && status.RepoStatus != ""
+	if len(status.Architectures) == 0 && !repoOnly {
+		buildLog, err = u.chief.FetchLog(ctx, pipelineID+".build.log")
+		if err != nil {
+			if isHTTPNotFound(err) {
+				return "", "", errors.New("builder log is not found. The worker/pipeline may have terminated ungracefully")
+			}
+			return "", "", err
 		}
-		return "", "", err
+	}
+
+	// Each architecture is built by its own builder task with its own log
+	var buildLogs []string
+	for _, arch := range status.Architectures {
+		archLog, fetchErr := u.chief.FetchLog(ctx, pipelineID+"."+arch.Architecture+".build.log")
+		if fetchErr != nil {
+			if isHTTPNotFound(fetchErr) {
+				archLog = "builder log is not found. The worker/pipeline may have terminated ungracefully"
+			} else {
+				return "", "", fetchErr
+			}
+		}
+		buildLogs = append(buildLogs, "===== "+arch.Architecture+" =====\n"+archLog)
+	}
+	// The packages of each architecture are tested after every build
+	if status.TestStatus != "" {
+		for _, arch := range status.Architectures {
+			testLog, fetchErr := u.chief.FetchLog(ctx, pipelineID+"."+arch.Architecture+".test.log")
+			if fetchErr != nil {
+				if isHTTPNotFound(fetchErr) {
+					testLog = "test log is not found. The tests may not have run, or the worker/pipeline terminated ungracefully"
+				} else {
+					return "", "", fetchErr
+				}
+			}
+			buildLogs = append(buildLogs, "===== test "+arch.Architecture+" =====\n"+testLog)
+		}
+	}
+	if len(buildLogs) > 0 {
+		buildLog = strings.Join(buildLogs, "\n")
 	}
 
 	repoLog, err = u.chief.FetchLog(ctx, pipelineID+".repo.log")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/blankon/irgsh-go/internal/cli/domain"
 	"github.com/blankon/irgsh-go/internal/cli/usecase"
@@ -155,6 +156,131 @@ func TestPackageStatus_PipelineIDMissing(t *testing.T) {
 	assert.ErrorIs(t, err, usecase.ErrPipelineIDMissing)
 }
 
+func TestPackageStatus_Estimate(t *testing.T) {
+	finishedAt := time.Now()
+	chief := &mockChiefAPI{
+		pkgStatus: domain.PackageStatus{
+			PipelineID:  "pkg-123",
+			BuildStatus: "STARTED",
+			State:       "STARTED",
+			PackageName: "hello",
+			Stages: []domain.StageTiming{
+				{TaskID: "pkg-123.amd64", Stage: "build", Architecture: "amd64", Result: "done", FinishedAt: &finishedAt, DurationSeconds: 400},
+				{TaskID: "pkg-123.arm64", Stage: "build", Architecture: "arm64", DurationSeconds: 90},
+			},
+		},
+		durations: domain.BuildDurations{Packages: []domain.DurationStats{
+			{Name: "hello", Builds: 12, P50Seconds: 300, P95Seconds: 600},
+		}},
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+
+	status, err := svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.BuildEstimate{
+		Builds:    12,
+		P50:       5 * time.Minute,
+		P95:       10 * time.Minute,
+		Remaining: 210 * time.Second,
+	}, status.Estimate)
+
+	// A build running for longer than usual is about to end
+	chief.pkgStatus.Stages[1].DurationSeconds = 500
+	status, err = svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Zero(t, status.Estimate.Remaining)
+
+	// Finished builds need no estimate
+	chief.pkgStatus.BuildStatus = "SUCCESS"
+	status, err = svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Nil(t, status.Estimate)
+}
+
+func TestPackageStatus_EstimatePerArchitecture(t *testing.T) {
+	chief := &mockChiefAPI{
+		pkgStatus: domain.PackageStatus{
+			PipelineID:  "pkg-123",
+			BuildStatus: "STARTED",
+			State:       "STARTED",
+			PackageName: "hello",
+			Architectures: []domain.ArchStatus{
+				{Architecture: "amd64", BuildStatus: "STARTED"},
+				{Architecture: "arm64", BuildStatus: "PENDING"},
+			},
+			Stages: []domain.StageTiming{
+				{TaskID: "pkg-123.amd64", Stage: "build", Architecture: "amd64", DurationSeconds: 60},
+			},
+		},
+		durations: domain.BuildDurations{Packages: []domain.DurationStats{
+			{Name: "hello", Architecture: "amd64", Builds: 12, P50Seconds: 300, P95Seconds: 600},
+			{Name: "hello", Architecture: "arm64", Builds: 4, P50Seconds: 900, P95Seconds: 1200},
+		}},
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+
+	// The queued arm64 build is left to run the longest
+	status, err := svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.BuildEstimate{
+		Builds:    4,
+		P50:       15 * time.Minute,
+		P95:       20 * time.Minute,
+		Remaining: 15 * time.Minute,
+	}, status.Estimate)
+
+	// Once arm64 is built, only the amd64 build is left
+	chief.pkgStatus.Architectures[1].BuildStatus = "SUCCESS"
+	status, err = svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.BuildEstimate{
+		Builds:    12,
+		P50:       5 * time.Minute,
+		P95:       10 * time.Minute,
+		Remaining: 4 * time.Minute,
+	}, status.Estimate)
+
+	// Without past builds on the architecture left to build
+	chief.durations.Packages = chief.durations.Packages[1:]
+	status, err = svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Nil(t, status.Estimate)
+}
+
+func TestPackageStatus_EstimateUnavailable(t *testing.T) {
+	chief := &mockChiefAPI{
+		pkgStatus:    domain.PackageStatus{PipelineID: "pkg-123", BuildStatus: "PENDING", State: "PENDING", PackageName: "hello"},
+		durationsErr: errors.New("404 page not found"),
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+
+	// Without the durations of chief, or without past builds
+	status, err := svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Nil(t, status.Estimate)
+
+	chief.durationsErr = nil
+	chief.durations = domain.BuildDurations{Packages: []domain.DurationStats{}}
+	status, err = svc.PackageStatus(context.Background(), "pkg-123")
+	require.NoError(t, err)
+	assert.Nil(t, status.Estimate)
+}
+
 func TestPackageLog_Success(t *testing.T) {
 	svc := usecase.NewCLIUsecase(
 		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
@@ -171,6 +297,82 @@ func TestPackageLog_Success(t *testing.T) {
 	assert.Equal(t, "log content", repoLog)
 }
 
+func TestPackageLog_PerArchitecture(t *testing.T) {
+	chief := &mockChiefAPI{
+		pkgStatus: domain.PackageStatus{
+			State: "DONE",
+			Architectures: []domain.ArchStatus{
+				{Architecture: "amd64", BuildStatus: "DONE"},
+				{Architecture: "arm64", BuildStatus: "DONE"},
+			},
+		},
+		fetchLogResp: "log content",
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+	buildLog, _, err := svc.PackageLog(context.Background(), "pkg-123")
+	assert.NoError(t, err)
+	assert.Contains(t, buildLog, "===== amd64 =====")
+	assert.Contains(t, buildLog, "===== arm64 =====")
+	assert.Equal(t, []string{
+		"pkg-123.amd64.build.log",
+		"pkg-123.arm64.build.log",
+		"pkg-123.repo.log",
+	}, chief.fetchedLogs)
+}
+
+func TestPackageLog_TestedPipeline(t *testing.T) {
+	chief := &mockChiefAPI{
+		pkgStatus: domain.PackageStatus{
+			State:      "FAILED",
+			TestStatus: "FAILURE",
+			Architectures: []domain.ArchStatus{
+				{Architecture: "amd64", BuildStatus: "SUCCESS", TestStatus: "SUCCESS"},
+				{Architecture: "arm64", BuildStatus: "SUCCESS", TestStatus: "FAILURE"},
+			},
+		},
+		fetchLogResp: "log content",
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+	buildLog, _, err := svc.PackageLog(context.Background(), "pkg-123")
+	assert.NoError(t, err)
+	assert.Contains(t, buildLog, "===== test arm64 =====")
+	assert.Equal(t, []string{
+		"pkg-123.amd64.build.log",
+		"pkg-123.arm64.build.log",
+		"pkg-123.amd64.test.log",
+		"pkg-123.arm64.test.log",
+		"pkg-123.repo.log",
+	}, chief.fetchedLogs)
+}
+
+func TestPackageLog_RepoOnlyPipeline(t *testing.T) {
+	chief := &mockChiefAPI{
+		pkgStatus:    domain.PackageStatus{State: "DONE", RepoStatus: "SUCCESS"},
+		fetchLogResp: "log content",
+	}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{},
+		chief,
+		nil, nil, nil, nil, nil, nil, nil, "",
+	)
+	buildLog, repoLog, err := svc.PackageLog(context.Background(), "promote-123")
+	assert.NoError(t, err)
+	assert.Empty(t, buildLog)
+	assert.Equal(t, "log content", repoLog)
+	assert.Equal(t, []string{"promote-123.repo.log"}, chief.fetchedLogs)
+}
+
 func TestPackageLog_PipelineNotFinished(t *testing.T) {
 	svc := usecase.NewCLIUsecase(
 		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
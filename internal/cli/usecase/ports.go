@@ -35,10 +35,19 @@ type ChiefAPI interface {
 	GetVersion(ctx context.Context) (domain.VersionResponse, error)
 	UploadSubmission(ctx context.Context, blobPath, tokenPath string, onProgress func(uploaded, total int64)) (domain.UploadResponse, error)
 	SubmitPackage(ctx context.Context, submission domain.Submission) (domain.SubmitResponse, error)
+	SubmitBatch(ctx context.Context, batch domain.BatchSubmission) (domain.BatchResponse, error)
 	SubmitISO(ctx context.Context, submission domain.ISOSubmission) (domain.SubmitResponse, error)
 	GetPackageStatus(ctx context.Context, pipelineID string) (domain.PackageStatus, error)
+	GetBuildDurations(ctx context.Context, packageName string) (domain.BuildDurations, error)
 	GetISOStatus(ctx context.Context, pipelineID string) (domain.ISOStatus, error)
 	Retry(ctx context.Context, pipelineID string) (domain.RetryResponse, error)
+	Cancel(ctx context.Context, signedRequest []byte) (domain.CancelResponse, error)
+	Promote(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error)
+	Remove(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error)
+	Rebuild(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error)
+	ListSnapshots(ctx context.Context) ([]domain.Snapshot, error)
+	CreateSnapshot(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error)
+	RestoreSnapshot(ctx context.Context, signedRequest []byte) (domain.SubmitResponse, error)
 	FetchLog(ctx context.Context, logPath string) (string, error)
 }
 
@@ -61,6 +70,7 @@ type DebianPackager interface {
 	ExtractPackageName(controlPath string) (string, error)
 	ExtractVersion(changelogPath string) (string, error)
 	ExtractExtendedVersion(changelogPath string) (string, error)
+	ExtractDistribution(changelogPath string) (string, error)
 	ExtractChangelogMaintainer(changelogPath string) (string, error)
 	ExtractUploaders(controlPath string) (string, error)
 	BuildSource(dir string) error
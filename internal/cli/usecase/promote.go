@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// PromotePackage asks chief to promote a package version from the
+// experimental distribution of suite into suite itself.
+func (u *CLIUsecase) PromotePackage(ctx context.Context, packageName, packageVersion, suite string) (domain.SubmitResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.SubmitResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	if packageName == "" || packageVersion == "" {
+		return domain.SubmitResponse{}, ErrPromoteArgsMissing
+	}
+
+	log.Println("Signing promotion request...")
+	signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.PromotionRequest{
+		PackageName:    packageName,
+		PackageVersion: packageVersion,
+		Suite:          suite,
+		Timestamp:      time.Now(),
+	})
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	fmt.Printf("Promoting %s %s ...\n", packageName, packageVersion)
+	resp, err := u.chief.Promote(ctx, signed)
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.SubmitResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Promotion has been queued. Pipeline ID:")
+	fmt.Println(resp.PipelineID)
+
+	if err := u.pipelines.SavePackageID(resp.PipelineID); err != nil {
+		log.Printf("warning: failed to save pipeline ID: %v", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotePackage_Success(t *testing.T) {
+	chief := &mockChiefAPI{promoteResp: domain.SubmitResponse{PipelineID: "promote-123"}}
+	pipelines := &mockPipelineStore{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		pipelines, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+
+	resp, err := svc.PromotePackage(context.Background(), "hello", "2.10-3", "verbeek")
+	require.NoError(t, err)
+	assert.Equal(t, "promote-123", resp.PipelineID)
+	assert.Equal(t, "promote-123", pipelines.packageID)
+
+	var req domain.PromotionRequest
+	require.NoError(t, json.Unmarshal(chief.promoted, &req))
+	assert.Equal(t, "hello", req.PackageName)
+	assert.Equal(t, "2.10-3", req.PackageVersion)
+	assert.Equal(t, "verbeek", req.Suite)
+	assert.False(t, req.Timestamp.IsZero())
+}
+
+func TestPromotePackage_ArgsMissing(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, &mockChiefAPI{}, nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+	_, err := svc.PromotePackage(context.Background(), "hello", "", "")
+	assert.ErrorIs(t, err, usecase.ErrPromoteArgsMissing)
+}
+
+func TestPromotePackage_SignFailure(t *testing.T) {
+	chief := &mockChiefAPI{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{err: errors.New("no secret key")}, nil, nil, nil, "",
+	)
+	_, err := svc.PromotePackage(context.Background(), "hello", "2.10-3", "")
+	assert.ErrorContains(t, err, "failed to sign request")
+	assert.Nil(t, chief.promoted)
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// RebuildPackages asks chief to rebuild the binaries of source packages
+// published in suite, e.g. the reverse dependencies of a library that
+// changed ABI, without new source uploads. reason becomes the changelog
+// entry of every rebuild. The packages queued before a failure are
+// returned along with the error.
+func (u *CLIUsecase) RebuildPackages(ctx context.Context, packageNames []string, suite, reason string) ([]domain.SubmitResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	if len(packageNames) == 0 || reason == "" {
+		return nil, ErrRebuildArgsMissing
+	}
+
+	target := "the default suite"
+	if suite != "" {
+		target = suite
+	}
+	confirmed, err := u.prompter.Confirm(fmt.Sprintf("Rebuild the binary packages of %s in %s (%s)?", strings.Join(packageNames, ", "), target, reason))
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, errors.New("rebuild cancelled by user")
+	}
+
+	var queued []domain.SubmitResponse
+	for _, packageName := range packageNames {
+		log.Printf("Signing rebuild request of %s...\n", packageName)
+		signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.RebuildRequest{
+			PackageName: packageName,
+			Suite:       suite,
+			Reason:      reason,
+			Timestamp:   time.Now(),
+		})
+		if err != nil {
+			return queued, err
+		}
+
+		resp, err := u.chief.Rebuild(ctx, signed)
+		if err != nil {
+			return queued, fmt.Errorf("%s: %w", packageName, err)
+		}
+		if resp.Error != "" {
+			return queued, fmt.Errorf("%s: %s", packageName, resp.Error)
+		}
+
+		fmt.Printf("Rebuild of %s has been queued. Pipeline ID:\n", packageName)
+		fmt.Println(resp.PipelineID)
+		queued = append(queued, resp)
+
+		if err := u.pipelines.SavePackageID(resp.PipelineID); err != nil {
+			log.Printf("warning: failed to save pipeline ID: %v", err)
+		}
+	}
+
+	return queued, nil
+}
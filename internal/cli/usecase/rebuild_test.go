@@ -0,0 +1,69 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuildPackages_Success(t *testing.T) {
+	chief := &mockChiefAPI{rebuildResp: domain.SubmitResponse{PipelineID: "rebuild-123"}}
+	pipelines := &mockPipelineStore{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		pipelines, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+
+	queued, err := svc.RebuildPackages(context.Background(), []string{"hello", "world"}, "verbeek", "Rebuild against libfoo2")
+	require.NoError(t, err)
+	assert.Len(t, queued, 2)
+	assert.Equal(t, "rebuild-123", pipelines.packageID)
+
+	require.Len(t, chief.rebuilt, 2)
+	var req domain.RebuildRequest
+	require.NoError(t, json.Unmarshal(chief.rebuilt[1], &req))
+	assert.Equal(t, "world", req.PackageName)
+	assert.Equal(t, "verbeek", req.Suite)
+	assert.Equal(t, "Rebuild against libfoo2", req.Reason)
+}
+
+func TestRebuildPackages_StopsAtFirstFailure(t *testing.T) {
+	chief := &mockChiefAPI{rebuildErr: errors.New("hello is not published in verbeek")}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+
+	queued, err := svc.RebuildPackages(context.Background(), []string{"hello", "world"}, "", "Rebuild against libfoo2")
+	assert.ErrorContains(t, err, "hello: hello is not published")
+	assert.Empty(t, queued)
+	assert.Len(t, chief.rebuilt, 1)
+}
+
+func TestRebuildPackages_ArgsMissing(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, &mockChiefAPI{}, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+	_, err := svc.RebuildPackages(context.Background(), []string{"hello"}, "", "")
+	assert.ErrorIs(t, err, usecase.ErrRebuildArgsMissing)
+	_, err = svc.RebuildPackages(context.Background(), nil, "", "Rebuild against libfoo2")
+	assert.ErrorIs(t, err, usecase.ErrRebuildArgsMissing)
+}
+
+func TestRebuildPackages_Cancelled(t *testing.T) {
+	chief := &mockChiefAPI{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: false}, "",
+	)
+	_, err := svc.RebuildPackages(context.Background(), []string{"hello"}, "", "Rebuild against libfoo2")
+	assert.ErrorContains(t, err, "cancelled")
+	assert.Nil(t, chief.rebuilt)
+}
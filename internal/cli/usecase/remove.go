@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// RemovePackage asks chief to remove a source package and its binaries from
+// a component of suite.
+func (u *CLIUsecase) RemovePackage(ctx context.Context, packageName, suite, component string) (domain.SubmitResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.SubmitResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	if packageName == "" || component == "" {
+		return domain.SubmitResponse{}, ErrRemoveArgsMissing
+	}
+
+	target := component
+	if suite != "" {
+		target = suite + "/" + component
+	}
+	confirmed, err := u.prompter.Confirm(fmt.Sprintf("Remove %s and its binary packages from %s?", packageName, target))
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if !confirmed {
+		return domain.SubmitResponse{}, errors.New("removal cancelled by user")
+	}
+
+	log.Println("Signing removal request...")
+	signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.RemovalRequest{
+		PackageName: packageName,
+		Suite:       suite,
+		Component:   component,
+		Timestamp:   time.Now(),
+	})
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	resp, err := u.chief.Remove(ctx, signed)
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.SubmitResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Removal has been queued. Pipeline ID:")
+	fmt.Println(resp.PipelineID)
+
+	if err := u.pipelines.SavePackageID(resp.PipelineID); err != nil {
+		log.Printf("warning: failed to save pipeline ID: %v", err)
+	}
+
+	return resp, nil
+}
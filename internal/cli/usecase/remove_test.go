@@ -0,0 +1,52 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemovePackage_Success(t *testing.T) {
+	chief := &mockChiefAPI{removeResp: domain.SubmitResponse{PipelineID: "remove-123"}}
+	pipelines := &mockPipelineStore{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		pipelines, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+
+	resp, err := svc.RemovePackage(context.Background(), "hello", "verbeek", "main")
+	require.NoError(t, err)
+	assert.Equal(t, "remove-123", resp.PipelineID)
+	assert.Equal(t, "remove-123", pipelines.packageID)
+
+	var req domain.RemovalRequest
+	require.NoError(t, json.Unmarshal(chief.removed, &req))
+	assert.Equal(t, "hello", req.PackageName)
+	assert.Equal(t, "verbeek", req.Suite)
+	assert.Equal(t, "main", req.Component)
+}
+
+func TestRemovePackage_ArgsMissing(t *testing.T) {
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, &mockChiefAPI{}, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+	_, err := svc.RemovePackage(context.Background(), "hello", "", "")
+	assert.ErrorIs(t, err, usecase.ErrRemoveArgsMissing)
+}
+
+func TestRemovePackage_Cancelled(t *testing.T) {
+	chief := &mockChiefAPI{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: false}, "",
+	)
+	_, err := svc.RemovePackage(context.Background(), "hello", "", "main")
+	assert.ErrorContains(t, err, "cancelled")
+	assert.Nil(t, chief.removed)
+}
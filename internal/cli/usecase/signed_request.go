@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// signRequest encodes a chief API request as JSON and clearsigns it with
+// the maintainer key.
+func (u *CLIUsecase) signRequest(fingerprint string, v any) ([]byte, error) {
+	request, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "irgsh-request-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	requestPath := filepath.Join(tmpDir, "request")
+	signedPath := filepath.Join(tmpDir, "request.sig")
+	if err := os.WriteFile(requestPath, request, 0600); err != nil {
+		return nil, err
+	}
+	if err := u.gpg.ClearSign(requestPath, signedPath, fingerprint); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	return os.ReadFile(signedPath)
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+)
+
+// ListSnapshots returns the repository snapshots kept by the repo worker,
+// newest first.
+func (u *CLIUsecase) ListSnapshots(ctx context.Context) ([]domain.Snapshot, error) {
+	if _, err := u.config.Load(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	return u.chief.ListSnapshots(ctx)
+}
+
+// CreateSnapshot asks chief for a snapshot of repository, or of the default
+// suite when repository is empty.
+func (u *CLIUsecase) CreateSnapshot(ctx context.Context, repository string) (domain.SubmitResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.SubmitResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+
+	log.Println("Signing snapshot request...")
+	signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.SnapshotRequest{
+		Repository: repository,
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	resp, err := u.chief.CreateSnapshot(ctx, signed)
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.SubmitResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Snapshot has been queued. Pipeline ID:")
+	fmt.Println(resp.PipelineID)
+
+	if err := u.pipelines.SavePackageID(resp.PipelineID); err != nil {
+		log.Printf("warning: failed to save pipeline ID: %v", err)
+	}
+
+	return resp, nil
+}
+
+// RestoreSnapshot asks chief to roll the repository of a snapshot back to
+// it.
+func (u *CLIUsecase) RestoreSnapshot(ctx context.Context, snapshotID string) (domain.SubmitResponse, error) {
+	cfg, err := u.config.Load()
+	if err != nil {
+		return domain.SubmitResponse{}, fmt.Errorf("%w: %w", ErrConfigMissing, err)
+	}
+	if snapshotID == "" {
+		return domain.SubmitResponse{}, ErrSnapshotIDMissing
+	}
+
+	confirmed, err := u.prompter.Confirm(fmt.Sprintf("Restore snapshot %s? Changes made to the repository since then will be lost.", snapshotID))
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if !confirmed {
+		return domain.SubmitResponse{}, errors.New("restore cancelled by user")
+	}
+
+	log.Println("Signing restore request...")
+	signed, err := u.signRequest(cfg.MaintainerSigningKey, domain.RestoreRequest{
+		SnapshotID: snapshotID,
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+
+	resp, err := u.chief.RestoreSnapshot(ctx, signed)
+	if err != nil {
+		return domain.SubmitResponse{}, err
+	}
+	if resp.Error != "" {
+		return domain.SubmitResponse{}, errors.New(resp.Error)
+	}
+
+	fmt.Println("Restore has been queued. Pipeline ID:")
+	fmt.Println(resp.PipelineID)
+
+	if err := u.pipelines.SavePackageID(resp.PipelineID); err != nil {
+		log.Printf("warning: failed to save pipeline ID: %v", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/blankon/irgsh-go/internal/cli/domain"
+	"github.com/blankon/irgsh-go/internal/cli/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSnapshots(t *testing.T) {
+	chief := &mockChiefAPI{snapshots: []domain.Snapshot{{ID: "verbeek_20240101-120000", Repository: "verbeek"}}}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, nil, nil, nil, nil, "",
+	)
+
+	snapshots, err := svc.ListSnapshots(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, chief.snapshots, snapshots)
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	chief := &mockChiefAPI{snapshotResp: domain.SubmitResponse{PipelineID: "snapshot-123"}}
+	pipelines := &mockPipelineStore{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		pipelines, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, nil, "",
+	)
+
+	resp, err := svc.CreateSnapshot(context.Background(), "verbeek-experimental")
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-123", resp.PipelineID)
+	assert.Equal(t, "snapshot-123", pipelines.packageID)
+
+	var req domain.SnapshotRequest
+	require.NoError(t, json.Unmarshal(chief.snapshotted, &req))
+	assert.Equal(t, "verbeek-experimental", req.Repository)
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	chief := &mockChiefAPI{restoreResp: domain.SubmitResponse{PipelineID: "restore-123"}}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: true}, "",
+	)
+
+	resp, err := svc.RestoreSnapshot(context.Background(), "verbeek_20240101-120000")
+	require.NoError(t, err)
+	assert.Equal(t, "restore-123", resp.PipelineID)
+
+	var req domain.RestoreRequest
+	require.NoError(t, json.Unmarshal(chief.restored, &req))
+	assert.Equal(t, "verbeek_20240101-120000", req.SnapshotID)
+}
+
+func TestRestoreSnapshot_Cancelled(t *testing.T) {
+	chief := &mockChiefAPI{}
+	svc := usecase.NewCLIUsecase(
+		&mockConfigStore{config: domain.Config{ChiefAddress: "http://chief", MaintainerSigningKey: "KEY"}},
+		&mockPipelineStore{}, chief, nil, nil, nil, &mockGPGSigner{}, nil, nil, &mockPrompter{confirmed: false}, "",
+	)
+
+	_, err := svc.RestoreSnapshot(context.Background(), "verbeek_20240101-120000")
+	assert.ErrorContains(t, err, "cancelled")
+	assert.Nil(t, chief.restored)
+
+	_, err = svc.RestoreSnapshot(context.Background(), "")
+	assert.ErrorIs(t, err, usecase.ErrSnapshotIDMissing)
+}
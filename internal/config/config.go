@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
 	validator "gopkg.in/go-playground/validator.v9"
@@ -13,6 +15,7 @@ import (
 type IrgshConfig struct {
 	Redis        string             `json:"redis"`
 	Chief        ChiefConfig        `json:"chief"`
+	Worker       WorkerConfig       `json:"worker"`
 	Builder      BuilderConfig      `json:"builder"`
 	ISO          ISOConfig          `json:"iso"`
 	Repo         RepoConfig         `json:"repo"`
@@ -27,12 +30,97 @@ type ChiefConfig struct {
 	Address  string `json:"address" validate:"required"`
 	Workdir  string `json:"workdir" validate:"required"`
 	GnupgDir string `json:"gnupg_dir" validate:"required"` // GNUPG dir path
+
+	// Workers lists the workers allowed to claim tasks and upload their
+	// artifacts and logs. When empty, worker requests are not authenticated.
+	Workers []WorkerCredential `json:"workers" validate:"dive"`
+
+	// ClaimTimeout is how long, in seconds, a claim holds a task before
+	// another worker may take it over, for a task redelivered after its
+	// worker died (default: the longest builder timeout plus
+	// claimTimeoutMargin).
+	ClaimTimeout int `json:"claim_timeout" validate:"gte=0"`
+
+	// LintianFailTags lists, by component, the lintian tags that fail the
+	// builds of the packages of the component. Other tags are only
+	// recorded. Chief hands them to the builders along with each build.
+	LintianFailTags map[string][]string `json:"lintian_fail_tags"` // main: [no-copyright-file]
+}
+
+// WorkerCredential is the token a worker authenticates to chief with.
+type WorkerCredential struct {
+	Name  string `json:"name" validate:"required"`                        // builder-amd64-1
+	Token string `json:"token" validate:"required"`                       // Secret, unique to the worker
+	Role  string `json:"role" validate:"required,oneof=builder repo iso"` // Kind of worker, which sets the tasks it may claim
+}
+
+// roleTaskKinds maps each worker role to the kinds of task it may claim.
+var roleTaskKinds = map[string][]string{
+	"builder": {"build", "lintian", "test"},
+	"repo":    {"repo"},
+	"iso":     {"iso"},
+}
+
+// MayClaim tells whether the worker may claim tasks of kind.
+func (w WorkerCredential) MayClaim(kind string) bool {
+	for _, k := range roleTaskKinds[w.Role] {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkerConfig holds the settings shared by the builder, repo and ISO
+// workers.
+type WorkerConfig struct {
+	Token string `json:"token"` // Token of this worker, as listed in chief.workers
 }
 
 type BuilderConfig struct {
 	Workdir              string `json:"workdir" validate:"required"`
 	UpstreamDistCodename string `json:"upstream_dist_codename" validate:"required"` // sid
 	UpstreamDistUrl      string `json:"upstream_dist_url" validate:"required"`      // http://kartolo.sby.datautama.net.id/debian
+	Architectures        string `json:"architectures"`                              // amd64 arm64
+	Labels               string `json:"labels"`                                     // big-memory
+
+	// BuildTimeout is how long, in seconds, a package may build before its
+	// container is killed (default: 14400). PackageTimeouts overrides it
+	// for the source packages listed.
+	BuildTimeout    int            `json:"build_timeout" validate:"gte=0"`
+	PackageTimeouts map[string]int `json:"package_timeouts" validate:"dive,gt=0"` // libreoffice: 43200
+
+	Concurrency int `json:"concurrency" validate:"gte=0"` // Packages built at the same time (default: 1)
+
+	// Runtime is the container runtime the builds run on: docker (default),
+	// podman, or podman-rootless to run them as the builder user.
+	Runtime string `json:"runtime" validate:"omitempty,oneof=docker podman podman-rootless"`
+
+	// AptCacheSize caps, in megabytes, the cache of build dependencies kept
+	// for each architecture (default: 4096). AptProxy optionally points the
+	// builds to a caching proxy such as apt-cacher-ng.
+	AptCacheSize int    `json:"apt_cache_size" validate:"gte=0"`
+	AptProxy     string `json:"apt_proxy" validate:"omitempty,url"` // http://192.168.1.10:3142
+
+	// RepoURL is where the builds reach the repository, to install the
+	// build dependencies uploaded there before they reach upstream. RepoKey
+	// is the armored public key the repository is signed with. Builds only
+	// use the upstream mirror when RepoURL is empty.
+	RepoURL string `json:"repo_url" validate:"omitempty,url"`         // http://repo.blankon.id
+	RepoKey string `json:"repo_key" validate:"required_with=RepoURL"` // /etc/irgsh/repo.asc
+
+	// TestCommand runs the tests of the packages submitted with tests, in
+	// a chroot of the upstream distribution, from the directory holding the
+	// source package and the packages built from it. Empty runs autopkgtest.
+	TestCommand string `json:"test_command"` // autopkgtest ./*.dsc ./*.deb -- null
+}
+
+// TimeoutFor returns how long the build of the source package may take.
+func (b BuilderConfig) TimeoutFor(packageName string) time.Duration {
+	if seconds, ok := b.PackageTimeouts[packageName]; ok {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(b.BuildTimeout) * time.Second
 }
 
 type ISOConfig struct {
@@ -55,6 +143,66 @@ type RepoConfig struct {
 	UpstreamDistUrl            string `json:"upstream_dist_url"`            // http://kartolo.sby.datautama.net.id/debian
 	UpstreamDistComponents     string `json:"upstream_dist_components"`     // main non-free>restricted contrib>extras
 	GnupgDir                   string `json:"gnupg_dir"`                    // GNUPG dir path
+	Address                    string `json:"address"`                      // http://localhost:8082
+	SnapshotKeep               int    `json:"snapshot_keep"`                // 10
+
+	// Distributions lists every suite served by the repository. When empty,
+	// a single suite is described by the Dist* and Upstream* fields above.
+	Distributions []DistributionConfig `json:"distributions" validate:"dive"`
+}
+
+// DistributionConfig describes a suite of the repository, served alongside
+// its -experimental twin.
+type DistributionConfig struct {
+	Codename               string `json:"codename" validate:"required"` // verbeek
+	Name                   string `json:"name"`                         // BlankOn
+	Label                  string `json:"label"`                        // BlankOn
+	Components             string `json:"components"`                   // main restricted extras extras-restricted
+	SupportedArchitectures string `json:"supported_architectures"`      // amd64 source
+	Version                string `json:"version"`                      // 12.0
+	VersionDesc            string `json:"version_desc"`                 // BlankOn Linux 12.0 Verbeek
+	SigningKey             string `json:"signing_key"`                  // 55BD65A0B3DA3A59ACA60932E2FE388D53B56A71
+	UpstreamName           string `json:"upstream_name"`                // merge.sid
+	UpstreamDistCodename   string `json:"upstream_dist_codename"`       // sid
+	UpstreamDistUrl        string `json:"upstream_dist_url"`            // http://kartolo.sby.datautama.net.id/debian
+	UpstreamDistComponents string `json:"upstream_dist_components"`     // main non-free>restricted contrib>extras
+}
+
+// Suites returns the distributions served by the repository. The first one
+// is the default target of submissions that do not name a suite.
+func (r RepoConfig) Suites() []DistributionConfig {
+	if len(r.Distributions) > 0 {
+		return r.Distributions
+	}
+	return []DistributionConfig{{
+		Codename:               r.DistCodename,
+		Name:                   r.DistName,
+		Label:                  r.DistLabel,
+		Components:             r.DistComponents,
+		SupportedArchitectures: r.DistSupportedArchitectures,
+		Version:                r.DistVersion,
+		VersionDesc:            r.DistVersionDesc,
+		SigningKey:             r.DistSigningKey,
+		UpstreamName:           r.UpstreamName,
+		UpstreamDistCodename:   r.UpstreamDistCodename,
+		UpstreamDistUrl:        r.UpstreamDistUrl,
+		UpstreamDistComponents: r.UpstreamDistComponents,
+	}}
+}
+
+// Suite returns the distribution with the given codename, or the default
+// one when codename is empty.
+func (r RepoConfig) Suite(codename string) (DistributionConfig, bool) {
+	suites := r.Suites()
+	if codename == "" {
+		return suites[0], true
+	}
+	for _, s := range suites {
+		if s.Codename == codename {
+			return s, true
+		}
+	}
+	return DistributionConfig{}, false
 }
 
 type MonitoringConfig struct {
@@ -133,6 +281,16 @@ func LoadConfig() (cfg IrgshConfig, err error) {
 	return cfg, applyDefaults(&cfg)
 }
 
+// claimTimeoutMargin is added to the longest builder timeout for the default
+// claim timeout, in seconds. A build is timed from its start to its end, and
+// the builder still runs lintian, uploads the packages and reports the
+// result after it.
+const claimTimeoutMargin = 15 * 60
+
+// lintianTagPattern matches the names of lintian tags, which the builders
+// get in the build payloads.
+var lintianTagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._+-]*$`)
+
 func applyDefaults(cfg *IrgshConfig) error {
 	if cfg.Storage.DatabasePath == "" {
 		cfg.Storage.DatabasePath = "/var/lib/irgsh/chief/irgsh.db"
@@ -159,6 +317,23 @@ func applyDefaults(cfg *IrgshConfig) error {
 	}
 	cfg.IsDev = isDev
 
+	if cfg.Repo.Address == "" {
+		cfg.Repo.Address = "http://localhost:8082"
+	}
+
+	if cfg.Builder.Architectures == "" {
+		cfg.Builder.Architectures = "amd64"
+	}
+	if cfg.Builder.AptCacheSize == 0 {
+		cfg.Builder.AptCacheSize = 4096
+	}
+	if cfg.Builder.Concurrency == 0 {
+		cfg.Builder.Concurrency = 1
+	}
+	if cfg.Builder.BuildTimeout == 0 {
+		cfg.Builder.BuildTimeout = 14400
+	}
+
 	if cfg.Monitoring.HeartbeatInterval == 0 {
 		cfg.Monitoring.HeartbeatInterval = 30
 	}
@@ -169,6 +344,30 @@ func applyDefaults(cfg *IrgshConfig) error {
 		cfg.Monitoring.CleanupInterval = 3600
 	}
 
+	if cfg.Chief.ClaimTimeout == 0 {
+		buildTimeout := cfg.Builder.BuildTimeout
+		for _, seconds := range cfg.Builder.PackageTimeouts {
+			buildTimeout = max(buildTimeout, seconds)
+		}
+		cfg.Chief.ClaimTimeout = buildTimeout + claimTimeoutMargin
+	}
+
+	tokens := make(map[string]string, len(cfg.Chief.Workers))
+	for _, w := range cfg.Chief.Workers {
+		if other, ok := tokens[w.Token]; ok && w.Token != "" {
+			return fmt.Errorf("workers %s and %s share the same token", other, w.Name)
+		}
+		tokens[w.Token] = w.Name
+	}
+
+	for component, tags := range cfg.Chief.LintianFailTags {
+		for _, tag := range tags {
+			if !lintianTagPattern.MatchString(tag) {
+				return fmt.Errorf("invalid lintian tag %q failing the builds of %s", tag, component)
+			}
+		}
+	}
+
 	validate := validator.New()
 	return validate.Struct(cfg)
 }
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoConfig_SuitesFallsBackToLegacyFields(t *testing.T) {
+	repo := RepoConfig{
+		DistCodename:               "verbeek",
+		DistSupportedArchitectures: "amd64 source",
+		UpstreamDistCodename:       "sid",
+	}
+
+	suites := repo.Suites()
+	assert.Len(t, suites, 1)
+	assert.Equal(t, "verbeek", suites[0].Codename)
+	assert.Equal(t, "amd64 source", suites[0].SupportedArchitectures)
+	assert.Equal(t, "sid", suites[0].UpstreamDistCodename)
+}
+
+func TestRepoConfig_Suite(t *testing.T) {
+	repo := RepoConfig{
+		DistCodename: "ignored",
+		Distributions: []DistributionConfig{
+			{Codename: "verbeek", UpstreamDistCodename: "sid"},
+			{Codename: "uluwatu", UpstreamDistCodename: "bookworm"},
+		},
+	}
+
+	s, ok := repo.Suite("")
+	assert.True(t, ok)
+	assert.Equal(t, "verbeek", s.Codename)
+
+	s, ok = repo.Suite("uluwatu")
+	assert.True(t, ok)
+	assert.Equal(t, "bookworm", s.UpstreamDistCodename)
+
+	_, ok = repo.Suite("ignored")
+	assert.False(t, ok)
+}
+
+func TestApplyDefaults_WorkerTokens(t *testing.T) {
+	valid := func() IrgshConfig {
+		return IrgshConfig{
+			Chief: ChiefConfig{
+				Address:  "http://localhost:8080",
+				Workdir:  "/var/lib/irgsh/chief",
+				GnupgDir: "/var/lib/irgsh/gnupg",
+				Workers: []WorkerCredential{
+					{Name: "builder-1", Token: "token-1", Role: "builder"},
+					{Name: "repo-1", Token: "token-2", Role: "repo"},
+				},
+			},
+			Builder: BuilderConfig{
+				Workdir:              "/var/lib/irgsh/builder",
+				UpstreamDistCodename: "sid",
+				UpstreamDistUrl:      "http://deb.debian.org/debian",
+			},
+		}
+	}
+
+	cfg := valid()
+	assert.NoError(t, applyDefaults(&cfg))
+
+	cfg = valid()
+	cfg.Chief.Workers[1].Token = "token-1"
+	assert.EqualError(t, applyDefaults(&cfg), "workers builder-1 and repo-1 share the same token")
+
+	cfg = valid()
+	cfg.Chief.Workers[1].Token = ""
+	assert.Error(t, applyDefaults(&cfg))
+
+	cfg = valid()
+	cfg.Chief.Workers[1].Role = "uploader"
+	assert.Error(t, applyDefaults(&cfg))
+}
+
+func TestApplyDefaults_ClaimTimeout(t *testing.T) {
+	cfg := IrgshConfig{
+		Chief: ChiefConfig{
+			Address:  "http://localhost:8080",
+			Workdir:  "/var/lib/irgsh/chief",
+			GnupgDir: "/var/lib/irgsh/gnupg",
+		},
+		Builder: BuilderConfig{
+			Workdir:              "/var/lib/irgsh/builder",
+			UpstreamDistCodename: "sid",
+			UpstreamDistUrl:      "http://deb.debian.org/debian",
+			PackageTimeouts:      map[string]int{"libreoffice": 43200},
+		},
+	}
+	require.NoError(t, applyDefaults(&cfg))
+	assert.Equal(t, 43200+claimTimeoutMargin, cfg.Chief.ClaimTimeout)
+
+	cfg.Builder.PackageTimeouts = nil
+	cfg.Chief.ClaimTimeout = 0
+	require.NoError(t, applyDefaults(&cfg))
+	assert.Equal(t, 14400+claimTimeoutMargin, cfg.Chief.ClaimTimeout)
+}
+
+func TestApplyDefaults_LintianFailTags(t *testing.T) {
+	cfg := IrgshConfig{
+		Chief: ChiefConfig{
+			Address:         "http://localhost:8080",
+			Workdir:         "/var/lib/irgsh/chief",
+			GnupgDir:        "/var/lib/irgsh/gnupg",
+			LintianFailTags: map[string][]string{"main": {"no-copyright-file", "binary-without-manpage"}},
+		},
+		Builder: BuilderConfig{
+			Workdir:              "/var/lib/irgsh/builder",
+			UpstreamDistCodename: "sid",
+			UpstreamDistUrl:      "http://deb.debian.org/debian",
+		},
+	}
+	assert.NoError(t, applyDefaults(&cfg))
+
+	cfg.Chief.LintianFailTags["main"] = []string{""}
+	assert.EqualError(t, applyDefaults(&cfg), `invalid lintian tag "" failing the builds of main`)
+
+	cfg.Chief.LintianFailTags["main"] = []string{"no-copyright-file; reboot"}
+	assert.Error(t, applyDefaults(&cfg))
+}
+
+func TestWorkerCredential_MayClaim(t *testing.T) {
+	builder := WorkerCredential{Name: "builder-1", Role: "builder"}
+	assert.True(t, builder.MayClaim("build"))
+	assert.True(t, builder.MayClaim("lintian"))
+	assert.True(t, builder.MayClaim("test"))
+	assert.False(t, builder.MayClaim("repo"))
+	assert.False(t, builder.MayClaim("iso"))
+
+	repo := WorkerCredential{Name: "repo-1", Role: "repo"}
+	assert.True(t, repo.MayClaim("repo"))
+	assert.False(t, repo.MayClaim("build"))
+}
+
+func TestBuilderConfig_TimeoutFor(t *testing.T) {
+	builder := BuilderConfig{
+		BuildTimeout:    3600,
+		PackageTimeouts: map[string]int{"libreoffice": 43200},
+	}
+
+	assert.Equal(t, time.Hour, builder.TimeoutFor("bromo-theme"))
+	assert.Equal(t, 12*time.Hour, builder.TimeoutFor("libreoffice"))
+}
+
+func TestApplyDefaults_Builder(t *testing.T) {
+	cfg := IrgshConfig{
+		Chief: ChiefConfig{
+			Address:  "http://localhost:8080",
+			Workdir:  "/var/lib/irgsh/chief",
+			GnupgDir: "/var/lib/irgsh/gnupg",
+		},
+		Builder: BuilderConfig{
+			Workdir:              "/var/lib/irgsh/builder",
+			UpstreamDistCodename: "sid",
+			UpstreamDistUrl:      "http://deb.debian.org/debian",
+		},
+	}
+	assert.NoError(t, applyDefaults(&cfg))
+	assert.Equal(t, 1, cfg.Builder.Concurrency)
+	assert.Equal(t, 14400, cfg.Builder.BuildTimeout)
+	assert.Equal(t, 4096, cfg.Builder.AptCacheSize)
+
+	cfg.Builder.Concurrency = -1
+	assert.Error(t, applyDefaults(&cfg))
+
+	cfg.Builder.Concurrency = 1
+	cfg.Builder.AptProxy = "not a url"
+	assert.Error(t, applyDefaults(&cfg))
+
+	cfg.Builder.AptProxy = ""
+	cfg.Builder.Runtime = "podman"
+	assert.NoError(t, applyDefaults(&cfg))
+	cfg.Builder.Runtime = "podman-rootless"
+	assert.NoError(t, applyDefaults(&cfg))
+	cfg.Builder.Runtime = "lxc"
+	assert.Error(t, applyDefaults(&cfg))
+
+	cfg.Builder.Runtime = ""
+	cfg.Builder.RepoURL = "http://repo.blankon.id"
+	assert.Error(t, applyDefaults(&cfg))
+	cfg.Builder.RepoKey = "/etc/irgsh/repo.asc"
+	assert.NoError(t, applyDefaults(&cfg))
+}
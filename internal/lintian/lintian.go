@@ -0,0 +1,52 @@
+// Package lintian parses the tags lintian(1) reports on a package. The
+// builders run lintian on the packages they build and upload its output as
+// the lintian log of the build, which chief parses again to store the tags
+// of the pipeline.
+package lintian
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+)
+
+// Severities of the tags, as lintian prints them.
+const (
+	Error          = "E"
+	Warning        = "W"
+	Info           = "I"
+	Pedantic       = "P"
+	Experimental   = "X"
+	Overridden     = "O"
+	Classification = "C"
+	Masked         = "M"
+)
+
+// tagPattern matches a tag line, such as
+// "E: hello: binary-without-manpage [usr/bin/hello]" or
+// "W: hello source: out-of-date-standards-version 4.1.0".
+var tagPattern = regexp.MustCompile(`^([EWIPXOCM]): ([^:\s]+(?: source)?): (\S+)(?: (.*))?$`)
+
+// Tag is a tag lintian reported.
+type Tag struct {
+	Severity string `json:"severity"`
+	Package  string `json:"package"` // Followed by " source" for the source package
+	Name     string `json:"name"`
+	Info     string `json:"info,omitempty"` // Context of the tag, such as a file path
+}
+
+// Parse reads the tags from the output of lintian. Any other line, such as
+// the commands logged around it, is skipped.
+func Parse(r io.Reader) ([]Tag, error) {
+	var tags []Tag
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := tagPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		tags = append(tags, Tag{Severity: m[1], Package: m[2], Name: m[3], Info: m[4]})
+	}
+	return tags, scanner.Err()
+}
@@ -0,0 +1,35 @@
+package lintian
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	output := `
+##### Running lintian
+##### RUN docker run --rm pbocker-amd64 bash -c /lintian.sh
+E: hello: binary-without-manpage [usr/bin/hello]
+W: hello source: out-of-date-standards-version 4.1.0 (released 2017-08-13) (current is 4.6.2)
+I: hello: spelling-error-in-description teh the
+N: 3 hints overridden (1 error, 2 warnings); 1 unused override
+E: hello-doc: no-copyright-file
+`
+	tags, err := Parse(strings.NewReader(output))
+	require.NoError(t, err)
+	assert.Equal(t, []Tag{
+		{Severity: Error, Package: "hello", Name: "binary-without-manpage", Info: "[usr/bin/hello]"},
+		{Severity: Warning, Package: "hello source", Name: "out-of-date-standards-version", Info: "4.1.0 (released 2017-08-13) (current is 4.6.2)"},
+		{Severity: Info, Package: "hello", Name: "spelling-error-in-description", Info: "teh the"},
+		{Severity: Error, Package: "hello-doc", Name: "no-copyright-file"},
+	}, tags)
+}
+
+func TestParse_Clean(t *testing.T) {
+	tags, err := Parse(strings.NewReader("##### RUN lintian ./*.dsc ./*.deb\n"))
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+}
@@ -8,6 +8,9 @@ import (
 )
 
 // StartHeartbeatLoop connects to Redis and sends periodic heartbeats.
+// concurrency is the number of tasks the instance runs at the same time.
+// aptCacheFn, when not nil, reports the build dependency cache of a
+// builder.
 // It blocks until ctx is cancelled; callers should invoke it in a goroutine.
 func StartHeartbeatLoop(
 	ctx context.Context,
@@ -16,7 +19,10 @@ func StartHeartbeatLoop(
 	instanceType InstanceType,
 	workdir string,
 	heartbeatInterval time.Duration,
+	capabilities Capabilities,
+	concurrency int,
 	activeTasksFn func() int,
+	aptCacheFn func() *AptCacheStats,
 ) {
 	registry, err := NewRegistry(redisAddr, ttl, nil, 0, 0)
 	if err != nil {
@@ -43,8 +49,9 @@ func StartHeartbeatLoop(
 			StartTime:     startTime,
 			LastHeartbeat: time.Now(),
 			Status:        StatusOnline,
-			Concurrency:   1,
+			Concurrency:   concurrency,
 			ActiveTasks:   activeTasksFn(),
+			Capabilities:  capabilities,
 			CPUUsage:      metrics.CPUUsage,
 			MemoryUsage:   metrics.MemoryUsage,
 			MemoryTotal:   metrics.MemoryTotal,
@@ -52,6 +59,9 @@ func StartHeartbeatLoop(
 			DiskTotal:     metrics.DiskTotal,
 			Version:       GetVersion(),
 		}
+		if aptCacheFn != nil {
+			instance.AptCache = aptCacheFn()
+		}
 		if err := registry.UpdateInstance(instance); err != nil {
 			log.Printf("Failed to send heartbeat: %v\n", err)
 		}
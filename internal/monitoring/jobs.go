@@ -55,6 +55,97 @@ func (r *Registry) UpdateJobStages(taskUUID, buildState, repoState, currentStage
 	return r.jobStore.UpdateJobStages(taskUUID, buildState, repoState, currentStage)
 }
 
+// UpdateJobArchStates records the per-architecture build states of a job in SQLite
+func (r *Registry) UpdateJobArchStates(taskUUID string, archStates map[string]string) error {
+	if r.jobStore == nil {
+		return fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.UpdateJobArchStates(taskUUID, archStates)
+}
+
+// UpdateJobTestState records the states of the test tasks of a job in SQLite
+func (r *Registry) UpdateJobTestState(taskUUID, testState string, archTestStates map[string]string) error {
+	if r.jobStore == nil {
+		return fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.UpdateJobTestState(taskUUID, testState, archTestStates)
+}
+
+// GetJobsByReproducibility retrieves the jobs whose reproducibility check has
+// the given verdict from SQLite
+func (r *Registry) GetJobsByReproducibility(verdict string) ([]*JobInfo, error) {
+	if r.jobStore == nil {
+		return nil, fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.GetJobsByReproducibility(verdict)
+}
+
+// GetUnfinishedJobs retrieves the jobs whose state is not terminal yet from
+// SQLite
+func (r *Registry) GetUnfinishedJobs() ([]*JobInfo, error) {
+	if r.jobStore == nil {
+		return nil, fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.GetUnfinishedJobs()
+}
+
+// UpdateJobReproducibility records the reproducibility verdict of a job in SQLite
+func (r *Registry) UpdateJobReproducibility(taskUUID, verdict, detail string) error {
+	if r.jobStore == nil {
+		return fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.UpdateJobReproducibility(taskUUID, verdict, detail)
+}
+
+// LintianResult is an alias to storage.LintianResult
+type LintianResult = storage.LintianResult
+
+// RecordLintianResult stores the lintian tags of a build in SQLite
+func (r *Registry) RecordLintianResult(result LintianResult) error {
+	if r.jobStore == nil {
+		return fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.RecordLintianResult(result)
+}
+
+// GetLintianResults retrieves the lintian tags of the builds of a pipeline from SQLite
+func (r *Registry) GetLintianResults(taskUUID string) ([]*LintianResult, error) {
+	if r.jobStore == nil {
+		return nil, fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.GetLintianResults(taskUUID)
+}
+
+// JobEvent is an alias to storage.JobEvent
+type JobEvent = storage.JobEvent
+
+// RecordJobEvent appends an event to the history of a pipeline in SQLite
+func (r *Registry) RecordJobEvent(event JobEvent) error {
+	if r.jobStore == nil {
+		return fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.RecordJobEvent(event)
+}
+
+// GetJobEvents retrieves the events of a pipeline from SQLite
+func (r *Registry) GetJobEvents(taskUUID string) ([]*JobEvent, error) {
+	if r.jobStore == nil {
+		return nil, fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.GetJobEvents(taskUUID)
+}
+
+// StageDuration is an alias to storage.StageDuration
+type StageDuration = storage.StageDuration
+
+// GetStageDurations retrieves the durations of the completed tasks of a stage from SQLite
+func (r *Registry) GetStageDurations(stage, packageName string) ([]*StageDuration, error) {
+	if r.jobStore == nil {
+		return nil, fmt.Errorf("job store not initialized")
+	}
+	return r.jobStore.GetStageDurations(stage, packageName)
+}
+
 // GetJobStagesFromMachinery queries both build and repo task states using machinery backend
 func GetJobStagesFromMachinery(backend iface.Backend, taskUUID string) (buildState, repoState, currentStage string) {
 	// Query build task state using machinery API
@@ -39,6 +39,9 @@ type InstanceInfo struct {
 	Concurrency int `json:"concurrency"` // Max concurrent tasks
 	ActiveTasks int `json:"active_tasks"` // Currently running tasks
 
+	// Capabilities advertised by the worker
+	Capabilities Capabilities `json:"capabilities"`
+
 	// System Metrics
 	CPUUsage    float64 `json:"cpu_usage"`    // CPU percentage (0-100)
 	MemoryUsage uint64  `json:"memory_usage"` // Memory used in bytes
@@ -46,10 +49,58 @@ type InstanceInfo struct {
 	DiskUsage   uint64  `json:"disk_usage"`   // Disk space used in bytes
 	DiskTotal   uint64  `json:"disk_total"`   // Total disk space in bytes
 
+	// Build dependency cache, builders only
+	AptCache *AptCacheStats `json:"apt_cache,omitempty"`
+
 	// Version
 	Version string `json:"version"` // Worker version
 }
 
+// AptCacheStats describes the cache of build dependencies a builder keeps
+type AptCacheStats struct {
+	Packages  int    `json:"packages"`   // .deb files in the cache
+	Size      uint64 `json:"size"`       // Bytes used by the cache
+	Limit     uint64 `json:"limit"`      // Size the cache is evicted down to, 0 for no limit
+	HitBytes  uint64 `json:"hit_bytes"`  // Dependency bytes builds took from the cache
+	MissBytes uint64 `json:"miss_bytes"` // Dependency bytes builds downloaded
+	Evictions uint64 `json:"evictions"`  // Packages evicted to stay under Limit
+}
+
+// HitRate returns the share of dependency bytes served from the cache,
+// between 0 and 1.
+func (s AptCacheStats) HitRate() float64 {
+	total := s.HitBytes + s.MissBytes
+	if total == 0 {
+		return 0
+	}
+	return float64(s.HitBytes) / float64(total)
+}
+
+// Capabilities describes what kind of tasks a worker instance can process
+type Capabilities struct {
+	Architectures []string `json:"architectures,omitempty"` // Debian architectures a builder can build for
+	DistCodename  string   `json:"dist_codename,omitempty"` // Upstream distribution the builder's base images track
+	Labels        []string `json:"labels,omitempty"`        // Free-form labels, e.g. big-memory
+}
+
+// CanBuild reports whether a builder with these capabilities consumes the
+// build queue for arch, codename and the optional label.
+func (c Capabilities) CanBuild(codename, arch, label string) bool {
+	if c.DistCodename != codename || !contains(c.Architectures, arch) {
+		return false
+	}
+	return label == "" || contains(c.Labels, label)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // HeartbeatRequest is sent by workers to Chief
 type HeartbeatRequest struct {
 	InstanceID   string       `json:"instance_id"`
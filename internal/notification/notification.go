@@ -108,6 +108,8 @@ func SendJobNotification(webhookURL, jobType, taskUUID, status string, jobInfo J
 		emoji = "✅"
 	case "FAILED":
 		emoji = "❌"
+	case "TIMEOUT":
+		emoji = "⏱️"
 	}
 
 	// Determine target repo
@@ -171,7 +173,7 @@ func SendJobNotification(webhookURL, jobType, taskUUID, status string, jobInfo J
 	)
 
 	// Append log URL on failure
-	if status == "FAILED" {
+	if status == "FAILED" || status == "TIMEOUT" {
 		var logType string
 		switch jobType {
 		case "Build":
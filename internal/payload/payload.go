@@ -0,0 +1,296 @@
+// Package payload defines the task payloads chief queues for the workers.
+// Chief, the builders and the repo worker share these types, so any change
+// to the layout of a payload has to bump SchemaVersion.
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SchemaVersion is the version of the payloads produced by this build.
+// Payloads queued before payloads were versioned have no version, which
+// decodes to 0, and share the layout of version 1. Workers reject the
+// payloads of a newer version, whose fields they would ignore:
+//   - 2: upstreamOnly builds
+//   - 3: binary-only rebuilds, binNMU and binNMUReason
+//   - 4: second builds of the reproducibility checks, reproCheck
+//   - 5: lintian tags failing the builds, lintianFailTags
+const SchemaVersion = 5
+
+// SafeIDPattern matches the identifiers that end up in file paths and shell
+// commands on the workers. Chief validates the submissions against it too.
+var SafeIDPattern = regexp.MustCompile(`^[a-zA-Z0-9._+-]+$`)
+
+// VersionPattern matches Debian package versions, which may also carry an
+// epoch and tildes.
+var VersionPattern = regexp.MustCompile(`^[a-zA-Z0-9.+~:-]+$`)
+
+// Payload is implemented by every task payload.
+type Payload interface {
+	header() *Header
+	// Validate checks the fields the workers rely on.
+	Validate() error
+}
+
+// Header carries the fields common to every task payload.
+type Header struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	TaskUUID      string `json:"taskUUID"`
+}
+
+func (h *Header) header() *Header {
+	return h
+}
+
+func (h *Header) validate() error {
+	if h.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("unsupported payload schema version %d, this worker handles up to version %d", h.SchemaVersion, SchemaVersion)
+	}
+	return checkID("taskUUID", h.TaskUUID)
+}
+
+// Encode stamps p with the current schema version and marshals it.
+func Encode(p Payload) ([]byte, error) {
+	p.header().SchemaVersion = SchemaVersion
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p)
+}
+
+// Decode unmarshals a task payload into p and validates it. Malformed
+// payloads, and payloads of a newer schema version than this build
+// supports, are rejected with a descriptive error.
+func Decode(data string, p Payload) error {
+	if err := json.Unmarshal([]byte(data), p); err != nil {
+		return fmt.Errorf("malformed payload: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	return nil
+}
+
+func checkID(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is missing", field)
+	}
+	if !SafeIDPattern.MatchString(value) {
+		return fmt.Errorf("%s %q contains invalid characters", field, value)
+	}
+	return nil
+}
+
+func checkOptionalID(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	return checkID(field, value)
+}
+
+func checkVersion(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is missing", field)
+	}
+	if !VersionPattern.MatchString(value) {
+		return fmt.Errorf("%s %q is not a valid version", field, value)
+	}
+	return nil
+}
+
+func checkOptionalVersion(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	return checkVersion(field, value)
+}
+
+// Build is the payload of the build tasks, one per architecture, and of the
+// repo task injecting their results.
+type Build struct {
+	Header
+	Timestamp              time.Time `json:"timestamp"`
+	PackageName            string    `json:"packageName"`
+	PackageVersion         string    `json:"packageVersion"`
+	PackageExtendedVersion string    `json:"packageExtendedVersion"`
+	PackageURL             string    `json:"packageUrl"`
+	SourceURL              string    `json:"sourceUrl"`
+	Maintainer             string    `json:"maintainer"`
+	MaintainerFingerprint  string    `json:"maintainerFingerprint"`
+	Component              string    `json:"component"`
+	IsExperimental         bool      `json:"isExperimental"`
+	ForceVersion           bool      `json:"forceVersion"`
+	Tarball                string    `json:"tarball"`
+	PackageBranch          string    `json:"packageBranch"`
+	SourceBranch           string    `json:"sourceBranch"`
+	BuilderLabel           string    `json:"builderLabel,omitempty"`
+	Suite                  string    `json:"suite,omitempty"`
+	UpstreamOnly           bool      `json:"upstreamOnly,omitempty"` // Build without the dependencies in our repository
+
+	Architecture   string   `json:"architecture,omitempty"`   // Target arch of a single build task
+	Architectures  []string `json:"architectures,omitempty"`  // All archs of the pipeline, used by repo
+	BuildArchIndep bool     `json:"buildArchIndep,omitempty"` // Whether this build also produces arch:all packages
+	ReproCheck     bool     `json:"reproCheck,omitempty"`     // Second build of a reproducibility check, never published
+
+	LintianFailTags []string `json:"lintianFailTags,omitempty"` // Lintian tags failing the build, the others are only recorded
+
+	// Binary-only rebuild (binNMU) of a source already in the repository
+	BinNMU       int    `json:"binNMU,omitempty"`       // Rebuild number, the binaries get version +bN
+	BinNMUReason string `json:"binNMUReason,omitempty"` // Changelog entry of the rebuild
+}
+
+func (b *Build) Validate() error {
+	if err := b.Header.validate(); err != nil {
+		return err
+	}
+	if err := checkID("packageName", b.PackageName); err != nil {
+		return err
+	}
+	if err := checkOptionalVersion("packageVersion", b.PackageVersion); err != nil {
+		return err
+	}
+	if err := checkOptionalVersion("packageExtendedVersion", b.PackageExtendedVersion); err != nil {
+		return err
+	}
+	if err := checkOptionalID("component", b.Component); err != nil {
+		return err
+	}
+	if err := checkOptionalID("suite", b.Suite); err != nil {
+		return err
+	}
+	if err := checkOptionalID("architecture", b.Architecture); err != nil {
+		return err
+	}
+	for _, arch := range b.Architectures {
+		if err := checkID("architectures", arch); err != nil {
+			return err
+		}
+	}
+	for _, tag := range b.LintianFailTags {
+		if err := checkID("lintianFailTags", tag); err != nil {
+			return err
+		}
+	}
+	if b.BinNMU < 0 {
+		return fmt.Errorf("binNMU %d is negative", b.BinNMU)
+	}
+	if b.BinNMU > 0 {
+		if b.BinNMUReason == "" {
+			return fmt.Errorf("binNMUReason is missing")
+		}
+		if strings.ContainsFunc(b.BinNMUReason, unicode.IsControl) {
+			return fmt.Errorf("binNMUReason contains control characters")
+		}
+	}
+	return nil
+}
+
+// Promote is the payload of the repo worker's promote task.
+type Promote struct {
+	Header
+	Timestamp             time.Time `json:"timestamp"`
+	PackageName           string    `json:"packageName"`
+	PackageVersion        string    `json:"packageVersion"`
+	Suite                 string    `json:"suite"`
+	Maintainer            string    `json:"maintainer"`
+	MaintainerFingerprint string    `json:"maintainerFingerprint"`
+}
+
+func (p *Promote) Validate() error {
+	if err := p.Header.validate(); err != nil {
+		return err
+	}
+	if err := checkID("packageName", p.PackageName); err != nil {
+		return err
+	}
+	if err := checkVersion("packageVersion", p.PackageVersion); err != nil {
+		return err
+	}
+	return checkOptionalID("suite", p.Suite)
+}
+
+// Remove is the payload of the repo worker's remove task.
+type Remove struct {
+	Header
+	Timestamp             time.Time `json:"timestamp"`
+	PackageName           string    `json:"packageName"`
+	Suite                 string    `json:"suite"`
+	Component             string    `json:"component"`
+	Maintainer            string    `json:"maintainer"`
+	MaintainerFingerprint string    `json:"maintainerFingerprint"`
+}
+
+func (r *Remove) Validate() error {
+	if err := r.Header.validate(); err != nil {
+		return err
+	}
+	if err := checkID("packageName", r.PackageName); err != nil {
+		return err
+	}
+	if err := checkID("component", r.Component); err != nil {
+		return err
+	}
+	return checkOptionalID("suite", r.Suite)
+}
+
+// Snapshot is the payload of the repo worker's snapshot task.
+type Snapshot struct {
+	Header
+	Timestamp             time.Time `json:"timestamp"`
+	Repository            string    `json:"repository"`
+	Maintainer            string    `json:"maintainer"`
+	MaintainerFingerprint string    `json:"maintainerFingerprint"`
+}
+
+func (s *Snapshot) Validate() error {
+	if err := s.Header.validate(); err != nil {
+		return err
+	}
+	return checkID("repository", s.Repository)
+}
+
+// Restore is the payload of the repo worker's restore task.
+type Restore struct {
+	Header
+	Timestamp             time.Time `json:"timestamp"`
+	SnapshotID            string    `json:"snapshotID"`
+	Repository            string    `json:"repository,omitempty"`
+	Maintainer            string    `json:"maintainer"`
+	MaintainerFingerprint string    `json:"maintainerFingerprint"`
+}
+
+func (r *Restore) Validate() error {
+	if err := r.Header.validate(); err != nil {
+		return err
+	}
+	if err := checkID("snapshotID", r.SnapshotID); err != nil {
+		return err
+	}
+	return checkOptionalID("repository", r.Repository)
+}
+
+// ISO is the payload of the ISO builder's iso task.
+type ISO struct {
+	Header
+	Timestamp time.Time `json:"timestamp"`
+	RepoURL   string    `json:"repoUrl"`
+	Branch    string    `json:"branch"`
+}
+
+func (i *ISO) Validate() error {
+	if err := i.Header.validate(); err != nil {
+		return err
+	}
+	if i.RepoURL == "" {
+		return fmt.Errorf("repoUrl is missing")
+	}
+	if i.Branch == "" {
+		return fmt.Errorf("branch is missing")
+	}
+	return nil
+}
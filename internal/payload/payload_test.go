@@ -0,0 +1,86 @@
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	data, err := Encode(&Promote{
+		Header:         Header{TaskUUID: "task"},
+		PackageName:    "hello",
+		PackageVersion: "2.10-3",
+	})
+	require.NoError(t, err)
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, float64(SchemaVersion), raw["schemaVersion"])
+	assert.Equal(t, "task", raw["taskUUID"])
+
+	_, err = Encode(&Promote{Header: Header{TaskUUID: "task"}, PackageName: "hello"})
+	assert.EqualError(t, err, "packageVersion is missing")
+}
+
+func TestDecode(t *testing.T) {
+	var build Build
+	require.NoError(t, Decode(`{"schemaVersion":1,"taskUUID":"task","packageName":"hello","packageVersion":"2.10","architectures":["amd64","arm64"]}`, &build))
+	assert.Equal(t, "task", build.TaskUUID)
+	assert.Equal(t, []string{"amd64", "arm64"}, build.Architectures)
+
+	// Payloads queued before payloads were versioned
+	var legacy Build
+	require.NoError(t, Decode(`{"taskUUID":"task","packageName":"hello","isExperimental":true}`, &legacy))
+	assert.Equal(t, 0, legacy.SchemaVersion)
+	assert.True(t, legacy.IsExperimental)
+}
+
+func TestDecode_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"not json", `{"taskUUID":`, "malformed payload"},
+		{"wrong type", `{"taskUUID":"task","packageName":42}`, "malformed payload"},
+		{"newer schema", fmt.Sprintf(`{"schemaVersion":%d,"taskUUID":"task","packageName":"hello"}`, SchemaVersion+1), fmt.Sprintf("unsupported payload schema version %d", SchemaVersion+1)},
+		{"missing task", `{"packageName":"hello"}`, "taskUUID is missing"},
+		{"unsafe task", `{"taskUUID":"../task","packageName":"hello"}`, `taskUUID "../task" contains invalid characters`},
+		{"missing package", `{"taskUUID":"task"}`, "packageName is missing"},
+		{"unsafe package", `{"taskUUID":"task","packageName":"hello; rm -rf /"}`, "contains invalid characters"},
+		{"bad version", `{"taskUUID":"task","packageName":"hello","packageVersion":"1.0 && true"}`, "is not a valid version"},
+		{"unsafe arch", `{"taskUUID":"task","packageName":"hello","architectures":["amd64","$(id)"]}`, "contains invalid characters"},
+		{"unsafe lintian tag", `{"taskUUID":"task","packageName":"hello","lintianFailTags":["no-copyright-file","$(id)"]}`, `lintianFailTags "$(id)" contains invalid characters`},
+		{"negative binNMU", `{"taskUUID":"task","packageName":"hello","binNMU":-1}`, "binNMU -1 is negative"},
+		{"binNMU without reason", `{"taskUUID":"task","packageName":"hello","binNMU":1}`, "binNMUReason is missing"},
+		{"multiline binNMU reason", `{"taskUUID":"task","packageName":"hello","binNMU":1,"binNMUReason":"a\n * b"}`, "binNMUReason contains control characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var build Build
+			assert.ErrorContains(t, Decode(tt.data, &build), tt.wantErr)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := Header{TaskUUID: "task"}
+
+	assert.NoError(t, (&Build{Header: h, PackageName: "hello", BinNMU: 2, BinNMUReason: "Rebuild against libfoo2"}).Validate())
+
+	assert.NoError(t, (&Remove{Header: h, PackageName: "hello", Component: "main"}).Validate())
+	assert.EqualError(t, (&Remove{Header: h, PackageName: "hello"}).Validate(), "component is missing")
+
+	assert.NoError(t, (&Snapshot{Header: h, Repository: "verbeek-experimental"}).Validate())
+	assert.EqualError(t, (&Snapshot{Header: h}).Validate(), "repository is missing")
+
+	assert.NoError(t, (&Restore{Header: h, SnapshotID: "verbeek_20240101-120000"}).Validate())
+	assert.EqualError(t, (&Restore{Header: h, SnapshotID: "../verbeek"}).Validate(), `snapshotID "../verbeek" contains invalid characters`)
+
+	assert.NoError(t, (&ISO{Header: h, RepoURL: "https://example.com/iso.git", Branch: "main"}).Validate())
+	assert.EqualError(t, (&ISO{Header: h, RepoURL: "https://example.com/iso.git"}).Validate(), "branch is missing")
+}
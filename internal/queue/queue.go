@@ -0,0 +1,18 @@
+// Package queue names the machinery queues irgsh workers consume from.
+package queue
+
+import "strings"
+
+// Default is the queue consumed by the repo and ISO workers.
+const Default = "irgsh"
+
+// Build returns the queue consumed by builders that build for arch on top of
+// the upstream distribution codename. A non-empty label narrows the queue
+// down to builders advertising that label.
+func Build(codename, arch, label string) string {
+	parts := []string{Default, "build", codename, arch}
+	if label != "" {
+		parts = append(parts, label)
+	}
+	return strings.Join(parts, ".")
+}
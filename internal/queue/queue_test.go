@@ -0,0 +1,12 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	assert.Equal(t, "irgsh.build.sid.amd64", Build("sid", "amd64", ""))
+	assert.Equal(t, "irgsh.build.sid.arm64.big-memory", Build("sid", "arm64", "big-memory"))
+}
@@ -0,0 +1,234 @@
+// Package repo drives reprepro, the tool managing the Debian repositories
+// served by the repo worker.
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Runner runs a command in a working directory, with env appended to the
+// environment, and returns what it wrote to stdout and stderr.
+type Runner interface {
+	Run(dir string, env []string, name string, args ...string) (stdout, stderr []byte, err error)
+}
+
+// ExecRunner runs commands with os/exec.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(dir string, env []string, name string, args ...string) ([]byte, []byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// Error is returned when a reprepro invocation fails.
+type Error struct {
+	Dir      string   // Base directory reprepro ran in
+	Args     []string // Arguments reprepro was given
+	ExitCode int      // -1 when reprepro could not be started
+	Stderr   string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("reprepro %s: %v", strings.Join(e.Args, " "), e.Err)
+	if last := lastLine(e.Stderr); last != "" {
+		msg += ": " + last
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// PoolFile is a file of a package in a reprepro pool.
+type PoolFile struct {
+	Component string
+	Version   string // Version of its package, with its epoch
+	Path      string
+}
+
+// poolFileFormat is the list format parsed by parsePoolFiles.
+const poolFileFormat = "${$component} ${Version} ${$fullfilename}\n"
+
+// parsePoolFiles parses the output of reprepro listfilter run with
+// poolFileFormat.
+func parsePoolFiles(out string) []PoolFile {
+	var files []PoolFile
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		files = append(files, PoolFile{Component: fields[0], Version: fields[1], Path: fields[2]})
+	}
+	return files
+}
+
+// IncludeOptions tunes how packages are included into a distribution.
+type IncludeOptions struct {
+	// IgnoreWrongDistribution accepts packages whose changelog targets
+	// another distribution.
+	IgnoreWrongDistribution bool
+}
+
+// Reprepro manages the repositories under a work directory. Each
+// distribution is a reprepro base directory of its own, named after its
+// codename, e.g. <workdir>/verbeek and <workdir>/verbeek-experimental.
+type Reprepro struct {
+	runner    Runner
+	workdir   string
+	gnupgHome string
+	logPath   string
+}
+
+// NewReprepro returns a client running reprepro through runner. Packages
+// are signed with the keyring in gnupgHome, or the user's default keyring
+// when it is empty. The output of every change is appended to logPath, if
+// set.
+func NewReprepro(runner Runner, workdir, gnupgHome, logPath string) *Reprepro {
+	return &Reprepro{
+		runner:    runner,
+		workdir:   workdir,
+		gnupgHome: gnupgHome,
+		logPath:   logPath,
+	}
+}
+
+// IncludeDeb adds binary packages to a component of a distribution.
+func (r *Reprepro) IncludeDeb(codename, component string, debs []string, opts IncludeOptions) error {
+	if len(debs) == 0 {
+		return errors.New("no deb file to include")
+	}
+	args := r.includeArgs(component, opts)
+	args = append(args, "includedeb", codename)
+	args = append(args, debs...)
+	return r.change(codename, "Injecting "+strings.Join(debs, " "), args...)
+}
+
+// IncludeDsc adds a source package to a component of a distribution.
+func (r *Reprepro) IncludeDsc(codename, component, dsc string, opts IncludeOptions) error {
+	args := r.includeArgs(component, opts)
+	args = append(args, "includedsc", codename, dsc)
+	return r.change(codename, "Injecting "+dsc, args...)
+}
+
+func (r *Reprepro) includeArgs(component string, opts IncludeOptions) []string {
+	args := []string{"--nothingiserror"}
+	if opts.IgnoreWrongDistribution {
+		args = append(args, "--ignore=wrongdistribution")
+	}
+	return append(args, "--component", component)
+}
+
+// RemoveSrc removes a version of a source package, along with the binary
+// packages built from it. Removing a package that is not there is not an
+// error.
+func (r *Reprepro) RemoveSrc(codename, source, version string) error {
+	return r.change(codename, fmt.Sprintf("Removing source package %s %s", source, version),
+		"--nothingiserror", "removesrc", codename, source, version)
+}
+
+// Remove removes packages from every component of a distribution. Removing
+// a package that is not there is not an error.
+func (r *Reprepro) Remove(codename string, packages ...string) error {
+	args := append([]string{"--nothingiserror", "remove", codename}, packages...)
+	return r.change(codename, "Removing "+strings.Join(packages, " "), args...)
+}
+
+// RemoveFilter removes the packages of a component of a distribution that
+// match a reprepro formula, e.g. "$Source (== hello)".
+func (r *Reprepro) RemoveFilter(codename, component, formula string) error {
+	return r.change(codename, "Removing the packages matching "+formula,
+		"-C", component, "removefilter", codename, formula)
+}
+
+// Export regenerates and signs the indices of a distribution.
+func (r *Reprepro) Export(codename string) error {
+	return r.change(codename, "Re-export and publish the reprepro repository", "export", codename)
+}
+
+// Update syncs a distribution against its upstream repository.
+func (r *Reprepro) Update(codename string) error {
+	return r.change(codename, "Sync the repository against upstream repository", "update", codename)
+}
+
+// List returns the pool files of the packages of a distribution that match
+// a reprepro formula. packageType restricts them to "dsc" or "deb" files,
+// all of them are returned when it is empty.
+func (r *Reprepro) List(codename, packageType, formula string) ([]PoolFile, error) {
+	var args []string
+	if packageType != "" {
+		args = append(args, "-T", packageType)
+	}
+	args = append(args, "--list-format", poolFileFormat, "listfilter", codename, formula)
+	stdout, _, err := r.run(codename, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parsePoolFiles(string(stdout)), nil
+}
+
+// change runs a verbose reprepro command modifying a distribution, and logs
+// its output.
+func (r *Reprepro) change(codename, desc string, args ...string) error {
+	args = append([]string{"-v", "-v", "-v"}, args...)
+	r.log("\n##### " + desc + "\n##### RUN reprepro " + strings.Join(args, " ") + "\n")
+	stdout, stderr, err := r.run(codename, args...)
+	r.log(string(stdout))
+	r.log(string(stderr))
+	return err
+}
+
+func (r *Reprepro) run(codename string, args ...string) ([]byte, []byte, error) {
+	dir := filepath.Join(r.workdir, codename)
+	var env []string
+	if r.gnupgHome != "" {
+		env = append(env, "GNUPGHOME="+r.gnupgHome)
+	}
+	stdout, stderr, err := r.runner.Run(dir, env, "reprepro", args...)
+	if err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return stdout, stderr, &Error{
+			Dir:      dir,
+			Args:     args,
+			ExitCode: exitCode,
+			Stderr:   string(stderr),
+			Err:      err,
+		}
+	}
+	return stdout, stderr, nil
+}
+
+func (r *Reprepro) log(s string) {
+	if r.logPath == "" || s == "" {
+		return
+	}
+	f, err := os.OpenFile(r.logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(s)
+}
@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type call struct {
+	Dir  string
+	Env  []string
+	Args []string
+}
+
+type fakeRunner struct {
+	calls  []call
+	stdout string
+	stderr string
+	err    error
+}
+
+func (f *fakeRunner) Run(dir string, env []string, name string, args ...string) ([]byte, []byte, error) {
+	f.calls = append(f.calls, call{Dir: dir, Env: env, Args: append([]string{name}, args...)})
+	return []byte(f.stdout), []byte(f.stderr), f.err
+}
+
+func TestReprepro_Commands(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(r *Reprepro) error
+		want []string
+	}{
+		{
+			"includedeb",
+			func(r *Reprepro) error {
+				return r.IncludeDeb("verbeek", "main", []string{"a.deb", "b.deb"}, IncludeOptions{})
+			},
+			[]string{"reprepro", "-v", "-v", "-v", "--nothingiserror", "--component", "main", "includedeb", "verbeek", "a.deb", "b.deb"},
+		},
+		{
+			"includedsc ignoring the distribution",
+			func(r *Reprepro) error {
+				return r.IncludeDsc("verbeek", "main; rm -rf /", "a.dsc", IncludeOptions{IgnoreWrongDistribution: true})
+			},
+			[]string{"reprepro", "-v", "-v", "-v", "--nothingiserror", "--ignore=wrongdistribution", "--component", "main; rm -rf /", "includedsc", "verbeek", "a.dsc"},
+		},
+		{
+			"removesrc",
+			func(r *Reprepro) error { return r.RemoveSrc("verbeek", "hello", "2.10-3") },
+			[]string{"reprepro", "-v", "-v", "-v", "--nothingiserror", "removesrc", "verbeek", "hello", "2.10-3"},
+		},
+		{
+			"remove",
+			func(r *Reprepro) error { return r.Remove("verbeek", "hello", "hello-doc") },
+			[]string{"reprepro", "-v", "-v", "-v", "--nothingiserror", "remove", "verbeek", "hello", "hello-doc"},
+		},
+		{
+			"removefilter",
+			func(r *Reprepro) error { return r.RemoveFilter("verbeek", "main", "$Source (== hello)") },
+			[]string{"reprepro", "-v", "-v", "-v", "-C", "main", "removefilter", "verbeek", "$Source (== hello)"},
+		},
+		{
+			"export",
+			func(r *Reprepro) error { return r.Export("verbeek") },
+			[]string{"reprepro", "-v", "-v", "-v", "export", "verbeek"},
+		},
+		{
+			"update",
+			func(r *Reprepro) error { return r.Update("verbeek") },
+			[]string{"reprepro", "-v", "-v", "-v", "update", "verbeek"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{}
+			r := NewReprepro(runner, "/srv/repo", "/srv/gnupg", "")
+			require.NoError(t, tt.run(r))
+			require.Len(t, runner.calls, 1)
+			assert.Equal(t, "/srv/repo/verbeek", runner.calls[0].Dir)
+			assert.Equal(t, []string{"GNUPGHOME=/srv/gnupg"}, runner.calls[0].Env)
+			assert.Equal(t, tt.want, runner.calls[0].Args)
+		})
+	}
+}
+
+func TestReprepro_IncludeDebWithoutFiles(t *testing.T) {
+	runner := &fakeRunner{}
+	r := NewReprepro(runner, "/srv/repo", "", "")
+	assert.Error(t, r.IncludeDeb("verbeek", "main", nil, IncludeOptions{}))
+	assert.Empty(t, runner.calls)
+}
+
+func TestReprepro_List(t *testing.T) {
+	runner := &fakeRunner{
+		stdout: "main 2.10-3 /srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3.dsc\n" +
+			"restricted 1:2.10-3 /srv/repo/verbeek-experimental/pool/restricted/h/hello/hello_2.10-3_amd64.deb\n" +
+			"\n",
+	}
+	r := NewReprepro(runner, "/srv/repo", "", "")
+
+	files, err := r.List("verbeek-experimental", "dsc", "Package (== hello)")
+	require.NoError(t, err)
+	assert.Equal(t, []PoolFile{
+		{Component: "main", Version: "2.10-3", Path: "/srv/repo/verbeek-experimental/pool/main/h/hello/hello_2.10-3.dsc"},
+		{Component: "restricted", Version: "1:2.10-3", Path: "/srv/repo/verbeek-experimental/pool/restricted/h/hello/hello_2.10-3_amd64.deb"},
+	}, files)
+	assert.Equal(t, []string{"reprepro", "-T", "dsc", "--list-format", "${$component} ${Version} ${$fullfilename}\n",
+		"listfilter", "verbeek-experimental", "Package (== hello)"}, runner.calls[0].Args)
+	assert.Empty(t, runner.calls[0].Env)
+
+	assert.Empty(t, parsePoolFiles(""))
+}
+
+func TestReprepro_Error(t *testing.T) {
+	runner := &fakeRunner{
+		stderr: "Exporting indices...\nError: gpgme gave error GPGME:117440529\n",
+		err:    errors.New("exit status 255"),
+	}
+	r := NewReprepro(runner, "/srv/repo", "", "")
+
+	err := r.Export("verbeek")
+	var repreproErr *Error
+	require.ErrorAs(t, err, &repreproErr)
+	assert.Equal(t, "/srv/repo/verbeek", repreproErr.Dir)
+	assert.Equal(t, -1, repreproErr.ExitCode)
+	assert.Equal(t, "reprepro -v -v -v export verbeek: exit status 255: Error: gpgme gave error GPGME:117440529", err.Error())
+	assert.ErrorIs(t, err, runner.err)
+}
+
+func TestReprepro_Log(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "repo.log")
+	runner := &fakeRunner{stdout: "Exporting indices...\n"}
+	r := NewReprepro(runner, "/srv/repo", "", logPath)
+
+	require.NoError(t, r.Export("verbeek"))
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "##### RUN reprepro -v -v -v export verbeek\n")
+	assert.Contains(t, string(data), "Exporting indices...\n")
+}
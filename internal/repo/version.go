@@ -0,0 +1,92 @@
+package repo
+
+import "strings"
+
+// CompareVersions compares two Debian package versions the way dpkg does,
+// and returns -1, 0 or 1 when a is lower than, equal to or higher than b.
+func CompareVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+	if c := compareVersionPart(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	if c := compareVersionPart(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareVersionPart(aRevision, bRevision)
+}
+
+// splitVersion splits a version into its epoch, upstream version and Debian
+// revision.
+func splitVersion(version string) (epoch, upstream, revision string) {
+	epoch, upstream, found := strings.Cut(version, ":")
+	if !found {
+		epoch, upstream = "0", version
+	}
+	if i := strings.LastIndex(upstream, "-"); i >= 0 {
+		upstream, revision = upstream[:i], upstream[i+1:]
+	}
+	return epoch, upstream, revision
+}
+
+// compareVersionPart compares a part of two versions by alternating runs of
+// non-digits, compared character by character, and of digits, compared as
+// numbers.
+func compareVersionPart(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := versionCharOrder(a), versionCharOrder(b)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		var aDigits, bDigits int
+		for aDigits < len(a) && isDigit(a[aDigits]) {
+			aDigits++
+		}
+		for bDigits < len(b) && isDigit(b[bDigits]) {
+			bDigits++
+		}
+		if aDigits != bDigits {
+			return sign(aDigits - bDigits)
+		}
+		if c := strings.Compare(a[:aDigits], b[:bDigits]); c != 0 {
+			return c
+		}
+		a, b = a[aDigits:], b[bDigits:]
+	}
+	return 0
+}
+
+// versionCharOrder returns the weight of the first character of s in a run
+// of non-digits: a tilde sorts before anything, even the end of the run, and
+// letters sort before the other characters.
+func versionCharOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case s[0] == '~':
+		return -1
+	case s[0] >= 'a' && s[0] <= 'z', s[0] >= 'A' && s[0] <= 'Z':
+		return int(s[0])
+	}
+	return int(s[0]) + 256
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.10-3", "2.10-3", 0},
+		{"2.10-3", "2.9-3", 1},
+		{"2.10-3", "2.10-3+b1", -1},
+		{"2.10-3+b2", "2.10-3+b10", -1},
+		{"1.0~rc1-1", "1.0-1", -1},
+		{"1.0-1", "1.0a-1", -1},
+		{"1.0+dfsg-1", "1.0-1", 1},
+		{"1:1.0-1", "2.0-1", 1},
+		{"0:2.0", "2.0", 0},
+		{"1.0-1-1", "1.0-1", 1},
+		{"1.0", "1.0-0", 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, CompareVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
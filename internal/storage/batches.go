@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Batch is a set of packages submitted together, built in the order of
+// their build dependencies.
+type Batch struct {
+	BatchID     string      `json:"batch_id"`
+	Maintainer  string      `json:"maintainer"`
+	SubmittedAt time.Time   `json:"submitted_at"`
+	State       string      `json:"state"` // RUNNING, DONE, FAILED
+	Items       []BatchItem `json:"items"`
+}
+
+// BatchItem is a package of a batch. Its pipeline is only queued once the
+// packages it depends on are in the repository.
+type BatchItem struct {
+	Position       int    `json:"position"`
+	PackageName    string `json:"package_name"`
+	PackageVersion string `json:"package_version"`
+	TaskUUID       string `json:"task_uuid"`  // Pipeline of the package, queued or not
+	DependsOn      []int  `json:"depends_on"` // Positions of the packages it build depends on
+	State          string `json:"state"`      // WAITING, SKIPPED, or the state of its pipeline
+	Queued         bool   `json:"queued"`
+	Submission     string `json:"submission"` // Submission queued once the dependencies are built, as JSON
+}
+
+// BatchStore handles batch persistence in SQLite
+type BatchStore struct {
+	db         *DB
+	maxBatches int
+}
+
+// NewBatchStore creates a new batch store
+func NewBatchStore(db *DB, maxBatches int) *BatchStore {
+	if maxBatches <= 0 {
+		maxBatches = 200 // Default maximum batches
+	}
+	return &BatchStore{
+		db:         db,
+		maxBatches: maxBatches,
+	}
+}
+
+// RecordBatch stores a new batch and its packages
+func (s *BatchStore) RecordBatch(batch Batch) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin batch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO batches (batch_id, maintainer, submitted_at, state)
+		VALUES (?, ?, ?, ?)
+	`, batch.BatchID, batch.Maintainer, batch.SubmittedAt, batch.State)
+	if err != nil {
+		return fmt.Errorf("failed to record batch: %w", err)
+	}
+	for _, item := range batch.Items {
+		_, err = tx.Exec(`
+			INSERT INTO batch_items (batch_id, position, package_name, package_version,
+			                         task_uuid, depends_on, state, queued, submission)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, batch.BatchID, item.Position, item.PackageName, item.PackageVersion,
+			item.TaskUUID, joinPositions(item.DependsOn), item.State, item.Queued, item.Submission)
+		if err != nil {
+			return fmt.Errorf("failed to record batch item: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	// Cleanup old batches if exceeding max
+	if err := s.cleanupOldBatches(); err != nil {
+		// Log but don't fail
+		fmt.Printf("Warning: failed to cleanup old batches: %v\n", err)
+	}
+
+	return nil
+}
+
+// GetBatch retrieves a batch and its packages
+func (s *BatchStore) GetBatch(batchID string) (*Batch, error) {
+	rows, err := s.db.Query(`
+		SELECT batch_id, maintainer, submitted_at, state
+		FROM batches
+		WHERE batch_id = ?
+	`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	batches, err := s.scanBatches(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("batch not found: %s", batchID)
+	}
+	return batches[0], nil
+}
+
+// GetRunningBatches retrieves the batches that still have packages to build,
+// oldest first
+func (s *BatchStore) GetRunningBatches() ([]*Batch, error) {
+	rows, err := s.db.Query(`
+		SELECT batch_id, maintainer, submitted_at, state
+		FROM batches
+		WHERE state = 'RUNNING'
+		ORDER BY submitted_at ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running batches: %w", err)
+	}
+	return s.scanBatches(rows)
+}
+
+// GetRecentBatches retrieves the N most recent batches
+func (s *BatchStore) GetRecentBatches(limit int) ([]*Batch, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	rows, err := s.db.Query(`
+		SELECT batch_id, maintainer, submitted_at, state
+		FROM batches
+		ORDER BY submitted_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", err)
+	}
+	return s.scanBatches(rows)
+}
+
+// UpdateBatchItem updates the state of a package of a batch, and whether its
+// pipeline was queued
+func (s *BatchStore) UpdateBatchItem(batchID string, position int, state string, queued bool) error {
+	result, err := s.db.Exec(`
+		UPDATE batch_items
+		SET state = ?, queued = ?
+		WHERE batch_id = ? AND position = ?
+	`, state, queued, batchID, position)
+	if err != nil {
+		return fmt.Errorf("failed to update batch item: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("batch item not found: %s/%d", batchID, position)
+	}
+	return nil
+}
+
+// UpdateBatchState updates the state of a batch
+func (s *BatchStore) UpdateBatchState(batchID, state string) error {
+	result, err := s.db.Exec(`
+		UPDATE batches
+		SET state = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE batch_id = ?
+	`, state, batchID)
+	if err != nil {
+		return fmt.Errorf("failed to update batch state: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("batch not found: %s", batchID)
+	}
+	return nil
+}
+
+// scanBatches reads batch rows, then loads the packages of every batch.
+func (s *BatchStore) scanBatches(rows *sql.Rows) ([]*Batch, error) {
+	var batches []*Batch
+	for rows.Next() {
+		var b Batch
+		if err := rows.Scan(&b.BatchID, &b.Maintainer, &b.SubmittedAt, &b.State); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, &b)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating batches: %w", err)
+	}
+	// The connection pool holds a single connection, the batch rows have to
+	// be released before querying the items.
+	rows.Close()
+
+	for _, b := range batches {
+		items, err := s.getBatchItems(b.BatchID)
+		if err != nil {
+			return nil, err
+		}
+		b.Items = items
+	}
+	return batches, nil
+}
+
+func (s *BatchStore) getBatchItems(batchID string) ([]BatchItem, error) {
+	rows, err := s.db.Query(`
+		SELECT position, package_name, package_version, task_uuid, depends_on,
+		       state, queued, submission
+		FROM batch_items
+		WHERE batch_id = ?
+		ORDER BY position ASC
+	`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batch items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []BatchItem
+	for rows.Next() {
+		var item BatchItem
+		var dependsOn string
+		if err := rows.Scan(&item.Position, &item.PackageName, &item.PackageVersion, &item.TaskUUID,
+			&dependsOn, &item.State, &item.Queued, &item.Submission); err != nil {
+			return nil, fmt.Errorf("failed to scan batch item: %w", err)
+		}
+		item.DependsOn, err = splitPositions(dependsOn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode dependencies of %s: %w", item.PackageName, err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch items: %w", err)
+	}
+	return items, nil
+}
+
+// cleanupOldBatches removes the oldest finished batches exceeding the
+// maximum count
+func (s *BatchStore) cleanupOldBatches() error {
+	keep := `
+		SELECT batch_id FROM batches
+		ORDER BY submitted_at DESC, id DESC
+		LIMIT ?
+	`
+	if _, err := s.db.Exec(`
+		DELETE FROM batch_items
+		WHERE batch_id IN (SELECT batch_id FROM batches WHERE state != 'RUNNING')
+		  AND batch_id NOT IN (`+keep+`)
+	`, s.maxBatches); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		DELETE FROM batches
+		WHERE state != 'RUNNING'
+		  AND batch_id NOT IN (`+keep+`)
+	`, s.maxBatches)
+	return err
+}
+
+func joinPositions(positions []int) string {
+	parts := make([]string, len(positions))
+	for i, p := range positions {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, " ")
+}
+
+func splitPositions(s string) ([]int, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	positions := make([]int, len(fields))
+	for i, f := range fields {
+		p, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		positions[i] = p
+	}
+	return positions, nil
+}
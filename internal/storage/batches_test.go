@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBatch(id string, submittedAt time.Time) Batch {
+	return Batch{
+		BatchID:     id,
+		Maintainer:  "Maintainer <m@example.com>",
+		SubmittedAt: submittedAt,
+		State:       "RUNNING",
+		Items: []BatchItem{
+			{Position: 0, PackageName: "libfoo", PackageVersion: "1.0-1", TaskUUID: id + "-libfoo", State: "PENDING", Queued: true, Submission: `{"packageName":"libfoo"}`},
+			{Position: 1, PackageName: "app", PackageVersion: "2.0-1", TaskUUID: id + "-app", DependsOn: []int{0}, State: "WAITING", Submission: `{"packageName":"app"}`},
+		},
+	}
+}
+
+func TestBatchStore_RecordAndGet(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewBatchStore(db, 100)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.RecordBatch(testBatch("batch-1", now)))
+
+	batch, err := store.GetBatch("batch-1")
+	require.NoError(t, err)
+	assert.Equal(t, "RUNNING", batch.State)
+	assert.True(t, now.Equal(batch.SubmittedAt))
+	require.Len(t, batch.Items, 2)
+	assert.Equal(t, "libfoo", batch.Items[0].PackageName)
+	assert.True(t, batch.Items[0].Queued)
+	assert.Nil(t, batch.Items[0].DependsOn)
+	assert.Equal(t, []int{0}, batch.Items[1].DependsOn)
+	assert.Equal(t, `{"packageName":"app"}`, batch.Items[1].Submission)
+
+	_, err = store.GetBatch("missing")
+	assert.Error(t, err)
+}
+
+func TestBatchStore_Updates(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewBatchStore(db, 100)
+	now := time.Now().UTC()
+	require.NoError(t, store.RecordBatch(testBatch("batch-1", now.Add(-time.Hour))))
+	require.NoError(t, store.RecordBatch(testBatch("batch-2", now)))
+
+	require.NoError(t, store.UpdateBatchItem("batch-1", 1, "PENDING", true))
+	require.NoError(t, store.UpdateBatchState("batch-2", "DONE"))
+	assert.Error(t, store.UpdateBatchItem("batch-1", 5, "PENDING", true))
+	assert.Error(t, store.UpdateBatchState("missing", "DONE"))
+
+	running, err := store.GetRunningBatches()
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	assert.Equal(t, "batch-1", running[0].BatchID)
+	assert.Equal(t, "PENDING", running[0].Items[1].State)
+	assert.True(t, running[0].Items[1].Queued)
+
+	recent, err := store.GetRecentBatches(10)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "batch-2", recent[0].BatchID)
+	assert.Len(t, recent[0].Items, 2)
+}
+
+func TestBatchStore_CleanupKeepsRunningBatches(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewBatchStore(db, 2)
+	now := time.Now().UTC()
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("batch-%d", i)
+		require.NoError(t, store.RecordBatch(testBatch(id, now.Add(time.Duration(i)*time.Minute))))
+		if i > 0 {
+			require.NoError(t, store.UpdateBatchState(id, "DONE"))
+		}
+	}
+	require.NoError(t, store.RecordBatch(testBatch("batch-4", now.Add(time.Hour))))
+
+	// The oldest batch is still running, it is kept
+	_, err = store.GetBatch("batch-0")
+	assert.NoError(t, err)
+	_, err = store.GetBatch("batch-1")
+	assert.Error(t, err)
+
+	var items int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM batch_items WHERE batch_id = 'batch-1'`).Scan(&items))
+	assert.Zero(t, items)
+}
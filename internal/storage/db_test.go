@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestNewDB_MigratesOldSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "irgsh.db")
+
+	// Create a jobs table as shipped before the migrated columns existed
+	old, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	_, err = old.Exec(`CREATE TABLE jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_uuid TEXT UNIQUE NOT NULL,
+		package_name TEXT NOT NULL,
+		package_version TEXT NOT NULL,
+		maintainer TEXT NOT NULL,
+		component TEXT NOT NULL,
+		is_experimental BOOLEAN DEFAULT FALSE,
+		submitted_at DATETIME NOT NULL,
+		state TEXT NOT NULL DEFAULT 'PENDING',
+		current_stage TEXT DEFAULT 'build',
+		build_state TEXT DEFAULT '',
+		repo_state TEXT DEFAULT '',
+		package_url TEXT DEFAULT '',
+		source_url TEXT DEFAULT '',
+		package_branch TEXT DEFAULT '',
+		source_branch TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = old.Exec(`INSERT INTO jobs (task_uuid, package_name, package_version, maintainer, component, submitted_at)
+		VALUES ('old-uuid', 'pkg', '1.0', 'Maintainer', 'main', ?)`, time.Now().UTC())
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	db, err := NewDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, m := range columnMigrations {
+		exists, err := db.columnExists(m.table, m.column)
+		require.NoError(t, err)
+		assert.True(t, exists, "%s.%s should have been added", m.table, m.column)
+	}
+
+	job, err := NewJobStore(db, 100).GetJob("old-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, "pkg", job.PackageName)
+	assert.Empty(t, job.Architectures)
+}
+
+func TestNewDB_MigrationIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "irgsh.db")
+
+	db, err := NewDB(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = NewDB(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
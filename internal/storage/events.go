@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// JobEvent is a state transition of a task of a pipeline, recorded by chief
+// or by the worker running the task.
+type JobEvent struct {
+	TaskUUID     string    `json:"task_uuid"`
+	TaskID       string    `json:"task_id"` // Build id for build tasks, the pipeline otherwise
+	Stage        string    `json:"stage"`   // build, test, repo...
+	Architecture string    `json:"architecture"`
+	Event        string    `json:"event"`    // queued, started, done, failed...
+	Instance     string    `json:"instance"` // Empty for the events of chief
+	Detail       string    `json:"detail"`   // Why the task failed, for the events ending it
+	Duration     float64   `json:"duration"` // Seconds the task ran for, for the events ending it
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// RecordJobEvent appends an event to the history of a pipeline
+func (s *JobStore) RecordJobEvent(event JobEvent) error {
+	query := `
+		INSERT INTO job_events (task_uuid, task_id, stage, architecture, event, instance, detail, duration_seconds, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query,
+		event.TaskUUID,
+		event.TaskID,
+		event.Stage,
+		event.Architecture,
+		event.Event,
+		event.Instance,
+		event.Detail,
+		event.Duration,
+		event.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record job event: %w", err)
+	}
+
+	return nil
+}
+
+// GetJobEvents retrieves the events of a pipeline, oldest first
+func (s *JobStore) GetJobEvents(taskUUID string) ([]*JobEvent, error) {
+	query := `
+		SELECT task_uuid, task_id, stage, architecture, event, instance, detail, duration_seconds, occurred_at
+		FROM job_events
+		WHERE task_uuid = ?
+		ORDER BY occurred_at ASC, id ASC
+	`
+
+	rows, err := s.db.Query(query, taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*JobEvent
+	for rows.Next() {
+		var event JobEvent
+		err := rows.Scan(
+			&event.TaskUUID,
+			&event.TaskID,
+			&event.Stage,
+			&event.Architecture,
+			&event.Event,
+			&event.Instance,
+			&event.Detail,
+			&event.Duration,
+			&event.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job events: %w", err)
+	}
+
+	return events, nil
+}
+
+// StageDuration is the time a task of a pipeline ran for, as reported by the
+// worker that completed it.
+type StageDuration struct {
+	TaskUUID     string    `json:"task_uuid"`
+	PackageName  string    `json:"package_name"`
+	Architecture string    `json:"architecture"`
+	Instance     string    `json:"instance"`
+	Duration     float64   `json:"duration"` // Seconds
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// GetStageDurations retrieves the durations of the tasks of a stage that
+// completed successfully, oldest first. The second builds of the
+// reproducibility checks are left out. An empty packageName matches every
+// package.
+func (s *JobStore) GetStageDurations(stage, packageName string) ([]*StageDuration, error) {
+	query := `
+		SELECT e.task_uuid, j.package_name, e.architecture, e.instance, e.duration_seconds, e.occurred_at
+		FROM job_events e
+		JOIN jobs j ON j.task_uuid = e.task_uuid
+		WHERE e.stage = ? AND e.event = 'done' AND e.duration_seconds > 0
+			AND e.task_id NOT LIKE '%.repro'
+			AND (? = '' OR j.package_name = ?)
+		ORDER BY e.occurred_at ASC, e.id ASC
+	`
+
+	rows, err := s.db.Query(query, stage, packageName, packageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stage durations: %w", err)
+	}
+	defer rows.Close()
+
+	var durations []*StageDuration
+	for rows.Next() {
+		var duration StageDuration
+		err := rows.Scan(
+			&duration.TaskUUID,
+			&duration.PackageName,
+			&duration.Architecture,
+			&duration.Instance,
+			&duration.Duration,
+			&duration.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stage duration: %w", err)
+		}
+		durations = append(durations, &duration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stage durations: %w", err)
+	}
+
+	return durations, nil
+}
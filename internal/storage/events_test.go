@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStore_JobEvents(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 1)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "event-uuid",
+		PackageName:    "hello",
+		PackageVersion: "1.0",
+		Maintainer:     "Test Maintainer",
+		Component:      "main",
+		SubmittedAt:    now,
+		State:          "PENDING",
+	}))
+
+	queued := JobEvent{TaskUUID: "event-uuid", TaskID: "event-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "queued", OccurredAt: now}
+	started := JobEvent{TaskUUID: "event-uuid", TaskID: "event-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "started", Instance: "builder1-builder", OccurredAt: now.Add(time.Second)}
+	failed := JobEvent{TaskUUID: "event-uuid", TaskID: "event-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "failed", Instance: "builder1-builder", Detail: "exit status 1", Duration: 59.5, OccurredAt: now.Add(time.Minute)}
+	require.NoError(t, store.RecordJobEvent(started))
+	require.NoError(t, store.RecordJobEvent(queued))
+	require.NoError(t, store.RecordJobEvent(failed))
+
+	events, err := store.GetJobEvents("event-uuid")
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, queued, *events[0])
+	assert.Equal(t, started, *events[1])
+	assert.Equal(t, failed, *events[2])
+
+	// The events go along with their job
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "newer-uuid",
+		PackageName:    "hello",
+		PackageVersion: "1.1",
+		Maintainer:     "Test Maintainer",
+		Component:      "main",
+		SubmittedAt:    now.Add(time.Hour),
+		State:          "PENDING",
+	}))
+	events, err = store.GetJobEvents("event-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestJobStore_GetStageDurations(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 10)
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, job := range []JobInfo{
+		{TaskUUID: "hello-uuid", PackageName: "hello", PackageVersion: "1.0", SubmittedAt: now, State: "DONE"},
+		{TaskUUID: "world-uuid", PackageName: "world", PackageVersion: "1.0", SubmittedAt: now, State: "FAILED"},
+	} {
+		require.NoError(t, store.RecordJob(job))
+	}
+	for _, event := range []JobEvent{
+		{TaskUUID: "hello-uuid", TaskID: "hello-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "started", Instance: "b1-builder", OccurredAt: now},
+		{TaskUUID: "hello-uuid", TaskID: "hello-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "done", Instance: "b1-builder", Duration: 120, OccurredAt: now.Add(2 * time.Minute)},
+		{TaskUUID: "hello-uuid", TaskID: "hello-uuid.amd64.repro", Stage: "build", Architecture: "amd64", Event: "done", Instance: "b2-builder", Duration: 150, OccurredAt: now.Add(150 * time.Second)},
+		{TaskUUID: "hello-uuid", TaskID: "hello-uuid", Stage: "repo", Event: "done", Instance: "r1-repo", Duration: 30, OccurredAt: now.Add(3 * time.Minute)},
+		{TaskUUID: "world-uuid", TaskID: "world-uuid.arm64", Stage: "build", Architecture: "arm64", Event: "done", Instance: "b2-builder", Duration: 300, OccurredAt: now.Add(4 * time.Minute)},
+		{TaskUUID: "world-uuid", TaskID: "world-uuid.amd64", Stage: "build", Architecture: "amd64", Event: "failed", Instance: "b1-builder", Duration: 10, OccurredAt: now.Add(5 * time.Minute)},
+	} {
+		require.NoError(t, store.RecordJobEvent(event))
+	}
+
+	durations, err := store.GetStageDurations("build", "")
+	require.NoError(t, err)
+	require.Len(t, durations, 2)
+	assert.Equal(t, StageDuration{TaskUUID: "hello-uuid", PackageName: "hello", Architecture: "amd64", Instance: "b1-builder", Duration: 120, FinishedAt: now.Add(2 * time.Minute)}, *durations[0])
+	assert.Equal(t, StageDuration{TaskUUID: "world-uuid", PackageName: "world", Architecture: "arm64", Instance: "b2-builder", Duration: 300, FinishedAt: now.Add(4 * time.Minute)}, *durations[1])
+
+	durations, err = store.GetStageDurations("build", "world")
+	require.NoError(t, err)
+	require.Len(t, durations, 1)
+	assert.Equal(t, "world-uuid", durations[0].TaskUUID)
+}
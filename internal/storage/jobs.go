@@ -2,10 +2,24 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
+// Job types. Jobs recorded before job types were introduced have an empty
+// type and are build jobs.
+const (
+	JobTypeBuild    = "build"
+	JobTypePromote  = "promote"
+	JobTypeRemove   = "remove"
+	JobTypeSnapshot = "snapshot"
+	JobTypeRestore  = "restore"
+	JobTypeRebuild  = "rebuild"
+)
+
 // JobInfo contains metadata about a build job
 type JobInfo struct {
 	TaskUUID       string    `json:"task_uuid"`
@@ -23,6 +37,81 @@ type JobInfo struct {
 	SourceURL      string    `json:"source_url"`     // Git repository URL for source
 	PackageBranch  string    `json:"package_branch"` // Branch name for package
 	SourceBranch   string    `json:"source_branch"`  // Branch name for source
+
+	Architectures   []string          `json:"architectures,omitempty"`     // Build architectures of the pipeline
+	ArchBuildStates map[string]string `json:"arch_build_states,omitempty"` // State of each per-architecture build task
+	Suite           string            `json:"suite,omitempty"`             // Target distribution, empty for the default one
+	JobType         string            `json:"job_type,omitempty"`          // JobTypeBuild when empty
+
+	// Verdict of the reproducibility check of the pipeline, empty when it
+	// was not asked for, and the .deb files that differed between builds
+	Reproducibility       string `json:"reproducibility,omitempty"`
+	ReproducibilityDetail string `json:"reproducibility_detail,omitempty"`
+
+	RunTests       bool              `json:"run_tests,omitempty"`        // Whether the built packages are tested before the repo task
+	TestState      string            `json:"test_state,omitempty"`       // State of the test tasks, aggregated like BuildState
+	ArchTestStates map[string]string `json:"arch_test_states,omitempty"` // State of each per-architecture test task
+
+	BuilderLabel string `json:"builder_label,omitempty"` // Label of the builders the pipeline was routed to
+	UpstreamOnly bool   `json:"upstream_only,omitempty"` // Whether the build dependencies only come from upstream
+}
+
+// IsBuild reports whether the job is a package build pipeline, rebuilds
+// included, as opposed to a repository-only task such as a promotion.
+func (j *JobInfo) IsBuild() bool {
+	return j.JobType == "" || j.JobType == JobTypeBuild || j.JobType == JobTypeRebuild
+}
+
+// jobColumns is the column list shared by the job SELECT queries; scanJob
+// expects the columns in this order.
+const jobColumns = `task_uuid, package_name, package_version, maintainer, component,
+			   is_experimental, submitted_at, state, current_stage, build_state,
+			   repo_state, package_url, source_url, package_branch, source_branch,
+			   architectures, arch_build_states, suite, job_type, reproducibility,
+			   reproducibility_detail, run_tests, test_state, builder_label, upstream_only,
+			   arch_test_states`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*JobInfo, error) {
+	var job JobInfo
+	var archs, archStates, archTestStates string
+	err := row.Scan(
+		&job.TaskUUID, &job.PackageName, &job.PackageVersion, &job.Maintainer, &job.Component,
+		&job.IsExperimental, &job.SubmittedAt, &job.State, &job.CurrentStage, &job.BuildState,
+		&job.RepoState, &job.PackageURL, &job.SourceURL, &job.PackageBranch, &job.SourceBranch,
+		&archs, &archStates, &job.Suite, &job.JobType, &job.Reproducibility,
+		&job.ReproducibilityDetail, &job.RunTests, &job.TestState, &job.BuilderLabel, &job.UpstreamOnly,
+		&archTestStates,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Architectures = strings.Fields(archs)
+	if archStates != "" {
+		if err := json.Unmarshal([]byte(archStates), &job.ArchBuildStates); err != nil {
+			return nil, fmt.Errorf("failed to decode arch build states: %w", err)
+		}
+	}
+	if archTestStates != "" {
+		if err := json.Unmarshal([]byte(archTestStates), &job.ArchTestStates); err != nil {
+			return nil, fmt.Errorf("failed to decode arch test states: %w", err)
+		}
+	}
+	return &job, nil
+}
+
+func encodeArchStates(archStates map[string]string) (string, error) {
+	if len(archStates) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(archStates)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arch states: %w", err)
+	}
+	return string(b), nil
 }
 
 // JobStore handles job persistence in SQLite
@@ -48,8 +137,11 @@ func (s *JobStore) RecordJob(job JobInfo) error {
 		INSERT INTO jobs (
 			task_uuid, package_name, package_version, maintainer, component,
 			is_experimental, submitted_at, state, current_stage, build_state,
-			repo_state, package_url, source_url, package_branch, source_branch
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			repo_state, package_url, source_url, package_branch, source_branch,
+			architectures, arch_build_states, suite, job_type, reproducibility,
+			reproducibility_detail, run_tests, test_state, builder_label, upstream_only,
+			arch_test_states
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(task_uuid) DO UPDATE SET
 			package_name = excluded.package_name,
 			package_version = excluded.package_version,
@@ -64,13 +156,36 @@ func (s *JobStore) RecordJob(job JobInfo) error {
 			source_url = excluded.source_url,
 			package_branch = excluded.package_branch,
 			source_branch = excluded.source_branch,
+			architectures = excluded.architectures,
+			arch_build_states = excluded.arch_build_states,
+			suite = excluded.suite,
+			job_type = excluded.job_type,
+			reproducibility = excluded.reproducibility,
+			reproducibility_detail = excluded.reproducibility_detail,
+			run_tests = excluded.run_tests,
+			test_state = excluded.test_state,
+			builder_label = excluded.builder_label,
+			upstream_only = excluded.upstream_only,
+			arch_test_states = excluded.arch_test_states,
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err := s.db.Exec(query,
+	archStates, err := encodeArchStates(job.ArchBuildStates)
+	if err != nil {
+		return err
+	}
+	archTestStates, err := encodeArchStates(job.ArchTestStates)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(query,
 		job.TaskUUID, job.PackageName, job.PackageVersion, job.Maintainer, job.Component,
 		job.IsExperimental, job.SubmittedAt, job.State, job.CurrentStage, job.BuildState,
 		job.RepoState, job.PackageURL, job.SourceURL, job.PackageBranch, job.SourceBranch,
+		strings.Join(job.Architectures, " "), archStates, job.Suite, job.JobType, job.Reproducibility,
+		job.ReproducibilityDetail, job.RunTests, job.TestState, job.BuilderLabel, job.UpstreamOnly,
+		archTestStates,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to record job: %w", err)
@@ -88,19 +203,12 @@ func (s *JobStore) RecordJob(job JobInfo) error {
 // GetJob retrieves a job by UUID
 func (s *JobStore) GetJob(taskUUID string) (*JobInfo, error) {
 	query := `
-		SELECT task_uuid, package_name, package_version, maintainer, component,
-			   is_experimental, submitted_at, state, current_stage, build_state,
-			   repo_state, package_url, source_url, package_branch, source_branch
+		SELECT ` + jobColumns + `
 		FROM jobs
 		WHERE task_uuid = ?
 	`
 
-	var job JobInfo
-	err := s.db.QueryRow(query, taskUUID).Scan(
-		&job.TaskUUID, &job.PackageName, &job.PackageVersion, &job.Maintainer, &job.Component,
-		&job.IsExperimental, &job.SubmittedAt, &job.State, &job.CurrentStage, &job.BuildState,
-		&job.RepoState, &job.PackageURL, &job.SourceURL, &job.PackageBranch, &job.SourceBranch,
-	)
+	job, err := scanJob(s.db.QueryRow(query, taskUUID))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("job not found: %s", taskUUID)
 	}
@@ -108,7 +216,7 @@ func (s *JobStore) GetJob(taskUUID string) (*JobInfo, error) {
 		return nil, fmt.Errorf("failed to get job: %w", err)
 	}
 
-	return &job, nil
+	return job, nil
 }
 
 // GetRecentJobs retrieves the N most recent jobs
@@ -118,9 +226,7 @@ func (s *JobStore) GetRecentJobs(limit int) ([]*JobInfo, error) {
 	}
 
 	query := `
-		SELECT task_uuid, package_name, package_version, maintainer, component,
-			   is_experimental, submitted_at, state, current_stage, build_state,
-			   repo_state, package_url, source_url, package_branch, source_branch
+		SELECT ` + jobColumns + `
 		FROM jobs
 		ORDER BY submitted_at DESC
 		LIMIT ?
@@ -134,16 +240,11 @@ func (s *JobStore) GetRecentJobs(limit int) ([]*JobInfo, error) {
 
 	var jobs []*JobInfo
 	for rows.Next() {
-		var job JobInfo
-		err := rows.Scan(
-			&job.TaskUUID, &job.PackageName, &job.PackageVersion, &job.Maintainer, &job.Component,
-			&job.IsExperimental, &job.SubmittedAt, &job.State, &job.CurrentStage, &job.BuildState,
-			&job.RepoState, &job.PackageURL, &job.SourceURL, &job.PackageBranch, &job.SourceBranch,
-		)
+		job, err := scanJob(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan job: %w", err)
 		}
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -153,23 +254,90 @@ func (s *JobStore) GetRecentJobs(limit int) ([]*JobInfo, error) {
 	return jobs, nil
 }
 
+// GetJobsByReproducibility retrieves the jobs whose reproducibility check
+// has the given verdict, oldest first
+func (s *JobStore) GetJobsByReproducibility(verdict string) ([]*JobInfo, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE reproducibility = ?
+		ORDER BY submitted_at ASC
+	`
+
+	rows, err := s.db.Query(query, verdict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*JobInfo
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetUnfinishedJobs retrieves the jobs whose state is not terminal yet,
+// oldest first
+func (s *JobStore) GetUnfinishedJobs() ([]*JobInfo, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE state NOT IN (?` + strings.Repeat(", ?", len(terminalStates)-1) + `)
+		ORDER BY submitted_at ASC
+	`
+
+	args := make([]any, len(terminalStates))
+	for i, state := range terminalStates {
+		args[i] = state
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*JobInfo
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// terminalStates are the final job states, which are not overwritten.
+var terminalStates = []string{"SUCCESS", "DONE", "FAILURE", "FAILED", "CANCELLED", "TIMEOUT", "SKIPPED"}
+
 // IsTerminalState returns true if the state is a final state that should not be overwritten.
 func IsTerminalState(state string) bool {
-	switch state {
-	case "SUCCESS", "DONE", "FAILURE", "FAILED":
-		return true
-	}
-	return false
+	return slices.Contains(terminalStates, state)
 }
 
 // UpdateJobState updates the state of a job.
-// Terminal states (SUCCESS, DONE, FAILURE, FAILED) are never overwritten.
+// Terminal states (SUCCESS, DONE, FAILURE, FAILED, CANCELLED, TIMEOUT) are never overwritten.
 func (s *JobStore) UpdateJobState(taskUUID, state string) error {
 	query := `
 		UPDATE jobs
 		SET state = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE task_uuid = ?
-		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED')
+		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED', 'CANCELLED', 'TIMEOUT')
 	`
 
 	result, err := s.db.Exec(query, state, taskUUID)
@@ -191,13 +359,13 @@ func (s *JobStore) UpdateJobState(taskUUID, state string) error {
 }
 
 // UpdateJobStages updates the build and repo states of a job.
-// Jobs already in a terminal state (SUCCESS, DONE, FAILURE, FAILED) are not updated.
+// Jobs already in a terminal state (SUCCESS, DONE, FAILURE, FAILED, CANCELLED, TIMEOUT) are not updated.
 func (s *JobStore) UpdateJobStages(taskUUID, buildState, repoState, currentStage string) error {
 	query := `
 		UPDATE jobs
 		SET build_state = ?, repo_state = ?, current_stage = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE task_uuid = ?
-		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED')
+		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED', 'CANCELLED', 'TIMEOUT')
 	`
 
 	_, err := s.db.Exec(query, buildState, repoState, currentStage, taskUUID)
@@ -208,7 +376,80 @@ func (s *JobStore) UpdateJobStages(taskUUID, buildState, repoState, currentStage
 	return nil
 }
 
-// cleanupOldJobs removes old jobs exceeding the maximum count
+// UpdateJobArchStates records the per-architecture build states of a job.
+// Jobs already in a terminal state are not updated.
+func (s *JobStore) UpdateJobArchStates(taskUUID string, archStates map[string]string) error {
+	encoded, err := encodeArchStates(archStates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE jobs
+		SET arch_build_states = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE task_uuid = ?
+		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED', 'CANCELLED', 'TIMEOUT')
+	`
+
+	_, err = s.db.Exec(query, encoded, taskUUID)
+	if err != nil {
+		return fmt.Errorf("failed to update job arch states: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateJobTestState records the state of the test tasks of a job, along
+// with the per-architecture ones. Jobs already in a terminal state are not
+// updated.
+func (s *JobStore) UpdateJobTestState(taskUUID, testState string, archTestStates map[string]string) error {
+	encoded, err := encodeArchStates(archTestStates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE jobs
+		SET test_state = ?, arch_test_states = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE task_uuid = ?
+		AND state NOT IN ('SUCCESS', 'DONE', 'FAILURE', 'FAILED', 'CANCELLED', 'TIMEOUT')
+	`
+
+	_, err = s.db.Exec(query, testState, encoded, taskUUID)
+	if err != nil {
+		return fmt.Errorf("failed to update job test state: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateJobReproducibility records the verdict of the reproducibility check
+// of a job. The check may end after the pipeline itself, so the verdict is
+// recorded whatever the state of the job.
+func (s *JobStore) UpdateJobReproducibility(taskUUID, verdict, detail string) error {
+	query := `
+		UPDATE jobs
+		SET reproducibility = ?, reproducibility_detail = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE task_uuid = ?
+	`
+
+	result, err := s.db.Exec(query, verdict, detail, taskUUID)
+	if err != nil {
+		return fmt.Errorf("failed to update job reproducibility: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("job not found: %s", taskUUID)
+	}
+
+	return nil
+}
+
+// cleanupOldJobs removes old jobs exceeding the maximum count, along with
+// their lintian results and events
 func (s *JobStore) cleanupOldJobs() error {
 	query := `
 		DELETE FROM jobs
@@ -219,6 +460,14 @@ func (s *JobStore) cleanupOldJobs() error {
 		)
 	`
 
-	_, err := s.db.Exec(query, s.maxJobs)
+	if _, err := s.db.Exec(query, s.maxJobs); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM lintian_results WHERE task_uuid NOT IN (SELECT task_uuid FROM jobs)`); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec(`DELETE FROM job_events WHERE task_uuid NOT IN (SELECT task_uuid FROM jobs)`)
 	return err
 }
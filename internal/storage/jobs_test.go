@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -31,6 +32,7 @@ func TestJobStore_RecordAndGetJob(t *testing.T) {
 		SourceURL:      "https://github.com/test/source.git",
 		PackageBranch:  "main",
 		SourceBranch:   "master",
+		Suite:          "verbeek",
 	}
 
 	// Record job
@@ -52,6 +54,32 @@ func TestJobStore_RecordAndGetJob(t *testing.T) {
 	assert.Equal(t, job.SourceURL, retrieved.SourceURL)
 	assert.Equal(t, job.PackageBranch, retrieved.PackageBranch)
 	assert.Equal(t, job.SourceBranch, retrieved.SourceBranch)
+	assert.Equal(t, job.Suite, retrieved.Suite)
+	assert.True(t, retrieved.IsBuild())
+}
+
+func TestJobStore_JobType(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+
+	err = store.RecordJob(JobInfo{
+		TaskUUID:       "promote-uuid",
+		PackageName:    "test-package",
+		PackageVersion: "1.0.0",
+		Maintainer:     "Test Maintainer <test@example.com>",
+		SubmittedAt:    time.Now().UTC(),
+		State:          "PENDING",
+		JobType:        JobTypePromote,
+	})
+	require.NoError(t, err)
+
+	retrieved, err := store.GetJob("promote-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, JobTypePromote, retrieved.JobType)
+	assert.False(t, retrieved.IsBuild())
 }
 
 func TestJobStore_GetJobNotFound(t *testing.T) {
@@ -210,6 +238,8 @@ func TestIsTerminalState(t *testing.T) {
 	assert.True(t, IsTerminalState("DONE"))
 	assert.True(t, IsTerminalState("FAILURE"))
 	assert.True(t, IsTerminalState("FAILED"))
+	assert.True(t, IsTerminalState("CANCELLED"))
+	assert.True(t, IsTerminalState("TIMEOUT"))
 	assert.False(t, IsTerminalState("PENDING"))
 	assert.False(t, IsTerminalState("STARTED"))
 	assert.False(t, IsTerminalState("UNKNOWN"))
@@ -343,3 +373,172 @@ func TestJobStore_RecordJobUpsert(t *testing.T) {
 	assert.Equal(t, "STARTED", retrieved.State)
 	assert.Equal(t, "2.0.0", retrieved.PackageVersion)
 }
+
+func TestJobStore_ArchBuildStates(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+
+	job := JobInfo{
+		TaskUUID:       "test-uuid-arch",
+		PackageName:    "test-package",
+		PackageVersion: "1.0.0",
+		Maintainer:     "Test Maintainer",
+		Component:      "main",
+		SubmittedAt:    time.Now().UTC(),
+		State:          "PENDING",
+		Architectures:  []string{"amd64", "arm64"},
+	}
+	require.NoError(t, store.RecordJob(job))
+
+	retrieved, err := store.GetJob("test-uuid-arch")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"amd64", "arm64"}, retrieved.Architectures)
+	assert.Empty(t, retrieved.ArchBuildStates)
+
+	states := map[string]string{"amd64": "SUCCESS", "arm64": "STARTED"}
+	require.NoError(t, store.UpdateJobArchStates("test-uuid-arch", states))
+
+	retrieved, err = store.GetJob("test-uuid-arch")
+	require.NoError(t, err)
+	assert.Equal(t, states, retrieved.ArchBuildStates)
+
+	// Terminal jobs keep their recorded states
+	require.NoError(t, store.UpdateJobState("test-uuid-arch", "FAILED"))
+	require.NoError(t, store.UpdateJobArchStates("test-uuid-arch", map[string]string{"amd64": "PENDING"}))
+
+	retrieved, err = store.GetJob("test-uuid-arch")
+	require.NoError(t, err)
+	assert.Equal(t, states, retrieved.ArchBuildStates)
+}
+
+func TestJobStore_Reproducibility(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+
+	now := time.Now().UTC()
+	for i, uuid := range []string{"repro-1", "plain", "repro-2"} {
+		job := JobInfo{
+			TaskUUID:       uuid,
+			PackageName:    "test-package",
+			PackageVersion: "1.0.0",
+			Maintainer:     "Test Maintainer",
+			SubmittedAt:    now.Add(time.Duration(i) * time.Minute),
+			State:          "PENDING",
+		}
+		if uuid != "plain" {
+			job.Reproducibility = "PENDING"
+		}
+		require.NoError(t, store.RecordJob(job))
+	}
+
+	pending, err := store.GetJobsByReproducibility("PENDING")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "repro-1", pending[0].TaskUUID)
+	assert.Equal(t, "repro-2", pending[1].TaskUUID)
+
+	// The verdict outlives the pipeline itself
+	require.NoError(t, store.UpdateJobState("repro-1", "DONE"))
+	require.NoError(t, store.UpdateJobReproducibility("repro-1", "UNREPRODUCIBLE", "amd64: hello_1.0_amd64.deb"))
+
+	retrieved, err := store.GetJob("repro-1")
+	require.NoError(t, err)
+	assert.Equal(t, "UNREPRODUCIBLE", retrieved.Reproducibility)
+	assert.Equal(t, "amd64: hello_1.0_amd64.deb", retrieved.ReproducibilityDetail)
+
+	pending, err = store.GetJobsByReproducibility("PENDING")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "repro-2", pending[0].TaskUUID)
+
+	assert.Error(t, store.UpdateJobReproducibility("missing", "REPRODUCIBLE", ""))
+}
+
+func TestJobStore_GetUnfinishedJobs(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+	submitted := time.Now().UTC().Add(-time.Hour)
+	for i, state := range []string{"PENDING", "DONE", "STARTED", "SKIPPED", "WAITING", "CANCELLED"} {
+		require.NoError(t, store.RecordJob(JobInfo{
+			TaskUUID:    fmt.Sprintf("job-%d", i),
+			PackageName: "test-package",
+			SubmittedAt: submitted.Add(time.Duration(i) * time.Minute),
+			State:       state,
+		}))
+	}
+
+	jobs, err := store.GetUnfinishedJobs()
+	require.NoError(t, err)
+	var uuids []string
+	for _, job := range jobs {
+		uuids = append(uuids, job.TaskUUID)
+	}
+	assert.Equal(t, []string{"job-0", "job-2", "job-4"}, uuids)
+}
+
+func TestJobStore_TestState(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "test-uuid-tested",
+		PackageName:    "test-package",
+		PackageVersion: "1.0.0",
+		Maintainer:     "Test Maintainer",
+		SubmittedAt:    time.Now().UTC(),
+		State:          "PENDING",
+		RunTests:       true,
+	}))
+
+	retrieved, err := store.GetJob("test-uuid-tested")
+	require.NoError(t, err)
+	assert.True(t, retrieved.RunTests)
+	assert.Empty(t, retrieved.TestState)
+
+	states := map[string]string{"amd64": "SUCCESS", "arm64": "FAILURE"}
+	require.NoError(t, store.UpdateJobTestState("test-uuid-tested", "FAILURE", states))
+	require.NoError(t, store.UpdateJobState("test-uuid-tested", "FAILED"))
+
+	// Terminal jobs keep their recorded state
+	require.NoError(t, store.UpdateJobTestState("test-uuid-tested", "SUCCESS", map[string]string{"amd64": "SUCCESS"}))
+
+	retrieved, err = store.GetJob("test-uuid-tested")
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", retrieved.TestState)
+	assert.Equal(t, states, retrieved.ArchTestStates)
+}
+
+func TestJobStore_SubmissionOptions(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 100)
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "test-uuid-labeled",
+		PackageName:    "test-package",
+		PackageVersion: "1.0.0",
+		Maintainer:     "Test Maintainer",
+		SubmittedAt:    time.Now().UTC(),
+		State:          "PENDING",
+		BuilderLabel:   "big-memory",
+		UpstreamOnly:   true,
+	}))
+
+	retrieved, err := store.GetJob("test-uuid-labeled")
+	require.NoError(t, err)
+	assert.Equal(t, "big-memory", retrieved.BuilderLabel)
+	assert.True(t, retrieved.UpstreamOnly)
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blankon/irgsh-go/internal/lintian"
+)
+
+// LintianResult holds the tags lintian reported on the packages built for
+// an architecture of a pipeline.
+type LintianResult struct {
+	TaskUUID     string        `json:"task_uuid"`
+	Architecture string        `json:"architecture"` // Empty for pipelines predating per-architecture builds
+	Tags         []lintian.Tag `json:"tags"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+}
+
+// RecordLintianResult stores the lintian tags of a build, replacing those of
+// an earlier run of the same build.
+func (s *JobStore) RecordLintianResult(result LintianResult) error {
+	tags, err := json.Marshal(result.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode lintian tags: %w", err)
+	}
+
+	query := `
+		INSERT INTO lintian_results (task_uuid, architecture, tags, recorded_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(task_uuid, architecture) DO UPDATE SET
+			tags = excluded.tags,
+			recorded_at = excluded.recorded_at
+	`
+
+	_, err = s.db.Exec(query, result.TaskUUID, result.Architecture, string(tags), result.RecordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record lintian result: %w", err)
+	}
+
+	return nil
+}
+
+// GetLintianResults retrieves the lintian tags of every build of a pipeline,
+// by architecture
+func (s *JobStore) GetLintianResults(taskUUID string) ([]*LintianResult, error) {
+	query := `
+		SELECT task_uuid, architecture, tags, recorded_at
+		FROM lintian_results
+		WHERE task_uuid = ?
+		ORDER BY architecture ASC
+	`
+
+	rows, err := s.db.Query(query, taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lintian results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*LintianResult
+	for rows.Next() {
+		var result LintianResult
+		var tags string
+		if err := rows.Scan(&result.TaskUUID, &result.Architecture, &tags, &result.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lintian result: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tags), &result.Tags); err != nil {
+			return nil, fmt.Errorf("failed to decode lintian tags: %w", err)
+		}
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lintian results: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blankon/irgsh-go/internal/lintian"
+)
+
+func TestJobStore_LintianResults(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewJobStore(db, 1)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "lint-uuid",
+		PackageName:    "hello",
+		PackageVersion: "1.0",
+		Maintainer:     "Test Maintainer",
+		Component:      "main",
+		SubmittedAt:    now,
+		State:          "PENDING",
+	}))
+
+	noCopyright := lintian.Tag{Severity: lintian.Error, Package: "hello", Name: "no-copyright-file"}
+	manpage := lintian.Tag{Severity: lintian.Warning, Package: "hello", Name: "binary-without-manpage", Info: "[usr/bin/hello]"}
+	require.NoError(t, store.RecordLintianResult(LintianResult{TaskUUID: "lint-uuid", Architecture: "arm64", RecordedAt: now}))
+	require.NoError(t, store.RecordLintianResult(LintianResult{TaskUUID: "lint-uuid", Architecture: "amd64", Tags: []lintian.Tag{noCopyright}, RecordedAt: now}))
+	// A build run again replaces its tags
+	require.NoError(t, store.RecordLintianResult(LintianResult{TaskUUID: "lint-uuid", Architecture: "amd64", Tags: []lintian.Tag{noCopyright, manpage}, RecordedAt: now}))
+
+	results, err := store.GetLintianResults("lint-uuid")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "amd64", results[0].Architecture)
+	assert.Equal(t, []lintian.Tag{noCopyright, manpage}, results[0].Tags)
+	assert.Equal(t, "arm64", results[1].Architecture)
+	assert.Empty(t, results[1].Tags)
+
+	// The results go along with their job
+	require.NoError(t, store.RecordJob(JobInfo{
+		TaskUUID:       "newer-uuid",
+		PackageName:    "hello",
+		PackageVersion: "1.1",
+		Maintainer:     "Test Maintainer",
+		Component:      "main",
+		SubmittedAt:    now.Add(time.Hour),
+		State:          "PENDING",
+	}))
+	results, err = store.GetLintianResults("lint-uuid")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -18,6 +18,17 @@ CREATE TABLE IF NOT EXISTS jobs (
     source_url TEXT DEFAULT '',
     package_branch TEXT DEFAULT '',
     source_branch TEXT DEFAULT '',
+    architectures TEXT DEFAULT '',
+    arch_build_states TEXT DEFAULT '',
+    suite TEXT DEFAULT '',
+    job_type TEXT DEFAULT '',
+    reproducibility TEXT DEFAULT '',
+    reproducibility_detail TEXT DEFAULT '',
+    run_tests BOOLEAN DEFAULT FALSE,
+    test_state TEXT DEFAULT '',
+    builder_label TEXT DEFAULT '',
+    upstream_only BOOLEAN DEFAULT FALSE,
+    arch_test_states TEXT DEFAULT '',
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -33,8 +44,96 @@ CREATE TABLE IF NOT EXISTS iso_jobs (
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS task_claims (
+    task_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    worker TEXT NOT NULL,
+    claimed_at DATETIME NOT NULL,
+    PRIMARY KEY (task_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS upload_rejections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT DEFAULT '',
+    kind TEXT DEFAULT '',
+    worker TEXT DEFAULT '',
+    remote_addr TEXT DEFAULT '',
+    reason TEXT NOT NULL,
+    rejected_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS batches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    batch_id TEXT UNIQUE NOT NULL,
+    maintainer TEXT NOT NULL,
+    submitted_at DATETIME NOT NULL,
+    state TEXT NOT NULL DEFAULT 'RUNNING',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS batch_items (
+    batch_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    package_name TEXT NOT NULL,
+    package_version TEXT NOT NULL,
+    task_uuid TEXT NOT NULL,
+    depends_on TEXT DEFAULT '',
+    state TEXT NOT NULL DEFAULT 'WAITING',
+    queued BOOLEAN DEFAULT FALSE,
+    submission TEXT NOT NULL,
+    PRIMARY KEY (batch_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS lintian_results (
+    task_uuid TEXT NOT NULL,
+    architecture TEXT NOT NULL,
+    tags TEXT NOT NULL DEFAULT '',
+    recorded_at DATETIME NOT NULL,
+    PRIMARY KEY (task_uuid, architecture)
+);
+
+CREATE TABLE IF NOT EXISTS job_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_uuid TEXT NOT NULL,
+    task_id TEXT NOT NULL,
+    stage TEXT NOT NULL,
+    architecture TEXT DEFAULT '',
+    event TEXT NOT NULL,
+    instance TEXT DEFAULT '',
+    detail TEXT DEFAULT '',
+    duration_seconds REAL DEFAULT 0,
+    occurred_at DATETIME NOT NULL
+);
+
 CREATE INDEX IF NOT EXISTS idx_jobs_submitted_at ON jobs(submitted_at DESC);
 CREATE INDEX IF NOT EXISTS idx_jobs_task_uuid ON jobs(task_uuid);
 CREATE INDEX IF NOT EXISTS idx_iso_jobs_submitted_at ON iso_jobs(submitted_at DESC);
 CREATE INDEX IF NOT EXISTS idx_iso_jobs_task_uuid ON iso_jobs(task_uuid);
+CREATE INDEX IF NOT EXISTS idx_task_claims_claimed_at ON task_claims(claimed_at);
+CREATE INDEX IF NOT EXISTS idx_upload_rejections_rejected_at ON upload_rejections(rejected_at DESC);
+CREATE INDEX IF NOT EXISTS idx_batches_submitted_at ON batches(submitted_at DESC);
+CREATE INDEX IF NOT EXISTS idx_batches_state ON batches(state);
+CREATE INDEX IF NOT EXISTS idx_job_events_task_uuid ON job_events(task_uuid);
 `
+
+// columnMigrations adds columns introduced after a table was first created.
+// CREATE TABLE IF NOT EXISTS leaves existing databases untouched, so every
+// column added to the schema above must also be listed here.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"jobs", "architectures", "TEXT DEFAULT ''"},
+	{"jobs", "arch_build_states", "TEXT DEFAULT ''"},
+	{"jobs", "suite", "TEXT DEFAULT ''"},
+	{"jobs", "job_type", "TEXT DEFAULT ''"},
+	{"jobs", "reproducibility", "TEXT DEFAULT ''"},
+	{"jobs", "reproducibility_detail", "TEXT DEFAULT ''"},
+	{"jobs", "run_tests", "BOOLEAN DEFAULT FALSE"},
+	{"jobs", "test_state", "TEXT DEFAULT ''"},
+	{"jobs", "builder_label", "TEXT DEFAULT ''"},
+	{"jobs", "upstream_only", "BOOLEAN DEFAULT FALSE"},
+	{"jobs", "arch_test_states", "TEXT DEFAULT ''"},
+	{"job_events", "duration_seconds", "REAL DEFAULT 0"},
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// claimRetention is how long task claims are kept. Uploads for a task only
+// happen while it runs, so older claims are of no use.
+const claimRetention = 30 * 24 * time.Hour
+
+// TaskClaim records which worker took a task, for one kind of task: the
+// build, repo and ISO tasks of a pipeline share its id.
+type TaskClaim struct {
+	TaskID    string    `json:"task_id"`
+	Kind      string    `json:"kind"` // build, repo, iso
+	Worker    string    `json:"worker"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
+
+// UploadRejection records a worker request chief turned down.
+type UploadRejection struct {
+	TaskID     string    `json:"task_id"`
+	Kind       string    `json:"kind"`
+	Worker     string    `json:"worker"` // Empty when the request was not authenticated
+	RemoteAddr string    `json:"remote_addr"`
+	Reason     string    `json:"reason"`
+	RejectedAt time.Time `json:"rejected_at"`
+}
+
+// WorkerStore handles task claims and rejected worker requests in SQLite
+type WorkerStore struct {
+	db            *DB
+	maxRejections int
+}
+
+// NewWorkerStore creates a new worker store
+func NewWorkerStore(db *DB, maxRejections int) *WorkerStore {
+	if maxRejections <= 0 {
+		maxRejections = 500 // Default maximum rejections
+	}
+	return &WorkerStore{
+		db:            db,
+		maxRejections: maxRejections,
+	}
+}
+
+// ClaimTask records claim unless the task is already claimed, and returns
+// the claim that holds the task afterwards, which tells whether claim won.
+// The holder refreshes its claim by claiming again, and a claim made before
+// staleBefore is taken over: its worker is gone, and the task queue handed
+// the task to another one.
+func (s *WorkerStore) ClaimTask(claim TaskClaim, staleBefore time.Time) (*TaskClaim, error) {
+	query := `
+		INSERT INTO task_claims (task_id, kind, worker, claimed_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(task_id, kind) DO UPDATE SET
+			worker = excluded.worker,
+			claimed_at = excluded.claimed_at
+		WHERE task_claims.worker = excluded.worker OR task_claims.claimed_at < ?
+	`
+
+	_, err := s.db.Exec(query, claim.TaskID, claim.Kind, claim.Worker, claim.ClaimedAt, staleBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %w", err)
+	}
+
+	// Cleanup old claims
+	if _, err := s.db.Exec(`DELETE FROM task_claims WHERE claimed_at < ?`, claim.ClaimedAt.Add(-claimRetention)); err != nil {
+		// Log but don't fail
+		fmt.Printf("Warning: failed to cleanup old task claims: %v\n", err)
+	}
+
+	holder, err := s.GetTaskClaim(claim.TaskID, claim.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if holder == nil {
+		return nil, fmt.Errorf("task claim not found: %s", claim.TaskID)
+	}
+	return holder, nil
+}
+
+// GetTaskClaim retrieves the claim on a task, or nil when it is unclaimed
+func (s *WorkerStore) GetTaskClaim(taskID, kind string) (*TaskClaim, error) {
+	query := `
+		SELECT task_id, kind, worker, claimed_at
+		FROM task_claims
+		WHERE task_id = ? AND kind = ?
+	`
+
+	rows, err := s.db.Query(query, taskID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task claim: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var claim TaskClaim
+	if err := rows.Scan(&claim.TaskID, &claim.Kind, &claim.Worker, &claim.ClaimedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan task claim: %w", err)
+	}
+	return &claim, nil
+}
+
+// RecordRejection stores a rejected worker request
+func (s *WorkerStore) RecordRejection(r UploadRejection) error {
+	query := `
+		INSERT INTO upload_rejections (task_id, kind, worker, remote_addr, reason, rejected_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, r.TaskID, r.Kind, r.Worker, r.RemoteAddr, r.Reason, r.RejectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record rejection: %w", err)
+	}
+
+	// Cleanup old rejections if exceeding max
+	if err := s.cleanupOldRejections(); err != nil {
+		// Log but don't fail
+		fmt.Printf("Warning: failed to cleanup old rejections: %v\n", err)
+	}
+
+	return nil
+}
+
+// GetRecentRejections retrieves the N most recent rejected worker requests
+func (s *WorkerStore) GetRecentRejections(limit int) ([]*UploadRejection, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := `
+		SELECT task_id, kind, worker, remote_addr, reason, rejected_at
+		FROM upload_rejections
+		ORDER BY rejected_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rejections: %w", err)
+	}
+	defer rows.Close()
+
+	var rejections []*UploadRejection
+	for rows.Next() {
+		var r UploadRejection
+		if err := rows.Scan(&r.TaskID, &r.Kind, &r.Worker, &r.RemoteAddr, &r.Reason, &r.RejectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rejection: %w", err)
+		}
+		rejections = append(rejections, &r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rejections: %w", err)
+	}
+
+	return rejections, nil
+}
+
+// cleanupOldRejections removes old rejections exceeding the maximum count
+func (s *WorkerStore) cleanupOldRejections() error {
+	query := `
+		DELETE FROM upload_rejections
+		WHERE id NOT IN (
+			SELECT id FROM upload_rejections
+			ORDER BY rejected_at DESC, id DESC
+			LIMIT ?
+		)
+	`
+
+	_, err := s.db.Exec(query, s.maxRejections)
+	return err
+}
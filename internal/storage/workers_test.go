@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerStore_ClaimTask(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewWorkerStore(db, 100)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	claim, err := store.GetTaskClaim("task", "build")
+	require.NoError(t, err)
+	assert.Nil(t, claim)
+
+	staleBefore := now.Add(-time.Hour)
+	claim, err = store.ClaimTask(TaskClaim{TaskID: "task", Kind: "build", Worker: "builder-1", ClaimedAt: now}, staleBefore)
+	require.NoError(t, err)
+	assert.Equal(t, "builder-1", claim.Worker)
+
+	// The first claim holds
+	claim, err = store.ClaimTask(TaskClaim{TaskID: "task", Kind: "build", Worker: "builder-2", ClaimedAt: now.Add(time.Minute)}, staleBefore)
+	require.NoError(t, err)
+	assert.Equal(t, "builder-1", claim.Worker)
+	assert.True(t, now.Equal(claim.ClaimedAt))
+
+	// Its holder refreshes it by claiming again
+	claim, err = store.ClaimTask(TaskClaim{TaskID: "task", Kind: "build", Worker: "builder-1", ClaimedAt: now.Add(2 * time.Minute)}, staleBefore)
+	require.NoError(t, err)
+	assert.Equal(t, "builder-1", claim.Worker)
+	assert.True(t, now.Add(2*time.Minute).Equal(claim.ClaimedAt))
+
+	// Other kinds of task of the same pipeline are claimed separately
+	claim, err = store.ClaimTask(TaskClaim{TaskID: "task", Kind: "repo", Worker: "repo-1", ClaimedAt: now}, staleBefore)
+	require.NoError(t, err)
+	assert.Equal(t, "repo-1", claim.Worker)
+}
+
+func TestWorkerStore_ClaimTask_ExpiresOldClaims(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewWorkerStore(db, 100)
+	now := time.Now().UTC()
+
+	staleBefore := now.Add(-time.Hour)
+	_, err = store.ClaimTask(TaskClaim{TaskID: "old", Kind: "build", Worker: "builder-1", ClaimedAt: now.Add(-claimRetention - time.Hour)}, staleBefore)
+	require.NoError(t, err)
+	_, err = store.ClaimTask(TaskClaim{TaskID: "new", Kind: "build", Worker: "builder-1", ClaimedAt: now}, staleBefore)
+	require.NoError(t, err)
+
+	claim, err := store.GetTaskClaim("old", "build")
+	require.NoError(t, err)
+	assert.Nil(t, claim)
+}
+
+func TestWorkerStore_ClaimTask_TakesOverStaleClaims(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewWorkerStore(db, 100)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err = store.ClaimTask(TaskClaim{TaskID: "task", Kind: "build", Worker: "builder-1", ClaimedAt: now.Add(-5 * time.Hour)}, now.Add(-6*time.Hour))
+	require.NoError(t, err)
+
+	// builder-1 stopped working on the task, which was redelivered to builder-2
+	claim, err := store.ClaimTask(TaskClaim{TaskID: "task", Kind: "build", Worker: "builder-2", ClaimedAt: now}, now.Add(-4*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "builder-2", claim.Worker)
+	assert.True(t, now.Equal(claim.ClaimedAt))
+}
+
+func TestWorkerStore_Rejections(t *testing.T) {
+	db, err := NewDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	store := NewWorkerStore(db, 2)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, reason := range []string{"invalid worker token", "task not claimed", "task claimed by builder-1"} {
+		require.NoError(t, store.RecordRejection(UploadRejection{
+			TaskID:     "task",
+			Kind:       "build",
+			Worker:     "builder-2",
+			RemoteAddr: "10.0.0.2:51234",
+			Reason:     reason,
+			RejectedAt: now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	rejections, err := store.GetRecentRejections(10)
+	require.NoError(t, err)
+	require.Len(t, rejections, 2)
+	assert.Equal(t, "task claimed by builder-1", rejections[0].Reason)
+	assert.Equal(t, "task not claimed", rejections[1].Reason)
+	assert.Equal(t, "10.0.0.2:51234", rejections[0].RemoteAddr)
+	assert.Equal(t, "builder-2", rejections[0].Worker)
+}
@@ -1,6 +1,7 @@
 package systemutil
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,12 +10,22 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hpcloud/tail"
 )
 
+// cmdWaitDelay is how long a killed command gets to release its output.
+const cmdWaitDelay = 10 * time.Second
+
 // CmdExec run os command
 func CmdExec(cmdStr string, cmdDesc string, logPath string) (out string, err error) {
+	return CmdExecContext(context.Background(), cmdStr, cmdDesc, logPath)
+}
+
+// CmdExecContext runs an os command like CmdExec, killing it once ctx is
+// done.
+func CmdExecContext(ctx context.Context, cmdStr string, cmdDesc string, logPath string) (out string, err error) {
 	if len(cmdStr) == 0 {
 		return "", errors.New("No command string provided.")
 	}
@@ -42,7 +53,13 @@ func CmdExec(cmdStr string, cmdDesc string, logPath string) (out string, err err
 		cmdStr += " 2>&1 | tee -a " + logPath
 	}
 	// `set -o pipefail` will forces to return the original exit code
-	output, err := exec.Command("bash", "-c", "set -o pipefail && "+cmdStr).Output()
+	cmd := exec.CommandContext(ctx, "bash", "-c", "set -o pipefail && "+cmdStr)
+	if ctx.Done() != nil {
+		// Children of a killed shell may hold its output open, don't wait
+		// for them forever
+		cmd.WaitDelay = cmdWaitDelay
+	}
+	output, err := cmd.Output()
 	out = string(output)
 
 	return